@@ -5,35 +5,68 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/huseyinbabal/kubetag/internal/cloudevents"
 	"github.com/huseyinbabal/kubetag/internal/database"
 	"github.com/huseyinbabal/kubetag/internal/handler"
 	"github.com/huseyinbabal/kubetag/internal/k8s"
+	"github.com/huseyinbabal/kubetag/internal/notifier"
+	"github.com/huseyinbabal/kubetag/internal/registry"
 	"github.com/huseyinbabal/kubetag/internal/repository"
 	"github.com/huseyinbabal/kubetag/internal/service"
 )
 
 func main() {
+	// The migrate subcommand manages the schema and exits without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
 	// Setup context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Initialize database connection
+	// Initialize repository, either in memory or backed by a database
+	var imageRepo repository.ImageRepositoryInterface
+	var memoryRepo *repository.MemoryImageRepository
+
 	dbConfig := database.NewConfigFromEnv()
-	db, err := database.Connect(dbConfig)
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
+	snapshotPath := os.Getenv("MEMORY_SNAPSHOT_PATH")
+	if dbConfig.Driver == database.DriverMemory {
+		memoryRepo = repository.NewMemoryImageRepository()
+		if snapshotPath != "" {
+			if err := memoryRepo.LoadSnapshot(snapshotPath); err != nil {
+				log.Fatalf("Failed to restore snapshot: %v", err)
+			}
+			log.Printf("Restored in-memory snapshot from %s", snapshotPath)
+		}
+		imageRepo = memoryRepo
+		log.Println("Using in-memory storage")
+	} else {
+		db, err := database.Connect(dbConfig)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+
+		// Run migrations unless they are applied separately with the migrate subcommand
+		if os.Getenv("AUTO_MIGRATE") != "false" {
+			if err := database.Migrate(db); err != nil {
+				log.Fatalf("Failed to run migrations: %v", err)
+			}
+		}
 
-	// Run migrations
-	if err := database.Migrate(db); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+		imageRepo = repository.NewImageRepository(db)
 	}
 
 	// Initialize Kubernetes client
@@ -42,9 +75,6 @@ func main() {
 		log.Fatalf("Failed to create Kubernetes client: %v", err)
 	}
 
-	// Initialize repository
-	imageRepo := repository.NewImageRepository(db)
-
 	// Get namespace filter configuration from environment
 	// Supports: "*" for all namespaces (default), or comma-separated list like "default,kube-system"
 	namespacesConfig := os.Getenv("WATCH_NAMESPACES")
@@ -57,37 +87,170 @@ func main() {
 		namespaces = []string{"*"}
 		log.Println("Watching all namespaces")
 	} else {
-		// Split by comma and trim whitespace
-		for _, ns := range strings.Split(namespacesConfig, ",") {
-			trimmed := strings.TrimSpace(ns)
-			if trimmed != "" {
-				namespaces = append(namespaces, trimmed)
-			}
-		}
+		namespaces = splitList(namespacesConfig)
 		log.Printf("Watching namespaces: %v", namespaces)
 	}
 
+	// Names the cluster in the source of CloudEvents
+	cluster := os.Getenv("CLUSTER_NAME")
+	if cluster == "" {
+		cluster = cloudevents.DefaultCluster
+	}
+
 	// Initialize service layer
 	var imageService *service.ImageService
 
 	// Create informer manager with event handler
+	// Events are queued so slow database writes never block the informers
 	informerManager := k8s.NewInformerManager(k8sClient.GetClientset(), func(event k8s.ImageEvent) {
 		if imageService != nil {
-			imageService.HandleImageEvent(event)
+			imageService.EnqueueImageEvent(event)
 		}
 	}, namespaces)
 
 	// Create service with repository and informer
 	imageService = service.NewImageService(imageRepo, informerManager)
 
+	// Post image changes to the configured webhooks, registered before any event is processed
+	if path := os.Getenv("WEBHOOKS_CONFIG"); path != "" {
+		webhookConfig := notifier.DefaultConfig()
+		webhookConfig.Cluster = cluster
+		webhookConfig.Targets, err = notifier.LoadTargets(path)
+		if err != nil {
+			log.Fatalf("Failed to load webhooks: %v", err)
+		}
+		if value := os.Getenv("WEBHOOK_MAX_RETRIES"); value != "" {
+			webhookConfig.MaxRetries, err = strconv.Atoi(value)
+			if err != nil {
+				log.Fatalf("Invalid WEBHOOK_MAX_RETRIES: %v", err)
+			}
+		}
+		if value := os.Getenv("WEBHOOK_TIMEOUT"); value != "" {
+			webhookConfig.Timeout, err = time.ParseDuration(value)
+			if err != nil {
+				log.Fatalf("Invalid WEBHOOK_TIMEOUT: %v", err)
+			}
+		}
+
+		webhooks := notifier.New(webhookConfig, imageRepo)
+		webhooks.Start(ctx)
+		imageService.SetNotifier(webhooks)
+		log.Printf("Delivering image events to %d webhooks", len(webhookConfig.Targets))
+	}
+
+	// Start the event queue before the informers deliver their initial events
+	queueConfig := service.DefaultEventQueueConfig()
+	if value := os.Getenv("EVENT_QUEUE_MAX_DEPTH"); value != "" {
+		queueConfig.MaxDepth, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("Invalid EVENT_QUEUE_MAX_DEPTH: %v", err)
+		}
+	}
+	if value := os.Getenv("EVENT_QUEUE_MAX_RETRIES"); value != "" {
+		queueConfig.MaxRetries, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("Invalid EVENT_QUEUE_MAX_RETRIES: %v", err)
+		}
+	}
+	if value := os.Getenv("EVENT_QUEUE_BATCH_SIZE"); value != "" {
+		queueConfig.BatchSize, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("Invalid EVENT_QUEUE_BATCH_SIZE: %v", err)
+		}
+	}
+	imageService.StartEventQueue(ctx, queueConfig)
+
 	// Start informers
 	log.Println("Starting Kubernetes informers...")
 	if err := informerManager.Start(ctx); err != nil {
 		log.Fatalf("Failed to start informers: %v", err)
 	}
 
+	// Write the initial listing before the first reconciliation, or both would record it at once
+	drainCtx, cancelDrain := context.WithTimeout(ctx, 5*time.Minute)
+	if err := imageService.DrainEventQueue(drainCtx); err != nil {
+		log.Printf("Error waiting for the initial image events: %v", err)
+	}
+	cancelDrain()
+
+	// Close out rows that went stale while KubeTag was not running, then keep reconciling periodically
+	reconcileInterval := 10 * time.Minute
+	if value := os.Getenv("RECONCILE_INTERVAL"); value != "" {
+		reconcileInterval, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid RECONCILE_INTERVAL: %v", err)
+		}
+	}
+	imageService.StartReconciler(ctx, reconcileInterval)
+
+	// Prune closed out history rows according to the retention policy, disabled unless a limit is set
+	var retention service.RetentionPolicy
+	if value := os.Getenv("RETENTION_MAX_AGE"); value != "" {
+		retention.MaxAge, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid RETENTION_MAX_AGE: %v", err)
+		}
+	}
+	if value := os.Getenv("RETENTION_MAX_INACTIVE"); value != "" {
+		retention.MaxInactive, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("Invalid RETENTION_MAX_INACTIVE: %v", err)
+		}
+	}
+	if value := os.Getenv("RETENTION_KEEP_LAST"); value != "" {
+		retention.KeepLast, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("Invalid RETENTION_KEEP_LAST: %v", err)
+		}
+	}
+	if value := os.Getenv("RETENTION_DRY_RUN"); value != "" {
+		retention.DryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("Invalid RETENTION_DRY_RUN: %v", err)
+		}
+	}
+	pruneInterval := time.Hour
+	if value := os.Getenv("RETENTION_INTERVAL"); value != "" {
+		pruneInterval, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid RETENTION_INTERVAL: %v", err)
+		}
+	}
+	imageService.StartPruner(ctx, retention, pruneInterval)
+
+	// Check the images in use for newer versions in their registries, off unless an interval is set
+	var updateInterval time.Duration
+	if value := os.Getenv("REGISTRY_CHECK_INTERVAL"); value != "" {
+		updateInterval, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid REGISTRY_CHECK_INTERVAL: %v", err)
+		}
+	}
+	if updateInterval > 0 {
+		registryConfig := registry.Config{Keychain: make(registry.Keychain), Timeout: 30 * time.Second}
+		if path := os.Getenv("REGISTRY_AUTH_FILE"); path != "" {
+			credentials, err := registry.LoadDockerConfig(path)
+			if err != nil {
+				log.Fatalf("Failed to load registry credentials: %v", err)
+			}
+			registryConfig.Keychain.Merge(credentials)
+		}
+		if value := os.Getenv("REGISTRY_PULL_SECRETS"); value != "" {
+			credentials, err := registry.LoadPullSecrets(ctx, k8sClient.GetClientset(), splitList(value))
+			if err != nil {
+				log.Fatalf("Failed to load registry pull secrets: %v", err)
+			}
+			registryConfig.Keychain.Merge(credentials)
+		}
+		registryConfig.PlainHTTP = splitList(os.Getenv("REGISTRY_PLAIN_HTTP"))
+
+		imageService.SetTagLister(registry.NewClient(registryConfig))
+		imageService.StartUpdateChecker(ctx, updateInterval)
+	}
+
 	// Initialize handlers
 	imageHandler := handler.NewImageHandler(imageService)
+	imageHandler.SetCluster(cluster)
 	metricsHandler := handler.NewMetricsHandler(imageService)
 
 	// Create Fiber app
@@ -115,7 +278,14 @@ func main() {
 	// API routes
 	api := app.Group("/api")
 	api.Get("/images", imageHandler.GetImages)
+	api.Get("/images/outdated", imageHandler.GetOutdatedImages)
 	api.Get("/images/:name/history", imageHandler.GetImageHistory)
+	api.Get("/events", imageHandler.GetEvents)
+	api.Get("/stream", imageHandler.StreamEvents)
+	api.Get("/webhooks/deliveries", imageHandler.GetWebhookDeliveries)
+	api.Get("/search", imageHandler.Search)
+	api.Get("/diff", imageHandler.DiffImages)
+	api.Get("/resources/:namespace/:type/:name/timeline", imageHandler.GetResourceTimeline)
 	api.Get("/health", imageHandler.HealthCheck)
 
 	// Get port from environment or use default
@@ -133,6 +303,9 @@ func main() {
 		log.Println("Shutting down gracefully...")
 		cancel() // Cancel context to stop informers
 
+		// Open event streams would otherwise keep the server from shutting down
+		imageService.CloseEventStreams()
+
 		if err := app.Shutdown(); err != nil {
 			log.Printf("Error during shutdown: %v", err)
 		}
@@ -142,4 +315,24 @@ func main() {
 	if err := app.Listen(":" + port); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
+
+	// Keep the in-memory inventory for the next start
+	if memoryRepo != nil && snapshotPath != "" {
+		if err := memoryRepo.SaveSnapshot(snapshotPath); err != nil {
+			log.Printf("Failed to save snapshot: %v", err)
+		} else {
+			log.Printf("Saved in-memory snapshot to %s", snapshotPath)
+		}
+	}
+}
+
+// splitList splits a comma-separated environment variable, dropping blank entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
 }
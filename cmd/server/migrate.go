@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/database"
+)
+
+const migrateUsage = `usage: kubetag migrate [command]
+
+Commands:
+  up          apply every pending migration (default)
+  status      list migrations and when they were applied
+  down [N]    roll back the latest N applied migrations (default 1)`
+
+// runMigrate handles the migrate subcommand against the database configured in the environment
+func runMigrate(args []string, out io.Writer) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	steps := 1
+	switch command {
+	case "up", "status":
+		if len(args) > 1 {
+			return fmt.Errorf("unexpected arguments: %v\n%s", args[1:], migrateUsage)
+		}
+	case "down":
+		if len(args) > 2 {
+			return fmt.Errorf("unexpected arguments: %v\n%s", args[2:], migrateUsage)
+		}
+		if len(args) == 2 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations to roll back: %s", args[1])
+			}
+		}
+	default:
+		return fmt.Errorf("unknown migrate command: %s\n%s", command, migrateUsage)
+	}
+
+	dbConfig := database.NewConfigFromEnv()
+	if dbConfig.Driver == database.DriverMemory {
+		return fmt.Errorf("the %s driver has no schema to migrate", database.DriverMemory)
+	}
+
+	db, err := database.Connect(dbConfig)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	switch command {
+	case "up":
+		return database.Migrate(db)
+	case "down":
+		return database.Rollback(db, steps)
+	default:
+		status, err := database.Status(db)
+		if err != nil {
+			return err
+		}
+		return printMigrationStatus(out, status)
+	}
+}
+
+// printMigrationStatus writes one row per known migration
+func printMigrationStatus(out io.Writer, status []database.MigrationStatus) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+
+	for _, s := range status {
+		appliedAt := "pending"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,134 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+// SpecVersion is the CloudEvents specification version events are encoded with
+const SpecVersion = "1.0"
+
+// Event types, one per image event type
+const (
+	TypeImageAdded   = "io.kubetag.image.added"   // ADD
+	TypeImageUpdated = "io.kubetag.image.updated" // UPDATE
+	TypeImageRemoved = "io.kubetag.image.removed" // DELETE, and UPDATE removing a container
+)
+
+// Content types of the HTTP protocol binding
+const (
+	ContentTypeStructured = "application/cloudevents+json"
+	ContentTypeBatch      = "application/cloudevents-batch+json"
+	ContentTypeData       = "application/json"
+)
+
+// DefaultCluster names the cluster in the event source when none is configured
+const DefaultCluster = "default"
+
+// Event is a CloudEvent carrying an image event log entry as its data
+type Event struct {
+	SpecVersion     string            `json:"specversion"`
+	ID              string            `json:"id"`
+	Source          string            `json:"source"`
+	Type            string            `json:"type"`
+	Subject         string            `json:"subject,omitempty"`
+	Time            time.Time         `json:"time"`
+	DataContentType string            `json:"datacontenttype"`
+	Data            models.ImageEvent `json:"data"`
+}
+
+// FromImageEvent encodes an image event log entry observed in cluster as a CloudEvent
+// The id is the event log ID, which is unique per source as long as the cluster has one KubeTag
+func FromImageEvent(cluster string, event models.ImageEvent) Event {
+	return Event{
+		SpecVersion:     SpecVersion,
+		ID:              strconv.FormatUint(uint64(event.ID), 10),
+		Source:          Source(cluster, event),
+		Type:            Type(event.Type, event.Change),
+		Subject:         Subject(event),
+		Time:            event.ObservedAt,
+		DataContentType: ContentTypeData,
+		Data:            event,
+	}
+}
+
+// FromImageEvents encodes image event log entries observed in cluster as CloudEvents
+func FromImageEvents(cluster string, events []models.ImageEvent) []Event {
+	encoded := make([]Event, 0, len(events))
+	for _, event := range events {
+		encoded = append(encoded, FromImageEvent(cluster, event))
+	}
+	return encoded
+}
+
+// Type returns the CloudEvents type of an image event type and, for UPDATE events, its change
+// An UPDATE that removed a container took its image away like a DELETE
+func Type(eventType, change string) string {
+	switch {
+	case eventType == "ADD":
+		return TypeImageAdded
+	case eventType == "DELETE", eventType == "UPDATE" && change == "REMOVED":
+		return TypeImageRemoved
+	default:
+		return TypeImageUpdated
+	}
+}
+
+// Source identifies the resource an event happened in,
+// e.g. /clusters/prod/namespaces/default/Deployment/web
+func Source(cluster string, event models.ImageEvent) string {
+	if cluster == "" {
+		cluster = DefaultCluster
+	}
+
+	return fmt.Sprintf("/clusters/%s/namespaces/%s/%s/%s",
+		url.PathEscape(cluster), url.PathEscape(event.Namespace),
+		url.PathEscape(event.ResourceType), url.PathEscape(event.ResourceName))
+}
+
+// Subject returns the image reference an event is about, e.g. docker.io/nginx:1.25
+// It is the image after the change, or the one that went away when there is none
+func Subject(event models.ImageEvent) string {
+	if event.ImageName != "" {
+		return event.NewReference()
+	}
+	return event.OldReference()
+}
+
+// EncodeStructured encodes the event for the structured content mode,
+// the whole event in a body of type ContentTypeStructured
+func (e Event) EncodeStructured() ([]byte, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cloud event: %w", err)
+	}
+	return body, nil
+}
+
+// EncodeBinary encodes the event for the binary content mode,
+// the attributes as ce- headers and only the data in the body
+func (e Event) EncodeBinary() (http.Header, []byte, error) {
+	body, err := json.Marshal(e.Data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode cloud event data: %w", err)
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", e.DataContentType)
+	header.Set("ce-specversion", e.SpecVersion)
+	header.Set("ce-id", e.ID)
+	header.Set("ce-source", e.Source)
+	header.Set("ce-type", e.Type)
+	header.Set("ce-time", e.Time.UTC().Format(time.RFC3339Nano))
+	if e.Subject != "" {
+		header.Set("ce-subject", e.Subject)
+	}
+
+	return header, body, nil
+}
@@ -0,0 +1,175 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+var observedAt = time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+var updateEvent = models.ImageEvent{
+	ID:            42,
+	Type:          "UPDATE",
+	Change:        "CHANGED",
+	ResourceType:  "Deployment",
+	ResourceName:  "web",
+	Namespace:     "default",
+	ContainerName: "nginx",
+	ImageName:     "nginx",
+	Repository:    "docker.io",
+	NewTag:        "1.25",
+	OldImageName:  "nginx",
+	OldRepository: "docker.io",
+	OldTag:        "1.24",
+	ObservedAt:    observedAt,
+}
+
+func TestFromImageEvent(t *testing.T) {
+	event := FromImageEvent("prod", updateEvent)
+
+	if event.SpecVersion != "1.0" || event.ID != "42" || event.DataContentType != "application/json" {
+		t.Errorf("Expected specversion 1.0, id 42 and JSON data, got %+v", event)
+	}
+	if event.Source != "/clusters/prod/namespaces/default/Deployment/web" {
+		t.Errorf("Expected the resource as source, got %s", event.Source)
+	}
+	if event.Type != TypeImageUpdated || event.Subject != "docker.io/nginx:1.25" {
+		t.Errorf("Expected an updated event about docker.io/nginx:1.25, got %s about %s", event.Type, event.Subject)
+	}
+	if !event.Time.Equal(observedAt) || event.Data.OldTag != "1.24" {
+		t.Errorf("Expected the observation time and the image event as data, got %+v", event)
+	}
+}
+
+func TestType(t *testing.T) {
+	tests := []struct {
+		eventType, change string
+		expected          string
+	}{
+		{eventType: "ADD", expected: "io.kubetag.image.added"},
+		{eventType: "UPDATE", change: "CHANGED", expected: "io.kubetag.image.updated"},
+		{eventType: "UPDATE", change: "ADDED", expected: "io.kubetag.image.updated"},
+		{eventType: "UPDATE", change: "REMOVED", expected: "io.kubetag.image.removed"},
+		{eventType: "DELETE", expected: "io.kubetag.image.removed"},
+	}
+
+	for _, tt := range tests {
+		if result := Type(tt.eventType, tt.change); result != tt.expected {
+			t.Errorf("Expected %s for %s %s, got %s", tt.expected, tt.eventType, tt.change, result)
+		}
+	}
+}
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		cluster  string
+		event    models.ImageEvent
+		expected string
+	}{
+		{name: "resource in cluster", cluster: "prod", event: updateEvent, expected: "/clusters/prod/namespaces/default/Deployment/web"},
+		{name: "default cluster", cluster: "", event: updateEvent, expected: "/clusters/default/namespaces/default/Deployment/web"},
+		{name: "escapes path segments", cluster: "eu west", event: models.ImageEvent{Namespace: "a/b", ResourceType: "Pod", ResourceName: "web"}, expected: "/clusters/eu%20west/namespaces/a%2Fb/Pod/web"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if source := Source(tt.cluster, tt.event); source != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, source)
+			}
+		})
+	}
+}
+
+func TestSubject(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    models.ImageEvent
+		expected string
+	}{
+		{name: "new image", event: updateEvent, expected: "docker.io/nginx:1.25"},
+		{name: "new image pinned by digest", event: models.ImageEvent{ImageName: "app", Repository: "gcr.io/team", NewTag: "v1", NewDigest: "sha256:abc"}, expected: "gcr.io/team/app:v1@sha256:abc"},
+		{name: "digest only", event: models.ImageEvent{ImageName: "app", Repository: "gcr.io/team", NewDigest: "sha256:abc"}, expected: "gcr.io/team/app@sha256:abc"},
+		{name: "removed image", event: models.ImageEvent{Type: "DELETE", OldImageName: "redis", OldRepository: "docker.io", OldTag: "7"}, expected: "docker.io/redis:7"},
+		{name: "no image", event: models.ImageEvent{}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if subject := Subject(tt.event); subject != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, subject)
+			}
+		})
+	}
+}
+
+func TestEncodeStructured(t *testing.T) {
+	body, err := FromImageEvent("prod", updateEvent).EncodeStructured()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("Failed to parse event: %v", err)
+	}
+
+	expected := map[string]string{
+		"specversion":     "1.0",
+		"id":              "42",
+		"source":          "/clusters/prod/namespaces/default/Deployment/web",
+		"type":            "io.kubetag.image.updated",
+		"subject":         "docker.io/nginx:1.25",
+		"time":            "2024-01-02T00:00:00Z",
+		"datacontenttype": "application/json",
+	}
+	for attribute, value := range expected {
+		if decoded[attribute] != value {
+			t.Errorf("Expected %s %q, got %v", attribute, value, decoded[attribute])
+		}
+	}
+
+	data, ok := decoded["data"].(map[string]any)
+	if !ok || data["new_tag"] != "1.25" || data["old_tag"] != "1.24" {
+		t.Errorf("Expected the image event as data, got %v", decoded["data"])
+	}
+}
+
+func TestEncodeBinary(t *testing.T) {
+	header, body, err := FromImageEvent("prod", updateEvent).EncodeBinary()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := map[string]string{
+		"Content-Type":   "application/json",
+		"ce-specversion": "1.0",
+		"ce-id":          "42",
+		"ce-source":      "/clusters/prod/namespaces/default/Deployment/web",
+		"ce-type":        "io.kubetag.image.updated",
+		"ce-subject":     "docker.io/nginx:1.25",
+		"ce-time":        "2024-01-02T00:00:00Z",
+	}
+	for key, value := range expected {
+		if header.Get(key) != value {
+			t.Errorf("Expected header %s %q, got %q", key, value, header.Get(key))
+		}
+	}
+
+	var data models.ImageEvent
+	if err := json.Unmarshal(body, &data); err != nil {
+		t.Fatalf("Failed to parse data: %v", err)
+	}
+	if data.ID != 42 || data.NewTag != "1.25" {
+		t.Errorf("Expected only the image event in the body, got %s", body)
+	}
+
+	// Without an image there is no subject to send
+	header, _, _ = FromImageEvent("prod", models.ImageEvent{ID: 1, Type: "DELETE"}).EncodeBinary()
+	if _, found := header["Ce-Subject"]; found {
+		t.Error("Expected no subject header without an image")
+	}
+}
@@ -6,12 +6,21 @@ import (
 	"os"
 	"time"
 
-	"github.com/huseyinbabal/kubetag/internal/models"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// Supported storage drivers
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+
+	// DriverMemory keeps everything in repository.MemoryImageRepository without opening a database
+	DriverMemory = "memory"
+)
+
 // Config holds database configuration
 type Config struct {
 	Host     string
@@ -20,6 +29,9 @@ type Config struct {
 	Password string
 	DBName   string
 	SSLMode  string
+
+	Driver string // postgres (default), sqlite or memory, which Connect leaves to the caller
+	Path   string // SQLite database file, only used by the sqlite driver
 }
 
 // NewConfigFromEnv creates database config from environment variables
@@ -31,17 +43,31 @@ func NewConfigFromEnv() *Config {
 		Password: getEnv("DB_PASSWORD", "postgres"),
 		DBName:   getEnv("DB_NAME", "kubetag"),
 		SSLMode:  getEnv("DB_SSLMODE", "disable"),
+
+		Driver: getEnv("DB_DRIVER", DriverPostgres),
+		Path:   getEnv("DB_PATH", "kubetag.db"),
 	}
 }
 
-// Connect establishes a connection to the PostgreSQL database
+// Connect establishes a connection to the configured database
 func Connect(config *Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		config.Host, config.Port, config.User, config.Password, config.DBName, config.SSLMode,
-	)
+	var dialector gorm.Dialector
+
+	switch config.Driver {
+	case "", DriverPostgres:
+		dsn := fmt.Sprintf(
+			"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+			config.Host, config.Port, config.User, config.Password, config.DBName, config.SSLMode,
+		)
+		dialector = postgres.Open(dsn)
+	case DriverSQLite:
+		// WAL lets the API read while the event queue writes; the busy timeout covers the rest
+		dialector = sqlite.Open(config.Path + "?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=on")
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", config.Driver)
+	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 		NowFunc: func() time.Time {
 			return time.Now().UTC()
@@ -58,37 +84,19 @@ func Connect(config *Config) (*gorm.DB, error) {
 	}
 
 	// Connection pool settings
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	// SQLite allows a single writer, so writes are serialized on one connection
+	if config.Driver == DriverSQLite {
+		sqlDB.SetMaxOpenConns(1)
+	} else {
+		sqlDB.SetMaxIdleConns(10)
+		sqlDB.SetMaxOpenConns(100)
+		sqlDB.SetConnMaxLifetime(time.Hour)
+	}
 
 	log.Println("Database connection established")
 	return db, nil
 }
 
-// Migrate runs database migrations
-func Migrate(db *gorm.DB) error {
-	log.Println("Running database migrations...")
-
-	// Drop old index if it exists before running AutoMigrate
-	db.Exec("DROP INDEX IF EXISTS idx_image_tag_unique")
-
-	err := db.AutoMigrate(
-		&models.Image{},
-		&models.ImageTag{},
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
-	}
-
-	// GORM will automatically create the idx_image_tag_resource unique index
-	// based on the struct tags in models.ImageTag
-
-	log.Println("Database migrations completed successfully")
-	return nil
-}
-
 // getEnv gets environment variable with a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
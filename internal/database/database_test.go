@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -25,6 +26,8 @@ func TestNewConfigFromEnv(t *testing.T) {
 		"DB_PASSWORD": os.Getenv("DB_PASSWORD"),
 		"DB_NAME":     os.Getenv("DB_NAME"),
 		"DB_SSLMODE":  os.Getenv("DB_SSLMODE"),
+		"DB_DRIVER":   os.Getenv("DB_DRIVER"),
+		"DB_PATH":     os.Getenv("DB_PATH"),
 	}
 
 	// Restore env vars after test
@@ -61,6 +64,12 @@ func TestNewConfigFromEnv(t *testing.T) {
 		if config.SSLMode != "disable" {
 			t.Errorf("Expected SSLMode to be 'disable', got '%s'", config.SSLMode)
 		}
+		if config.Driver != DriverPostgres {
+			t.Errorf("Expected Driver to be '%s', got '%s'", DriverPostgres, config.Driver)
+		}
+		if config.Path != "kubetag.db" {
+			t.Errorf("Expected Path to be 'kubetag.db', got '%s'", config.Path)
+		}
 	})
 
 	t.Run("Custom values from environment variables", func(t *testing.T) {
@@ -70,6 +79,8 @@ func TestNewConfigFromEnv(t *testing.T) {
 		os.Setenv("DB_PASSWORD", "custompass")
 		os.Setenv("DB_NAME", "customdb")
 		os.Setenv("DB_SSLMODE", "require")
+		os.Setenv("DB_DRIVER", "sqlite")
+		os.Setenv("DB_PATH", "/data/kubetag.db")
 
 		config := NewConfigFromEnv()
 
@@ -91,6 +102,12 @@ func TestNewConfigFromEnv(t *testing.T) {
 		if config.SSLMode != "require" {
 			t.Errorf("Expected SSLMode to be 'require', got '%s'", config.SSLMode)
 		}
+		if config.Driver != DriverSQLite {
+			t.Errorf("Expected Driver to be '%s', got '%s'", DriverSQLite, config.Driver)
+		}
+		if config.Path != "/data/kubetag.db" {
+			t.Errorf("Expected Path to be '/data/kubetag.db', got '%s'", config.Path)
+		}
 	})
 }
 
@@ -375,6 +392,36 @@ func TestMigrate(t *testing.T) {
 			t.Errorf("Failed to insert test image tag: %v", err)
 		}
 	})
+
+	t.Run("Concurrent replicas migrate once", func(t *testing.T) {
+		if err := Rollback(db, len(migrations)); err != nil {
+			t.Fatalf("Rollback failed: %v", err)
+		}
+
+		// Each replica has its own pool, only the advisory lock keeps them apart
+		errs := make(chan error, 3)
+		for i := 0; i < 3; i++ {
+			go func() {
+				replica, err := Connect(config)
+				if err != nil {
+					errs <- err
+					return
+				}
+				errs <- Migrate(replica)
+			}()
+		}
+		for i := 0; i < 3; i++ {
+			if err := <-errs; err != nil {
+				t.Errorf("Expected concurrent migrations to succeed, got error: %v", err)
+			}
+		}
+
+		var applied int64
+		db.Model(&SchemaMigration{}).Count(&applied)
+		if applied != int64(len(migrations)) {
+			t.Errorf("Expected %d applied migrations, got %d", len(migrations), applied)
+		}
+	})
 }
 
 // isDockerAvailable checks if Docker is available
@@ -846,6 +893,48 @@ func TestConnectWithSQLiteDriver(t *testing.T) {
 	})
 }
 
+func TestConnectSQLiteFile(t *testing.T) {
+	t.Run("Connect opens and migrates a SQLite file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "kubetag.db")
+
+		db, err := Connect(&Config{Driver: DriverSQLite, Path: path})
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+
+		sqlDB, err := db.DB()
+		if err != nil {
+			t.Fatalf("Failed to get SQL DB: %v", err)
+		}
+		defer sqlDB.Close()
+
+		if err := sqlDB.Ping(); err != nil {
+			t.Errorf("Failed to ping database: %v", err)
+		}
+		if got := sqlDB.Stats().MaxOpenConnections; got != 1 {
+			t.Errorf("Expected 1 max open connection, got %d", got)
+		}
+
+		if err := Migrate(db); err != nil {
+			t.Fatalf("Failed to run migrations: %v", err)
+		}
+		if !db.Migrator().HasTable(&models.ImageTag{}) {
+			t.Error("Expected image_tags table to exist")
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected database file at %s: %v", path, err)
+		}
+	})
+
+	t.Run("Connect rejects unknown drivers", func(t *testing.T) {
+		_, err := Connect(&Config{Driver: "mysql"})
+		if err == nil {
+			t.Error("Expected error for unsupported driver, got nil")
+		}
+	})
+}
+
 func TestMigrateDropsOldIndex(t *testing.T) {
 	t.Run("Migrate drops old index before creating new schema", func(t *testing.T) {
 		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
@@ -877,6 +966,45 @@ func TestMigrateDropsOldIndex(t *testing.T) {
 	})
 }
 
+func TestMigrateRebuildsResourceIndexWithDigest(t *testing.T) {
+	t.Run("Migrate recreates idx_image_tag_resource including digest", func(t *testing.T) {
+		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create in-memory database: %v", err)
+		}
+
+		// Simulate the schema before digests were tracked
+		db.Exec(`CREATE TABLE image_tags (
+			id INTEGER PRIMARY KEY, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME,
+			image_id INTEGER NOT NULL, tag TEXT NOT NULL, first_seen DATETIME NOT NULL, last_seen DATETIME NOT NULL,
+			resource_type TEXT NOT NULL, resource_name TEXT NOT NULL, namespace TEXT NOT NULL, container_name TEXT NOT NULL)`)
+		db.Exec("CREATE UNIQUE INDEX idx_image_tag_resource ON image_tags(image_id, tag, resource_type, resource_name, namespace, container_name)")
+
+		err = Migrate(db)
+		if err != nil {
+			t.Fatalf("Migrate failed: %v", err)
+		}
+
+		if !db.Migrator().HasColumn(&models.ImageTag{}, "Digest") {
+			t.Fatal("Expected digest column to be added")
+		}
+
+		// Same tag with two digests must be allowed by the rebuilt index
+		now := time.Now().UTC()
+		for _, digest := range []string{"sha256:aaa", "sha256:bbb"} {
+			err = db.Create(&models.ImageTag{
+				ImageID: 1, Tag: "v1", Digest: digest, FirstSeen: now, LastSeen: now,
+				ResourceType: "Deployment", ResourceName: "app", Namespace: "default", ContainerName: "app",
+			}).Error
+			if err != nil {
+				t.Fatalf("Failed to insert tag with digest %s: %v", digest, err)
+			}
+		}
+	})
+}
+
 func TestConfigStructure(t *testing.T) {
 	t.Run("Config struct has all required fields", func(t *testing.T) {
 		config := &Config{
@@ -0,0 +1,247 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one versioned schema change
+// Up and Down run inside a transaction together with the schema_migrations bookkeeping
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration records an applied migration in the schema_migrations table
+type SchemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName overrides the table name
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus reports whether a known migration has been applied
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time // nil while pending
+}
+
+// migrationLockID is the PostgreSQL advisory lock key held while migrating, "kubetag" in ASCII
+const migrationLockID = 0x6b756265746167
+
+// Migrate applies every pending migration in version order
+func Migrate(db *gorm.DB) error {
+	log.Println("Running database migrations...")
+
+	applied, err := migrateUp(db, migrations)
+	if err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	log.Printf("Database migrations completed successfully, %d applied", applied)
+	return nil
+}
+
+// Rollback reverts the latest steps applied migrations, newest first
+func Rollback(db *gorm.DB, steps int) error {
+	if err := migrateDown(db, migrations, steps); err != nil {
+		return fmt.Errorf("failed to roll back migrations: %w", err)
+	}
+
+	return nil
+}
+
+// Status lists every known migration and when it was applied
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration status: %w", err)
+	}
+
+	var status []MigrationStatus
+	for _, m := range migrations {
+		s := MigrationStatus{Version: m.Version, Name: m.Name}
+		if record, found := applied[m.Version]; found {
+			appliedAt := record.AppliedAt
+			s.AppliedAt = &appliedAt
+		}
+		status = append(status, s)
+	}
+
+	return status, nil
+}
+
+// migrateUp applies the pending migrations of list and returns how many ran
+func migrateUp(db *gorm.DB, list []Migration) (int, error) {
+	if err := validateMigrations(list); err != nil {
+		return 0, err
+	}
+
+	unlock, err := lockMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	// Read the applied versions only after locking, another replica may have just migrated
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	known := make(map[int64]bool)
+	for _, m := range list {
+		known[m.Version] = true
+	}
+	for version := range applied {
+		if !known[version] {
+			log.Printf("Database has migration %d applied which this version does not know about", version)
+		}
+	}
+
+	count := 0
+	for _, m := range list {
+		if _, found := applied[m.Version]; found {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+
+		log.Printf("Applied migration %d_%s", m.Version, m.Name)
+		count++
+	}
+
+	return count, nil
+}
+
+// migrateDown reverts the latest steps applied migrations of list
+func migrateDown(db *gorm.DB, list []Migration, steps int) error {
+	if err := validateMigrations(list); err != nil {
+		return err
+	}
+
+	unlock, err := lockMigrations(db)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	for i := len(list) - 1; i >= 0 && steps > 0; i-- {
+		m := list[i]
+		if _, found := applied[m.Version]; !found {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("rollback of migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+
+		log.Printf("Rolled back migration %d_%s", m.Version, m.Name)
+		steps--
+	}
+
+	return nil
+}
+
+// validateMigrations checks that list is in strictly increasing version order and fully defined
+func validateMigrations(list []Migration) error {
+	if !sort.SliceIsSorted(list, func(i, j int) bool { return list[i].Version < list[j].Version }) {
+		return fmt.Errorf("migrations are not ordered by version")
+	}
+
+	for i, m := range list {
+		if i > 0 && list[i-1].Version == m.Version {
+			return fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+		if m.Up == nil || m.Down == nil {
+			return fmt.Errorf("migration %d_%s must define Up and Down", m.Version, m.Name)
+		}
+	}
+
+	return nil
+}
+
+// appliedMigrations returns the schema_migrations rows keyed by version, none before the first migration
+func appliedMigrations(db *gorm.DB) (map[int64]SchemaMigration, error) {
+	applied := make(map[int64]SchemaMigration)
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return applied, nil
+	}
+
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+
+	return applied, nil
+}
+
+// lockMigrations keeps other replicas from migrating at the same time and returns the unlock function
+// PostgreSQL uses a session advisory lock on a dedicated connection; SQLite serializes writers itself
+func lockMigrations(db *gorm.DB) (func(), error) {
+	if db.Dialector.Name() != DriverPostgres {
+		return func() {}, nil
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
+	}
+
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migration lock connection: %w", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	return func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			log.Printf("Failed to release migration lock: %v", err)
+		}
+		conn.Close()
+	}, nil
+}
@@ -0,0 +1,329 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openMigrateTestDB opens an empty in-memory SQLite database
+func openMigrateTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return db
+}
+
+// createTableMigration creates and drops a single table
+func createTableMigration(version int64, table string) Migration {
+	return Migration{
+		Version: version,
+		Name:    "create_" + table,
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("CREATE TABLE " + table + " (id INTEGER PRIMARY KEY)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE " + table).Error
+		},
+	}
+}
+
+func TestMigrateRecordsVersions(t *testing.T) {
+	db := openMigrateTestDB(t)
+
+	status, err := Status(db)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	for _, s := range status {
+		if s.AppliedAt != nil {
+			t.Errorf("Expected migration %d to be pending, got applied at %v", s.Version, s.AppliedAt)
+		}
+	}
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		t.Error("Expected Status not to create schema_migrations")
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	status, err = Status(db)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(status) != len(migrations) {
+		t.Fatalf("Expected %d migrations, got %d", len(migrations), len(status))
+	}
+	for _, s := range status {
+		if s.AppliedAt == nil {
+			t.Errorf("Expected migration %d_%s to be applied", s.Version, s.Name)
+		}
+	}
+
+	t.Run("rollback of the baseline drops the schema", func(t *testing.T) {
+		if err := Rollback(db, len(migrations)); err != nil {
+			t.Fatalf("Rollback failed: %v", err)
+		}
+		if db.Migrator().HasTable(&models.ImageTag{}) {
+			t.Error("Expected image_tags table to be dropped")
+		}
+
+		if err := Migrate(db); err != nil {
+			t.Fatalf("Migrate after rollback failed: %v", err)
+		}
+		if !db.Migrator().HasTable(&models.ImageTag{}) {
+			t.Error("Expected image_tags table to be recreated")
+		}
+	})
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	db := openMigrateTestDB(t)
+	list := []Migration{
+		createTableMigration(1, "first"),
+		createTableMigration(2, "second"),
+		createTableMigration(3, "third"),
+	}
+
+	applied, err := migrateUp(db, list)
+	if err != nil {
+		t.Fatalf("migrateUp failed: %v", err)
+	}
+	if applied != 3 {
+		t.Errorf("Expected 3 migrations applied, got %d", applied)
+	}
+
+	applied, err = migrateUp(db, list)
+	if err != nil {
+		t.Fatalf("Second migrateUp failed: %v", err)
+	}
+	if applied != 0 {
+		t.Errorf("Expected no migrations on second run, got %d", applied)
+	}
+
+	if err := migrateDown(db, list, 2); err != nil {
+		t.Fatalf("migrateDown failed: %v", err)
+	}
+	for table, expected := range map[string]bool{"first": true, "second": false, "third": false} {
+		if db.Migrator().HasTable(table) != expected {
+			t.Errorf("Expected table %s to exist: %t", table, expected)
+		}
+	}
+
+	var versions []int64
+	db.Model(&SchemaMigration{}).Order("version").Pluck("version", &versions)
+	if len(versions) != 1 || versions[0] != 1 {
+		t.Errorf("Expected only version 1 to be recorded, got %v", versions)
+	}
+
+	applied, err = migrateUp(db, list)
+	if err != nil {
+		t.Fatalf("migrateUp after rollback failed: %v", err)
+	}
+	if applied != 2 {
+		t.Errorf("Expected 2 migrations re-applied, got %d", applied)
+	}
+}
+
+func TestMigrateFailureRollsBack(t *testing.T) {
+	db := openMigrateTestDB(t)
+	list := []Migration{
+		createTableMigration(1, "first"),
+		{
+			Version: 2,
+			Name:    "broken",
+			Up: func(tx *gorm.DB) error {
+				if err := tx.Exec("CREATE TABLE second (id INTEGER PRIMARY KEY)").Error; err != nil {
+					return err
+				}
+				return errors.New("boom")
+			},
+			Down: func(tx *gorm.DB) error { return nil },
+		},
+		createTableMigration(3, "third"),
+	}
+
+	applied, err := migrateUp(db, list)
+	if err == nil {
+		t.Fatal("Expected error from broken migration, got nil")
+	}
+	if applied != 1 {
+		t.Errorf("Expected 1 migration applied before the failure, got %d", applied)
+	}
+
+	if db.Migrator().HasTable("second") {
+		t.Error("Expected the failed migration to be rolled back")
+	}
+	if db.Migrator().HasTable("third") {
+		t.Error("Expected migrations after the failure not to run")
+	}
+
+	var count int64
+	db.Model(&SchemaMigration{}).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 recorded migration, got %d", count)
+	}
+}
+
+func TestValidateMigrations(t *testing.T) {
+	noop := func(tx *gorm.DB) error { return nil }
+
+	tests := []struct {
+		name    string
+		list    []Migration
+		wantErr bool
+	}{
+		{
+			name:    "registered migrations are valid",
+			list:    migrations,
+			wantErr: false,
+		},
+		{
+			name:    "out of order",
+			list:    []Migration{createTableMigration(2, "b"), createTableMigration(1, "a")},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate version",
+			list:    []Migration{createTableMigration(1, "a"), createTableMigration(1, "b")},
+			wantErr: true,
+		},
+		{
+			name:    "missing down",
+			list:    []Migration{{Version: 1, Name: "a", Up: noop}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMigrations(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error: %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestMigrateImageTagIntervals(t *testing.T) {
+	db := openMigrateTestDB(t)
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	now := time.Now().UTC()
+	interval := func(removed bool) error {
+		row := models.ImageTag{
+			ImageID: 1, Tag: "v1", FirstSeen: now, LastSeen: now,
+			ResourceType: "Deployment", ResourceName: "app", Namespace: "default", ContainerName: "app",
+		}
+		if removed {
+			row.RemovedAt = &now
+			row.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		}
+		return db.Create(&row).Error
+	}
+
+	// Closed out intervals of the same tag live next to the active one
+	for _, removed := range []bool{true, true, false} {
+		if err := interval(removed); err != nil {
+			t.Fatalf("Failed to insert interval: %v", err)
+		}
+	}
+	if err := interval(false); err == nil {
+		t.Error("Expected a second active row of the same tag to be rejected")
+	}
+
+	t.Run("rollback keeps the newest interval", func(t *testing.T) {
+		// Roll back image_tag_intervals and every later migration
+		if err := Rollback(db, len(migrations)-5); err != nil {
+			t.Fatalf("Rollback failed: %v", err)
+		}
+
+		var count int64
+		db.Unscoped().Model(&models.ImageTag{}).Count(&count)
+		if count != 1 {
+			t.Errorf("Expected 1 image tag row, got %d", count)
+		}
+		if err := interval(true); err == nil {
+			t.Error("Expected the full unique index to reject another interval")
+		}
+	})
+}
+
+func TestMigrateDockerHubLibrary(t *testing.T) {
+	db := openMigrateTestDB(t)
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if err := Rollback(db, 1); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	// nginx was recorded as docker.io/nginx and, from a library/nginx reference, as docker.io/library/nginx
+	now := time.Now().UTC()
+	images := []models.Image{
+		{Name: "nginx", Repository: "docker.io", FullName: "docker.io/nginx"},
+		{Name: "redis", Repository: "docker.io", FullName: "docker.io/redis"},
+		{Name: "nginx", Repository: "docker.io/library", FullName: "docker.io/library/nginx"},
+		{Name: "app", Repository: "ghcr.io/acme", FullName: "ghcr.io/acme/app"},
+	}
+	if err := db.Create(&images).Error; err != nil {
+		t.Fatalf("Failed to insert images: %v", err)
+	}
+	for i, image := range images {
+		row := models.ImageTag{
+			ImageID: image.ID, Tag: "v1", FirstSeen: now, LastSeen: now,
+			ResourceType: "Deployment", ResourceName: "app", Namespace: "default", ContainerName: fmt.Sprint(i),
+		}
+		if err := db.Create(&row).Error; err != nil {
+			t.Fatalf("Failed to insert image tag: %v", err)
+		}
+	}
+	running := models.RunningImage{
+		ImageName: "redis", Repository: "docker.io", Tag: "v1", ResourceType: "Deployment", ResourceName: "app",
+		Namespace: "default", PodName: "app-1", ContainerName: "1", Digest: "sha256:aaa", LastSeen: now,
+	}
+	if err := db.Create(&running).Error; err != nil {
+		t.Fatalf("Failed to insert running image: %v", err)
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	var fullNames []string
+	db.Model(&models.Image{}).Order("full_name").Pluck("full_name", &fullNames)
+	expected := []string{"docker.io/library/nginx", "docker.io/library/redis", "ghcr.io/acme/app"}
+	if !reflect.DeepEqual(fullNames, expected) {
+		t.Errorf("Expected images %v, got %v", expected, fullNames)
+	}
+
+	var nginxTags int64
+	db.Model(&models.ImageTag{}).Where("image_id = ?", images[2].ID).Count(&nginxTags)
+	if nginxTags != 2 {
+		t.Errorf("Expected both nginx tags under docker.io/library/nginx, got %d", nginxTags)
+	}
+
+	var repository string
+	db.Model(&models.RunningImage{}).Where("id = ?", running.ID).Pluck("repository", &repository)
+	if repository != "docker.io/library" {
+		t.Errorf("Expected the running image in docker.io/library, got %s", repository)
+	}
+}
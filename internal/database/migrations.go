@@ -0,0 +1,379 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrations lists every schema change in version order
+// Applied migrations must never be edited, add a new one instead
+// Each migration declares its own copy of the tables it touches so later model changes do not alter it
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		Up:      baselineUp,
+		Down:    baselineDown,
+	},
+	{
+		Version: 2,
+		Name:    "image_events",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&imageEventsV2{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&imageEventsV2{})
+		},
+	},
+	{
+		Version: 3,
+		Name:    "search_indexes",
+		Up:      searchIndexesUp,
+		Down:    searchIndexesDown,
+	},
+	{
+		Version: 4,
+		Name:    "webhook_deliveries",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&webhookDeliveriesV4{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&webhookDeliveriesV4{})
+		},
+	},
+	{
+		Version: 5,
+		Name:    "image_updates",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&imageUpdatesV5{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&imageUpdatesV5{})
+		},
+	},
+	{
+		Version: 6,
+		Name:    "image_tag_intervals",
+		Up:      imageTagIntervalsUp,
+		Down:    imageTagIntervalsDown,
+	},
+	{
+		Version: 7,
+		Name:    "docker_hub_library",
+		Up: func(tx *gorm.DB) error {
+			return moveRepository(tx, "docker.io", "docker.io/library")
+		},
+		Down: func(tx *gorm.DB) error {
+			return moveRepository(tx, "docker.io/library", "docker.io")
+		},
+	},
+}
+
+// baselineImage is the images table as of the baseline migration
+type baselineImage struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	Name       string `gorm:"index;not null"`
+	Repository string `gorm:"index;not null"`
+	FullName   string `gorm:"uniqueIndex;not null"`
+
+	ImageTags []baselineImageTag `gorm:"foreignKey:ImageID"`
+}
+
+// TableName overrides the table name
+func (baselineImage) TableName() string {
+	return "images"
+}
+
+// baselineImageTag is the image_tags table as of the baseline migration
+type baselineImageTag struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	ImageID uint          `gorm:"uniqueIndex:idx_image_tag_resource;not null"`
+	Image   baselineImage `gorm:"constraint:OnDelete:CASCADE;"`
+
+	Tag           string    `gorm:"uniqueIndex:idx_image_tag_resource;not null"`
+	FirstSeen     time.Time `gorm:"not null"`
+	LastSeen      time.Time `gorm:"not null"`
+	ResourceType  string    `gorm:"uniqueIndex:idx_image_tag_resource;not null"`
+	ResourceName  string    `gorm:"uniqueIndex:idx_image_tag_resource;not null"`
+	Namespace     string    `gorm:"uniqueIndex:idx_image_tag_resource;not null"`
+	ContainerName string    `gorm:"uniqueIndex:idx_image_tag_resource;not null"`
+
+	Digest    string     `gorm:"uniqueIndex:idx_image_tag_resource;not null;default:''"`
+	RemovedAt *time.Time `gorm:"index"`
+}
+
+// TableName overrides the table name
+func (baselineImageTag) TableName() string {
+	return "image_tags"
+}
+
+// baselineRunningImage is the running_images table as of the baseline migration
+type baselineRunningImage struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	ImageName  string `gorm:"not null"`
+	Repository string `gorm:"not null"`
+	Tag        string `gorm:"not null"`
+
+	ResourceType  string `gorm:"index:idx_running_image_resource;not null"`
+	ResourceName  string `gorm:"index:idx_running_image_resource;not null"`
+	Namespace     string `gorm:"index:idx_running_image_resource;uniqueIndex:idx_running_image_pod;not null"`
+	PodName       string `gorm:"uniqueIndex:idx_running_image_pod;not null"`
+	ContainerName string `gorm:"uniqueIndex:idx_running_image_pod;not null"`
+
+	Digest   string    `gorm:"not null"`
+	LastSeen time.Time `gorm:"not null"`
+}
+
+// TableName overrides the table name
+func (baselineRunningImage) TableName() string {
+	return "running_images"
+}
+
+// baselineUp creates the schema, or brings a database created by the former AutoMigrate setup up to it
+func baselineUp(tx *gorm.DB) error {
+	// Unique index replaced by idx_image_tag_resource
+	if err := tx.Exec("DROP INDEX IF EXISTS idx_image_tag_unique").Error; err != nil {
+		return err
+	}
+
+	// The digest column joined idx_image_tag_resource; AutoMigrate does not alter
+	// an existing index, so drop it to have it recreated with the new column
+	if tx.Migrator().HasTable(&baselineImageTag{}) && !tx.Migrator().HasColumn(&baselineImageTag{}, "Digest") {
+		if err := tx.Exec("DROP INDEX IF EXISTS idx_image_tag_resource").Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.AutoMigrate(&baselineImage{}, &baselineImageTag{}, &baselineRunningImage{})
+}
+
+// baselineDown drops every table of the baseline schema
+func baselineDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&baselineRunningImage{}, &baselineImageTag{}, &baselineImage{})
+}
+
+// imageEventsV2 is the append-only image_events table
+type imageEventsV2 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+
+	Type   string `gorm:"not null"`
+	Change string `gorm:"not null;default:''"`
+
+	ResourceType  string `gorm:"index:idx_image_event_resource;not null"`
+	ResourceName  string `gorm:"index:idx_image_event_resource;not null"`
+	Namespace     string `gorm:"index:idx_image_event_resource;not null"`
+	ContainerName string `gorm:"not null"`
+
+	ImageName  string `gorm:"index;not null;default:''"`
+	Repository string `gorm:"not null;default:''"`
+	NewTag     string `gorm:"not null;default:''"`
+	NewDigest  string `gorm:"not null;default:''"`
+
+	OldImageName  string `gorm:"index;not null;default:''"`
+	OldRepository string `gorm:"not null;default:''"`
+	OldTag        string `gorm:"not null;default:''"`
+	OldDigest     string `gorm:"not null;default:''"`
+
+	ObservedAt time.Time `gorm:"index;not null"`
+}
+
+// TableName overrides the table name
+func (imageEventsV2) TableName() string {
+	return "image_events"
+}
+
+// searchIndexes are the trigram indexes GET /api/search matches against, by index name
+var searchIndexes = []struct {
+	name, table, column string
+}{
+	{"idx_images_name_trgm", "images", "name"},
+	{"idx_images_repository_trgm", "images", "repository"},
+	{"idx_image_tags_tag_trgm", "image_tags", "tag"},
+	{"idx_image_tags_resource_name_trgm", "image_tags", "resource_name"},
+	{"idx_image_tags_namespace_trgm", "image_tags", "namespace"},
+	{"idx_image_tags_container_name_trgm", "image_tags", "container_name"},
+}
+
+// searchIndexesUp creates GIN trigram indexes serving ILIKE and similarity matches on PostgreSQL
+// SQLite has no trigram indexes, search falls back to scanning there
+func searchIndexesUp(tx *gorm.DB) error {
+	if tx.Dialector.Name() != DriverPostgres {
+		return nil
+	}
+
+	if err := tx.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		return err
+	}
+
+	for _, index := range searchIndexes {
+		err := tx.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING gin (%s gin_trgm_ops)",
+			index.name, index.table, index.column)).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// searchIndexesDown drops the trigram indexes, the pg_trgm extension is left installed
+func searchIndexesDown(tx *gorm.DB) error {
+	if tx.Dialector.Name() != DriverPostgres {
+		return nil
+	}
+
+	for _, index := range searchIndexes {
+		if err := tx.Exec("DROP INDEX IF EXISTS " + index.name).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// webhookDeliveriesV4 is the webhook_deliveries table, one row per delivery attempt
+type webhookDeliveriesV4 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+
+	Target    string `gorm:"index;not null"`
+	URL       string `gorm:"not null"`
+	EventID   uint   `gorm:"index;not null"`
+	EventType string `gorm:"not null"`
+	Attempt   int    `gorm:"not null"`
+
+	Success    bool   `gorm:"not null"`
+	StatusCode int    `gorm:"not null;default:0"`
+	Error      string `gorm:"not null;default:''"`
+	DurationMs int64  `gorm:"not null"`
+}
+
+// TableName overrides the table name
+func (webhookDeliveriesV4) TableName() string {
+	return "webhook_deliveries"
+}
+
+// imageUpdatesV5 is the image_updates table, the newest registry versions per image tag in use
+type imageUpdatesV5 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	ImageName  string `gorm:"uniqueIndex:idx_image_update;not null"`
+	Repository string `gorm:"uniqueIndex:idx_image_update;not null"`
+	Tag        string `gorm:"uniqueIndex:idx_image_update;not null"`
+
+	LatestCompatible string `gorm:"not null;default:''"`
+	Latest           string `gorm:"not null;default:''"`
+	Outdated         bool   `gorm:"index;not null"`
+
+	CheckedAt time.Time `gorm:"not null"`
+	Error     string    `gorm:"not null;default:''"`
+}
+
+// TableName overrides the table name
+func (imageUpdatesV5) TableName() string {
+	return "image_updates"
+}
+
+// imageTagResourceColumns are the columns of idx_image_tag_resource
+const imageTagResourceColumns = "image_id, tag, digest, resource_type, resource_name, namespace, container_name"
+
+// imageTagIntervalsUp limits idx_image_tag_resource to active rows, so a tag coming back
+// after a rollback gets a new row and the interval it ran before is kept
+func imageTagIntervalsUp(tx *gorm.DB) error {
+	if err := tx.Exec("DROP INDEX IF EXISTS idx_image_tag_resource").Error; err != nil {
+		return err
+	}
+
+	return tx.Exec("CREATE UNIQUE INDEX idx_image_tag_resource ON image_tags (" + imageTagResourceColumns +
+		") WHERE deleted_at IS NULL AND removed_at IS NULL").Error
+}
+
+// imageTagIntervalsDown makes idx_image_tag_resource cover every row again
+// Only the newest row of each container and tag is kept, earlier intervals are deleted
+func imageTagIntervalsDown(tx *gorm.DB) error {
+	err := tx.Exec("DELETE FROM image_tags WHERE id NOT IN (SELECT MAX(id) FROM image_tags GROUP BY " +
+		imageTagResourceColumns + ")").Error
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Exec("DROP INDEX IF EXISTS idx_image_tag_resource").Error; err != nil {
+		return err
+	}
+
+	return tx.Exec("CREATE UNIQUE INDEX idx_image_tag_resource ON image_tags (" + imageTagResourceColumns + ")").Error
+}
+
+// moveRepository moves the images of one repository to another, e.g. official Docker Hub images
+// from docker.io, where references like nginx used to be recorded, to docker.io/library.
+// Tags of an image recorded under both are merged into the one of the target repository.
+func moveRepository(tx *gorm.DB, from, to string) error {
+	var images []baselineImage
+	if err := tx.Unscoped().Where("repository = ?", from).Find(&images).Error; err != nil {
+		return err
+	}
+
+	for _, image := range images {
+		fullName := to + "/" + image.Name
+
+		var existing []baselineImage
+		if err := tx.Unscoped().Where("full_name = ?", fullName).Find(&existing).Error; err != nil {
+			return err
+		}
+
+		if len(existing) == 0 {
+			err := tx.Exec("UPDATE images SET repository = ?, full_name = ? WHERE id = ?", to, fullName, image.ID).Error
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// An active row recorded under both images is kept once, as the one of the target image
+		err := tx.Exec("DELETE FROM image_tags WHERE image_id = ? AND deleted_at IS NULL AND removed_at IS NULL AND EXISTS ("+
+			"SELECT 1 FROM image_tags target WHERE target.image_id = ? AND target.tag = image_tags.tag AND "+
+			"target.digest = image_tags.digest AND target.resource_type = image_tags.resource_type AND "+
+			"target.resource_name = image_tags.resource_name AND target.namespace = image_tags.namespace AND "+
+			"target.container_name = image_tags.container_name AND target.deleted_at IS NULL AND target.removed_at IS NULL)",
+			image.ID, existing[0].ID).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE image_tags SET image_id = ? WHERE image_id = ?", existing[0].ID, image.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM images WHERE id = ?", image.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	statements := []string{
+		"UPDATE running_images SET repository = ? WHERE repository = ?",
+		"UPDATE image_events SET repository = ? WHERE repository = ?",
+		"UPDATE image_events SET old_repository = ? WHERE old_repository = ?",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement, to, from).Error; err != nil {
+			return err
+		}
+	}
+
+	// Registry checks are cached per repository, the next check records them again
+	return tx.Exec("DELETE FROM image_updates WHERE repository = ?", from).Error
+}
@@ -1,13 +1,25 @@
 package handler
 
 import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/huseyinbabal/kubetag/internal/cloudevents"
+	"github.com/huseyinbabal/kubetag/internal/models"
 	"github.com/huseyinbabal/kubetag/internal/service"
 )
 
 // ImageHandler handles HTTP requests for images
 type ImageHandler struct {
 	service service.ImageServiceInterface
+	cluster string // Names the cluster in the source of CloudEvents
 }
 
 // NewImageHandler creates a new image handler
@@ -17,11 +29,56 @@ func NewImageHandler(service service.ImageServiceInterface) *ImageHandler {
 	}
 }
 
+// SetCluster sets the cluster name CloudEvents are sourced from
+func (h *ImageHandler) SetCluster(cluster string) {
+	h.cluster = cluster
+}
+
+// imageQueryParams are the GET /api/images parameters that filter, sort or page the inventory
+var imageQueryParams = []string{
+	"repository", "name", "namePrefix", "tag", "resourceType", "container", "sort", "order", "limit", "offset",
+}
+
+// maxImagesLimit caps the page size of GET /api/images
+const maxImagesLimit = 1000
+
 // GetImages handles GET /api/images
+// An at query parameter returns the inventory as it was at that instant
+// Filter, sort and page parameters return a page of the current inventory
 func (h *ImageHandler) GetImages(c *fiber.Ctx) error {
 	namespace := c.Query("namespace", "")
 
-	images, err := h.service.GetImages(c.Context(), namespace)
+	at, err := timeQuery(c, "at")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	paged := false
+	for _, param := range imageQueryParams {
+		paged = paged || c.Query(param) != ""
+	}
+
+	var images *models.ImagesResponse
+	switch {
+	case at != nil && paged:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "at can only be combined with namespace",
+		})
+	case at != nil:
+		images, err = h.service.GetImagesAt(c.Context(), namespace, *at)
+	case paged:
+		query, queryErr := imageQuery(c)
+		if queryErr != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": queryErr.Error(),
+			})
+		}
+		images, err = h.service.QueryImages(c.Context(), query)
+	default:
+		images, err = h.service.GetImages(c.Context(), namespace)
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -31,9 +88,75 @@ func (h *ImageHandler) GetImages(c *fiber.Ctx) error {
 	return c.JSON(images)
 }
 
+// imageQuery reads and validates the filter, sort and page parameters of GET /api/images
+func imageQuery(c *fiber.Ctx) (models.ImageQuery, error) {
+	query := models.ImageQuery{
+		Namespace:    c.Query("namespace", ""),
+		Repository:   c.Query("repository", ""),
+		Name:         c.Query("name", ""),
+		NamePrefix:   c.Query("namePrefix", ""),
+		Tag:          c.Query("tag", ""),
+		ResourceType: c.Query("resourceType", ""),
+		Container:    c.Query("container", ""),
+		Sort:         c.Query("sort", models.ImageSortName),
+	}
+
+	switch query.Sort {
+	case models.ImageSortName, models.ImageSortTag, models.ImageSortNamespace, models.ImageSortFirstSeen, models.ImageSortLastSeen:
+	default:
+		return query, fmt.Errorf("sort must be one of name, tag, namespace, first_seen, last_seen")
+	}
+
+	switch c.Query("order", "asc") {
+	case "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, fmt.Errorf("order must be asc or desc")
+	}
+
+	var err error
+	if query.Limit, err = intQuery(c, "limit"); err != nil {
+		return query, err
+	}
+	if c.Query("limit") != "" && (query.Limit < 1 || query.Limit > maxImagesLimit) {
+		return query, fmt.Errorf("limit must be between 1 and %d", maxImagesLimit)
+	}
+
+	if query.Offset, err = intQuery(c, "offset"); err != nil {
+		return query, err
+	}
+	if query.Offset < 0 {
+		return query, fmt.Errorf("offset must not be negative")
+	}
+
+	return query, nil
+}
+
+// intQuery parses an optional integer query parameter, zero when missing
+func intQuery(c *fiber.Ctx, param string) (int, error) {
+	value := c.Query(param, "")
+	if value == "" {
+		return 0, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", param)
+	}
+
+	return parsed, nil
+}
+
 // GetImageHistory handles GET /api/images/:name/history
+// name is a bare image name or a URL encoded full reference, e.g. ghcr.io%2Fbitnami%2Fredis
 func (h *ImageHandler) GetImageHistory(c *fiber.Ctx) error {
-	imageName := c.Params("name")
+	imageName, err := url.PathUnescape(c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid image reference",
+		})
+	}
 	namespace := c.Query("namespace", "")
 
 	if imageName == "" {
@@ -52,6 +175,385 @@ func (h *ImageHandler) GetImageHistory(c *fiber.Ctx) error {
 	return c.JSON(history)
 }
 
+// maxEventsLimit caps the number of image events returned by one request
+const maxEventsLimit = 1000
+
+// GetEvents handles GET /api/events
+// format=cloudevents, or accepting a CloudEvents batch, returns the events as a CloudEvents batch
+func (h *ImageHandler) GetEvents(c *fiber.Ctx) error {
+	filter := models.ImageEventFilter{
+		Namespace:    c.Query("namespace", ""),
+		ImageName:    c.Query("image", ""),
+		ResourceType: c.Query("resourceType", ""),
+		ResourceName: c.Query("resourceName", ""),
+		Limit:        c.QueryInt("limit", 100),
+	}
+
+	if filter.Limit < 1 || filter.Limit > maxEventsLimit {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("limit must be between 1 and %d", maxEventsLimit),
+		})
+	}
+
+	var err error
+	if filter.Since, err = timeQuery(c, "since"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if filter.Until, err = timeQuery(c, "until"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	format := c.Query("format", "json")
+	if strings.Contains(c.Get(fiber.HeaderAccept), cloudevents.ContentTypeBatch) {
+		format = "cloudevents"
+	}
+	if format != "json" && format != "cloudevents" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("unsupported format %s, must be json or cloudevents", format),
+		})
+	}
+
+	events, err := h.service.GetImageEvents(c.Context(), filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// A CloudEvents batch is a bare array of structured events
+	if format == "cloudevents" {
+		return c.JSON(cloudevents.FromImageEvents(h.cluster, events.Events), cloudevents.ContentTypeBatch)
+	}
+
+	return c.JSON(events)
+}
+
+// GetOutdatedImages handles GET /api/images/outdated
+func (h *ImageHandler) GetOutdatedImages(c *fiber.Ctx) error {
+	namespace := c.Query("namespace", "")
+	compatibleOnly := c.QueryBool("compatible", false)
+
+	images, err := h.service.GetOutdatedImages(c.Context(), namespace, compatibleOnly)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(images)
+}
+
+// maxSearchLimit caps the number of search results returned by one request
+const maxSearchLimit = 200
+
+// Search handles GET /api/search
+func (h *ImageHandler) Search(c *fiber.Ctx) error {
+	query := models.SearchQuery{
+		Text:      strings.TrimSpace(c.Query("q", "")),
+		Namespace: c.Query("namespace", ""),
+		Limit:     c.QueryInt("limit", 50),
+	}
+
+	if query.Text == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "q is required",
+		})
+	}
+	if query.Limit < 1 || query.Limit > maxSearchLimit {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit),
+		})
+	}
+
+	results, err := h.service.Search(c.Context(), query)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(results)
+}
+
+// GetWebhookDeliveries handles GET /api/webhooks/deliveries
+func (h *ImageHandler) GetWebhookDeliveries(c *fiber.Ctx) error {
+	filter := models.WebhookDeliveryFilter{
+		Target: c.Query("target", ""),
+		Failed: c.QueryBool("failed", false),
+		Limit:  c.QueryInt("limit", 100),
+	}
+
+	if filter.Limit < 1 || filter.Limit > maxEventsLimit {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("limit must be between 1 and %d", maxEventsLimit),
+		})
+	}
+
+	eventID, err := intQuery(c, "eventId")
+	if err != nil || eventID < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("invalid eventId: %s", c.Query("eventId")),
+		})
+	}
+	filter.EventID = uint(eventID)
+
+	deliveries, err := h.service.GetWebhookDeliveries(c.Context(), filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(deliveries)
+}
+
+// maxStreamReplay caps the missed events replayed to a resuming stream client
+const maxStreamReplay = 1000
+
+// streamKeepAlive is how often an idle stream sends a comment, so proxies keep it open and gone clients are noticed
+var streamKeepAlive = 15 * time.Second
+
+// StreamEvents handles GET /api/stream
+// Image events are pushed as Server-Sent Events once persisted; a client resuming with Last-Event-ID,
+// or the lastEventId query parameter, first receives the events it missed from the event log
+func (h *ImageHandler) StreamEvents(c *fiber.Ctx) error {
+	filter := models.ImageEventFilter{
+		Namespace: c.Query("namespace", ""),
+		ImageName: c.Query("image", ""),
+	}
+
+	if lastEventID := c.Get("Last-Event-ID", c.Query("lastEventId")); lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("invalid last event id: %s", lastEventID),
+			})
+		}
+		filter.AfterID = uint(id)
+	}
+
+	// Subscribe before reading the log so no event falls between the replay and live delivery
+	events, unsubscribe := h.service.SubscribeImageEvents(filter)
+
+	var missed []models.ImageEvent
+	if filter.AfterID > 0 {
+		replay := filter
+		replay.Limit = maxStreamReplay + 1
+
+		response, err := h.service.GetImageEvents(c.Context(), replay)
+		if err != nil {
+			unsubscribe()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		missed = response.Events
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+		streamImageEvents(w, missed, events)
+	})
+
+	return nil
+}
+
+// streamImageEvents writes the missed events, oldest first, then live events until the client or the stream goes away
+// missed is newest first, as the event log returns it
+func streamImageEvents(w *bufio.Writer, missed []models.ImageEvent, events <-chan models.ImageEvent) {
+	// The client missed more than is replayed, so it has to reload instead of applying the gap
+	if len(missed) > maxStreamReplay {
+		missed = missed[:maxStreamReplay]
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+
+	sort.Slice(missed, func(i, j int) bool { return missed[i].ID < missed[j].ID })
+
+	var lastID uint
+	for _, event := range missed {
+		if err := writeImageEvent(w, event); err != nil {
+			return
+		}
+		lastID = event.ID
+	}
+
+	// Opens the stream on the client even when nothing was replayed
+	fmt.Fprint(w, ": connected\n\n")
+	if err := w.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			// Persisted while the log was read, so it was replayed already
+			if event.ID <= lastID {
+				continue
+			}
+			if err := writeImageEvent(w, event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeImageEvent writes one image event as a Server-Sent Event carrying its log ID
+func writeImageEvent(w *bufio.Writer, event models.ImageEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode image event: %w", err)
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", event.ID, data)
+	return err
+}
+
+// GetResourceTimeline handles GET /api/resources/:namespace/:type/:name/timeline
+func (h *ImageHandler) GetResourceTimeline(c *fiber.Ctx) error {
+	namespace := c.Params("namespace")
+	resourceType := c.Params("type")
+	resourceName := c.Params("name")
+
+	timeline, err := h.service.GetResourceTimeline(c.Context(), namespace, resourceType, resourceName)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if len(timeline.Entries) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": fmt.Sprintf("no image history found for %s %s/%s", resourceType, namespace, resourceName),
+		})
+	}
+
+	return c.JSON(timeline)
+}
+
+// DiffImages handles GET /api/diff
+// Compares two points in time with from and to, or any two inventories with left and right selectors
+func (h *ImageHandler) DiffImages(c *fiber.Ctx) error {
+	left, right, err := diffSelectors(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	diff, err := h.service.DiffImages(c.Context(), left, right)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(diff)
+}
+
+// diffSelectors reads the two sides of a diff from the query
+// from and to share the namespace parameter; to defaults to the current inventory
+func diffSelectors(c *fiber.Ctx) (models.InventorySelector, models.InventorySelector, error) {
+	var left, right models.InventorySelector
+
+	if c.Query("left") != "" || c.Query("right") != "" {
+		if c.Query("from") != "" || c.Query("to") != "" {
+			return left, right, fmt.Errorf("use either from and to, or left and right")
+		}
+
+		var err error
+		if left, err = parseInventorySelector(c.Query("left")); err != nil {
+			return left, right, fmt.Errorf("invalid left selector: %w", err)
+		}
+		if right, err = parseInventorySelector(c.Query("right")); err != nil {
+			return left, right, fmt.Errorf("invalid right selector: %w", err)
+		}
+
+		return left, right, nil
+	}
+
+	from, err := timeQuery(c, "from")
+	if err != nil {
+		return left, right, err
+	}
+	if from == nil {
+		return left, right, fmt.Errorf("from and to, or left and right are required")
+	}
+
+	to, err := timeQuery(c, "to")
+	if err != nil {
+		return left, right, err
+	}
+
+	namespace := c.Query("namespace", "")
+	left = models.InventorySelector{Namespace: namespace, At: from}
+	right = models.InventorySelector{Namespace: namespace, At: to}
+
+	return left, right, nil
+}
+
+// parseInventorySelector parses a selector like ns=staging or ns=production,at=2024-01-02T15:04:05Z
+func parseInventorySelector(value string) (models.InventorySelector, error) {
+	var selector models.InventorySelector
+
+	if value == "" {
+		return selector, fmt.Errorf("selector is required")
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		key, val, found := strings.Cut(part, "=")
+		if !found || val == "" {
+			return selector, fmt.Errorf("expected key=value, got %q", part)
+		}
+
+		switch key {
+		case "ns", "namespace":
+			selector.Namespace = val
+		case "at":
+			at, err := time.Parse(time.RFC3339, val)
+			if err != nil {
+				return selector, fmt.Errorf("at must be an RFC3339 timestamp")
+			}
+			selector.At = &at
+		default:
+			return selector, fmt.Errorf("unknown key %q", key)
+		}
+	}
+
+	return selector, nil
+}
+
+// timeQuery parses an optional RFC3339 query parameter
+func timeQuery(c *fiber.Ctx, param string) (*time.Time, error) {
+	value := c.Query(param, "")
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC3339 timestamp", param)
+	}
+
+	return &parsed, nil
+}
+
 // HealthCheck handles GET /health
 func (h *ImageHandler) HealthCheck(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
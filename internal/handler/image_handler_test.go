@@ -5,10 +5,13 @@ import (
 	"errors"
 	"io"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/huseyinbabal/kubetag/internal/cloudevents"
 	"github.com/huseyinbabal/kubetag/internal/mocks"
 	"github.com/huseyinbabal/kubetag/internal/models"
 	"github.com/stretchr/testify/mock"
@@ -231,6 +234,175 @@ func TestGetImages(t *testing.T) {
 	}
 }
 
+func TestQueryImages(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expected       models.ImageQuery
+		expectedStatus int
+	}{
+		{
+			name:           "page with defaults",
+			query:          "?limit=50",
+			expected:       models.ImageQuery{Sort: models.ImageSortName, Limit: 50},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:  "filters and sort",
+			query: "?namespace=default&repository=ghcr.io/bitnami&name=red&namePrefix=r&tag=7.0&resourceType=StatefulSet&container=redis&sort=last_seen&order=desc&limit=10&offset=20",
+			expected: models.ImageQuery{
+				Namespace: "default", Repository: "ghcr.io/bitnami", Name: "red", NamePrefix: "r", Tag: "7.0",
+				ResourceType: "StatefulSet", Container: "redis", Sort: models.ImageSortLastSeen, Descending: true,
+				Limit: 10, Offset: 20,
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "filter without a page",
+			query:          "?tag=latest",
+			expected:       models.ImageQuery{Tag: "latest", Sort: models.ImageSortName},
+			expectedStatus: fiber.StatusOK,
+		},
+		{name: "unsupported sort", query: "?sort=size", expectedStatus: fiber.StatusBadRequest},
+		{name: "invalid order", query: "?sort=tag&order=up", expectedStatus: fiber.StatusBadRequest},
+		{name: "limit too large", query: "?limit=5000", expectedStatus: fiber.StatusBadRequest},
+		{name: "zero limit", query: "?limit=0", expectedStatus: fiber.StatusBadRequest},
+		{name: "limit not a number", query: "?limit=ten", expectedStatus: fiber.StatusBadRequest},
+		{name: "negative offset", query: "?offset=-1", expectedStatus: fiber.StatusBadRequest},
+		{name: "combined with at", query: "?at=2024-01-02T14:32:00Z&limit=10", expectedStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := mocks.NewMockImageService(t)
+
+			if tt.expectedStatus == fiber.StatusOK {
+				mockSvc.EXPECT().
+					QueryImages(mock.Anything, tt.expected).
+					Return(&models.ImagesResponse{
+						Images: []models.ImageInfo{{Name: "redis", Tag: "7.0"}},
+						Total:  42,
+						Limit:  tt.expected.Limit,
+						Offset: tt.expected.Offset,
+					}, nil).
+					Once()
+			}
+
+			handler := NewImageHandler(mockSvc)
+			app := fiber.New()
+			app.Get("/api/images", handler.GetImages)
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/api/images"+tt.query, nil))
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+
+			if tt.expectedStatus == fiber.StatusOK {
+				var response models.ImagesResponse
+				body, _ := io.ReadAll(resp.Body)
+				if err := json.Unmarshal(body, &response); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if response.Total != 42 || len(response.Images) != 1 {
+					t.Errorf("Expected 1 image of 42, got %d of %d", len(response.Images), response.Total)
+				}
+			}
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetImagesAt(t *testing.T) {
+	at := time.Date(2024, 1, 2, 14, 32, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		mockCall       bool
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "inventory at an instant",
+			query:          "?at=2024-01-02T14:32:00Z",
+			mockCall:       true,
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "inventory at an instant with namespace filter",
+			query:          "?at=2024-01-02T16:32:00%2B02:00&namespace=default",
+			mockCall:       true,
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "service returns error",
+			query:          "?at=2024-01-02T14:32:00Z",
+			mockCall:       true,
+			mockError:      errors.New("database connection failed"),
+			expectedStatus: fiber.StatusInternalServerError,
+		},
+		{
+			name:           "invalid timestamp",
+			query:          "?at=yesterday",
+			expectedStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := mocks.NewMockImageService(t)
+
+			if tt.mockCall {
+				var response *models.ImagesResponse
+				if tt.mockError == nil {
+					response = &models.ImagesResponse{
+						Images: []models.ImageInfo{{Name: "nginx", Tag: "1.25", Namespace: "default"}},
+						Total:  1,
+					}
+				}
+
+				mockSvc.EXPECT().
+					GetImagesAt(mock.Anything, mock.AnythingOfType("string"), mock.MatchedBy(func(got time.Time) bool {
+						return got.Equal(at)
+					})).
+					Return(response, tt.mockError).
+					Once()
+			}
+
+			handler := NewImageHandler(mockSvc)
+			app := fiber.New()
+			app.Get("/api/images", handler.GetImages)
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/api/images"+tt.query, nil))
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+
+			if tt.expectedStatus == fiber.StatusOK {
+				var response models.ImagesResponse
+				body, _ := io.ReadAll(resp.Body)
+				if err := json.Unmarshal(body, &response); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if response.Total != 1 || response.Images[0].Tag != "1.25" {
+					t.Errorf("Expected nginx:1.25, got %+v", response)
+				}
+			}
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
 func TestGetImageHistory(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -396,3 +568,785 @@ func TestGetImageHistory(t *testing.T) {
 		})
 	}
 }
+
+func TestGetImageHistoryFullReference(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		reference      string
+		expectedStatus int
+	}{
+		{
+			name:           "encoded full reference",
+			path:           "/api/images/ghcr.io%2Fbitnami%2Fredis/history",
+			reference:      "ghcr.io/bitnami/redis",
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "registry with port",
+			path:           "/api/images/localhost%3A5000%2Fteam%2Fapp/history",
+			reference:      "localhost:5000/team/app",
+			expectedStatus: fiber.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := mocks.NewMockImageService(t)
+
+			mockSvc.EXPECT().
+				GetImageTagHistory(mock.Anything, tt.reference, "").
+				Return(&models.ImageTagHistory{ImageName: "redis"}, nil).
+				Once()
+
+			handler := NewImageHandler(mockSvc)
+			app := fiber.New()
+			app.Get("/api/images/:name/history", handler.GetImageHistory)
+
+			resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetEvents(t *testing.T) {
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedFilter *models.ImageEventFilter
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "defaults without filters",
+			query:          "",
+			expectedFilter: &models.ImageEventFilter{Limit: 100},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:  "passes every filter to the service",
+			query: "?namespace=default&image=nginx&resourceType=Deployment&resourceName=web&since=2025-01-01T00:00:00Z&limit=10",
+			expectedFilter: &models.ImageEventFilter{
+				Namespace:    "default",
+				ImageName:    "nginx",
+				ResourceType: "Deployment",
+				ResourceName: "web",
+				Since:        &since,
+				Limit:        10,
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "invalid time range",
+			query:          "?until=yesterday",
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "limit out of range",
+			query:          "?limit=5000",
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "service returns error",
+			query:          "",
+			expectedFilter: &models.ImageEventFilter{Limit: 100},
+			mockError:      errors.New("database error"),
+			expectedStatus: fiber.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := mocks.NewMockImageService(t)
+
+			if tt.expectedFilter != nil {
+				response := &models.ImageEventsResponse{
+					Events: []models.ImageEvent{{Type: "UPDATE", ImageName: "nginx", OldTag: "1.24", NewTag: "1.25"}},
+					Total:  1,
+				}
+				if tt.mockError != nil {
+					response = nil
+				}
+
+				mockSvc.EXPECT().
+					GetImageEvents(mock.Anything, mock.MatchedBy(func(filter models.ImageEventFilter) bool {
+						sinceMatches := (filter.Since == nil) == (tt.expectedFilter.Since == nil) &&
+							(filter.Since == nil || filter.Since.Equal(*tt.expectedFilter.Since))
+						filter.Since, filter.Until = nil, nil
+						expected := *tt.expectedFilter
+						expected.Since, expected.Until = nil, nil
+						return sinceMatches && filter == expected
+					})).
+					Return(response, tt.mockError).
+					Once()
+			}
+
+			handler := NewImageHandler(mockSvc)
+
+			app := fiber.New()
+			app.Get("/api/events", handler.GetEvents)
+
+			req := httptest.NewRequest("GET", "/api/events"+tt.query, nil)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+
+			if tt.expectedStatus == fiber.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				var response models.ImageEventsResponse
+				if err := json.Unmarshal(body, &response); err != nil {
+					t.Fatalf("Failed to parse response: %v", err)
+				}
+				if response.Total != 1 || response.Events[0].OldTag != "1.24" {
+					t.Errorf("Expected 1 event from 1.24, got %+v", response)
+				}
+			}
+		})
+	}
+}
+
+func TestGetEventsCloudEvents(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		accept         string
+		expectedStatus int
+	}{
+		{name: "format parameter", query: "?format=cloudevents", expectedStatus: fiber.StatusOK},
+		{name: "accept header", accept: "application/cloudevents-batch+json", expectedStatus: fiber.StatusOK},
+		{name: "unsupported format", query: "?format=xml", expectedStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := mocks.NewMockImageService(t)
+
+			if tt.expectedStatus == fiber.StatusOK {
+				mockSvc.EXPECT().
+					GetImageEvents(mock.Anything, models.ImageEventFilter{Limit: 100}).
+					Return(&models.ImageEventsResponse{
+						Events: []models.ImageEvent{{ID: 42, Type: "DELETE", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", OldImageName: "nginx", OldRepository: "docker.io", OldTag: "1.24"}},
+						Total:  1,
+					}, nil).
+					Once()
+			}
+
+			handler := NewImageHandler(mockSvc)
+			handler.SetCluster("prod")
+
+			app := fiber.New()
+			app.Get("/api/events", handler.GetEvents)
+
+			req := httptest.NewRequest("GET", "/api/events"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+			if tt.expectedStatus != fiber.StatusOK {
+				return
+			}
+
+			if contentType := resp.Header.Get("Content-Type"); contentType != "application/cloudevents-batch+json" {
+				t.Errorf("Expected a CloudEvents batch, got %s", contentType)
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			var batch []cloudevents.Event
+			if err := json.Unmarshal(body, &batch); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if len(batch) != 1 {
+				t.Fatalf("Expected 1 event, got %d", len(batch))
+			}
+			if batch[0].ID != "42" || batch[0].Type != "io.kubetag.image.removed" || batch[0].Subject != "docker.io/nginx:1.24" ||
+				batch[0].Source != "/clusters/prod/namespaces/default/Deployment/web" {
+				t.Errorf("Expected the removal of docker.io/nginx:1.24 from prod, got %+v", batch[0])
+			}
+		})
+	}
+}
+
+func TestGetWebhookDeliveries(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedFilter *models.WebhookDeliveryFilter
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "defaults without filters",
+			query:          "",
+			expectedFilter: &models.WebhookDeliveryFilter{Limit: 100},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "passes every filter to the service",
+			query:          "?target=deploy-bot&eventId=42&failed=true&limit=10",
+			expectedFilter: &models.WebhookDeliveryFilter{Target: "deploy-bot", EventID: 42, Failed: true, Limit: 10},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "invalid event id",
+			query:          "?eventId=latest",
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "limit out of range",
+			query:          "?limit=0",
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "service returns error",
+			query:          "",
+			expectedFilter: &models.WebhookDeliveryFilter{Limit: 100},
+			mockError:      errors.New("database error"),
+			expectedStatus: fiber.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := mocks.NewMockImageService(t)
+
+			if tt.expectedFilter != nil {
+				response := &models.WebhookDeliveriesResponse{
+					Deliveries: []models.WebhookDelivery{{ID: 1, Target: "deploy-bot", EventID: 42, Attempt: 2, StatusCode: 503}},
+					Total:      1,
+				}
+				if tt.mockError != nil {
+					response = nil
+				}
+
+				mockSvc.EXPECT().
+					GetWebhookDeliveries(mock.Anything, *tt.expectedFilter).
+					Return(response, tt.mockError).
+					Once()
+			}
+
+			handler := NewImageHandler(mockSvc)
+
+			app := fiber.New()
+			app.Get("/api/webhooks/deliveries", handler.GetWebhookDeliveries)
+
+			req := httptest.NewRequest("GET", "/api/webhooks/deliveries"+tt.query, nil)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+
+			if tt.expectedStatus == fiber.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				var response models.WebhookDeliveriesResponse
+				if err := json.Unmarshal(body, &response); err != nil {
+					t.Fatalf("Failed to parse response: %v", err)
+				}
+				if response.Total != 1 || response.Deliveries[0].StatusCode != 503 {
+					t.Errorf("Expected 1 delivery with status 503, got %+v", response)
+				}
+			}
+		})
+	}
+}
+
+func TestGetOutdatedImages(t *testing.T) {
+	tests := []struct {
+		name                   string
+		query                  string
+		expectedNamespace      string
+		expectedCompatibleOnly bool
+		mockError              error
+		expectedStatus         int
+	}{
+		{name: "every namespace", query: "", expectedStatus: fiber.StatusOK},
+		{name: "namespace and compatible filters", query: "?namespace=default&compatible=true", expectedNamespace: "default", expectedCompatibleOnly: true, expectedStatus: fiber.StatusOK},
+		{name: "service returns error", query: "", mockError: errors.New("database error"), expectedStatus: fiber.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := mocks.NewMockImageService(t)
+
+			response := &models.OutdatedImagesResponse{
+				Images: []models.OutdatedImage{{
+					Name:             "nginx",
+					Repository:       "docker.io",
+					Tag:              "1.25.3",
+					LatestCompatible: "1.27.2",
+					Latest:           "2.0.1",
+					Resources:        []models.ImageResource{{ResourceType: "Deployment", ResourceName: "web", Namespace: "default", Container: "nginx"}},
+				}},
+				Total: 1,
+			}
+			if tt.mockError != nil {
+				response = nil
+			}
+
+			mockSvc.EXPECT().
+				GetOutdatedImages(mock.Anything, tt.expectedNamespace, tt.expectedCompatibleOnly).
+				Return(response, tt.mockError).
+				Once()
+
+			handler := NewImageHandler(mockSvc)
+
+			app := fiber.New()
+			app.Get("/api/images/outdated", handler.GetOutdatedImages)
+
+			req := httptest.NewRequest("GET", "/api/images/outdated"+tt.query, nil)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+
+			if tt.expectedStatus == fiber.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				var result models.OutdatedImagesResponse
+				if err := json.Unmarshal(body, &result); err != nil {
+					t.Fatalf("Failed to parse response: %v", err)
+				}
+				if result.Total != 1 || result.Images[0].LatestCompatible != "1.27.2" || result.Images[0].Resources[0].Container != "nginx" {
+					t.Errorf("Expected nginx 1.25.3 with compatible update 1.27.2, got %+v", result)
+				}
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedQuery  *models.SearchQuery
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "defaults the limit",
+			query:          "?q=nginx",
+			expectedQuery:  &models.SearchQuery{Text: "nginx", Limit: 50},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "passes namespace and limit",
+			query:          "?q=nginx+1.25&namespace=default&limit=10",
+			expectedQuery:  &models.SearchQuery{Text: "nginx 1.25", Namespace: "default", Limit: 10},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "missing query",
+			query:          "?q=+",
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "limit out of range",
+			query:          "?q=nginx&limit=0",
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "service returns error",
+			query:          "?q=nginx",
+			expectedQuery:  &models.SearchQuery{Text: "nginx", Limit: 50},
+			mockError:      errors.New("database error"),
+			expectedStatus: fiber.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := mocks.NewMockImageService(t)
+
+			if tt.expectedQuery != nil {
+				response := &models.SearchResponse{
+					Query:   tt.expectedQuery.Text,
+					Results: []models.SearchResult{{Score: 1, Matches: []string{"name"}, Name: "nginx", Tag: "1.25", Active: true}},
+					Total:   1,
+				}
+				if tt.mockError != nil {
+					response = nil
+				}
+
+				mockSvc.EXPECT().Search(mock.Anything, *tt.expectedQuery).Return(response, tt.mockError).Once()
+			}
+
+			handler := NewImageHandler(mockSvc)
+
+			app := fiber.New()
+			app.Get("/api/search", handler.Search)
+
+			req := httptest.NewRequest("GET", "/api/search"+tt.query, nil)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+
+			if tt.expectedStatus == fiber.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				var response models.SearchResponse
+				if err := json.Unmarshal(body, &response); err != nil {
+					t.Fatalf("Failed to parse response: %v", err)
+				}
+				if response.Total != 1 || response.Results[0].Name != "nginx" {
+					t.Errorf("Expected 1 result for nginx, got %+v", response)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	// live returns a closed subscription holding events, so the stream ends after writing them
+	live := func(events ...models.ImageEvent) <-chan models.ImageEvent {
+		ch := make(chan models.ImageEvent, len(events))
+		for _, event := range events {
+			ch <- event
+		}
+		close(ch)
+		return ch
+	}
+
+	missed := func(ids ...uint) []models.ImageEvent {
+		var events []models.ImageEvent
+		for _, id := range ids {
+			events = append(events, models.ImageEvent{ID: id, Type: "UPDATE", Namespace: "default", ImageName: "nginx"})
+		}
+		return events
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		lastEventID    string
+		expectedFilter models.ImageEventFilter
+		missed         []models.ImageEvent
+		live           []models.ImageEvent
+		expectedStatus int
+		expectedIDs    []string
+		expectReset    bool
+	}{
+		{
+			name:           "streams live events with filters",
+			query:          "?namespace=default&image=nginx",
+			expectedFilter: models.ImageEventFilter{Namespace: "default", ImageName: "nginx"},
+			live:           missed(4, 5),
+			expectedStatus: fiber.StatusOK,
+			expectedIDs:    []string{"4", "5"},
+		},
+		{
+			name:           "resumes from Last-Event-ID oldest first without duplicates",
+			lastEventID:    "2",
+			expectedFilter: models.ImageEventFilter{AfterID: 2},
+			missed:         missed(4, 3),
+			live:           missed(4, 5),
+			expectedStatus: fiber.StatusOK,
+			expectedIDs:    []string{"3", "4", "5"},
+		},
+		{
+			name:           "resumes from the lastEventId query parameter",
+			query:          "?lastEventId=2",
+			expectedFilter: models.ImageEventFilter{AfterID: 2},
+			missed:         missed(3),
+			expectedStatus: fiber.StatusOK,
+			expectedIDs:    []string{"3"},
+		},
+		{
+			name:           "asks the client to reload when too many events were missed",
+			lastEventID:    "1",
+			expectedFilter: models.ImageEventFilter{AfterID: 1},
+			missed:         make([]models.ImageEvent, maxStreamReplay+1),
+			expectedStatus: fiber.StatusOK,
+			expectReset:    true,
+		},
+		{
+			name:           "invalid last event id",
+			lastEventID:    "latest",
+			expectedStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := mocks.NewMockImageService(t)
+
+			if tt.expectedStatus == fiber.StatusOK {
+				mockSvc.EXPECT().SubscribeImageEvents(tt.expectedFilter).Return(live(tt.live...), func() {}).Once()
+			}
+			if tt.expectedFilter.AfterID > 0 {
+				replay := tt.expectedFilter
+				replay.Limit = maxStreamReplay + 1
+				mockSvc.EXPECT().GetImageEvents(mock.Anything, replay).
+					Return(&models.ImageEventsResponse{Events: tt.missed, Total: len(tt.missed)}, nil).Once()
+			}
+
+			handler := NewImageHandler(mockSvc)
+
+			app := fiber.New()
+			app.Get("/api/stream", handler.StreamEvents)
+
+			req := httptest.NewRequest("GET", "/api/stream"+tt.query, nil)
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+			if tt.expectedStatus != fiber.StatusOK {
+				return
+			}
+
+			if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+				t.Errorf("Expected Content-Type text/event-stream, got %q", contentType)
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			var ids []string
+			for _, line := range strings.Split(string(body), "\n") {
+				if id, found := strings.CutPrefix(line, "id: "); found && !tt.expectReset {
+					ids = append(ids, id)
+				}
+			}
+			if !reflect.DeepEqual(ids, tt.expectedIDs) {
+				t.Errorf("Expected event ids %v, got %v in %q", tt.expectedIDs, ids, body)
+			}
+			if reset := strings.HasPrefix(string(body), "event: reset\n"); reset != tt.expectReset {
+				t.Errorf("Expected reset %v, got %q", tt.expectReset, body[:min(len(body), 40)])
+			}
+			if !tt.expectReset && !strings.Contains(string(body), `"image_name":"nginx"`) {
+				t.Errorf("Expected events to carry the JSON image event, got %q", body)
+			}
+		})
+	}
+}
+
+func TestDiffImages(t *testing.T) {
+	from := time.Date(2024, 1, 2, 14, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 2, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		left           models.InventorySelector
+		right          models.InventorySelector
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "two points in time",
+			query:          "?from=2024-01-02T14:00:00Z&to=2024-01-02T18:00:00Z&namespace=default",
+			left:           models.InventorySelector{Namespace: "default", At: &from},
+			right:          models.InventorySelector{Namespace: "default", At: &to},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "point in time against now",
+			query:          "?from=2024-01-02T14:00:00Z",
+			left:           models.InventorySelector{At: &from},
+			right:          models.InventorySelector{},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "two namespaces",
+			query:          "?left=ns=staging&right=ns=production",
+			left:           models.InventorySelector{Namespace: "staging"},
+			right:          models.InventorySelector{Namespace: "production"},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "namespace at an instant",
+			query:          "?left=ns=staging,at=2024-01-02T14:00:00Z&right=namespace=production",
+			left:           models.InventorySelector{Namespace: "staging", At: &from},
+			right:          models.InventorySelector{Namespace: "production"},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "service returns error",
+			query:          "?left=ns=staging&right=ns=production",
+			left:           models.InventorySelector{Namespace: "staging"},
+			right:          models.InventorySelector{Namespace: "production"},
+			mockError:      errors.New("database connection failed"),
+			expectedStatus: fiber.StatusInternalServerError,
+		},
+		{name: "no selectors", query: "", expectedStatus: fiber.StatusBadRequest},
+		{name: "missing right selector", query: "?left=ns=staging", expectedStatus: fiber.StatusBadRequest},
+		{name: "mixed selectors", query: "?left=ns=staging&right=ns=production&from=2024-01-02T14:00:00Z", expectedStatus: fiber.StatusBadRequest},
+		{name: "unknown selector key", query: "?left=cluster=a&right=ns=production", expectedStatus: fiber.StatusBadRequest},
+		{name: "invalid from", query: "?from=yesterday", expectedStatus: fiber.StatusBadRequest},
+		{name: "invalid selector time", query: "?left=ns=a,at=noon&right=ns=b", expectedStatus: fiber.StatusBadRequest},
+	}
+
+	sameSelector := func(expected models.InventorySelector) interface{} {
+		return mock.MatchedBy(func(got models.InventorySelector) bool {
+			if got.Namespace != expected.Namespace || (got.At == nil) != (expected.At == nil) {
+				return false
+			}
+			return got.At == nil || got.At.Equal(*expected.At)
+		})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := mocks.NewMockImageService(t)
+
+			if tt.expectedStatus != fiber.StatusBadRequest {
+				var diff *models.ImageDiff
+				if tt.mockError == nil {
+					diff = &models.ImageDiff{
+						Left:  tt.left,
+						Right: tt.right,
+						Changed: []models.ImageDiffEntry{{
+							ResourceType: "Deployment", ResourceName: "web", Container: "nginx",
+							Left:  &models.ImageDiffSide{Name: "nginx", Tag: "1.24"},
+							Right: &models.ImageDiffSide{Name: "nginx", Tag: "1.25"},
+						}},
+					}
+				}
+
+				mockSvc.EXPECT().
+					DiffImages(mock.Anything, sameSelector(tt.left), sameSelector(tt.right)).
+					Return(diff, tt.mockError).
+					Once()
+			}
+
+			handler := NewImageHandler(mockSvc)
+			app := fiber.New()
+			app.Get("/api/diff", handler.DiffImages)
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/api/diff"+tt.query, nil))
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			if tt.expectedStatus == fiber.StatusOK {
+				var diff models.ImageDiff
+				if err := json.Unmarshal(body, &diff); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if len(diff.Changed) != 1 || diff.Changed[0].Right.Tag != "1.25" {
+					t.Errorf("Expected one changed container, got %+v", diff.Changed)
+				}
+			} else {
+				var errResponse map[string]string
+				json.Unmarshal(body, &errResponse)
+				if errResponse["error"] == "" {
+					t.Error("Expected error in response")
+				}
+			}
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetResourceTimeline(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		mockResponse   *models.ResourceTimeline
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name: "timeline with a rollback",
+			mockResponse: &models.ResourceTimeline{
+				ResourceType: "Deployment", ResourceName: "web", Namespace: "default",
+				Entries: []models.TimelineEntry{
+					{Container: "nginx", ImageName: "nginx", Tag: "1.24", Start: start},
+					{Container: "nginx", ImageName: "nginx", Tag: "1.25", Start: start.Add(time.Hour)},
+					{Container: "nginx", ImageName: "nginx", Tag: "1.24", Start: start.Add(2 * time.Hour), Active: true, Rollback: true},
+				},
+				Rollbacks: 1,
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "unknown resource",
+			mockResponse:   &models.ResourceTimeline{ResourceType: "Deployment", ResourceName: "web", Namespace: "default", Entries: []models.TimelineEntry{}},
+			expectedStatus: fiber.StatusNotFound,
+		},
+		{
+			name:           "service returns error",
+			mockError:      errors.New("database connection failed"),
+			expectedStatus: fiber.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := mocks.NewMockImageService(t)
+			mockSvc.EXPECT().
+				GetResourceTimeline(mock.Anything, "default", "Deployment", "web").
+				Return(tt.mockResponse, tt.mockError).
+				Once()
+
+			handler := NewImageHandler(mockSvc)
+			app := fiber.New()
+			app.Get("/api/resources/:namespace/:type/:name/timeline", handler.GetResourceTimeline)
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/api/resources/default/Deployment/web/timeline", nil))
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+
+			if tt.expectedStatus == fiber.StatusOK {
+				var timeline models.ResourceTimeline
+				body, _ := io.ReadAll(resp.Body)
+				if err := json.Unmarshal(body, &timeline); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if len(timeline.Entries) != 3 || timeline.Rollbacks != 1 || !timeline.Entries[2].Rollback {
+					t.Errorf("Expected 3 entries with a rollback, got %+v", timeline)
+				}
+			}
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/gofiber/adaptor/v2"
 	"github.com/gofiber/fiber/v2"
@@ -50,6 +51,65 @@ func NewMetricsHandler(service *service.ImageService) *MetricsHandler {
 	prometheus.MustRegister(imageTagInfoGauge)
 	prometheus.MustRegister(imageVersionGauge)
 
+	// Corrections are read from the service on scrape
+	for _, action := range []string{"upserted", "deleted"} {
+		prometheus.MustRegister(prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Name:        "kubetag_reconcile_corrections_total",
+				Help:        "Total number of rows corrected by reconciliation with the cluster state",
+				ConstLabels: prometheus.Labels{"action": action},
+			},
+			func() float64 {
+				corrections := service.ReconcileCorrections()
+				if action == "upserted" {
+					return float64(corrections.Upserted)
+				}
+				return float64(corrections.Deleted)
+			},
+		))
+	}
+
+	// Event queue state is read from the service on scrape
+	prometheus.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "kubetag_event_queue_depth",
+			Help: "Number of image events waiting to be written to the database",
+		},
+		func() float64 { return float64(service.EventQueueStats().Depth) },
+	))
+	prometheus.MustRegister(prometheus.NewCounterFunc(
+		prometheus.CounterOpts{
+			Name: "kubetag_event_queue_retries_total",
+			Help: "Total number of image event writes that failed and were retried",
+		},
+		func() float64 { return float64(service.EventQueueStats().Retries) },
+	))
+	prometheus.MustRegister(prometheus.NewCounterFunc(
+		prometheus.CounterOpts{
+			Name: "kubetag_event_queue_dropped_total",
+			Help: "Total number of image events dropped because the queue was full or retries ran out",
+		},
+		func() float64 { return float64(service.EventQueueStats().Dropped) },
+	))
+
+	// Pruned rows are read from the service on scrape, dry runs are reported separately
+	for _, dryRun := range []bool{false, true} {
+		prometheus.MustRegister(prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Name:        "kubetag_retention_pruned_rows_total",
+				Help:        "Total number of closed out image tag rows pruned by the retention policy",
+				ConstLabels: prometheus.Labels{"dry_run": strconv.FormatBool(dryRun)},
+			},
+			func() float64 {
+				pruned, dryRunPruned := service.PrunedImageTags()
+				if dryRun {
+					return float64(dryRunPruned)
+				}
+				return float64(pruned)
+			},
+		))
+	}
+
 	return &MetricsHandler{
 		service:           service,
 		imageGauge:        imageGauge,
@@ -91,7 +151,7 @@ func (h *MetricsHandler) updateMetrics() {
 			h.imageGauge.WithLabelValues(
 				img.Name,
 				img.Tag,
-				"", // repository not available in ImageInfo, could be enhanced
+				img.Repository,
 				img.ResourceType,
 				img.ResourceName,
 				img.Namespace,
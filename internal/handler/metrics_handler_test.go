@@ -375,3 +375,44 @@ func TestUpdateMetrics(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestServiceStateMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	prometheus.DefaultRegisterer = registry
+	prometheus.DefaultGatherer = registry
+
+	mockRepo := mocks.NewMockImageRepository(t)
+	imageService := service.NewImageService(mockRepo, nil)
+	handler := NewMetricsHandler(imageService)
+
+	app := fiber.New()
+	app.Get("/metrics", handler.GetMetrics)
+
+	mockRepo.On("GetAllImages", "").Return([]models.ImageInfo{}, nil)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("Failed to execute request: %v", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+	bodyStr := string(body)
+
+	for _, expected := range []string{
+		`kubetag_reconcile_corrections_total{action="upserted"} 0`,
+		`kubetag_reconcile_corrections_total{action="deleted"} 0`,
+		`kubetag_event_queue_depth 0`,
+		`kubetag_event_queue_retries_total 0`,
+		`kubetag_event_queue_dropped_total 0`,
+		`kubetag_retention_pruned_rows_total{dry_run="false"} 0`,
+		`kubetag_retention_pruned_rows_total{dry_run="true"} 0`,
+	} {
+		if !strings.Contains(bodyStr, expected) {
+			t.Errorf("Expected response to contain %s", expected)
+		}
+	}
+}
@@ -3,7 +3,6 @@ package k8s
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/huseyinbabal/kubetag/internal/models"
 	corev1 "k8s.io/api/core/v1"
@@ -149,8 +148,10 @@ func extractImagesFromPodSpec(spec corev1.PodSpec, resourceType, resourceName, n
 	allContainers := append(spec.Containers, spec.InitContainers...)
 
 	for _, container := range allContainers {
-		name, tag := parseImage(container.Image)
-		key := fmt.Sprintf("%s:%s", name, tag)
+		ref := ParseImageReference(container.Image)
+		// Keep the name as written in the spec, including registry and path
+		name, _, _ := splitReference(container.Image)
+		key := fmt.Sprintf("%s:%s@%s", name, ref.Tag, ref.Digest)
 
 		if existing, found := imageMap[key]; found {
 			// Add container name to existing image entry
@@ -159,7 +160,9 @@ func extractImagesFromPodSpec(spec corev1.PodSpec, resourceType, resourceName, n
 			// Create new image entry
 			imageMap[key] = &models.ImageInfo{
 				Name:         name,
-				Tag:          tag,
+				Repository:   ref.Repository,
+				Tag:          ref.Tag,
+				Digest:       ref.Digest,
 				ResourceType: resourceType,
 				ResourceName: resourceName,
 				Namespace:    namespace,
@@ -175,12 +178,3 @@ func extractImagesFromPodSpec(spec corev1.PodSpec, resourceType, resourceName, n
 
 	return images
 }
-
-// parseImage splits image string into name and tag
-func parseImage(image string) (name, tag string) {
-	parts := strings.Split(image, ":")
-	if len(parts) == 2 {
-		return parts[0], parts[1]
-	}
-	return image, "latest"
-}
@@ -7,52 +7,6 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
-func TestParseImage(t *testing.T) {
-	tests := []struct {
-		name         string
-		input        string
-		expectedName string
-		expectedTag  string
-	}{
-		{
-			name:         "Image with tag",
-			input:        "nginx:1.19",
-			expectedName: "nginx",
-			expectedTag:  "1.19",
-		},
-		{
-			name:         "Image without tag",
-			input:        "nginx",
-			expectedName: "nginx",
-			expectedTag:  "latest",
-		},
-		{
-			name:         "Image with repository and tag",
-			input:        "gcr.io/my-project/app:v1.0",
-			expectedName: "gcr.io/my-project/app",
-			expectedTag:  "v1.0",
-		},
-		{
-			name:         "Image with repository",
-			input:        "gcr.io/project/app",
-			expectedName: "gcr.io/project/app",
-			expectedTag:  "latest",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			name, tag := parseImage(tt.input)
-			if name != tt.expectedName {
-				t.Errorf("Expected name '%s', got '%s'", tt.expectedName, name)
-			}
-			if tag != tt.expectedTag {
-				t.Errorf("Expected tag '%s', got '%s'", tt.expectedTag, tag)
-			}
-		})
-	}
-}
-
 func TestExtractImagesFromPodSpec(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -206,81 +160,6 @@ func TestExtractImagesFromPodSpecContainerAggregation(t *testing.T) {
 	}
 }
 
-func TestParseImageFull(t *testing.T) {
-	tests := []struct {
-		name         string
-		input        string
-		expectedName string
-		expectedTag  string
-		expectedRepo string
-	}{
-		{
-			name:         "Simple image with tag",
-			input:        "nginx:1.19",
-			expectedName: "nginx",
-			expectedTag:  "1.19",
-			expectedRepo: "docker.io",
-		},
-		{
-			name:         "Simple image without tag",
-			input:        "nginx",
-			expectedName: "nginx",
-			expectedTag:  "latest",
-			expectedRepo: "docker.io",
-		},
-		{
-			name:         "Docker Hub with namespace",
-			input:        "library/nginx:1.19",
-			expectedName: "nginx",
-			expectedTag:  "1.19",
-			expectedRepo: "docker.io/library",
-		},
-		{
-			name:         "GCR image",
-			input:        "gcr.io/my-project/app:v1.0",
-			expectedName: "app",
-			expectedTag:  "v1.0",
-			expectedRepo: "gcr.io/my-project",
-		},
-		{
-			name:         "Private registry",
-			input:        "registry.example.com/team/app:v2.0",
-			expectedName: "app",
-			expectedTag:  "v2.0",
-			expectedRepo: "registry.example.com/team",
-		},
-		{
-			name:         "ECR image",
-			input:        "123456789012.dkr.ecr.us-east-1.amazonaws.com/myapp:latest",
-			expectedName: "myapp",
-			expectedTag:  "latest",
-			expectedRepo: "123456789012.dkr.ecr.us-east-1.amazonaws.com",
-		},
-		{
-			name:         "Deep path repository",
-			input:        "gcr.io/project/team/subteam/app:v1.0",
-			expectedName: "app",
-			expectedTag:  "v1.0",
-			expectedRepo: "gcr.io/project/team/subteam",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			name, tag, repo := parseImageFull(tt.input)
-			if name != tt.expectedName {
-				t.Errorf("Expected name '%s', got '%s'", tt.expectedName, name)
-			}
-			if tag != tt.expectedTag {
-				t.Errorf("Expected tag '%s', got '%s'", tt.expectedTag, tag)
-			}
-			if repo != tt.expectedRepo {
-				t.Errorf("Expected repo '%s', got '%s'", tt.expectedRepo, repo)
-			}
-		})
-	}
-}
-
 func TestImageInfoStructure(t *testing.T) {
 	// Test that ImageInfo struct can be properly created
 	info := models.ImageInfo{
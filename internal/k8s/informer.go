@@ -4,12 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strings"
+	"sort"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
@@ -22,19 +23,48 @@ const (
 	EventTypeAdd    ImageEventType = "ADD"
 	EventTypeUpdate ImageEventType = "UPDATE"
 	EventTypeDelete ImageEventType = "DELETE"
+
+	// EventTypeRunning reports the digest a Pod is actually running for a container
+	EventTypeRunning ImageEventType = "RUNNING"
+	// EventTypeStopped reports that a Pod is gone and no longer runs any digest
+	EventTypeStopped ImageEventType = "STOPPED"
+)
+
+// ContainerChange describes how a container differs between the old and new pod spec of an UPDATE
+type ContainerChange string
+
+const (
+	ContainerAdded   ContainerChange = "ADDED"
+	ContainerRemoved ContainerChange = "REMOVED"
+	ContainerChanged ContainerChange = "CHANGED"
 )
 
+// ContainerDiff lists the container names whose image differs between two pod specs
+type ContainerDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
 // ImageEvent represents an image change event
 type ImageEvent struct {
 	Type          ImageEventType
-	ResourceType  string // Deployment, DaemonSet, CronJob
+	ResourceType  string // Deployment, DaemonSet, CronJob, StatefulSet, Job, ReplicaSet, Pod
 	ResourceName  string
 	Namespace     string
 	ContainerName string
 	ImageName     string
 	ImageTag      string
+	ImageDigest   string // e.g. sha256:...; empty when the image is not pinned by digest
 	Repository    string
+	PodName       string // Only set for RUNNING and STOPPED events
+	RunningDigest string // Digest resolved from the Pod status imageID, only set for RUNNING events
 	Timestamp     time.Time
+
+	// How the container differs from the old pod spec, only set for UPDATE events
+	Change ContainerChange
+	// Image the container ran in the old pod spec, only set for CHANGED updates
+	Previous *ImageReference
 }
 
 // ImageEventHandler is the callback function for image events
@@ -47,6 +77,8 @@ type InformerManager struct {
 	stopCh       chan struct{}
 	eventHandler ImageEventHandler
 	namespaces   []string // List of namespaces to watch, empty means all
+
+	handlersSynced []cache.InformerSynced // Report when a handler received the initial listing
 }
 
 // NewInformerManager creates a new informer manager
@@ -97,6 +129,22 @@ func (im *InformerManager) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to setup cronjob informer: %w", err)
 	}
 
+	if err := im.setupStatefulSetInformer(); err != nil {
+		return fmt.Errorf("failed to setup statefulset informer: %w", err)
+	}
+
+	if err := im.setupJobInformer(); err != nil {
+		return fmt.Errorf("failed to setup job informer: %w", err)
+	}
+
+	if err := im.setupReplicaSetInformer(); err != nil {
+		return fmt.Errorf("failed to setup replicaset informer: %w", err)
+	}
+
+	if err := im.setupPodInformer(); err != nil {
+		return fmt.Errorf("failed to setup pod informer: %w", err)
+	}
+
 	// Start all informers
 	im.factory.Start(im.stopCh)
 
@@ -106,10 +154,19 @@ func (im *InformerManager) Start(ctx context.Context) error {
 		im.factory.Apps().V1().Deployments().Informer().HasSynced,
 		im.factory.Apps().V1().DaemonSets().Informer().HasSynced,
 		im.factory.Batch().V1().CronJobs().Informer().HasSynced,
+		im.factory.Apps().V1().StatefulSets().Informer().HasSynced,
+		im.factory.Batch().V1().Jobs().Informer().HasSynced,
+		im.factory.Apps().V1().ReplicaSets().Informer().HasSynced,
+		im.factory.Core().V1().Pods().Informer().HasSynced,
 	) {
 		return fmt.Errorf("failed to sync informer caches")
 	}
 
+	// A synced cache does not mean its handlers were called yet, wait until every ADD was delivered
+	if !cache.WaitForCacheSync(im.stopCh, im.handlersSynced...) {
+		return fmt.Errorf("failed to deliver the initial informer events")
+	}
+
 	log.Println("Informer caches synced successfully")
 
 	// Wait for context cancellation
@@ -127,11 +184,22 @@ func (im *InformerManager) Stop() {
 	close(im.stopCh)
 }
 
+// addEventHandler registers a handler on an informer and tracks when it received the initial listing
+func (im *InformerManager) addEventHandler(informer cache.SharedIndexInformer, handler cache.ResourceEventHandler) error {
+	registration, err := informer.AddEventHandler(handler)
+	if err != nil {
+		return err
+	}
+
+	im.handlersSynced = append(im.handlersSynced, registration.HasSynced)
+	return nil
+}
+
 // setupDeploymentInformer sets up the Deployment informer
 func (im *InformerManager) setupDeploymentInformer() error {
 	informer := im.factory.Apps().V1().Deployments().Informer()
 
-	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	return im.addEventHandler(informer, cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			deployment := obj.(*appsv1.Deployment)
 			im.handlePodSpecChange(EventTypeAdd, "Deployment", deployment.Name, deployment.Namespace, deployment.Spec.Template.Spec)
@@ -141,24 +209,25 @@ func (im *InformerManager) setupDeploymentInformer() error {
 			newDeployment := newObj.(*appsv1.Deployment)
 
 			// Check if image has changed
-			if im.hasImageChanged(oldDeployment.Spec.Template.Spec, newDeployment.Spec.Template.Spec) {
-				im.handlePodSpecChange(EventTypeUpdate, "Deployment", newDeployment.Name, newDeployment.Namespace, newDeployment.Spec.Template.Spec)
+			if diff, changed := im.hasImageChanged(oldDeployment.Spec.Template.Spec, newDeployment.Spec.Template.Spec); changed {
+				im.handlePodSpecUpdate("Deployment", newDeployment.Name, newDeployment.Namespace, oldDeployment.Spec.Template.Spec, newDeployment.Spec.Template.Spec, diff)
 			}
 		},
 		DeleteFunc: func(obj interface{}) {
-			deployment := obj.(*appsv1.Deployment)
+			deployment, ok := deletedObject[*appsv1.Deployment](obj)
+			if !ok {
+				return
+			}
 			im.handlePodSpecChange(EventTypeDelete, "Deployment", deployment.Name, deployment.Namespace, deployment.Spec.Template.Spec)
 		},
 	})
-
-	return err
 }
 
 // setupDaemonSetInformer sets up the DaemonSet informer
 func (im *InformerManager) setupDaemonSetInformer() error {
 	informer := im.factory.Apps().V1().DaemonSets().Informer()
 
-	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	return im.addEventHandler(informer, cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			daemonset := obj.(*appsv1.DaemonSet)
 			im.handlePodSpecChange(EventTypeAdd, "DaemonSet", daemonset.Name, daemonset.Namespace, daemonset.Spec.Template.Spec)
@@ -167,24 +236,25 @@ func (im *InformerManager) setupDaemonSetInformer() error {
 			oldDaemonSet := oldObj.(*appsv1.DaemonSet)
 			newDaemonSet := newObj.(*appsv1.DaemonSet)
 
-			if im.hasImageChanged(oldDaemonSet.Spec.Template.Spec, newDaemonSet.Spec.Template.Spec) {
-				im.handlePodSpecChange(EventTypeUpdate, "DaemonSet", newDaemonSet.Name, newDaemonSet.Namespace, newDaemonSet.Spec.Template.Spec)
+			if diff, changed := im.hasImageChanged(oldDaemonSet.Spec.Template.Spec, newDaemonSet.Spec.Template.Spec); changed {
+				im.handlePodSpecUpdate("DaemonSet", newDaemonSet.Name, newDaemonSet.Namespace, oldDaemonSet.Spec.Template.Spec, newDaemonSet.Spec.Template.Spec, diff)
 			}
 		},
 		DeleteFunc: func(obj interface{}) {
-			daemonset := obj.(*appsv1.DaemonSet)
+			daemonset, ok := deletedObject[*appsv1.DaemonSet](obj)
+			if !ok {
+				return
+			}
 			im.handlePodSpecChange(EventTypeDelete, "DaemonSet", daemonset.Name, daemonset.Namespace, daemonset.Spec.Template.Spec)
 		},
 	})
-
-	return err
 }
 
 // setupCronJobInformer sets up the CronJob informer
 func (im *InformerManager) setupCronJobInformer() error {
 	informer := im.factory.Batch().V1().CronJobs().Informer()
 
-	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	return im.addEventHandler(informer, cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			cronjob := obj.(*batchv1.CronJob)
 			im.handlePodSpecChange(EventTypeAdd, "CronJob", cronjob.Name, cronjob.Namespace, cronjob.Spec.JobTemplate.Spec.Template.Spec)
@@ -193,17 +263,190 @@ func (im *InformerManager) setupCronJobInformer() error {
 			oldCronJob := oldObj.(*batchv1.CronJob)
 			newCronJob := newObj.(*batchv1.CronJob)
 
-			if im.hasImageChanged(oldCronJob.Spec.JobTemplate.Spec.Template.Spec, newCronJob.Spec.JobTemplate.Spec.Template.Spec) {
-				im.handlePodSpecChange(EventTypeUpdate, "CronJob", newCronJob.Name, newCronJob.Namespace, newCronJob.Spec.JobTemplate.Spec.Template.Spec)
+			if diff, changed := im.hasImageChanged(oldCronJob.Spec.JobTemplate.Spec.Template.Spec, newCronJob.Spec.JobTemplate.Spec.Template.Spec); changed {
+				im.handlePodSpecUpdate("CronJob", newCronJob.Name, newCronJob.Namespace, oldCronJob.Spec.JobTemplate.Spec.Template.Spec, newCronJob.Spec.JobTemplate.Spec.Template.Spec, diff)
 			}
 		},
 		DeleteFunc: func(obj interface{}) {
-			cronjob := obj.(*batchv1.CronJob)
+			cronjob, ok := deletedObject[*batchv1.CronJob](obj)
+			if !ok {
+				return
+			}
 			im.handlePodSpecChange(EventTypeDelete, "CronJob", cronjob.Name, cronjob.Namespace, cronjob.Spec.JobTemplate.Spec.Template.Spec)
 		},
 	})
+}
+
+// setupStatefulSetInformer sets up the StatefulSet informer
+func (im *InformerManager) setupStatefulSetInformer() error {
+	informer := im.factory.Apps().V1().StatefulSets().Informer()
+
+	return im.addEventHandler(informer, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			statefulset := obj.(*appsv1.StatefulSet)
+			im.handlePodSpecChange(EventTypeAdd, "StatefulSet", statefulset.Name, statefulset.Namespace, statefulset.Spec.Template.Spec)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldStatefulSet := oldObj.(*appsv1.StatefulSet)
+			newStatefulSet := newObj.(*appsv1.StatefulSet)
 
-	return err
+			if diff, changed := im.hasImageChanged(oldStatefulSet.Spec.Template.Spec, newStatefulSet.Spec.Template.Spec); changed {
+				im.handlePodSpecUpdate("StatefulSet", newStatefulSet.Name, newStatefulSet.Namespace, oldStatefulSet.Spec.Template.Spec, newStatefulSet.Spec.Template.Spec, diff)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			statefulset, ok := deletedObject[*appsv1.StatefulSet](obj)
+			if !ok {
+				return
+			}
+			im.handlePodSpecChange(EventTypeDelete, "StatefulSet", statefulset.Name, statefulset.Namespace, statefulset.Spec.Template.Spec)
+		},
+	})
+}
+
+// setupJobInformer sets up the Job informer
+// Jobs created by a CronJob are skipped since the CronJob already reports their images
+func (im *InformerManager) setupJobInformer() error {
+	informer := im.factory.Batch().V1().Jobs().Informer()
+
+	return im.addEventHandler(informer, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			job := obj.(*batchv1.Job)
+			if isControlledBy(job, "CronJob") {
+				return
+			}
+			im.handlePodSpecChange(EventTypeAdd, "Job", job.Name, job.Namespace, job.Spec.Template.Spec)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldJob := oldObj.(*batchv1.Job)
+			newJob := newObj.(*batchv1.Job)
+			if isControlledBy(newJob, "CronJob") {
+				return
+			}
+
+			if diff, changed := im.hasImageChanged(oldJob.Spec.Template.Spec, newJob.Spec.Template.Spec); changed {
+				im.handlePodSpecUpdate("Job", newJob.Name, newJob.Namespace, oldJob.Spec.Template.Spec, newJob.Spec.Template.Spec, diff)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			job, ok := deletedObject[*batchv1.Job](obj)
+			if !ok {
+				return
+			}
+			if isControlledBy(job, "CronJob") {
+				return
+			}
+			im.handlePodSpecChange(EventTypeDelete, "Job", job.Name, job.Namespace, job.Spec.Template.Spec)
+		},
+	})
+}
+
+// setupReplicaSetInformer sets up the ReplicaSet informer
+// ReplicaSets created by a Deployment are skipped since the Deployment already reports their images
+func (im *InformerManager) setupReplicaSetInformer() error {
+	informer := im.factory.Apps().V1().ReplicaSets().Informer()
+
+	return im.addEventHandler(informer, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			replicaset := obj.(*appsv1.ReplicaSet)
+			if isControlledBy(replicaset, "Deployment") {
+				return
+			}
+			im.handlePodSpecChange(EventTypeAdd, "ReplicaSet", replicaset.Name, replicaset.Namespace, replicaset.Spec.Template.Spec)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldReplicaSet := oldObj.(*appsv1.ReplicaSet)
+			newReplicaSet := newObj.(*appsv1.ReplicaSet)
+			if isControlledBy(newReplicaSet, "Deployment") {
+				return
+			}
+
+			if diff, changed := im.hasImageChanged(oldReplicaSet.Spec.Template.Spec, newReplicaSet.Spec.Template.Spec); changed {
+				im.handlePodSpecUpdate("ReplicaSet", newReplicaSet.Name, newReplicaSet.Namespace, oldReplicaSet.Spec.Template.Spec, newReplicaSet.Spec.Template.Spec, diff)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			replicaset, ok := deletedObject[*appsv1.ReplicaSet](obj)
+			if !ok {
+				return
+			}
+			if isControlledBy(replicaset, "Deployment") {
+				return
+			}
+			im.handlePodSpecChange(EventTypeDelete, "ReplicaSet", replicaset.Name, replicaset.Namespace, replicaset.Spec.Template.Spec)
+		},
+	})
+}
+
+// setupPodInformer sets up the Pod informer
+// Bare Pods are reported as workloads; every Pod also reports the digests it is actually running
+func (im *InformerManager) setupPodInformer() error {
+	informer := im.factory.Core().V1().Pods().Informer()
+
+	return im.addEventHandler(informer, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			pod := obj.(*corev1.Pod)
+			if !isControlledBy(pod) {
+				im.handlePodSpecChange(EventTypeAdd, "Pod", pod.Name, pod.Namespace, pod.Spec)
+			}
+			im.handleRunningImages(nil, pod)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod := oldObj.(*corev1.Pod)
+			newPod := newObj.(*corev1.Pod)
+
+			if diff, changed := im.hasImageChanged(oldPod.Spec, newPod.Spec); changed && !isControlledBy(newPod) {
+				im.handlePodSpecUpdate("Pod", newPod.Name, newPod.Namespace, oldPod.Spec, newPod.Spec, diff)
+			}
+			im.handleRunningImages(oldPod, newPod)
+		},
+		DeleteFunc: func(obj interface{}) {
+			pod, ok := deletedObject[*corev1.Pod](obj)
+			if !ok {
+				return
+			}
+			if !isControlledBy(pod) {
+				im.handlePodSpecChange(EventTypeDelete, "Pod", pod.Name, pod.Namespace, pod.Spec)
+			}
+			im.handlePodStopped(pod)
+		},
+	})
+}
+
+// deletedObject returns the object passed to a DeleteFunc, unwrapping the tombstone client-go
+// delivers when the delete was missed during a watch disconnect and only noticed on relist
+func deletedObject[T metav1.Object](obj interface{}) (T, bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	object, ok := obj.(T)
+	if !ok {
+		log.Printf("Unexpected object type in delete handler: %T", obj)
+	}
+
+	return object, ok
+}
+
+// isControlledBy reports whether the object has a controller owner of one of the given kinds
+// With no kinds given, any controller owner matches
+func isControlledBy(obj metav1.Object, kinds ...string) bool {
+	owner := metav1.GetControllerOf(obj)
+	if owner == nil {
+		return false
+	}
+
+	if len(kinds) == 0 {
+		return true
+	}
+
+	for _, kind := range kinds {
+		if owner.Kind == kind {
+			return true
+		}
+	}
+
+	return false
 }
 
 // shouldWatchNamespace checks if a namespace should be watched based on the filter
@@ -230,46 +473,124 @@ func (im *InformerManager) handlePodSpecChange(eventType ImageEventType, resourc
 		return
 	}
 
+	for _, event := range podSpecEvents(eventType, resourceType, resourceName, namespace, spec) {
+		// Call the event handler
+		if im.eventHandler != nil {
+			im.eventHandler(event)
+		}
+	}
+}
+
+// handlePodSpecUpdate emits UPDATE events for the containers in the diff only
+// Removed containers are reported with the image they ran in the old spec
+func (im *InformerManager) handlePodSpecUpdate(resourceType, resourceName, namespace string, oldSpec, newSpec corev1.PodSpec, diff ContainerDiff) {
+	if !im.shouldWatchNamespace(namespace) {
+		return
+	}
+
+	changes := make(map[string]ContainerChange)
+	for _, name := range diff.Added {
+		changes[name] = ContainerAdded
+	}
+	for _, name := range diff.Changed {
+		changes[name] = ContainerChanged
+	}
+
+	oldImages := containerImages(oldSpec)
+
+	var events []ImageEvent
+	for _, event := range podSpecEvents(EventTypeUpdate, resourceType, resourceName, namespace, newSpec) {
+		if change, found := changes[event.ContainerName]; found {
+			event.Change = change
+			if change == ContainerChanged {
+				previous := ParseImageReference(oldImages[event.ContainerName])
+				event.Previous = &previous
+			}
+			events = append(events, event)
+		}
+	}
+
+	removed := make(map[string]bool)
+	for _, name := range diff.Removed {
+		removed[name] = true
+	}
+	for _, event := range podSpecEvents(EventTypeUpdate, resourceType, resourceName, namespace, oldSpec) {
+		if removed[event.ContainerName] {
+			event.Change = ContainerRemoved
+			events = append(events, event)
+		}
+	}
+
+	for _, event := range events {
+		if im.eventHandler != nil {
+			im.eventHandler(event)
+		}
+	}
+}
+
+// podSpecEvents builds one image event per container (including init containers) of a pod spec
+func podSpecEvents(eventType ImageEventType, resourceType, resourceName, namespace string, spec corev1.PodSpec) []ImageEvent {
+	var events []ImageEvent
 	allContainers := append(spec.Containers, spec.InitContainers...)
 
 	for _, container := range allContainers {
-		name, tag, repo := parseImageFull(container.Image)
+		ref := ParseImageReference(container.Image)
 
-		event := ImageEvent{
+		events = append(events, ImageEvent{
 			Type:          eventType,
 			ResourceType:  resourceType,
 			ResourceName:  resourceName,
 			Namespace:     namespace,
 			ContainerName: container.Name,
-			ImageName:     name,
-			ImageTag:      tag,
-			Repository:    repo,
+			ImageName:     ref.Name,
+			ImageTag:      ref.Tag,
+			ImageDigest:   ref.Digest,
+			Repository:    ref.Repository,
 			Timestamp:     time.Now().UTC(),
-		}
-
-		// Call the event handler
-		if im.eventHandler != nil {
-			im.eventHandler(event)
-		}
+		})
 	}
-}
 
-// hasImageChanged checks if images in pod specs have changed
-func (im *InformerManager) hasImageChanged(oldSpec, newSpec corev1.PodSpec) bool {
-	oldImages := im.extractImagesFromSpec(oldSpec)
-	newImages := im.extractImagesFromSpec(newSpec)
+	return events
+}
 
-	if len(oldImages) != len(newImages) {
-		return true
+// hasImageChanged checks if images in pod specs have changed and returns which containers differ
+// Containers are matched by name, so a renamed container shows up as removed and added
+func (im *InformerManager) hasImageChanged(oldSpec, newSpec corev1.PodSpec) (ContainerDiff, bool) {
+	oldImages := containerImages(oldSpec)
+	newImages := containerImages(newSpec)
+
+	var diff ContainerDiff
+	for name, newImage := range newImages {
+		oldImage, found := oldImages[name]
+		switch {
+		case !found:
+			diff.Added = append(diff.Added, name)
+		case oldImage != newImage:
+			diff.Changed = append(diff.Changed, name)
+		}
 	}
 
-	for i := range oldImages {
-		if oldImages[i] != newImages[i] {
-			return true
+	for name := range oldImages {
+		if _, found := newImages[name]; !found {
+			diff.Removed = append(diff.Removed, name)
 		}
 	}
 
-	return false
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+
+	changed := len(diff.Added) > 0 || len(diff.Removed) > 0 || len(diff.Changed) > 0
+	return diff, changed
+}
+
+// containerImages maps container name to image for all containers of a pod spec
+func containerImages(spec corev1.PodSpec) map[string]string {
+	images := make(map[string]string)
+	for _, container := range append(spec.Containers, spec.InitContainers...) {
+		images[container.Name] = container.Image
+	}
+	return images
 }
 
 // extractImagesFromSpec extracts all image strings from a pod spec
@@ -283,45 +604,3 @@ func (im *InformerManager) extractImagesFromSpec(spec corev1.PodSpec) []string {
 
 	return images
 }
-
-// parseImageFull splits image string into name, tag, and repository
-// Examples:
-//   - nginx:latest -> name: nginx, tag: latest, repo: docker.io
-//   - gcr.io/my-project/app:v1.0 -> name: app, tag: v1.0, repo: gcr.io/my-project
-func parseImageFull(image string) (name, tag, repository string) {
-	// Default repository
-	repository = "docker.io"
-	tag = "latest"
-
-	// Split by tag separator
-	parts := strings.Split(image, ":")
-	imagePart := parts[0]
-	if len(parts) == 2 {
-		tag = parts[1]
-	}
-
-	// Check if there's a repository prefix (contains /)
-	slashParts := strings.Split(imagePart, "/")
-
-	if len(slashParts) == 1 {
-		// Just image name, e.g., "nginx"
-		name = slashParts[0]
-	} else if len(slashParts) == 2 {
-		// Could be "library/nginx" or "gcr.io/nginx"
-		if strings.Contains(slashParts[0], ".") {
-			// It's a registry, e.g., "gcr.io/nginx"
-			repository = slashParts[0]
-			name = slashParts[1]
-		} else {
-			// It's a namespace, e.g., "library/nginx"
-			repository = "docker.io/" + slashParts[0]
-			name = slashParts[1]
-		}
-	} else {
-		// Full path with registry, e.g., "gcr.io/project/app"
-		name = slashParts[len(slashParts)-1]
-		repository = strings.Join(slashParts[:len(slashParts)-1], "/")
-	}
-
-	return name, tag, repository
-}
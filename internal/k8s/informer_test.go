@@ -1,11 +1,21 @@
 package k8s
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 )
 
 func TestShouldWatchNamespace(t *testing.T) {
@@ -222,7 +232,7 @@ func TestHasImageChanged(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := im.hasImageChanged(tt.oldSpec, tt.newSpec)
+			_, result := im.hasImageChanged(tt.oldSpec, tt.newSpec)
 			if result != tt.expected {
 				t.Errorf("Expected %v, got %v", tt.expected, result)
 			}
@@ -230,6 +240,97 @@ func TestHasImageChanged(t *testing.T) {
 	}
 }
 
+func TestHasImageChangedDiff(t *testing.T) {
+	im := &InformerManager{}
+
+	oldSpec := corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "migrate", Image: "flyway:9"}},
+		Containers: []corev1.Container{
+			{Name: "app", Image: "app:v1"},
+			{Name: "proxy", Image: "envoy:1.30"},
+			{Name: "logger", Image: "fluent-bit:3.0"},
+		},
+	}
+	newSpec := corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "migrate", Image: "flyway:10"}},
+		Containers: []corev1.Container{
+			{Name: "app", Image: "app:v1"},
+			{Name: "proxy", Image: "envoy:1.31"},
+			{Name: "metrics", Image: "exporter:2"},
+		},
+	}
+
+	diff, changed := im.hasImageChanged(oldSpec, newSpec)
+	if !changed {
+		t.Fatal("Expected change to be detected")
+	}
+
+	check := func(kind string, got, expected []string) {
+		if len(got) != len(expected) {
+			t.Errorf("Expected %s %v, got %v", kind, expected, got)
+			return
+		}
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Errorf("Expected %s %v, got %v", kind, expected, got)
+				return
+			}
+		}
+	}
+
+	check("added", diff.Added, []string{"metrics"})
+	check("removed", diff.Removed, []string{"logger"})
+	check("changed", diff.Changed, []string{"migrate", "proxy"})
+}
+
+func TestHandlePodSpecUpdate(t *testing.T) {
+	var events []ImageEvent
+	im := &InformerManager{eventHandler: func(event ImageEvent) {
+		events = append(events, event)
+	}}
+
+	oldSpec := corev1.PodSpec{Containers: []corev1.Container{
+		{Name: "app", Image: "app:v1"},
+		{Name: "proxy", Image: "envoy:1.30"},
+		{Name: "sidecar", Image: "fluent-bit:3.0"},
+	}}
+	newSpec := corev1.PodSpec{Containers: []corev1.Container{
+		{Name: "app", Image: "app:v2"},
+		{Name: "proxy", Image: "envoy:1.30"},
+		{Name: "metrics", Image: "exporter:2"},
+	}}
+
+	diff, _ := im.hasImageChanged(oldSpec, newSpec)
+	im.handlePodSpecUpdate("Deployment", "web", "default", oldSpec, newSpec, diff)
+
+	got := make(map[string]ImageEvent)
+	for _, event := range events {
+		if event.Type != EventTypeUpdate {
+			t.Errorf("Expected UPDATE event, got %s", event.Type)
+		}
+		got[event.ContainerName] = event
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("Expected events for 3 containers, got %d", len(got))
+	}
+	if _, found := got["proxy"]; found {
+		t.Error("Expected no event for the unchanged proxy container")
+	}
+	if got["app"].Change != ContainerChanged || got["app"].ImageTag != "v2" {
+		t.Errorf("Expected app CHANGED to v2, got %s to %s", got["app"].Change, got["app"].ImageTag)
+	}
+	if previous := got["app"].Previous; previous == nil || previous.Tag != "v1" {
+		t.Errorf("Expected app to carry its previous tag v1, got %+v", previous)
+	}
+	if got["metrics"].Change != ContainerAdded || got["metrics"].Previous != nil {
+		t.Errorf("Expected metrics ADDED without a previous image, got %s with %+v", got["metrics"].Change, got["metrics"].Previous)
+	}
+	if got["sidecar"].Change != ContainerRemoved || got["sidecar"].ImageName != "fluent-bit" {
+		t.Errorf("Expected sidecar REMOVED with its old image, got %s with %s", got["sidecar"].Change, got["sidecar"].ImageName)
+	}
+}
+
 func TestImageEventType(t *testing.T) {
 	// Test that event types are properly defined
 	if EventTypeAdd != "ADD" {
@@ -317,6 +418,41 @@ func TestHandlePodSpecChange(t *testing.T) {
 		}
 	})
 
+	t.Run("carries registry port and digest", func(t *testing.T) {
+		var capturedEvents []ImageEvent
+
+		im := &InformerManager{
+			eventHandler: func(event ImageEvent) { capturedEvents = append(capturedEvents, event) },
+			namespaces:   []string{},
+		}
+
+		spec := corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "app", Image: "localhost:5000/team/app:v1@sha256:abc123"},
+			},
+		}
+
+		im.handlePodSpecChange(EventTypeAdd, "Deployment", "test-deploy", "default", spec)
+
+		if len(capturedEvents) != 1 {
+			t.Fatalf("Expected 1 event, got %d", len(capturedEvents))
+		}
+
+		event := capturedEvents[0]
+		if event.ImageName != "app" {
+			t.Errorf("Expected ImageName 'app', got '%s'", event.ImageName)
+		}
+		if event.ImageTag != "v1" {
+			t.Errorf("Expected ImageTag 'v1', got '%s'", event.ImageTag)
+		}
+		if event.ImageDigest != "sha256:abc123" {
+			t.Errorf("Expected ImageDigest 'sha256:abc123', got '%s'", event.ImageDigest)
+		}
+		if event.Repository != "localhost:5000/team" {
+			t.Errorf("Expected Repository 'localhost:5000/team', got '%s'", event.Repository)
+		}
+	})
+
 	t.Run("handles pod spec change with multiple containers", func(t *testing.T) {
 		var capturedEvents []ImageEvent
 		var mu sync.Mutex
@@ -461,3 +597,284 @@ func TestHandlePodSpecChange(t *testing.T) {
 		}
 	})
 }
+
+func TestIsControlledBy(t *testing.T) {
+	isController := true
+	owned := func(kind string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:            "child",
+			OwnerReferences: []metav1.OwnerReference{{Kind: kind, Name: "parent", Controller: &isController}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		meta     metav1.ObjectMeta
+		kinds    []string
+		expected bool
+	}{
+		{name: "No owner", meta: metav1.ObjectMeta{Name: "bare"}, expected: false},
+		{name: "Any controller matches without kinds", meta: owned("ReplicaSet"), expected: true},
+		{name: "Matching kind", meta: owned("CronJob"), kinds: []string{"CronJob"}, expected: true},
+		{name: "Different kind", meta: owned("Workflow"), kinds: []string{"CronJob"}, expected: false},
+		{
+			name: "Non-controller owner is ignored",
+			meta: metav1.ObjectMeta{
+				Name:            "child",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "CronJob", Name: "parent"}},
+			},
+			kinds:    []string{"CronJob"},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &batchv1.Job{ObjectMeta: tt.meta}
+			if result := isControlledBy(job, tt.kinds...); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+// waitForEvents polls until the predicate holds or the timeout expires
+func waitForEvents(t *testing.T, mu *sync.Mutex, events *[]ImageEvent, done func([]ImageEvent) bool) []ImageEvent {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		snapshot := append([]ImageEvent(nil), *events...)
+		mu.Unlock()
+
+		if done(snapshot) {
+			return snapshot
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("Timed out waiting for informer events")
+	return nil
+}
+
+func TestInformerManagerWorkloadTypes(t *testing.T) {
+	isController := true
+	controlledBy := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &isController}}
+	}
+	podSpec := func(image string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "main", Image: image}}}}
+	}
+
+	fakeClient := fake.NewSimpleClientset(
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "default"},
+			Spec:       appsv1.StatefulSetSpec{Template: podSpec("postgres:16")},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "default"},
+			Spec:       batchv1.JobSpec{Template: podSpec("migrate:v1")},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "backup-123", Namespace: "default", OwnerReferences: controlledBy("CronJob", "backup")},
+			Spec:       batchv1.JobSpec{Template: podSpec("backup:v1")},
+		},
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "default"},
+			Spec:       appsv1.ReplicaSetSpec{Template: podSpec("legacy:v1")},
+		},
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "default", OwnerReferences: controlledBy("Deployment", "web")},
+			Spec:       appsv1.ReplicaSetSpec{Template: podSpec("web:v1")},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default"},
+			Spec:       podSpec("busybox:1.36").Spec,
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy-xyz", Namespace: "default", OwnerReferences: controlledBy("ReplicaSet", "legacy")},
+			Spec:       podSpec("legacy:v1").Spec,
+		},
+	)
+
+	var mu sync.Mutex
+	var events []ImageEvent
+	im := NewInformerManager(fakeClient, func(event ImageEvent) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := im.Start(ctx); err != nil {
+		t.Fatalf("Failed to start informers: %v", err)
+	}
+
+	waitForEvents(t, &mu, &events, func(events []ImageEvent) bool { return len(events) >= 4 })
+
+	// Give late handlers a moment so skipped children would show up if they were not filtered
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	got := append([]ImageEvent(nil), events...)
+	mu.Unlock()
+
+	resources := make(map[string]bool)
+	for _, event := range got {
+		resources[event.ResourceType+"/"+event.ResourceName] = true
+	}
+
+	expected := []string{"StatefulSet/postgres", "Job/migrate", "ReplicaSet/legacy", "Pod/debug"}
+	for _, key := range expected {
+		if !resources[key] {
+			t.Errorf("Expected event for %s, got %v", key, resources)
+		}
+	}
+
+	skipped := []string{"Job/backup-123", "ReplicaSet/web-abc", "Pod/legacy-xyz"}
+	for _, key := range skipped {
+		if resources[key] {
+			t.Errorf("Expected %s to be skipped as it is covered by its owner", key)
+		}
+	}
+
+	if len(got) != len(expected) {
+		t.Errorf("Expected %d events, got %d", len(expected), len(got))
+	}
+}
+
+func TestDeletedObject(t *testing.T) {
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+
+	tests := []struct {
+		name     string
+		obj      interface{}
+		expectOK bool
+	}{
+		{name: "Plain object", obj: deployment, expectOK: true},
+		{name: "Tombstone", obj: cache.DeletedFinalStateUnknown{Key: "default/web", Obj: deployment}, expectOK: true},
+		{name: "Tombstone with unexpected object", obj: cache.DeletedFinalStateUnknown{Key: "default/web", Obj: &corev1.Pod{}}, expectOK: false},
+		{name: "Unexpected object", obj: "default/web", expectOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := deletedObject[*appsv1.Deployment](tt.obj)
+			if ok != tt.expectOK {
+				t.Fatalf("Expected ok %v, got %v", tt.expectOK, ok)
+			}
+			if ok && result.Name != "web" {
+				t.Errorf("Expected 'web', got '%s'", result.Name)
+			}
+		})
+	}
+}
+
+func TestInformerManagerMissedDelete(t *testing.T) {
+	podSpec := func(image string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "main", Image: image}}}}
+	}
+	meta := metav1.ObjectMeta{Name: "gone", Namespace: "default"}
+
+	tests := []struct {
+		resourceType string
+		gvr          schema.GroupVersionResource
+		obj          runtime.Object
+	}{
+		{
+			resourceType: "Deployment",
+			gvr:          appsv1.SchemeGroupVersion.WithResource("deployments"),
+			obj:          &appsv1.Deployment{ObjectMeta: meta, Spec: appsv1.DeploymentSpec{Template: podSpec("app:v1")}},
+		},
+		{
+			resourceType: "DaemonSet",
+			gvr:          appsv1.SchemeGroupVersion.WithResource("daemonsets"),
+			obj:          &appsv1.DaemonSet{ObjectMeta: meta, Spec: appsv1.DaemonSetSpec{Template: podSpec("app:v1")}},
+		},
+		{
+			resourceType: "CronJob",
+			gvr:          batchv1.SchemeGroupVersion.WithResource("cronjobs"),
+			obj: &batchv1.CronJob{ObjectMeta: meta, Spec: batchv1.CronJobSpec{
+				JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: podSpec("app:v1")}},
+			}},
+		},
+		{
+			resourceType: "StatefulSet",
+			gvr:          appsv1.SchemeGroupVersion.WithResource("statefulsets"),
+			obj:          &appsv1.StatefulSet{ObjectMeta: meta, Spec: appsv1.StatefulSetSpec{Template: podSpec("app:v1")}},
+		},
+		{
+			resourceType: "Job",
+			gvr:          batchv1.SchemeGroupVersion.WithResource("jobs"),
+			obj:          &batchv1.Job{ObjectMeta: meta, Spec: batchv1.JobSpec{Template: podSpec("app:v1")}},
+		},
+		{
+			resourceType: "ReplicaSet",
+			gvr:          appsv1.SchemeGroupVersion.WithResource("replicasets"),
+			obj:          &appsv1.ReplicaSet{ObjectMeta: meta, Spec: appsv1.ReplicaSetSpec{Template: podSpec("app:v1")}},
+		},
+		{
+			resourceType: "Pod",
+			gvr:          corev1.SchemeGroupVersion.WithResource("pods"),
+			obj:          &corev1.Pod{ObjectMeta: meta, Spec: podSpec("app:v1").Spec},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.resourceType, func(t *testing.T) {
+			fakeClient := fake.NewSimpleClientset(tt.obj)
+
+			// Hand out watches we control so the delete below is never seen by the informer
+			watchers := make(chan *watch.FakeWatcher, 10)
+			fakeClient.PrependWatchReactor(tt.gvr.Resource, func(action k8stesting.Action) (bool, watch.Interface, error) {
+				watcher := watch.NewFakeWithChanSize(1, false)
+				watchers <- watcher
+				return true, watcher, nil
+			})
+
+			var mu sync.Mutex
+			var events []ImageEvent
+			im := NewInformerManager(fakeClient, func(event ImageEvent) {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, event)
+			}, nil)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if err := im.Start(ctx); err != nil {
+				t.Fatalf("Failed to start informers: %v", err)
+			}
+
+			var watcher *watch.FakeWatcher
+			select {
+			case watcher = <-watchers:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for the informer to watch")
+			}
+
+			if err := fakeClient.Tracker().Delete(tt.gvr, "default", "gone"); err != nil {
+				t.Fatalf("Failed to delete object: %v", err)
+			}
+
+			// An expired watch forces a relist, which reports the missed delete as a tombstone
+			watcher.Error(&metav1.Status{
+				Status: metav1.StatusFailure,
+				Code:   410,
+				Reason: metav1.StatusReasonExpired,
+			})
+
+			waitForEvents(t, &mu, &events, func(events []ImageEvent) bool {
+				for _, event := range events {
+					if event.Type == EventTypeDelete && event.ResourceType == tt.resourceType && event.ResourceName == "gone" {
+						return true
+					}
+				}
+				return false
+			})
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package k8s
+
+import (
+	"strings"
+)
+
+// defaultRegistry is assumed when an image reference has no registry host
+const defaultRegistry = "docker.io"
+
+// officialImages is the Docker Hub namespace of images referenced by their name only
+const officialImages = "library"
+
+// ImageReference represents a parsed container image reference
+type ImageReference struct {
+	Name       string // Last path component, e.g. nginx
+	Repository string // Registry plus parent path, e.g. docker.io, localhost:5000/team
+	Tag        string // e.g. v1.0; empty when the image is pinned by digest only
+	Digest     string // e.g. sha256:...; empty when the image is not pinned
+}
+
+// ParseImageReference parses an image reference as written in a pod spec
+// Docker Hub official images get the library namespace however they are spelled, so nginx,
+// docker.io/nginx and docker.io/library/nginx are the same image.
+// Examples:
+//   - nginx -> name: nginx, tag: latest, repo: docker.io/library
+//   - gcr.io/my-project/app:v1.0 -> name: app, tag: v1.0, repo: gcr.io/my-project
+//   - localhost:5000/team/app:v1 -> name: app, tag: v1, repo: localhost:5000/team
+//   - nginx@sha256:abc -> name: nginx, tag: "", digest: sha256:abc, repo: docker.io/library
+//   - nginx:1.25@sha256:abc -> name: nginx, tag: 1.25, digest: sha256:abc, repo: docker.io/library
+func ParseImageReference(image string) ImageReference {
+	locator, tag, digest := splitReference(image)
+
+	ref := ImageReference{
+		Repository: defaultRegistry,
+		Tag:        tag,
+		Digest:     digest,
+	}
+
+	// Only default the tag when nothing pins the image
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	parts := strings.Split(locator, "/")
+	if len(parts) > 1 && isRegistryHost(parts[0]) {
+		ref.Repository = parts[0]
+		parts = parts[1:]
+	}
+	if ref.Repository == defaultRegistry && len(parts) == 1 {
+		parts = []string{officialImages, parts[0]}
+	}
+
+	ref.Name = parts[len(parts)-1]
+	if len(parts) > 1 {
+		ref.Repository = ref.Repository + "/" + strings.Join(parts[:len(parts)-1], "/")
+	}
+
+	return ref
+}
+
+// FullName returns the repository and name joined, e.g. docker.io/nginx
+func (r ImageReference) FullName() string {
+	return r.Repository + "/" + r.Name
+}
+
+// splitReference separates an image reference into the part before the tag,
+// the tag and the digest. The registry port is never mistaken for a tag
+// because only a colon after the last slash introduces one.
+func splitReference(image string) (locator, tag, digest string) {
+	locator = strings.TrimSpace(image)
+
+	if i := strings.Index(locator, "@"); i >= 0 {
+		digest = locator[i+1:]
+		locator = locator[:i]
+	}
+
+	if i := strings.LastIndex(locator, ":"); i > strings.LastIndex(locator, "/") {
+		tag = locator[i+1:]
+		locator = locator[:i]
+	}
+
+	return locator, tag, digest
+}
+
+// isRegistryHost reports whether the first path component is a registry host
+// rather than a Docker Hub namespace (same rules as the Docker CLI)
+func isRegistryHost(component string) bool {
+	return strings.ContainsAny(component, ".:") || component == "localhost"
+}
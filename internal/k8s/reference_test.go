@@ -0,0 +1,212 @@
+package k8s
+
+import (
+	"testing"
+)
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		expectedName   string
+		expectedTag    string
+		expectedDigest string
+		expectedRepo   string
+	}{
+		{
+			name:         "Simple image with tag",
+			input:        "nginx:1.19",
+			expectedName: "nginx",
+			expectedTag:  "1.19",
+			expectedRepo: "docker.io/library",
+		},
+		{
+			name:         "Simple image without tag",
+			input:        "nginx",
+			expectedName: "nginx",
+			expectedTag:  "latest",
+			expectedRepo: "docker.io/library",
+		},
+		{
+			name:         "Docker Hub official image with registry",
+			input:        "docker.io/nginx:1.19",
+			expectedName: "nginx",
+			expectedTag:  "1.19",
+			expectedRepo: "docker.io/library",
+		},
+		{
+			name:         "Docker Hub official image fully spelled out",
+			input:        "docker.io/library/nginx:1.19",
+			expectedName: "nginx",
+			expectedTag:  "1.19",
+			expectedRepo: "docker.io/library",
+		},
+		{
+			name:         "Docker Hub user image",
+			input:        "bitnami/redis:7.2",
+			expectedName: "redis",
+			expectedTag:  "7.2",
+			expectedRepo: "docker.io/bitnami",
+		},
+		{
+			name:         "Docker Hub with namespace",
+			input:        "library/nginx:1.19",
+			expectedName: "nginx",
+			expectedTag:  "1.19",
+			expectedRepo: "docker.io/library",
+		},
+		{
+			name:         "GCR image",
+			input:        "gcr.io/my-project/app:v1.0",
+			expectedName: "app",
+			expectedTag:  "v1.0",
+			expectedRepo: "gcr.io/my-project",
+		},
+		{
+			name:         "Private registry",
+			input:        "registry.example.com/team/app:v2.0",
+			expectedName: "app",
+			expectedTag:  "v2.0",
+			expectedRepo: "registry.example.com/team",
+		},
+		{
+			name:         "ECR image",
+			input:        "123456789012.dkr.ecr.us-east-1.amazonaws.com/myapp:latest",
+			expectedName: "myapp",
+			expectedTag:  "latest",
+			expectedRepo: "123456789012.dkr.ecr.us-east-1.amazonaws.com",
+		},
+		{
+			name:         "Deep path repository",
+			input:        "gcr.io/project/team/subteam/app:v1.0",
+			expectedName: "app",
+			expectedTag:  "v1.0",
+			expectedRepo: "gcr.io/project/team/subteam",
+		},
+		{
+			name:         "Registry with port",
+			input:        "localhost:5000/team/app:v1",
+			expectedName: "app",
+			expectedTag:  "v1",
+			expectedRepo: "localhost:5000/team",
+		},
+		{
+			name:         "Registry with port without tag",
+			input:        "registry.local:5000/app",
+			expectedName: "app",
+			expectedTag:  "latest",
+			expectedRepo: "registry.local:5000",
+		},
+		{
+			name:         "Localhost registry without port",
+			input:        "localhost/app:v1",
+			expectedName: "app",
+			expectedTag:  "v1",
+			expectedRepo: "localhost",
+		},
+		{
+			name:           "Digest only",
+			input:          "nginx@sha256:4c0fdaa8b6341bfdeca5f18f7837462c80cff90527ee35ef185571e1c327beac",
+			expectedName:   "nginx",
+			expectedTag:    "",
+			expectedDigest: "sha256:4c0fdaa8b6341bfdeca5f18f7837462c80cff90527ee35ef185571e1c327beac",
+			expectedRepo:   "docker.io/library",
+		},
+		{
+			name:           "Tag and digest",
+			input:          "nginx:1.25@sha256:4c0fdaa8b6341bfdeca5f18f7837462c80cff90527ee35ef185571e1c327beac",
+			expectedName:   "nginx",
+			expectedTag:    "1.25",
+			expectedDigest: "sha256:4c0fdaa8b6341bfdeca5f18f7837462c80cff90527ee35ef185571e1c327beac",
+			expectedRepo:   "docker.io/library",
+		},
+		{
+			name:           "Registry with port, tag and digest",
+			input:          "localhost:5000/team/app:v1@sha256:abc123",
+			expectedName:   "app",
+			expectedTag:    "v1",
+			expectedDigest: "sha256:abc123",
+			expectedRepo:   "localhost:5000/team",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := ParseImageReference(tt.input)
+			if ref.Name != tt.expectedName {
+				t.Errorf("Expected name '%s', got '%s'", tt.expectedName, ref.Name)
+			}
+			if ref.Tag != tt.expectedTag {
+				t.Errorf("Expected tag '%s', got '%s'", tt.expectedTag, ref.Tag)
+			}
+			if ref.Digest != tt.expectedDigest {
+				t.Errorf("Expected digest '%s', got '%s'", tt.expectedDigest, ref.Digest)
+			}
+			if ref.Repository != tt.expectedRepo {
+				t.Errorf("Expected repo '%s', got '%s'", tt.expectedRepo, ref.Repository)
+			}
+		})
+	}
+}
+
+func TestSplitReference(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           string
+		expectedLocator string
+		expectedTag     string
+		expectedDigest  string
+	}{
+		{
+			name:            "Image with tag",
+			input:           "nginx:1.19",
+			expectedLocator: "nginx",
+			expectedTag:     "1.19",
+		},
+		{
+			name:            "Image without tag",
+			input:           "nginx",
+			expectedLocator: "nginx",
+		},
+		{
+			name:            "Image with repository and tag",
+			input:           "gcr.io/my-project/app:v1.0",
+			expectedLocator: "gcr.io/my-project/app",
+			expectedTag:     "v1.0",
+		},
+		{
+			name:            "Registry port is not a tag",
+			input:           "localhost:5000/app",
+			expectedLocator: "localhost:5000/app",
+		},
+		{
+			name:            "Tag and digest",
+			input:           "localhost:5000/app:v1@sha256:abc",
+			expectedLocator: "localhost:5000/app",
+			expectedTag:     "v1",
+			expectedDigest:  "sha256:abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locator, tag, digest := splitReference(tt.input)
+			if locator != tt.expectedLocator {
+				t.Errorf("Expected locator '%s', got '%s'", tt.expectedLocator, locator)
+			}
+			if tag != tt.expectedTag {
+				t.Errorf("Expected tag '%s', got '%s'", tt.expectedTag, tag)
+			}
+			if digest != tt.expectedDigest {
+				t.Errorf("Expected digest '%s', got '%s'", tt.expectedDigest, digest)
+			}
+		})
+	}
+}
+
+func TestImageReferenceFullName(t *testing.T) {
+	ref := ParseImageReference("localhost:5000/team/app:v1")
+	if ref.FullName() != "localhost:5000/team/app" {
+		t.Errorf("Expected full name 'localhost:5000/team/app', got '%s'", ref.FullName())
+	}
+}
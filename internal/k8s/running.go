@@ -0,0 +1,175 @@
+package k8s
+
+import (
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// handleRunningImages emits RUNNING events for the digests reported in the Pod status
+// Events are only emitted when the resolved digests differ from the previous Pod state
+func (im *InformerManager) handleRunningImages(oldPod, newPod *corev1.Pod) {
+	if !im.shouldWatchNamespace(newPod.Namespace) {
+		return
+	}
+
+	newDigests := runningDigests(newPod)
+	if len(newDigests) == 0 {
+		return
+	}
+
+	if oldPod != nil && sameDigests(runningDigests(oldPod), newDigests) {
+		return
+	}
+
+	for _, event := range im.runningEvents(newPod, newDigests) {
+		if im.eventHandler != nil {
+			im.eventHandler(event)
+		}
+	}
+}
+
+// runningEvents builds one RUNNING event per container that reported a digest
+func (im *InformerManager) runningEvents(pod *corev1.Pod, digests map[string]string) []ImageEvent {
+	var events []ImageEvent
+	resourceType, resourceName := im.resolvePodOwner(pod)
+	allContainers := append(pod.Spec.Containers, pod.Spec.InitContainers...)
+
+	for _, container := range allContainers {
+		digest, found := digests[container.Name]
+		if !found {
+			continue
+		}
+
+		ref := ParseImageReference(container.Image)
+
+		events = append(events, ImageEvent{
+			Type:          EventTypeRunning,
+			ResourceType:  resourceType,
+			ResourceName:  resourceName,
+			Namespace:     pod.Namespace,
+			ContainerName: container.Name,
+			ImageName:     ref.Name,
+			ImageTag:      ref.Tag,
+			ImageDigest:   ref.Digest,
+			Repository:    ref.Repository,
+			PodName:       pod.Name,
+			RunningDigest: digest,
+			Timestamp:     time.Now().UTC(),
+		})
+	}
+
+	return events
+}
+
+// handlePodStopped emits a STOPPED event so the running digests of the Pod are forgotten
+func (im *InformerManager) handlePodStopped(pod *corev1.Pod) {
+	if !im.shouldWatchNamespace(pod.Namespace) {
+		return
+	}
+
+	resourceType, resourceName := im.resolvePodOwner(pod)
+
+	event := ImageEvent{
+		Type:         EventTypeStopped,
+		ResourceType: resourceType,
+		ResourceName: resourceName,
+		Namespace:    pod.Namespace,
+		PodName:      pod.Name,
+		Timestamp:    time.Now().UTC(),
+	}
+
+	if im.eventHandler != nil {
+		im.eventHandler(event)
+	}
+}
+
+// resolvePodOwner walks the controller chain of a Pod up to the workload KubeTag tracks
+// e.g. Pod -> ReplicaSet -> Deployment, Pod -> Job -> CronJob
+func (im *InformerManager) resolvePodOwner(pod *corev1.Pod) (resourceType, resourceName string) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "Pod", pod.Name
+	}
+
+	switch owner.Kind {
+	case "ReplicaSet":
+		if im.factory != nil {
+			rs, err := im.factory.Apps().V1().ReplicaSets().Lister().ReplicaSets(pod.Namespace).Get(owner.Name)
+			if err == nil {
+				if parent := metav1.GetControllerOf(rs); parent != nil && parent.Kind == "Deployment" {
+					return "Deployment", parent.Name
+				}
+				return "ReplicaSet", owner.Name
+			}
+		}
+
+		// ReplicaSet not cached yet: Deployments name their ReplicaSets <deployment>-<pod-template-hash>
+		if hash := pod.Labels["pod-template-hash"]; hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+			return "Deployment", strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+		return "ReplicaSet", owner.Name
+
+	case "Job":
+		if im.factory != nil {
+			job, err := im.factory.Batch().V1().Jobs().Lister().Jobs(pod.Namespace).Get(owner.Name)
+			if err == nil {
+				if parent := metav1.GetControllerOf(job); parent != nil && parent.Kind == "CronJob" {
+					return "CronJob", parent.Name
+				}
+			}
+		}
+		return "Job", owner.Name
+
+	default:
+		return owner.Kind, owner.Name
+	}
+}
+
+// runningDigests maps container name to the digest it runs, for containers that reported one
+func runningDigests(pod *corev1.Pod) map[string]string {
+	digests := make(map[string]string)
+	allStatuses := append(pod.Status.ContainerStatuses, pod.Status.InitContainerStatuses...)
+
+	for _, status := range allStatuses {
+		if digest := parseImageID(status.ImageID); digest != "" {
+			digests[status.Name] = digest
+		}
+	}
+
+	return digests
+}
+
+// sameDigests checks if two container-to-digest maps are equal
+func sameDigests(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for container, digest := range a {
+		if b[container] != digest {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseImageID extracts the digest from a container status imageID
+// Examples:
+//   - docker-pullable://nginx@sha256:abc -> sha256:abc
+//   - docker.io/library/nginx@sha256:abc -> sha256:abc
+//   - sha256:abc -> sha256:abc
+func parseImageID(imageID string) string {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		return imageID[i+1:]
+	}
+
+	if strings.HasPrefix(imageID, "sha256:") {
+		return imageID
+	}
+
+	return ""
+}
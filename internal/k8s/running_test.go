@@ -0,0 +1,191 @@
+package k8s
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseImageID(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Docker pullable", input: "docker-pullable://nginx@sha256:abc", expected: "sha256:abc"},
+		{name: "Containerd repo digest", input: "docker.io/library/nginx@sha256:abc", expected: "sha256:abc"},
+		{name: "Registry with port", input: "localhost:5000/team/app@sha256:abc", expected: "sha256:abc"},
+		{name: "Bare digest", input: "sha256:abc", expected: "sha256:abc"},
+		{name: "Empty while pulling", input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := parseImageID(tt.input); result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestResolvePodOwner(t *testing.T) {
+	isController := true
+	controlledBy := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &isController}}
+	}
+
+	im := NewInformerManager(fake.NewSimpleClientset(), nil, nil)
+
+	// Seed the informer caches the listers read from
+	im.factory.Apps().V1().ReplicaSets().Informer().GetIndexer().Add(&appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-7d9f", Namespace: "default", OwnerReferences: controlledBy("Deployment", "web")},
+	})
+	im.factory.Apps().V1().ReplicaSets().Informer().GetIndexer().Add(&appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "default"},
+	})
+	im.factory.Batch().V1().Jobs().Informer().GetIndexer().Add(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup-123", Namespace: "default", OwnerReferences: controlledBy("CronJob", "backup")},
+	})
+
+	tests := []struct {
+		name         string
+		pod          *corev1.Pod
+		expectedType string
+		expectedName string
+	}{
+		{
+			name:         "Bare pod",
+			pod:          &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default"}},
+			expectedType: "Pod",
+			expectedName: "debug",
+		},
+		{
+			name: "Deployment through cached ReplicaSet",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name: "web-7d9f-x1", Namespace: "default", OwnerReferences: controlledBy("ReplicaSet", "web-7d9f"),
+			}},
+			expectedType: "Deployment",
+			expectedName: "web",
+		},
+		{
+			name: "Deployment from pod-template-hash when ReplicaSet is not cached",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name: "api-5c6b-x1", Namespace: "default", OwnerReferences: controlledBy("ReplicaSet", "api-5c6b"),
+				Labels: map[string]string{"pod-template-hash": "5c6b"},
+			}},
+			expectedType: "Deployment",
+			expectedName: "api",
+		},
+		{
+			name: "Standalone ReplicaSet",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name: "legacy-x1", Namespace: "default", OwnerReferences: controlledBy("ReplicaSet", "legacy"),
+			}},
+			expectedType: "ReplicaSet",
+			expectedName: "legacy",
+		},
+		{
+			name: "CronJob through Job",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name: "backup-123-x1", Namespace: "default", OwnerReferences: controlledBy("Job", "backup-123"),
+			}},
+			expectedType: "CronJob",
+			expectedName: "backup",
+		},
+		{
+			name: "StatefulSet",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name: "postgres-0", Namespace: "default", OwnerReferences: controlledBy("StatefulSet", "postgres"),
+			}},
+			expectedType: "StatefulSet",
+			expectedName: "postgres",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resourceType, resourceName := im.resolvePodOwner(tt.pod)
+			if resourceType != tt.expectedType || resourceName != tt.expectedName {
+				t.Errorf("Expected %s/%s, got %s/%s", tt.expectedType, tt.expectedName, resourceType, resourceName)
+			}
+		})
+	}
+}
+
+func TestHandleRunningImages(t *testing.T) {
+	newPod := func(imageID string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "registry.local:5000/app:latest"}},
+			},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "app", ImageID: imageID}},
+			},
+		}
+	}
+
+	t.Run("emits running digest for each container", func(t *testing.T) {
+		var events []ImageEvent
+		im := &InformerManager{eventHandler: func(event ImageEvent) { events = append(events, event) }}
+
+		im.handleRunningImages(nil, newPod("registry.local:5000/app@sha256:aaa"))
+
+		if len(events) != 1 {
+			t.Fatalf("Expected 1 event, got %d", len(events))
+		}
+
+		event := events[0]
+		if event.Type != EventTypeRunning {
+			t.Errorf("Expected EventTypeRunning, got %s", event.Type)
+		}
+		if event.RunningDigest != "sha256:aaa" {
+			t.Errorf("Expected running digest 'sha256:aaa', got '%s'", event.RunningDigest)
+		}
+		if event.ImageTag != "latest" || event.Repository != "registry.local:5000" {
+			t.Errorf("Expected declared image registry.local:5000/app:latest, got %s/%s:%s", event.Repository, event.ImageName, event.ImageTag)
+		}
+		if event.ResourceType != "Pod" || event.PodName != "debug" {
+			t.Errorf("Expected Pod/debug, got %s/%s", event.ResourceType, event.PodName)
+		}
+	})
+
+	t.Run("skips updates that do not change digests", func(t *testing.T) {
+		var events []ImageEvent
+		im := &InformerManager{eventHandler: func(event ImageEvent) { events = append(events, event) }}
+
+		im.handleRunningImages(newPod("app@sha256:aaa"), newPod("app@sha256:aaa"))
+		if len(events) != 0 {
+			t.Errorf("Expected no events, got %d", len(events))
+		}
+
+		im.handleRunningImages(newPod("app@sha256:aaa"), newPod("app@sha256:bbb"))
+		if len(events) != 1 {
+			t.Errorf("Expected 1 event after digest change, got %d", len(events))
+		}
+	})
+
+	t.Run("skips containers without imageID", func(t *testing.T) {
+		var events []ImageEvent
+		im := &InformerManager{eventHandler: func(event ImageEvent) { events = append(events, event) }}
+
+		im.handleRunningImages(nil, newPod(""))
+		if len(events) != 0 {
+			t.Errorf("Expected no events while the image is pulling, got %d", len(events))
+		}
+	})
+
+	t.Run("stopped pod emits STOPPED", func(t *testing.T) {
+		var events []ImageEvent
+		im := &InformerManager{eventHandler: func(event ImageEvent) { events = append(events, event) }}
+
+		im.handlePodStopped(newPod("app@sha256:aaa"))
+		if len(events) != 1 || events[0].Type != EventTypeStopped || events[0].PodName != "debug" {
+			t.Errorf("Expected one STOPPED event for debug, got %+v", events)
+		}
+	})
+}
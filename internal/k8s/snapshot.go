@@ -0,0 +1,118 @@
+package k8s
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ListImages returns an ADD event for every container image currently declared in the informer caches
+// The same skip rules as the event handlers apply, so the snapshot matches what the informers report
+func (im *InformerManager) ListImages() ([]ImageEvent, error) {
+	var events []ImageEvent
+
+	deployments, err := im.factory.Apps().V1().Deployments().Lister().List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, deployment := range deployments {
+		events = append(events, im.snapshotEvents("Deployment", deployment.Name, deployment.Namespace, deployment.Spec.Template.Spec)...)
+	}
+
+	daemonsets, err := im.factory.Apps().V1().DaemonSets().Lister().List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %w", err)
+	}
+	for _, daemonset := range daemonsets {
+		events = append(events, im.snapshotEvents("DaemonSet", daemonset.Name, daemonset.Namespace, daemonset.Spec.Template.Spec)...)
+	}
+
+	cronjobs, err := im.factory.Batch().V1().CronJobs().Lister().List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cronjobs: %w", err)
+	}
+	for _, cronjob := range cronjobs {
+		events = append(events, im.snapshotEvents("CronJob", cronjob.Name, cronjob.Namespace, cronjob.Spec.JobTemplate.Spec.Template.Spec)...)
+	}
+
+	statefulsets, err := im.factory.Apps().V1().StatefulSets().Lister().List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for _, statefulset := range statefulsets {
+		events = append(events, im.snapshotEvents("StatefulSet", statefulset.Name, statefulset.Namespace, statefulset.Spec.Template.Spec)...)
+	}
+
+	jobs, err := im.factory.Batch().V1().Jobs().Lister().List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	for _, job := range jobs {
+		if isControlledBy(job, "CronJob") {
+			continue
+		}
+		events = append(events, im.snapshotEvents("Job", job.Name, job.Namespace, job.Spec.Template.Spec)...)
+	}
+
+	replicasets, err := im.factory.Apps().V1().ReplicaSets().Lister().List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replicasets: %w", err)
+	}
+	for _, rs := range replicasets {
+		if isControlledBy(rs, "Deployment") {
+			continue
+		}
+		events = append(events, im.snapshotEvents("ReplicaSet", rs.Name, rs.Namespace, rs.Spec.Template.Spec)...)
+	}
+
+	pods, err := im.factory.Core().V1().Pods().Lister().List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	for _, pod := range pods {
+		if isControlledBy(pod) {
+			continue
+		}
+		events = append(events, im.snapshotEvents("Pod", pod.Name, pod.Namespace, pod.Spec)...)
+	}
+
+	return events, nil
+}
+
+// ListRunningImages returns a RUNNING event for every digest reported by the cached Pods
+func (im *InformerManager) ListRunningImages() ([]ImageEvent, error) {
+	pods, err := im.factory.Core().V1().Pods().Lister().List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	var events []ImageEvent
+	for _, pod := range pods {
+		if !im.shouldWatchNamespace(pod.Namespace) {
+			continue
+		}
+
+		digests := runningDigests(pod)
+		if len(digests) == 0 {
+			continue
+		}
+		events = append(events, im.runningEvents(pod, digests)...)
+	}
+
+	return events, nil
+}
+
+// snapshotEvents builds ADD events for a workload when its namespace is watched
+func (im *InformerManager) snapshotEvents(resourceType, resourceName, namespace string, spec corev1.PodSpec) []ImageEvent {
+	if !im.shouldWatchNamespace(namespace) {
+		return nil
+	}
+
+	return podSpecEvents(EventTypeAdd, resourceType, resourceName, namespace, spec)
+}
+
+// WatchesNamespace reports whether events from the namespace are tracked by this manager
+func (im *InformerManager) WatchesNamespace(namespace string) bool {
+	return im.shouldWatchNamespace(namespace)
+}
@@ -0,0 +1,86 @@
+package k8s
+
+import (
+	"sort"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestListImages(t *testing.T) {
+	isController := true
+	controlledBy := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &isController}}
+	}
+	podSpec := func(image string) corev1.PodSpec {
+		return corev1.PodSpec{Containers: []corev1.Container{{Name: "main", Image: image}}}
+	}
+
+	im := NewInformerManager(fake.NewSimpleClientset(), nil, []string{"default", "staging"})
+
+	im.factory.Apps().V1().Deployments().Informer().GetIndexer().Add(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpec("nginx:1.25")}},
+	})
+	im.factory.Apps().V1().Deployments().Informer().GetIndexer().Add(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "ignored", Namespace: "kube-system"},
+		Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpec("coredns:1.11")}},
+	})
+	im.factory.Apps().V1().ReplicaSets().Informer().GetIndexer().Add(&appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-7d9f", Namespace: "default", OwnerReferences: controlledBy("Deployment", "web")},
+		Spec:       appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{Spec: podSpec("nginx:1.25")}},
+	})
+	im.factory.Batch().V1().Jobs().Informer().GetIndexer().Add(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "staging"},
+		Spec:       batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: podSpec("flyway:10")}},
+	})
+	im.factory.Core().V1().Pods().Informer().GetIndexer().Add(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-7d9f-x1", Namespace: "default", OwnerReferences: controlledBy("ReplicaSet", "web-7d9f")},
+		Spec:       podSpec("nginx:1.25"),
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "main", ImageID: "docker.io/library/nginx@sha256:abc"},
+		}},
+	})
+
+	events, err := im.ListImages()
+	if err != nil {
+		t.Fatalf("ListImages failed: %v", err)
+	}
+
+	var got []string
+	for _, event := range events {
+		if event.Type != EventTypeAdd {
+			t.Errorf("Expected ADD event, got %s", event.Type)
+		}
+		got = append(got, event.ResourceType+"/"+event.ResourceName+"="+event.ImageName+":"+event.ImageTag)
+	}
+	sort.Strings(got)
+
+	expected := []string{"Deployment/web=nginx:1.25", "Job/migrate=flyway:10"}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], got[i])
+		}
+	}
+
+	running, err := im.ListRunningImages()
+	if err != nil {
+		t.Fatalf("ListRunningImages failed: %v", err)
+	}
+	if len(running) != 1 {
+		t.Fatalf("Expected 1 running image, got %d", len(running))
+	}
+	if running[0].ResourceType != "Deployment" || running[0].ResourceName != "web" {
+		t.Errorf("Expected Deployment/web, got %s/%s", running[0].ResourceType, running[0].ResourceName)
+	}
+	if running[0].PodName != "web-7d9f-x1" || running[0].RunningDigest != "sha256:abc" {
+		t.Errorf("Expected web-7d9f-x1 running sha256:abc, got %s running %s", running[0].PodName, running[0].RunningDigest)
+	}
+}
@@ -5,6 +5,7 @@ package mocks
 import (
 	models "github.com/huseyinbabal/kubetag/internal/models"
 	mock "github.com/stretchr/testify/mock"
+	time "time"
 )
 
 // MockImageRepository is an autogenerated mock type for the ImageRepositoryInterface type
@@ -20,17 +21,109 @@ func (_m *MockImageRepository) EXPECT() *MockImageRepository_Expecter {
 	return &MockImageRepository_Expecter{mock: &_m.Mock}
 }
 
-// DeleteImageTag provides a mock function with given fields: resourceType, resourceName, namespace
-func (_m *MockImageRepository) DeleteImageTag(resourceType string, resourceName string, namespace string) error {
-	ret := _m.Called(resourceType, resourceName, namespace)
+// AppendImageEvents provides a mock function with given fields: events
+func (_m *MockImageRepository) AppendImageEvents(events []models.ImageEvent) error {
+	ret := _m.Called(events)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AppendImageEvents")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]models.ImageEvent) error); ok {
+		r0 = rf(events)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockImageRepository_AppendImageEvents_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AppendImageEvents'
+type MockImageRepository_AppendImageEvents_Call struct {
+	*mock.Call
+}
+
+// AppendImageEvents is a helper method to define mock.On call
+//   - events []models.ImageEvent
+func (_e *MockImageRepository_Expecter) AppendImageEvents(events interface{}) *MockImageRepository_AppendImageEvents_Call {
+	return &MockImageRepository_AppendImageEvents_Call{Call: _e.mock.On("AppendImageEvents", events)}
+}
+
+func (_c *MockImageRepository_AppendImageEvents_Call) Run(run func(events []models.ImageEvent)) *MockImageRepository_AppendImageEvents_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]models.ImageEvent))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_AppendImageEvents_Call) Return(_a0 error) *MockImageRepository_AppendImageEvents_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockImageRepository_AppendImageEvents_Call) RunAndReturn(run func([]models.ImageEvent) error) *MockImageRepository_AppendImageEvents_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AppendWebhookDelivery provides a mock function with given fields: delivery
+func (_m *MockImageRepository) AppendWebhookDelivery(delivery *models.WebhookDelivery) error {
+	ret := _m.Called(delivery)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AppendWebhookDelivery")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.WebhookDelivery) error); ok {
+		r0 = rf(delivery)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockImageRepository_AppendWebhookDelivery_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AppendWebhookDelivery'
+type MockImageRepository_AppendWebhookDelivery_Call struct {
+	*mock.Call
+}
+
+// AppendWebhookDelivery is a helper method to define mock.On call
+//   - delivery *models.WebhookDelivery
+func (_e *MockImageRepository_Expecter) AppendWebhookDelivery(delivery interface{}) *MockImageRepository_AppendWebhookDelivery_Call {
+	return &MockImageRepository_AppendWebhookDelivery_Call{Call: _e.mock.On("AppendWebhookDelivery", delivery)}
+}
+
+func (_c *MockImageRepository_AppendWebhookDelivery_Call) Run(run func(delivery *models.WebhookDelivery)) *MockImageRepository_AppendWebhookDelivery_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*models.WebhookDelivery))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_AppendWebhookDelivery_Call) Return(_a0 error) *MockImageRepository_AppendWebhookDelivery_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockImageRepository_AppendWebhookDelivery_Call) RunAndReturn(run func(*models.WebhookDelivery) error) *MockImageRepository_AppendWebhookDelivery_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteImageTag provides a mock function with given fields: resourceType, resourceName, namespace, containerName
+func (_m *MockImageRepository) DeleteImageTag(resourceType string, resourceName string, namespace string, containerName string) error {
+	ret := _m.Called(resourceType, resourceName, namespace, containerName)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteImageTag")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string, string) error); ok {
-		r0 = rf(resourceType, resourceName, namespace)
+	if rf, ok := ret.Get(0).(func(string, string, string, string) error); ok {
+		r0 = rf(resourceType, resourceName, namespace, containerName)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -47,13 +140,14 @@ type MockImageRepository_DeleteImageTag_Call struct {
 //   - resourceType string
 //   - resourceName string
 //   - namespace string
-func (_e *MockImageRepository_Expecter) DeleteImageTag(resourceType interface{}, resourceName interface{}, namespace interface{}) *MockImageRepository_DeleteImageTag_Call {
-	return &MockImageRepository_DeleteImageTag_Call{Call: _e.mock.On("DeleteImageTag", resourceType, resourceName, namespace)}
+//   - containerName string
+func (_e *MockImageRepository_Expecter) DeleteImageTag(resourceType interface{}, resourceName interface{}, namespace interface{}, containerName interface{}) *MockImageRepository_DeleteImageTag_Call {
+	return &MockImageRepository_DeleteImageTag_Call{Call: _e.mock.On("DeleteImageTag", resourceType, resourceName, namespace, containerName)}
 }
 
-func (_c *MockImageRepository_DeleteImageTag_Call) Run(run func(resourceType string, resourceName string, namespace string)) *MockImageRepository_DeleteImageTag_Call {
+func (_c *MockImageRepository_DeleteImageTag_Call) Run(run func(resourceType string, resourceName string, namespace string, containerName string)) *MockImageRepository_DeleteImageTag_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string), args[1].(string), args[2].(string))
+		run(args[0].(string), args[1].(string), args[2].(string), args[3].(string))
 	})
 	return _c
 }
@@ -63,7 +157,100 @@ func (_c *MockImageRepository_DeleteImageTag_Call) Return(_a0 error) *MockImageR
 	return _c
 }
 
-func (_c *MockImageRepository_DeleteImageTag_Call) RunAndReturn(run func(string, string, string) error) *MockImageRepository_DeleteImageTag_Call {
+func (_c *MockImageRepository_DeleteImageTag_Call) RunAndReturn(run func(string, string, string, string) error) *MockImageRepository_DeleteImageTag_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteImageTagsByID provides a mock function with given fields: ids
+func (_m *MockImageRepository) DeleteImageTagsByID(ids []uint) error {
+	ret := _m.Called(ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteImageTagsByID")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]uint) error); ok {
+		r0 = rf(ids)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockImageRepository_DeleteImageTagsByID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteImageTagsByID'
+type MockImageRepository_DeleteImageTagsByID_Call struct {
+	*mock.Call
+}
+
+// DeleteImageTagsByID is a helper method to define mock.On call
+//   - ids []uint
+func (_e *MockImageRepository_Expecter) DeleteImageTagsByID(ids interface{}) *MockImageRepository_DeleteImageTagsByID_Call {
+	return &MockImageRepository_DeleteImageTagsByID_Call{Call: _e.mock.On("DeleteImageTagsByID", ids)}
+}
+
+func (_c *MockImageRepository_DeleteImageTagsByID_Call) Run(run func(ids []uint)) *MockImageRepository_DeleteImageTagsByID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]uint))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_DeleteImageTagsByID_Call) Return(_a0 error) *MockImageRepository_DeleteImageTagsByID_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockImageRepository_DeleteImageTagsByID_Call) RunAndReturn(run func([]uint) error) *MockImageRepository_DeleteImageTagsByID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteRunningImages provides a mock function with given fields: namespace, podName
+func (_m *MockImageRepository) DeleteRunningImages(namespace string, podName string) error {
+	ret := _m.Called(namespace, podName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteRunningImages")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(namespace, podName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockImageRepository_DeleteRunningImages_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteRunningImages'
+type MockImageRepository_DeleteRunningImages_Call struct {
+	*mock.Call
+}
+
+// DeleteRunningImages is a helper method to define mock.On call
+//   - namespace string
+//   - podName string
+func (_e *MockImageRepository_Expecter) DeleteRunningImages(namespace interface{}, podName interface{}) *MockImageRepository_DeleteRunningImages_Call {
+	return &MockImageRepository_DeleteRunningImages_Call{Call: _e.mock.On("DeleteRunningImages", namespace, podName)}
+}
+
+func (_c *MockImageRepository_DeleteRunningImages_Call) Run(run func(namespace string, podName string)) *MockImageRepository_DeleteRunningImages_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_DeleteRunningImages_Call) Return(_a0 error) *MockImageRepository_DeleteRunningImages_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockImageRepository_DeleteRunningImages_Call) RunAndReturn(run func(string, string) error) *MockImageRepository_DeleteRunningImages_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -185,30 +372,997 @@ func (_c *MockImageRepository_GetImageTagHistory_Call) RunAndReturn(run func(str
 	return _c
 }
 
-// UpsertImageTag provides a mock function with given fields: imageName, _a1, tag, resourceType, resourceName, namespace, containerName
-func (_m *MockImageRepository) UpsertImageTag(imageName string, _a1 string, tag string, resourceType string, resourceName string, namespace string, containerName string) error {
-	ret := _m.Called(imageName, _a1, tag, resourceType, resourceName, namespace, containerName)
+// GetImagesAt provides a mock function with given fields: namespace, at
+func (_m *MockImageRepository) GetImagesAt(namespace string, at time.Time) ([]models.ImageInfo, error) {
+	ret := _m.Called(namespace, at)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UpsertImageTag")
+		panic("no return value specified for GetImagesAt")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string, string, string, string, string, string) error); ok {
-		r0 = rf(imageName, _a1, tag, resourceType, resourceName, namespace, containerName)
+	var r0 []models.ImageInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, time.Time) ([]models.ImageInfo, error)); ok {
+		return rf(namespace, at)
+	}
+	if rf, ok := ret.Get(0).(func(string, time.Time) []models.ImageInfo); ok {
+		r0 = rf(namespace, at)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ImageInfo)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(string, time.Time) error); ok {
+		r1 = rf(namespace, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// MockImageRepository_UpsertImageTag_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpsertImageTag'
-type MockImageRepository_UpsertImageTag_Call struct {
+// MockImageRepository_GetImagesAt_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetImagesAt'
+type MockImageRepository_GetImagesAt_Call struct {
 	*mock.Call
 }
 
-// UpsertImageTag is a helper method to define mock.On call
+// GetImagesAt is a helper method to define mock.On call
+//   - namespace string
+//   - at time.Time
+func (_e *MockImageRepository_Expecter) GetImagesAt(namespace interface{}, at interface{}) *MockImageRepository_GetImagesAt_Call {
+	return &MockImageRepository_GetImagesAt_Call{Call: _e.mock.On("GetImagesAt", namespace, at)}
+}
+
+func (_c *MockImageRepository_GetImagesAt_Call) Run(run func(namespace string, at time.Time)) *MockImageRepository_GetImagesAt_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(time.Time))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_GetImagesAt_Call) Return(_a0 []models.ImageInfo, _a1 error) *MockImageRepository_GetImagesAt_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageRepository_GetImagesAt_Call) RunAndReturn(run func(string, time.Time) ([]models.ImageInfo, error)) *MockImageRepository_GetImagesAt_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetResourceImageTags provides a mock function with given fields: resourceType, resourceName, namespace
+func (_m *MockImageRepository) GetResourceImageTags(resourceType string, resourceName string, namespace string) ([]models.ImageTag, error) {
+	ret := _m.Called(resourceType, resourceName, namespace)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetResourceImageTags")
+	}
+
+	var r0 []models.ImageTag
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string, string) ([]models.ImageTag, error)); ok {
+		return rf(resourceType, resourceName, namespace)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, string) []models.ImageTag); ok {
+		r0 = rf(resourceType, resourceName, namespace)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ImageTag)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
+		r1 = rf(resourceType, resourceName, namespace)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageRepository_GetResourceImageTags_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetResourceImageTags'
+type MockImageRepository_GetResourceImageTags_Call struct {
+	*mock.Call
+}
+
+// GetResourceImageTags is a helper method to define mock.On call
+//   - resourceType string
+//   - resourceName string
+//   - namespace string
+func (_e *MockImageRepository_Expecter) GetResourceImageTags(resourceType interface{}, resourceName interface{}, namespace interface{}) *MockImageRepository_GetResourceImageTags_Call {
+	return &MockImageRepository_GetResourceImageTags_Call{Call: _e.mock.On("GetResourceImageTags", resourceType, resourceName, namespace)}
+}
+
+func (_c *MockImageRepository_GetResourceImageTags_Call) Run(run func(resourceType string, resourceName string, namespace string)) *MockImageRepository_GetResourceImageTags_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_GetResourceImageTags_Call) Return(_a0 []models.ImageTag, _a1 error) *MockImageRepository_GetResourceImageTags_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageRepository_GetResourceImageTags_Call) RunAndReturn(run func(string, string, string) ([]models.ImageTag, error)) *MockImageRepository_GetResourceImageTags_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListActiveImageTags provides a mock function with no fields
+func (_m *MockImageRepository) ListActiveImageTags() ([]models.ImageTag, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListActiveImageTags")
+	}
+
+	var r0 []models.ImageTag
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]models.ImageTag, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []models.ImageTag); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ImageTag)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageRepository_ListActiveImageTags_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListActiveImageTags'
+type MockImageRepository_ListActiveImageTags_Call struct {
+	*mock.Call
+}
+
+// ListActiveImageTags is a helper method to define mock.On call
+func (_e *MockImageRepository_Expecter) ListActiveImageTags() *MockImageRepository_ListActiveImageTags_Call {
+	return &MockImageRepository_ListActiveImageTags_Call{Call: _e.mock.On("ListActiveImageTags")}
+}
+
+func (_c *MockImageRepository_ListActiveImageTags_Call) Run(run func()) *MockImageRepository_ListActiveImageTags_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_ListActiveImageTags_Call) Return(_a0 []models.ImageTag, _a1 error) *MockImageRepository_ListActiveImageTags_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageRepository_ListActiveImageTags_Call) RunAndReturn(run func() ([]models.ImageTag, error)) *MockImageRepository_ListActiveImageTags_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListImageEvents provides a mock function with given fields: filter
+func (_m *MockImageRepository) ListImageEvents(filter models.ImageEventFilter) ([]models.ImageEvent, error) {
+	ret := _m.Called(filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListImageEvents")
+	}
+
+	var r0 []models.ImageEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(models.ImageEventFilter) ([]models.ImageEvent, error)); ok {
+		return rf(filter)
+	}
+	if rf, ok := ret.Get(0).(func(models.ImageEventFilter) []models.ImageEvent); ok {
+		r0 = rf(filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ImageEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(models.ImageEventFilter) error); ok {
+		r1 = rf(filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageRepository_ListImageEvents_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListImageEvents'
+type MockImageRepository_ListImageEvents_Call struct {
+	*mock.Call
+}
+
+// ListImageEvents is a helper method to define mock.On call
+//   - filter models.ImageEventFilter
+func (_e *MockImageRepository_Expecter) ListImageEvents(filter interface{}) *MockImageRepository_ListImageEvents_Call {
+	return &MockImageRepository_ListImageEvents_Call{Call: _e.mock.On("ListImageEvents", filter)}
+}
+
+func (_c *MockImageRepository_ListImageEvents_Call) Run(run func(filter models.ImageEventFilter)) *MockImageRepository_ListImageEvents_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(models.ImageEventFilter))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_ListImageEvents_Call) Return(_a0 []models.ImageEvent, _a1 error) *MockImageRepository_ListImageEvents_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageRepository_ListImageEvents_Call) RunAndReturn(run func(models.ImageEventFilter) ([]models.ImageEvent, error)) *MockImageRepository_ListImageEvents_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListImageUpdates provides a mock function with no fields
+func (_m *MockImageRepository) ListImageUpdates() ([]models.ImageUpdate, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListImageUpdates")
+	}
+
+	var r0 []models.ImageUpdate
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]models.ImageUpdate, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []models.ImageUpdate); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ImageUpdate)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageRepository_ListImageUpdates_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListImageUpdates'
+type MockImageRepository_ListImageUpdates_Call struct {
+	*mock.Call
+}
+
+// ListImageUpdates is a helper method to define mock.On call
+func (_e *MockImageRepository_Expecter) ListImageUpdates() *MockImageRepository_ListImageUpdates_Call {
+	return &MockImageRepository_ListImageUpdates_Call{Call: _e.mock.On("ListImageUpdates")}
+}
+
+func (_c *MockImageRepository_ListImageUpdates_Call) Run(run func()) *MockImageRepository_ListImageUpdates_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_ListImageUpdates_Call) Return(_a0 []models.ImageUpdate, _a1 error) *MockImageRepository_ListImageUpdates_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageRepository_ListImageUpdates_Call) RunAndReturn(run func() ([]models.ImageUpdate, error)) *MockImageRepository_ListImageUpdates_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListPrunableImageTags provides a mock function with given fields: query
+func (_m *MockImageRepository) ListPrunableImageTags(query models.PruneQuery) ([]uint, error) {
+	ret := _m.Called(query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPrunableImageTags")
+	}
+
+	var r0 []uint
+	var r1 error
+	if rf, ok := ret.Get(0).(func(models.PruneQuery) ([]uint, error)); ok {
+		return rf(query)
+	}
+	if rf, ok := ret.Get(0).(func(models.PruneQuery) []uint); ok {
+		r0 = rf(query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uint)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(models.PruneQuery) error); ok {
+		r1 = rf(query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageRepository_ListPrunableImageTags_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListPrunableImageTags'
+type MockImageRepository_ListPrunableImageTags_Call struct {
+	*mock.Call
+}
+
+// ListPrunableImageTags is a helper method to define mock.On call
+//   - query models.PruneQuery
+func (_e *MockImageRepository_Expecter) ListPrunableImageTags(query interface{}) *MockImageRepository_ListPrunableImageTags_Call {
+	return &MockImageRepository_ListPrunableImageTags_Call{Call: _e.mock.On("ListPrunableImageTags", query)}
+}
+
+func (_c *MockImageRepository_ListPrunableImageTags_Call) Run(run func(query models.PruneQuery)) *MockImageRepository_ListPrunableImageTags_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(models.PruneQuery))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_ListPrunableImageTags_Call) Return(_a0 []uint, _a1 error) *MockImageRepository_ListPrunableImageTags_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageRepository_ListPrunableImageTags_Call) RunAndReturn(run func(models.PruneQuery) ([]uint, error)) *MockImageRepository_ListPrunableImageTags_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListRunningImages provides a mock function with no fields
+func (_m *MockImageRepository) ListRunningImages() ([]models.RunningImage, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRunningImages")
+	}
+
+	var r0 []models.RunningImage
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]models.RunningImage, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []models.RunningImage); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.RunningImage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageRepository_ListRunningImages_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListRunningImages'
+type MockImageRepository_ListRunningImages_Call struct {
+	*mock.Call
+}
+
+// ListRunningImages is a helper method to define mock.On call
+func (_e *MockImageRepository_Expecter) ListRunningImages() *MockImageRepository_ListRunningImages_Call {
+	return &MockImageRepository_ListRunningImages_Call{Call: _e.mock.On("ListRunningImages")}
+}
+
+func (_c *MockImageRepository_ListRunningImages_Call) Run(run func()) *MockImageRepository_ListRunningImages_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_ListRunningImages_Call) Return(_a0 []models.RunningImage, _a1 error) *MockImageRepository_ListRunningImages_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageRepository_ListRunningImages_Call) RunAndReturn(run func() ([]models.RunningImage, error)) *MockImageRepository_ListRunningImages_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListWebhookDeliveries provides a mock function with given fields: filter
+func (_m *MockImageRepository) ListWebhookDeliveries(filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	ret := _m.Called(filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListWebhookDeliveries")
+	}
+
+	var r0 []models.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error)); ok {
+		return rf(filter)
+	}
+	if rf, ok := ret.Get(0).(func(models.WebhookDeliveryFilter) []models.WebhookDelivery); ok {
+		r0 = rf(filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(models.WebhookDeliveryFilter) error); ok {
+		r1 = rf(filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageRepository_ListWebhookDeliveries_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListWebhookDeliveries'
+type MockImageRepository_ListWebhookDeliveries_Call struct {
+	*mock.Call
+}
+
+// ListWebhookDeliveries is a helper method to define mock.On call
+//   - filter models.WebhookDeliveryFilter
+func (_e *MockImageRepository_Expecter) ListWebhookDeliveries(filter interface{}) *MockImageRepository_ListWebhookDeliveries_Call {
+	return &MockImageRepository_ListWebhookDeliveries_Call{Call: _e.mock.On("ListWebhookDeliveries", filter)}
+}
+
+func (_c *MockImageRepository_ListWebhookDeliveries_Call) Run(run func(filter models.WebhookDeliveryFilter)) *MockImageRepository_ListWebhookDeliveries_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(models.WebhookDeliveryFilter))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_ListWebhookDeliveries_Call) Return(_a0 []models.WebhookDelivery, _a1 error) *MockImageRepository_ListWebhookDeliveries_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageRepository_ListWebhookDeliveries_Call) RunAndReturn(run func(models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error)) *MockImageRepository_ListWebhookDeliveries_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PurgeImageEvents provides a mock function with given fields: before, dryRun
+func (_m *MockImageRepository) PurgeImageEvents(before time.Time, dryRun bool) (int64, error) {
+	ret := _m.Called(before, dryRun)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeImageEvents")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(time.Time, bool) (int64, error)); ok {
+		return rf(before, dryRun)
+	}
+	if rf, ok := ret.Get(0).(func(time.Time, bool) int64); ok {
+		r0 = rf(before, dryRun)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(time.Time, bool) error); ok {
+		r1 = rf(before, dryRun)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageRepository_PurgeImageEvents_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PurgeImageEvents'
+type MockImageRepository_PurgeImageEvents_Call struct {
+	*mock.Call
+}
+
+// PurgeImageEvents is a helper method to define mock.On call
+//   - before time.Time
+//   - dryRun bool
+func (_e *MockImageRepository_Expecter) PurgeImageEvents(before interface{}, dryRun interface{}) *MockImageRepository_PurgeImageEvents_Call {
+	return &MockImageRepository_PurgeImageEvents_Call{Call: _e.mock.On("PurgeImageEvents", before, dryRun)}
+}
+
+func (_c *MockImageRepository_PurgeImageEvents_Call) Run(run func(before time.Time, dryRun bool)) *MockImageRepository_PurgeImageEvents_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(time.Time), args[1].(bool))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_PurgeImageEvents_Call) Return(_a0 int64, _a1 error) *MockImageRepository_PurgeImageEvents_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageRepository_PurgeImageEvents_Call) RunAndReturn(run func(time.Time, bool) (int64, error)) *MockImageRepository_PurgeImageEvents_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PurgeImageTags provides a mock function with given fields: ids
+func (_m *MockImageRepository) PurgeImageTags(ids []uint) error {
+	ret := _m.Called(ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeImageTags")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]uint) error); ok {
+		r0 = rf(ids)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockImageRepository_PurgeImageTags_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PurgeImageTags'
+type MockImageRepository_PurgeImageTags_Call struct {
+	*mock.Call
+}
+
+// PurgeImageTags is a helper method to define mock.On call
+//   - ids []uint
+func (_e *MockImageRepository_Expecter) PurgeImageTags(ids interface{}) *MockImageRepository_PurgeImageTags_Call {
+	return &MockImageRepository_PurgeImageTags_Call{Call: _e.mock.On("PurgeImageTags", ids)}
+}
+
+func (_c *MockImageRepository_PurgeImageTags_Call) Run(run func(ids []uint)) *MockImageRepository_PurgeImageTags_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]uint))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_PurgeImageTags_Call) Return(_a0 error) *MockImageRepository_PurgeImageTags_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockImageRepository_PurgeImageTags_Call) RunAndReturn(run func([]uint) error) *MockImageRepository_PurgeImageTags_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PurgeWebhookDeliveries provides a mock function with given fields: before, dryRun
+func (_m *MockImageRepository) PurgeWebhookDeliveries(before time.Time, dryRun bool) (int64, error) {
+	ret := _m.Called(before, dryRun)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeWebhookDeliveries")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(time.Time, bool) (int64, error)); ok {
+		return rf(before, dryRun)
+	}
+	if rf, ok := ret.Get(0).(func(time.Time, bool) int64); ok {
+		r0 = rf(before, dryRun)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(time.Time, bool) error); ok {
+		r1 = rf(before, dryRun)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageRepository_PurgeWebhookDeliveries_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PurgeWebhookDeliveries'
+type MockImageRepository_PurgeWebhookDeliveries_Call struct {
+	*mock.Call
+}
+
+// PurgeWebhookDeliveries is a helper method to define mock.On call
+//   - before time.Time
+//   - dryRun bool
+func (_e *MockImageRepository_Expecter) PurgeWebhookDeliveries(before interface{}, dryRun interface{}) *MockImageRepository_PurgeWebhookDeliveries_Call {
+	return &MockImageRepository_PurgeWebhookDeliveries_Call{Call: _e.mock.On("PurgeWebhookDeliveries", before, dryRun)}
+}
+
+func (_c *MockImageRepository_PurgeWebhookDeliveries_Call) Run(run func(before time.Time, dryRun bool)) *MockImageRepository_PurgeWebhookDeliveries_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(time.Time), args[1].(bool))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_PurgeWebhookDeliveries_Call) Return(_a0 int64, _a1 error) *MockImageRepository_PurgeWebhookDeliveries_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageRepository_PurgeWebhookDeliveries_Call) RunAndReturn(run func(time.Time, bool) (int64, error)) *MockImageRepository_PurgeWebhookDeliveries_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// QueryImages provides a mock function with given fields: query
+func (_m *MockImageRepository) QueryImages(query models.ImageQuery) ([]models.ImageInfo, int, error) {
+	ret := _m.Called(query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueryImages")
+	}
+
+	var r0 []models.ImageInfo
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func(models.ImageQuery) ([]models.ImageInfo, int, error)); ok {
+		return rf(query)
+	}
+	if rf, ok := ret.Get(0).(func(models.ImageQuery) []models.ImageInfo); ok {
+		r0 = rf(query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ImageInfo)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(models.ImageQuery) int); ok {
+		r1 = rf(query)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	if rf, ok := ret.Get(2).(func(models.ImageQuery) error); ok {
+		r2 = rf(query)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// MockImageRepository_QueryImages_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QueryImages'
+type MockImageRepository_QueryImages_Call struct {
+	*mock.Call
+}
+
+// QueryImages is a helper method to define mock.On call
+//   - query models.ImageQuery
+func (_e *MockImageRepository_Expecter) QueryImages(query interface{}) *MockImageRepository_QueryImages_Call {
+	return &MockImageRepository_QueryImages_Call{Call: _e.mock.On("QueryImages", query)}
+}
+
+func (_c *MockImageRepository_QueryImages_Call) Run(run func(query models.ImageQuery)) *MockImageRepository_QueryImages_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(models.ImageQuery))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_QueryImages_Call) Return(_a0 []models.ImageInfo, _a1 int, _a2 error) *MockImageRepository_QueryImages_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *MockImageRepository_QueryImages_Call) RunAndReturn(run func(models.ImageQuery) ([]models.ImageInfo, int, error)) *MockImageRepository_QueryImages_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ReplaceImageTag provides a mock function with given fields: imageName, _a1, tag, digest, resourceType, resourceName, namespace, containerName
+func (_m *MockImageRepository) ReplaceImageTag(imageName string, _a1 string, tag string, digest string, resourceType string, resourceName string, namespace string, containerName string) error {
+	ret := _m.Called(imageName, _a1, tag, digest, resourceType, resourceName, namespace, containerName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplaceImageTag")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string, string, string, string, string, string) error); ok {
+		r0 = rf(imageName, _a1, tag, digest, resourceType, resourceName, namespace, containerName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockImageRepository_ReplaceImageTag_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReplaceImageTag'
+type MockImageRepository_ReplaceImageTag_Call struct {
+	*mock.Call
+}
+
+// ReplaceImageTag is a helper method to define mock.On call
+//   - imageName string
+//   - _a1 string
+//   - tag string
+//   - digest string
+//   - resourceType string
+//   - resourceName string
+//   - namespace string
+//   - containerName string
+func (_e *MockImageRepository_Expecter) ReplaceImageTag(imageName interface{}, _a1 interface{}, tag interface{}, digest interface{}, resourceType interface{}, resourceName interface{}, namespace interface{}, containerName interface{}) *MockImageRepository_ReplaceImageTag_Call {
+	return &MockImageRepository_ReplaceImageTag_Call{Call: _e.mock.On("ReplaceImageTag", imageName, _a1, tag, digest, resourceType, resourceName, namespace, containerName)}
+}
+
+func (_c *MockImageRepository_ReplaceImageTag_Call) Run(run func(imageName string, _a1 string, tag string, digest string, resourceType string, resourceName string, namespace string, containerName string)) *MockImageRepository_ReplaceImageTag_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(string), args[3].(string), args[4].(string), args[5].(string), args[6].(string), args[7].(string))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_ReplaceImageTag_Call) Return(_a0 error) *MockImageRepository_ReplaceImageTag_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockImageRepository_ReplaceImageTag_Call) RunAndReturn(run func(string, string, string, string, string, string, string, string) error) *MockImageRepository_ReplaceImageTag_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SearchImageTags provides a mock function with given fields: query
+func (_m *MockImageRepository) SearchImageTags(query models.SearchQuery) ([]models.ImageTag, error) {
+	ret := _m.Called(query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchImageTags")
+	}
+
+	var r0 []models.ImageTag
+	var r1 error
+	if rf, ok := ret.Get(0).(func(models.SearchQuery) ([]models.ImageTag, error)); ok {
+		return rf(query)
+	}
+	if rf, ok := ret.Get(0).(func(models.SearchQuery) []models.ImageTag); ok {
+		r0 = rf(query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ImageTag)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(models.SearchQuery) error); ok {
+		r1 = rf(query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageRepository_SearchImageTags_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SearchImageTags'
+type MockImageRepository_SearchImageTags_Call struct {
+	*mock.Call
+}
+
+// SearchImageTags is a helper method to define mock.On call
+//   - query models.SearchQuery
+func (_e *MockImageRepository_Expecter) SearchImageTags(query interface{}) *MockImageRepository_SearchImageTags_Call {
+	return &MockImageRepository_SearchImageTags_Call{Call: _e.mock.On("SearchImageTags", query)}
+}
+
+func (_c *MockImageRepository_SearchImageTags_Call) Run(run func(query models.SearchQuery)) *MockImageRepository_SearchImageTags_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(models.SearchQuery))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_SearchImageTags_Call) Return(_a0 []models.ImageTag, _a1 error) *MockImageRepository_SearchImageTags_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageRepository_SearchImageTags_Call) RunAndReturn(run func(models.SearchQuery) ([]models.ImageTag, error)) *MockImageRepository_SearchImageTags_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpsertImageTag provides a mock function with given fields: imageName, _a1, tag, digest, resourceType, resourceName, namespace, containerName
+func (_m *MockImageRepository) UpsertImageTag(imageName string, _a1 string, tag string, digest string, resourceType string, resourceName string, namespace string, containerName string) (bool, error) {
+	ret := _m.Called(imageName, _a1, tag, digest, resourceType, resourceName, namespace, containerName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertImageTag")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string, string, string, string, string, string, string) (bool, error)); ok {
+		return rf(imageName, _a1, tag, digest, resourceType, resourceName, namespace, containerName)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, string, string, string, string, string, string) bool); ok {
+		r0 = rf(imageName, _a1, tag, digest, resourceType, resourceName, namespace, containerName)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, string, string, string, string, string, string) error); ok {
+		r1 = rf(imageName, _a1, tag, digest, resourceType, resourceName, namespace, containerName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageRepository_UpsertImageTag_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpsertImageTag'
+type MockImageRepository_UpsertImageTag_Call struct {
+	*mock.Call
+}
+
+// UpsertImageTag is a helper method to define mock.On call
+//   - imageName string
+//   - _a1 string
+//   - tag string
+//   - digest string
+//   - resourceType string
+//   - resourceName string
+//   - namespace string
+//   - containerName string
+func (_e *MockImageRepository_Expecter) UpsertImageTag(imageName interface{}, _a1 interface{}, tag interface{}, digest interface{}, resourceType interface{}, resourceName interface{}, namespace interface{}, containerName interface{}) *MockImageRepository_UpsertImageTag_Call {
+	return &MockImageRepository_UpsertImageTag_Call{Call: _e.mock.On("UpsertImageTag", imageName, _a1, tag, digest, resourceType, resourceName, namespace, containerName)}
+}
+
+func (_c *MockImageRepository_UpsertImageTag_Call) Run(run func(imageName string, _a1 string, tag string, digest string, resourceType string, resourceName string, namespace string, containerName string)) *MockImageRepository_UpsertImageTag_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(string), args[3].(string), args[4].(string), args[5].(string), args[6].(string), args[7].(string))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_UpsertImageTag_Call) Return(_a0 bool, _a1 error) *MockImageRepository_UpsertImageTag_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageRepository_UpsertImageTag_Call) RunAndReturn(run func(string, string, string, string, string, string, string, string) (bool, error)) *MockImageRepository_UpsertImageTag_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpsertImageTags provides a mock function with given fields: tags
+func (_m *MockImageRepository) UpsertImageTags(tags []models.ImageTagUpsert) ([]bool, error) {
+	ret := _m.Called(tags)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertImageTags")
+	}
+
+	var r0 []bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]models.ImageTagUpsert) ([]bool, error)); ok {
+		return rf(tags)
+	}
+	if rf, ok := ret.Get(0).(func([]models.ImageTagUpsert) []bool); ok {
+		r0 = rf(tags)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bool)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]models.ImageTagUpsert) error); ok {
+		r1 = rf(tags)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageRepository_UpsertImageTags_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpsertImageTags'
+type MockImageRepository_UpsertImageTags_Call struct {
+	*mock.Call
+}
+
+// UpsertImageTags is a helper method to define mock.On call
+//   - tags []models.ImageTagUpsert
+func (_e *MockImageRepository_Expecter) UpsertImageTags(tags interface{}) *MockImageRepository_UpsertImageTags_Call {
+	return &MockImageRepository_UpsertImageTags_Call{Call: _e.mock.On("UpsertImageTags", tags)}
+}
+
+func (_c *MockImageRepository_UpsertImageTags_Call) Run(run func(tags []models.ImageTagUpsert)) *MockImageRepository_UpsertImageTags_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]models.ImageTagUpsert))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_UpsertImageTags_Call) Return(_a0 []bool, _a1 error) *MockImageRepository_UpsertImageTags_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageRepository_UpsertImageTags_Call) RunAndReturn(run func([]models.ImageTagUpsert) ([]bool, error)) *MockImageRepository_UpsertImageTags_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpsertImageUpdates provides a mock function with given fields: updates
+func (_m *MockImageRepository) UpsertImageUpdates(updates []models.ImageUpdate) error {
+	ret := _m.Called(updates)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertImageUpdates")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]models.ImageUpdate) error); ok {
+		r0 = rf(updates)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockImageRepository_UpsertImageUpdates_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpsertImageUpdates'
+type MockImageRepository_UpsertImageUpdates_Call struct {
+	*mock.Call
+}
+
+// UpsertImageUpdates is a helper method to define mock.On call
+//   - updates []models.ImageUpdate
+func (_e *MockImageRepository_Expecter) UpsertImageUpdates(updates interface{}) *MockImageRepository_UpsertImageUpdates_Call {
+	return &MockImageRepository_UpsertImageUpdates_Call{Call: _e.mock.On("UpsertImageUpdates", updates)}
+}
+
+func (_c *MockImageRepository_UpsertImageUpdates_Call) Run(run func(updates []models.ImageUpdate)) *MockImageRepository_UpsertImageUpdates_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]models.ImageUpdate))
+	})
+	return _c
+}
+
+func (_c *MockImageRepository_UpsertImageUpdates_Call) Return(_a0 error) *MockImageRepository_UpsertImageUpdates_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockImageRepository_UpsertImageUpdates_Call) RunAndReturn(run func([]models.ImageUpdate) error) *MockImageRepository_UpsertImageUpdates_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpsertRunningImage provides a mock function with given fields: imageName, _a1, tag, resourceType, resourceName, namespace, containerName, podName, digest
+func (_m *MockImageRepository) UpsertRunningImage(imageName string, _a1 string, tag string, resourceType string, resourceName string, namespace string, containerName string, podName string, digest string) error {
+	ret := _m.Called(imageName, _a1, tag, resourceType, resourceName, namespace, containerName, podName, digest)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertRunningImage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string, string, string, string, string, string, string) error); ok {
+		r0 = rf(imageName, _a1, tag, resourceType, resourceName, namespace, containerName, podName, digest)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockImageRepository_UpsertRunningImage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpsertRunningImage'
+type MockImageRepository_UpsertRunningImage_Call struct {
+	*mock.Call
+}
+
+// UpsertRunningImage is a helper method to define mock.On call
 //   - imageName string
 //   - _a1 string
 //   - tag string
@@ -216,23 +1370,25 @@ type MockImageRepository_UpsertImageTag_Call struct {
 //   - resourceName string
 //   - namespace string
 //   - containerName string
-func (_e *MockImageRepository_Expecter) UpsertImageTag(imageName interface{}, _a1 interface{}, tag interface{}, resourceType interface{}, resourceName interface{}, namespace interface{}, containerName interface{}) *MockImageRepository_UpsertImageTag_Call {
-	return &MockImageRepository_UpsertImageTag_Call{Call: _e.mock.On("UpsertImageTag", imageName, _a1, tag, resourceType, resourceName, namespace, containerName)}
+//   - podName string
+//   - digest string
+func (_e *MockImageRepository_Expecter) UpsertRunningImage(imageName interface{}, _a1 interface{}, tag interface{}, resourceType interface{}, resourceName interface{}, namespace interface{}, containerName interface{}, podName interface{}, digest interface{}) *MockImageRepository_UpsertRunningImage_Call {
+	return &MockImageRepository_UpsertRunningImage_Call{Call: _e.mock.On("UpsertRunningImage", imageName, _a1, tag, resourceType, resourceName, namespace, containerName, podName, digest)}
 }
 
-func (_c *MockImageRepository_UpsertImageTag_Call) Run(run func(imageName string, _a1 string, tag string, resourceType string, resourceName string, namespace string, containerName string)) *MockImageRepository_UpsertImageTag_Call {
+func (_c *MockImageRepository_UpsertRunningImage_Call) Run(run func(imageName string, _a1 string, tag string, resourceType string, resourceName string, namespace string, containerName string, podName string, digest string)) *MockImageRepository_UpsertRunningImage_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string), args[1].(string), args[2].(string), args[3].(string), args[4].(string), args[5].(string), args[6].(string))
+		run(args[0].(string), args[1].(string), args[2].(string), args[3].(string), args[4].(string), args[5].(string), args[6].(string), args[7].(string), args[8].(string))
 	})
 	return _c
 }
 
-func (_c *MockImageRepository_UpsertImageTag_Call) Return(_a0 error) *MockImageRepository_UpsertImageTag_Call {
+func (_c *MockImageRepository_UpsertRunningImage_Call) Return(_a0 error) *MockImageRepository_UpsertRunningImage_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *MockImageRepository_UpsertImageTag_Call) RunAndReturn(run func(string, string, string, string, string, string, string) error) *MockImageRepository_UpsertImageTag_Call {
+func (_c *MockImageRepository_UpsertRunningImage_Call) RunAndReturn(run func(string, string, string, string, string, string, string, string, string) error) *MockImageRepository_UpsertRunningImage_Call {
 	_c.Call.Return(run)
 	return _c
 }
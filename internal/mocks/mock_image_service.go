@@ -4,11 +4,10 @@ package mocks
 
 import (
 	context "context"
-
 	k8s "github.com/huseyinbabal/kubetag/internal/k8s"
-	mock "github.com/stretchr/testify/mock"
-
 	models "github.com/huseyinbabal/kubetag/internal/models"
+	mock "github.com/stretchr/testify/mock"
+	time "time"
 )
 
 // MockImageService is an autogenerated mock type for the ImageServiceInterface type
@@ -24,6 +23,125 @@ func (_m *MockImageService) EXPECT() *MockImageService_Expecter {
 	return &MockImageService_Expecter{mock: &_m.Mock}
 }
 
+// DiffImages provides a mock function with given fields: ctx, left, right
+func (_m *MockImageService) DiffImages(ctx context.Context, left models.InventorySelector, right models.InventorySelector) (*models.ImageDiff, error) {
+	ret := _m.Called(ctx, left, right)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DiffImages")
+	}
+
+	var r0 *models.ImageDiff
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.InventorySelector, models.InventorySelector) (*models.ImageDiff, error)); ok {
+		return rf(ctx, left, right)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.InventorySelector, models.InventorySelector) *models.ImageDiff); ok {
+		r0 = rf(ctx, left, right)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ImageDiff)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.InventorySelector, models.InventorySelector) error); ok {
+		r1 = rf(ctx, left, right)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageService_DiffImages_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DiffImages'
+type MockImageService_DiffImages_Call struct {
+	*mock.Call
+}
+
+// DiffImages is a helper method to define mock.On call
+//   - ctx context.Context
+//   - left models.InventorySelector
+//   - right models.InventorySelector
+func (_e *MockImageService_Expecter) DiffImages(ctx interface{}, left interface{}, right interface{}) *MockImageService_DiffImages_Call {
+	return &MockImageService_DiffImages_Call{Call: _e.mock.On("DiffImages", ctx, left, right)}
+}
+
+func (_c *MockImageService_DiffImages_Call) Run(run func(ctx context.Context, left models.InventorySelector, right models.InventorySelector)) *MockImageService_DiffImages_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.InventorySelector), args[2].(models.InventorySelector))
+	})
+	return _c
+}
+
+func (_c *MockImageService_DiffImages_Call) Return(_a0 *models.ImageDiff, _a1 error) *MockImageService_DiffImages_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageService_DiffImages_Call) RunAndReturn(run func(context.Context, models.InventorySelector, models.InventorySelector) (*models.ImageDiff, error)) *MockImageService_DiffImages_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetImageEvents provides a mock function with given fields: ctx, filter
+func (_m *MockImageService) GetImageEvents(ctx context.Context, filter models.ImageEventFilter) (*models.ImageEventsResponse, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetImageEvents")
+	}
+
+	var r0 *models.ImageEventsResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.ImageEventFilter) (*models.ImageEventsResponse, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.ImageEventFilter) *models.ImageEventsResponse); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ImageEventsResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.ImageEventFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageService_GetImageEvents_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetImageEvents'
+type MockImageService_GetImageEvents_Call struct {
+	*mock.Call
+}
+
+// GetImageEvents is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter models.ImageEventFilter
+func (_e *MockImageService_Expecter) GetImageEvents(ctx interface{}, filter interface{}) *MockImageService_GetImageEvents_Call {
+	return &MockImageService_GetImageEvents_Call{Call: _e.mock.On("GetImageEvents", ctx, filter)}
+}
+
+func (_c *MockImageService_GetImageEvents_Call) Run(run func(ctx context.Context, filter models.ImageEventFilter)) *MockImageService_GetImageEvents_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.ImageEventFilter))
+	})
+	return _c
+}
+
+func (_c *MockImageService_GetImageEvents_Call) Return(_a0 *models.ImageEventsResponse, _a1 error) *MockImageService_GetImageEvents_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageService_GetImageEvents_Call) RunAndReturn(run func(context.Context, models.ImageEventFilter) (*models.ImageEventsResponse, error)) *MockImageService_GetImageEvents_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetImageTagHistory provides a mock function with given fields: ctx, imageName, namespace
 func (_m *MockImageService) GetImageTagHistory(ctx context.Context, imageName string, namespace string) (*models.ImageTagHistory, error) {
 	ret := _m.Called(ctx, imageName, namespace)
@@ -143,6 +261,246 @@ func (_c *MockImageService_GetImages_Call) RunAndReturn(run func(context.Context
 	return _c
 }
 
+// GetImagesAt provides a mock function with given fields: ctx, namespace, at
+func (_m *MockImageService) GetImagesAt(ctx context.Context, namespace string, at time.Time) (*models.ImagesResponse, error) {
+	ret := _m.Called(ctx, namespace, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetImagesAt")
+	}
+
+	var r0 *models.ImagesResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) (*models.ImagesResponse, error)); ok {
+		return rf(ctx, namespace, at)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) *models.ImagesResponse); ok {
+		r0 = rf(ctx, namespace, at)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ImagesResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) error); ok {
+		r1 = rf(ctx, namespace, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageService_GetImagesAt_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetImagesAt'
+type MockImageService_GetImagesAt_Call struct {
+	*mock.Call
+}
+
+// GetImagesAt is a helper method to define mock.On call
+//   - ctx context.Context
+//   - namespace string
+//   - at time.Time
+func (_e *MockImageService_Expecter) GetImagesAt(ctx interface{}, namespace interface{}, at interface{}) *MockImageService_GetImagesAt_Call {
+	return &MockImageService_GetImagesAt_Call{Call: _e.mock.On("GetImagesAt", ctx, namespace, at)}
+}
+
+func (_c *MockImageService_GetImagesAt_Call) Run(run func(ctx context.Context, namespace string, at time.Time)) *MockImageService_GetImagesAt_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(time.Time))
+	})
+	return _c
+}
+
+func (_c *MockImageService_GetImagesAt_Call) Return(_a0 *models.ImagesResponse, _a1 error) *MockImageService_GetImagesAt_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageService_GetImagesAt_Call) RunAndReturn(run func(context.Context, string, time.Time) (*models.ImagesResponse, error)) *MockImageService_GetImagesAt_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetOutdatedImages provides a mock function with given fields: ctx, namespace, compatibleOnly
+func (_m *MockImageService) GetOutdatedImages(ctx context.Context, namespace string, compatibleOnly bool) (*models.OutdatedImagesResponse, error) {
+	ret := _m.Called(ctx, namespace, compatibleOnly)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOutdatedImages")
+	}
+
+	var r0 *models.OutdatedImagesResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) (*models.OutdatedImagesResponse, error)); ok {
+		return rf(ctx, namespace, compatibleOnly)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) *models.OutdatedImagesResponse); ok {
+		r0 = rf(ctx, namespace, compatibleOnly)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OutdatedImagesResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool) error); ok {
+		r1 = rf(ctx, namespace, compatibleOnly)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageService_GetOutdatedImages_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetOutdatedImages'
+type MockImageService_GetOutdatedImages_Call struct {
+	*mock.Call
+}
+
+// GetOutdatedImages is a helper method to define mock.On call
+//   - ctx context.Context
+//   - namespace string
+//   - compatibleOnly bool
+func (_e *MockImageService_Expecter) GetOutdatedImages(ctx interface{}, namespace interface{}, compatibleOnly interface{}) *MockImageService_GetOutdatedImages_Call {
+	return &MockImageService_GetOutdatedImages_Call{Call: _e.mock.On("GetOutdatedImages", ctx, namespace, compatibleOnly)}
+}
+
+func (_c *MockImageService_GetOutdatedImages_Call) Run(run func(ctx context.Context, namespace string, compatibleOnly bool)) *MockImageService_GetOutdatedImages_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(bool))
+	})
+	return _c
+}
+
+func (_c *MockImageService_GetOutdatedImages_Call) Return(_a0 *models.OutdatedImagesResponse, _a1 error) *MockImageService_GetOutdatedImages_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageService_GetOutdatedImages_Call) RunAndReturn(run func(context.Context, string, bool) (*models.OutdatedImagesResponse, error)) *MockImageService_GetOutdatedImages_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetResourceTimeline provides a mock function with given fields: ctx, namespace, resourceType, resourceName
+func (_m *MockImageService) GetResourceTimeline(ctx context.Context, namespace string, resourceType string, resourceName string) (*models.ResourceTimeline, error) {
+	ret := _m.Called(ctx, namespace, resourceType, resourceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetResourceTimeline")
+	}
+
+	var r0 *models.ResourceTimeline
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (*models.ResourceTimeline, error)); ok {
+		return rf(ctx, namespace, resourceType, resourceName)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *models.ResourceTimeline); ok {
+		r0 = rf(ctx, namespace, resourceType, resourceName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ResourceTimeline)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, namespace, resourceType, resourceName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageService_GetResourceTimeline_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetResourceTimeline'
+type MockImageService_GetResourceTimeline_Call struct {
+	*mock.Call
+}
+
+// GetResourceTimeline is a helper method to define mock.On call
+//   - ctx context.Context
+//   - namespace string
+//   - resourceType string
+//   - resourceName string
+func (_e *MockImageService_Expecter) GetResourceTimeline(ctx interface{}, namespace interface{}, resourceType interface{}, resourceName interface{}) *MockImageService_GetResourceTimeline_Call {
+	return &MockImageService_GetResourceTimeline_Call{Call: _e.mock.On("GetResourceTimeline", ctx, namespace, resourceType, resourceName)}
+}
+
+func (_c *MockImageService_GetResourceTimeline_Call) Run(run func(ctx context.Context, namespace string, resourceType string, resourceName string)) *MockImageService_GetResourceTimeline_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string))
+	})
+	return _c
+}
+
+func (_c *MockImageService_GetResourceTimeline_Call) Return(_a0 *models.ResourceTimeline, _a1 error) *MockImageService_GetResourceTimeline_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageService_GetResourceTimeline_Call) RunAndReturn(run func(context.Context, string, string, string) (*models.ResourceTimeline, error)) *MockImageService_GetResourceTimeline_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetWebhookDeliveries provides a mock function with given fields: ctx, filter
+func (_m *MockImageService) GetWebhookDeliveries(ctx context.Context, filter models.WebhookDeliveryFilter) (*models.WebhookDeliveriesResponse, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWebhookDeliveries")
+	}
+
+	var r0 *models.WebhookDeliveriesResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.WebhookDeliveryFilter) (*models.WebhookDeliveriesResponse, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.WebhookDeliveryFilter) *models.WebhookDeliveriesResponse); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebhookDeliveriesResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.WebhookDeliveryFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageService_GetWebhookDeliveries_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetWebhookDeliveries'
+type MockImageService_GetWebhookDeliveries_Call struct {
+	*mock.Call
+}
+
+// GetWebhookDeliveries is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter models.WebhookDeliveryFilter
+func (_e *MockImageService_Expecter) GetWebhookDeliveries(ctx interface{}, filter interface{}) *MockImageService_GetWebhookDeliveries_Call {
+	return &MockImageService_GetWebhookDeliveries_Call{Call: _e.mock.On("GetWebhookDeliveries", ctx, filter)}
+}
+
+func (_c *MockImageService_GetWebhookDeliveries_Call) Run(run func(ctx context.Context, filter models.WebhookDeliveryFilter)) *MockImageService_GetWebhookDeliveries_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.WebhookDeliveryFilter))
+	})
+	return _c
+}
+
+func (_c *MockImageService_GetWebhookDeliveries_Call) Return(_a0 *models.WebhookDeliveriesResponse, _a1 error) *MockImageService_GetWebhookDeliveries_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageService_GetWebhookDeliveries_Call) RunAndReturn(run func(context.Context, models.WebhookDeliveryFilter) (*models.WebhookDeliveriesResponse, error)) *MockImageService_GetWebhookDeliveries_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // HandleImageEvent provides a mock function with given fields: event
 func (_m *MockImageService) HandleImageEvent(event k8s.ImageEvent) {
 	_m.Called(event)
@@ -176,6 +534,184 @@ func (_c *MockImageService_HandleImageEvent_Call) RunAndReturn(run func(k8s.Imag
 	return _c
 }
 
+// QueryImages provides a mock function with given fields: ctx, query
+func (_m *MockImageService) QueryImages(ctx context.Context, query models.ImageQuery) (*models.ImagesResponse, error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueryImages")
+	}
+
+	var r0 *models.ImagesResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.ImageQuery) (*models.ImagesResponse, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.ImageQuery) *models.ImagesResponse); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ImagesResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.ImageQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageService_QueryImages_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QueryImages'
+type MockImageService_QueryImages_Call struct {
+	*mock.Call
+}
+
+// QueryImages is a helper method to define mock.On call
+//   - ctx context.Context
+//   - query models.ImageQuery
+func (_e *MockImageService_Expecter) QueryImages(ctx interface{}, query interface{}) *MockImageService_QueryImages_Call {
+	return &MockImageService_QueryImages_Call{Call: _e.mock.On("QueryImages", ctx, query)}
+}
+
+func (_c *MockImageService_QueryImages_Call) Run(run func(ctx context.Context, query models.ImageQuery)) *MockImageService_QueryImages_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.ImageQuery))
+	})
+	return _c
+}
+
+func (_c *MockImageService_QueryImages_Call) Return(_a0 *models.ImagesResponse, _a1 error) *MockImageService_QueryImages_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageService_QueryImages_Call) RunAndReturn(run func(context.Context, models.ImageQuery) (*models.ImagesResponse, error)) *MockImageService_QueryImages_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Search provides a mock function with given fields: ctx, query
+func (_m *MockImageService) Search(ctx context.Context, query models.SearchQuery) (*models.SearchResponse, error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Search")
+	}
+
+	var r0 *models.SearchResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.SearchQuery) (*models.SearchResponse, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.SearchQuery) *models.SearchResponse); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.SearchResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.SearchQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockImageService_Search_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Search'
+type MockImageService_Search_Call struct {
+	*mock.Call
+}
+
+// Search is a helper method to define mock.On call
+//   - ctx context.Context
+//   - query models.SearchQuery
+func (_e *MockImageService_Expecter) Search(ctx interface{}, query interface{}) *MockImageService_Search_Call {
+	return &MockImageService_Search_Call{Call: _e.mock.On("Search", ctx, query)}
+}
+
+func (_c *MockImageService_Search_Call) Run(run func(ctx context.Context, query models.SearchQuery)) *MockImageService_Search_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.SearchQuery))
+	})
+	return _c
+}
+
+func (_c *MockImageService_Search_Call) Return(_a0 *models.SearchResponse, _a1 error) *MockImageService_Search_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageService_Search_Call) RunAndReturn(run func(context.Context, models.SearchQuery) (*models.SearchResponse, error)) *MockImageService_Search_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SubscribeImageEvents provides a mock function with given fields: filter
+func (_m *MockImageService) SubscribeImageEvents(filter models.ImageEventFilter) (<-chan models.ImageEvent, func()) {
+	ret := _m.Called(filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SubscribeImageEvents")
+	}
+
+	var r0 <-chan models.ImageEvent
+	var r1 func()
+	if rf, ok := ret.Get(0).(func(models.ImageEventFilter) (<-chan models.ImageEvent, func())); ok {
+		return rf(filter)
+	}
+	if rf, ok := ret.Get(0).(func(models.ImageEventFilter) <-chan models.ImageEvent); ok {
+		r0 = rf(filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan models.ImageEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(models.ImageEventFilter) func()); ok {
+		r1 = rf(filter)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(func())
+		}
+	}
+
+	return r0, r1
+}
+
+// MockImageService_SubscribeImageEvents_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SubscribeImageEvents'
+type MockImageService_SubscribeImageEvents_Call struct {
+	*mock.Call
+}
+
+// SubscribeImageEvents is a helper method to define mock.On call
+//   - filter models.ImageEventFilter
+func (_e *MockImageService_Expecter) SubscribeImageEvents(filter interface{}) *MockImageService_SubscribeImageEvents_Call {
+	return &MockImageService_SubscribeImageEvents_Call{Call: _e.mock.On("SubscribeImageEvents", filter)}
+}
+
+func (_c *MockImageService_SubscribeImageEvents_Call) Run(run func(filter models.ImageEventFilter)) *MockImageService_SubscribeImageEvents_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(models.ImageEventFilter))
+	})
+	return _c
+}
+
+func (_c *MockImageService_SubscribeImageEvents_Call) Return(_a0 <-chan models.ImageEvent, _a1 func()) *MockImageService_SubscribeImageEvents_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockImageService_SubscribeImageEvents_Call) RunAndReturn(run func(models.ImageEventFilter) (<-chan models.ImageEvent, func())) *MockImageService_SubscribeImageEvents_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockImageService creates a new instance of MockImageService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockImageService(t interface {
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -30,20 +31,27 @@ type ImageTag struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Foreign key
-	ImageID uint  `gorm:"uniqueIndex:idx_image_tag_resource;not null" json:"image_id"`
+	ImageID uint  `gorm:"uniqueIndex:idx_image_tag_resource,where:deleted_at IS NULL AND removed_at IS NULL;not null" json:"image_id"`
 	Image   Image `gorm:"constraint:OnDelete:CASCADE;" json:"image,omitempty"`
 
 	// Tag information
 	Tag           string    `gorm:"uniqueIndex:idx_image_tag_resource;not null" json:"tag"`            // e.g., latest, v1.2.3
 	FirstSeen     time.Time `gorm:"not null" json:"first_seen"`                                        // When first detected
 	LastSeen      time.Time `gorm:"not null" json:"last_seen"`                                         // When last detected
-	ResourceType  string    `gorm:"uniqueIndex:idx_image_tag_resource;not null" json:"resource_type"`  // Deployment, DaemonSet, CronJob
+	ResourceType  string    `gorm:"uniqueIndex:idx_image_tag_resource;not null" json:"resource_type"`  // Deployment, DaemonSet, CronJob, StatefulSet, Job, ReplicaSet, Pod
 	ResourceName  string    `gorm:"uniqueIndex:idx_image_tag_resource;not null" json:"resource_name"`  // Name of the resource
 	Namespace     string    `gorm:"uniqueIndex:idx_image_tag_resource;not null" json:"namespace"`      // Kubernetes namespace
 	ContainerName string    `gorm:"uniqueIndex:idx_image_tag_resource;not null" json:"container_name"` // Container name within the pod
 
-	// Composite unique index idx_image_tag_resource prevents duplicates
-	// Fields: image_id, tag, resource_type, resource_name, namespace, container_name
+	// Digest the image is pinned to in the pod spec, e.g., sha256:...; empty when pinned by tag only
+	Digest string `gorm:"uniqueIndex:idx_image_tag_resource;not null;default:''" json:"digest,omitempty"`
+
+	// When the tag stopped being used by the container, set together with DeletedAt; nil while active
+	RemovedAt *time.Time `gorm:"index" json:"removed_at,omitempty"`
+
+	// Composite unique index idx_image_tag_resource prevents duplicate active rows
+	// Fields: image_id, tag, digest, resource_type, resource_name, namespace, container_name
+	// It only covers active rows, so each interval a tag was in use is kept as its own row
 }
 
 // TableName overrides the table name
@@ -51,33 +59,315 @@ func (ImageTag) TableName() string {
 	return "image_tags"
 }
 
+// PruneQuery selects the closed out image tag rows a retention policy no longer keeps
+// Rows are ranked newest first per image and resource; zero limits are disabled
+type PruneQuery struct {
+	ClosedBefore time.Time // Rows closed out before this instant
+	MaxInactive  int       // Rows ranked after this many
+	KeepLast     int       // Rows ranked up to this many are always kept
+	AfterID      uint      // Only rows after this one, for paging
+	Limit        int
+}
+
+// ImageTagUpsert describes one container image to record in a batch upsert
+type ImageTagUpsert struct {
+	ImageName     string
+	Repository    string
+	Tag           string
+	Digest        string
+	ResourceType  string
+	ResourceName  string
+	Namespace     string
+	ContainerName string
+
+	// Appended to the image event log in the same transaction when the tag creates a row, nil for none
+	Event *ImageEvent
+}
+
+// RunningImage represents the digest a Pod actually runs for a container of a workload
+// Rows are keyed by the same resource/container identity as ImageTag and live only as long as the Pod
+type RunningImage struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Declared image as written in the pod spec
+	ImageName  string `gorm:"not null" json:"image_name"`
+	Repository string `gorm:"not null" json:"repository"`
+	Tag        string `gorm:"not null" json:"tag"`
+
+	// Owning workload and the Pod reporting the digest
+	ResourceType  string `gorm:"index:idx_running_image_resource;not null" json:"resource_type"`
+	ResourceName  string `gorm:"index:idx_running_image_resource;not null" json:"resource_name"`
+	Namespace     string `gorm:"index:idx_running_image_resource;uniqueIndex:idx_running_image_pod;not null" json:"namespace"`
+	PodName       string `gorm:"uniqueIndex:idx_running_image_pod;not null" json:"pod_name"`
+	ContainerName string `gorm:"uniqueIndex:idx_running_image_pod;not null" json:"container_name"`
+
+	// Digest resolved from the Pod status imageID, e.g., sha256:...
+	Digest   string    `gorm:"not null" json:"digest"`
+	LastSeen time.Time `gorm:"not null" json:"last_seen"`
+}
+
+// TableName overrides the table name
+func (RunningImage) TableName() string {
+	return "running_images"
+}
+
+// ImageEvent is one entry of the append-only log of image changes, one per container and event
+// Unlike ImageTag it keeps every transition, so v1 -> v2 -> v1 is three rows
+type ImageEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Type   string `gorm:"not null" json:"type"`              // ADD, UPDATE or DELETE
+	Change string `gorm:"not null;default:''" json:"change"` // ADDED, REMOVED or CHANGED for UPDATE events
+
+	// Resource and container the change happened in
+	ResourceType  string `gorm:"index:idx_image_event_resource;not null" json:"resource_type"`
+	ResourceName  string `gorm:"index:idx_image_event_resource;not null" json:"resource_name"`
+	Namespace     string `gorm:"index:idx_image_event_resource;not null" json:"namespace"`
+	ContainerName string `gorm:"not null" json:"container_name"`
+
+	// Image after the change; empty when the container or resource went away
+	ImageName  string `gorm:"index;not null;default:''" json:"image_name"`
+	Repository string `gorm:"not null;default:''" json:"repository"`
+	NewTag     string `gorm:"not null;default:''" json:"new_tag"`
+	NewDigest  string `gorm:"not null;default:''" json:"new_digest,omitempty"`
+
+	// Image before the change; empty when the container is new
+	OldImageName  string `gorm:"index;not null;default:''" json:"old_image_name,omitempty"`
+	OldRepository string `gorm:"not null;default:''" json:"old_repository,omitempty"`
+	OldTag        string `gorm:"not null;default:''" json:"old_tag"`
+	OldDigest     string `gorm:"not null;default:''" json:"old_digest,omitempty"`
+
+	ObservedAt time.Time `gorm:"index;not null" json:"observed_at"` // When the informer saw the change
+}
+
+// TableName overrides the table name
+func (ImageEvent) TableName() string {
+	return "image_events"
+}
+
+// NewReference returns the image after the change, e.g. docker.io/nginx:1.25, empty when there is none
+func (e ImageEvent) NewReference() string {
+	return imageReference(e.Repository, e.ImageName, e.NewTag, e.NewDigest)
+}
+
+// OldReference returns the image before the change, empty when there is none
+func (e ImageEvent) OldReference() string {
+	return imageReference(e.OldRepository, e.OldImageName, e.OldTag, e.OldDigest)
+}
+
+// imageReference joins the parts of an image reference, leaving out the empty ones
+func imageReference(repository, name, tag, digest string) string {
+	if name == "" {
+		return ""
+	}
+
+	ref := name
+	if repository != "" {
+		ref = repository + "/" + ref
+	}
+	if tag != "" {
+		ref += ":" + tag
+	}
+	if digest != "" {
+		ref += "@" + digest
+	}
+	return ref
+}
+
+// ImageEventFilter narrows down the image event log, empty fields match everything
+type ImageEventFilter struct {
+	Namespace    string
+	ImageName    string // Matches the image before or after the change
+	ResourceType string
+	ResourceName string
+	Since        *time.Time // Inclusive
+	Until        *time.Time // Exclusive
+	AfterID      uint       // Only events logged after this one, for resuming a stream
+	Limit        int
+}
+
+// Matches reports whether an event passes every filter except Limit
+func (f ImageEventFilter) Matches(event ImageEvent) bool {
+	switch {
+	case f.Namespace != "" && event.Namespace != f.Namespace:
+		return false
+	case f.ImageName != "" && event.ImageName != f.ImageName && event.OldImageName != f.ImageName:
+		return false
+	case f.ResourceType != "" && event.ResourceType != f.ResourceType:
+		return false
+	case f.ResourceName != "" && event.ResourceName != f.ResourceName:
+		return false
+	case f.Since != nil && event.ObservedAt.Before(*f.Since):
+		return false
+	case f.Until != nil && !event.ObservedAt.Before(*f.Until):
+		return false
+	case event.ID <= f.AfterID:
+		return false
+	}
+
+	return true
+}
+
+// ImageEventsResponse represents the image event log API response
+type ImageEventsResponse struct {
+	Events []ImageEvent `json:"events"`
+	Total  int          `json:"total"`
+}
+
+// WebhookDelivery is one attempt to deliver an image event to a webhook target
+type WebhookDelivery struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Target    string `gorm:"index;not null" json:"target"`   // Name of the configured target
+	URL       string `gorm:"not null" json:"url"`            // Scheme and host only, the rest may carry a token
+	EventID   uint   `gorm:"index;not null" json:"event_id"` // ImageEvent delivered
+	EventType string `gorm:"not null" json:"event_type"`
+	Attempt   int    `gorm:"not null" json:"attempt"` // 1 for the first try
+
+	Success    bool   `gorm:"not null" json:"success"`
+	StatusCode int    `gorm:"not null;default:0" json:"status_code,omitempty"` // Zero when no response was received
+	Error      string `gorm:"not null;default:''" json:"error,omitempty"`
+	DurationMs int64  `gorm:"not null" json:"duration_ms"`
+}
+
+// TableName overrides the table name
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookDeliveryFilter narrows down the webhook delivery log, empty fields match everything
+type WebhookDeliveryFilter struct {
+	Target  string
+	EventID uint
+	Failed  bool // Only unsuccessful attempts
+	Limit   int
+}
+
+// WebhookDeliveriesResponse represents the webhook delivery log API response
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Total      int               `json:"total"`
+}
+
+// ImageUpdate is the newest versions a registry offers for an image tag in use
+// Only tags of the same shape are compared, e.g. 1.25-alpine with other x.y-alpine tags
+type ImageUpdate struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ImageName  string `gorm:"uniqueIndex:idx_image_update;not null" json:"image_name"`
+	Repository string `gorm:"uniqueIndex:idx_image_update;not null" json:"repository"`
+	Tag        string `gorm:"uniqueIndex:idx_image_update;not null" json:"tag"`
+
+	LatestCompatible string `gorm:"not null;default:''" json:"latest_compatible,omitempty"` // Newest newer tag with the same major version
+	Latest           string `gorm:"not null;default:''" json:"latest,omitempty"`            // Newest newer tag, empty when Tag is the newest
+	Outdated         bool   `gorm:"index;not null" json:"outdated"`                         // A newer tag exists
+
+	CheckedAt time.Time `gorm:"not null" json:"checked_at"`
+	Error     string    `gorm:"not null;default:''" json:"error,omitempty"` // Why the tag could not be checked
+}
+
+// TableName overrides the table name
+func (ImageUpdate) TableName() string {
+	return "image_updates"
+}
+
+// OutdatedImage is an image tag in use that has a newer version in its registry (API response)
+type OutdatedImage struct {
+	Name             string          `json:"name"`
+	Repository       string          `json:"repository"`
+	Tag              string          `json:"tag"`
+	LatestCompatible string          `json:"latest_compatible,omitempty"` // Empty when Tag is the newest of its major version
+	Latest           string          `json:"latest"`
+	CheckedAt        time.Time       `json:"checked_at"`
+	Resources        []ImageResource `json:"resources"`
+}
+
+// ImageResource is a container of a workload that uses an image
+type ImageResource struct {
+	ResourceType string `json:"resource_type"`
+	ResourceName string `json:"resource_name"`
+	Namespace    string `json:"namespace"`
+	Container    string `json:"container"`
+}
+
+// OutdatedImagesResponse represents the outdated images API response
+type OutdatedImagesResponse struct {
+	Images []OutdatedImage `json:"images"`
+	Total  int             `json:"total"`
+}
+
 // ImageInfo represents a container image with its metadata (API response)
 type ImageInfo struct {
 	Name         string   `json:"name"`
+	Repository   string   `json:"repository"` // e.g., docker.io, ghcr.io/bitnami
 	Tag          string   `json:"tag"`
-	ResourceType string   `json:"resourceType"` // deployment, cronjob, daemonset
+	Digest       string   `json:"digest,omitempty"` // sha256 digest when the image is pinned
+	ResourceType string   `json:"resourceType"`     // deployment, cronjob, daemonset
 	ResourceName string   `json:"resourceName"`
 	Namespace    string   `json:"namespace"`
 	Containers   []string `json:"containers"` // container names using this image
 	FirstSeen    string   `json:"first_seen"`
 	LastSeen     string   `json:"last_seen"`
+
+	// Digests the replicas actually run for this tag, resolved from Pod status
+	RunningDigests []string `json:"runningDigests,omitempty"`
+	DigestMismatch bool     `json:"digestMismatch,omitempty"` // Replicas run different digests for the same tag
 }
 
 // ImagesResponse represents the API response
 type ImagesResponse struct {
 	Images []ImageInfo `json:"images"`
-	Total  int         `json:"total"`
+	Total  int         `json:"total"` // Every image matching the query, not only this page
+
+	// Page of the response, zero when the whole inventory was returned
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
+}
+
+// Sort keys accepted by ImageQuery
+const (
+	ImageSortName      = "name"
+	ImageSortTag       = "tag"
+	ImageSortNamespace = "namespace"
+	ImageSortFirstSeen = "first_seen"
+	ImageSortLastSeen  = "last_seen"
+)
+
+// ImageQuery filters, sorts and pages the image inventory, empty fields match everything
+type ImageQuery struct {
+	Namespace    string
+	Repository   string
+	Name         string // Case-insensitive substring of the image name
+	NamePrefix   string // Case-insensitive prefix of the image name
+	Tag          string
+	ResourceType string
+	Container    string // Images used by this container
+
+	Sort       string // One of the ImageSort keys, name when empty
+	Descending bool
+
+	Limit  int // Zero returns every match
+	Offset int
 }
 
 // ImageTagHistory represents the history of tags for a specific image
 type ImageTagHistory struct {
-	ImageName string            `json:"image_name"`
-	Tags      []ImageTagDetails `json:"tags"`
+	ImageName    string            `json:"image_name"`
+	Repositories []string          `json:"repositories"` // Every repository the name matched, sorted
+	Tags         []ImageTagDetails `json:"tags"`
 }
 
 // ImageTagDetails provides detailed information about a specific tag
 type ImageTagDetails struct {
+	Repository   string    `json:"repository"`
 	Tag          string    `json:"tag"`
+	Digest       string    `json:"digest,omitempty"`
 	FirstSeen    time.Time `json:"first_seen"`
 	LastSeen     time.Time `json:"last_seen"`
 	ResourceType string    `json:"resource_type"`
@@ -85,4 +375,122 @@ type ImageTagDetails struct {
 	Namespace    string    `json:"namespace"`
 	Container    string    `json:"container"`
 	Active       bool      `json:"active"` // Currently in use
+
+	RunningDigests []string `json:"running_digests,omitempty"` // Digests running for this tag, active tags only
+
+	RemovedAt *time.Time `json:"removed_at,omitempty"` // When this row was replaced or its resource deleted
+}
+
+// InventorySelector picks one side of an inventory diff
+type InventorySelector struct {
+	Namespace string     `json:"namespace,omitempty"` // Empty selects every namespace
+	At        *time.Time `json:"at,omitempty"`        // Nil selects the current inventory
+}
+
+// ImageDiffSide is the image a container runs on one side of a diff
+type ImageDiffSide struct {
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	Digest     string `json:"digest,omitempty"`
+}
+
+// ImageDiffEntry is a container whose image differs between the two sides of a diff
+type ImageDiffEntry struct {
+	ResourceType string         `json:"resourceType"`
+	ResourceName string         `json:"resourceName"`
+	Container    string         `json:"container"`
+	Left         *ImageDiffSide `json:"left,omitempty"`  // Nil for added containers
+	Right        *ImageDiffSide `json:"right,omitempty"` // Nil for removed containers
+}
+
+// ImageDiff represents the inventory diff API response
+type ImageDiff struct {
+	Left    InventorySelector `json:"left"`
+	Right   InventorySelector `json:"right"`
+	Added   []ImageDiffEntry  `json:"added"`   // Only on the right side
+	Removed []ImageDiffEntry  `json:"removed"` // Only on the left side
+	Changed []ImageDiffEntry  `json:"changed"` // On both sides with a different image, tag or digest
+}
+
+// ResourceTimeline is the ordered list of image versions a workload ran
+type ResourceTimeline struct {
+	ResourceType string          `json:"resource_type"`
+	ResourceName string          `json:"resource_name"`
+	Namespace    string          `json:"namespace"`
+	Entries      []TimelineEntry `json:"entries"`   // Oldest first
+	Rollbacks    int             `json:"rollbacks"` // Entries flagged as rollback
+}
+
+// TimelineEntry is one uninterrupted period a container ran an image version
+type TimelineEntry struct {
+	Container  string     `json:"container"`
+	ImageName  string     `json:"image_name"`
+	Repository string     `json:"repository"`
+	Tag        string     `json:"tag"`
+	Digest     string     `json:"digest,omitempty"`
+	Start      time.Time  `json:"start"`
+	End        *time.Time `json:"end,omitempty"` // Nil while the version is still running
+	Duration   int64      `json:"duration_seconds"`
+	Active     bool       `json:"active"`
+	Rollback   bool       `json:"rollback"` // The container returned to a version it ran before
+}
+
+// SearchQuery is a ranked search across images, tags and resources, current and historical
+type SearchQuery struct {
+	Text      string // Whitespace separated terms, every term must match one of the searched fields
+	Namespace string
+	Limit     int // Ranked results returned, zero returns every match
+}
+
+// Terms returns the lower case terms of the search text
+func (q SearchQuery) Terms() []string {
+	return strings.Fields(strings.ToLower(q.Text))
+}
+
+// Fields a search term is matched against
+const (
+	SearchFieldName       = "name"
+	SearchFieldRepository = "repository"
+	SearchFieldTag        = "tag"
+	SearchFieldResource   = "resource_name"
+	SearchFieldNamespace  = "namespace"
+	SearchFieldContainer  = "container"
+)
+
+// SearchFieldWeights ranks a match on the image name above one on its namespace
+var SearchFieldWeights = map[string]float64{
+	SearchFieldName:       1.0,
+	SearchFieldResource:   0.9,
+	SearchFieldTag:        0.8,
+	SearchFieldContainer:  0.7,
+	SearchFieldRepository: 0.6,
+	SearchFieldNamespace:  0.5,
+}
+
+// SearchResult is one image tag row matching a search
+type SearchResult struct {
+	Score   float64  `json:"score"`   // Higher is a better match
+	Matches []string `json:"matches"` // Fields the terms matched best, in term order
+
+	Name         string     `json:"name"`
+	Repository   string     `json:"repository"`
+	Tag          string     `json:"tag"`
+	Digest       string     `json:"digest,omitempty"`
+	ResourceType string     `json:"resource_type"`
+	ResourceName string     `json:"resource_name"`
+	Namespace    string     `json:"namespace"`
+	Container    string     `json:"container"`
+	FirstSeen    time.Time  `json:"first_seen"`
+	LastSeen     time.Time  `json:"last_seen"`
+	Active       bool       `json:"active"`
+	RemovedAt    *time.Time `json:"removed_at,omitempty"`
+}
+
+// SearchResponse represents the search API response
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+	Total   int            `json:"total"` // Every ranked match, not only the returned ones
 }
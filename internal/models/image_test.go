@@ -507,3 +507,40 @@ func TestImageRelationship(t *testing.T) {
 		}
 	})
 }
+
+func TestImageEventReferences(t *testing.T) {
+	tests := []struct {
+		name        string
+		event       ImageEvent
+		expectedNew string
+		expectedOld string
+	}{
+		{
+			name:        "tag change",
+			event:       ImageEvent{ImageName: "nginx", Repository: "docker.io", NewTag: "1.25", OldImageName: "nginx", OldRepository: "docker.io", OldTag: "1.24"},
+			expectedNew: "docker.io/nginx:1.25",
+			expectedOld: "docker.io/nginx:1.24",
+		},
+		{
+			name:        "pinned by digest",
+			event:       ImageEvent{ImageName: "app", Repository: "gcr.io/team", NewTag: "v1", NewDigest: "sha256:abc"},
+			expectedNew: "gcr.io/team/app:v1@sha256:abc",
+		},
+		{
+			name:        "removed image",
+			event:       ImageEvent{OldImageName: "redis", OldRepository: "docker.io", OldDigest: "sha256:def"},
+			expectedOld: "docker.io/redis@sha256:def",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ref := tt.event.NewReference(); ref != tt.expectedNew {
+				t.Errorf("Expected new reference %q, got %q", tt.expectedNew, ref)
+			}
+			if ref := tt.event.OldReference(); ref != tt.expectedOld {
+				t.Errorf("Expected old reference %q, got %q", tt.expectedOld, ref)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+// defaultTemplates are the chat message templates of targets that do not override them, per event type
+var defaultTemplates = map[string]string{
+	"ADD": `{{.ResourceType}} {{.Namespace}}/{{.ResourceName}} runs {{.Image}} in container {{.ContainerName}}`,
+	"UPDATE": `{{.ResourceType}} {{.Namespace}}/{{.ResourceName}} ` +
+		`{{if not .OldImage}}added container {{.ContainerName}} running {{.Image}}` +
+		`{{else if not .Image}}removed container {{.ContainerName}}, which ran {{.OldImage}}` +
+		`{{else}}updated container {{.ContainerName}} from {{.OldImage}} to {{.Image}}{{end}}`,
+	"DELETE": `{{.ResourceType}} {{.Namespace}}/{{.ResourceName}} was deleted, container {{.ContainerName}} ran {{.OldImage}}`,
+}
+
+// chatTitles head chat messages, per event type
+var chatTitles = map[string]string{
+	"ADD":    "Image added",
+	"UPDATE": "Image updated",
+	"DELETE": "Image removed",
+}
+
+// Message is what chat message templates are executed with
+// The image event fields are promoted, e.g. {{.Namespace}} or {{.NewTag}}
+type Message struct {
+	models.ImageEvent
+	Cluster  string
+	Image    string // Reference of the image after the change, e.g. docker.io/nginx:1.25
+	OldImage string // Reference of the image before the change
+}
+
+// parseTemplates parses the target's chat message templates over the defaults
+func (t Target) parseTemplates() (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template, len(defaultTemplates))
+	for eventType, text := range defaultTemplates {
+		if override, found := t.Templates[eventType]; found {
+			text = override
+		}
+
+		tmpl, err := template.New(eventType).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %q: invalid %s template: %w", t.Name, eventType, err)
+		}
+		templates[eventType] = tmpl
+	}
+
+	return templates, nil
+}
+
+// parsedDefaultTemplates are the default chat message templates, parsed once
+var parsedDefaultTemplates = func() map[string]*template.Template {
+	templates, err := Target{}.parseTemplates()
+	if err != nil {
+		panic(err)
+	}
+	return templates
+}()
+
+// renderText executes the target's template for the event type, the default one for targets not built by NewTarget
+func (t Target) renderText(message Message) (string, error) {
+	templates := t.templates
+	if templates == nil {
+		templates = parsedDefaultTemplates
+	}
+
+	tmpl, found := templates[message.Type]
+	if !found {
+		return "", fmt.Errorf("no template for %s events", message.Type)
+	}
+
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, message); err != nil {
+		return "", fmt.Errorf("failed to render message: %w", err)
+	}
+
+	return text.String(), nil
+}
+
+// chatFacts are the details listed below a chat message
+func chatFacts(message Message) [][2]string {
+	var facts [][2]string
+	if message.Cluster != "" {
+		facts = append(facts, [2]string{"Cluster", message.Cluster})
+	}
+	facts = append(facts,
+		[2]string{"Namespace", message.Namespace},
+		[2]string{"Resource", message.ResourceType + "/" + message.ResourceName},
+		[2]string{"Container", message.ContainerName},
+	)
+	if message.Image != "" {
+		facts = append(facts, [2]string{"Image", message.Image})
+	}
+	if message.OldImage != "" {
+		facts = append(facts, [2]string{"Previous image", message.OldImage})
+	}
+	return facts
+}
+
+// encodeChat renders an event as a message in the chat format of the target
+func (n *Notifier) encodeChat(target Target, event models.ImageEvent) ([]byte, error) {
+	message := Message{
+		ImageEvent: event,
+		Cluster:    n.config.Cluster,
+		Image:      event.NewReference(),
+		OldImage:   event.OldReference(),
+	}
+
+	text, err := target.renderText(message)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload any
+	switch target.Format {
+	case FormatSlack:
+		payload = slackPayload(message, text)
+	case FormatTeams:
+		payload = teamsPayload(message, text)
+	case FormatTeamsMessageCard:
+		payload = teamsMessageCardPayload(message, text)
+	case FormatMattermost:
+		payload = mattermostPayload(message, text)
+	default:
+		return nil, fmt.Errorf("unsupported chat format %s", target.Format)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode chat message: %w", err)
+	}
+	return body, nil
+}
+
+// slackPayload builds a Slack incoming webhook message with Block Kit blocks
+// The text is the fallback shown in notifications
+func slackPayload(message Message, text string) map[string]any {
+	var fields []map[string]any
+	for _, fact := range chatFacts(message) {
+		fields = append(fields, map[string]any{"type": "mrkdwn", "text": fmt.Sprintf("*%s*\n%s", fact[0], fact[1])})
+	}
+
+	return map[string]any{
+		"text": text,
+		"blocks": []map[string]any{
+			{"type": "header", "text": map[string]any{"type": "plain_text", "text": chatTitles[message.Type]}},
+			{"type": "section", "text": map[string]any{"type": "plain_text", "text": text}},
+			{"type": "section", "fields": fields},
+			{"type": "context", "elements": []map[string]any{
+				{"type": "mrkdwn", "text": "KubeTag · " + message.ObservedAt.UTC().Format(time.RFC1123)},
+			}},
+		},
+	}
+}
+
+// teamsPayload builds a Microsoft Teams message carrying an Adaptive Card, as accepted by Workflows webhooks
+func teamsPayload(message Message, text string) map[string]any {
+	var facts []map[string]any
+	for _, fact := range chatFacts(message) {
+		facts = append(facts, map[string]any{"title": fact[0], "value": fact[1]})
+	}
+
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body": []map[string]any{
+					{"type": "TextBlock", "text": chatTitles[message.Type], "size": "Medium", "weight": "Bolder"},
+					{"type": "TextBlock", "text": text, "wrap": true},
+					{"type": "FactSet", "facts": facts},
+				},
+			},
+		}},
+	}
+}
+
+// teamsMessageCardPayload builds a legacy MessageCard, as accepted by Office 365 connector webhooks
+func teamsMessageCardPayload(message Message, text string) map[string]any {
+	var facts []map[string]any
+	for _, fact := range chatFacts(message) {
+		facts = append(facts, map[string]any{"name": fact[0], "value": fact[1]})
+	}
+
+	return map[string]any{
+		"@type":    "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"summary":  text,
+		"title":    chatTitles[message.Type],
+		"sections": []map[string]any{{"text": text, "facts": facts}},
+	}
+}
+
+// mattermostPayload builds a Mattermost incoming webhook message, the details as a Markdown table
+func mattermostPayload(message Message, text string) map[string]any {
+	var table strings.Builder
+	table.WriteString("| | |\n|---|---|\n")
+	for _, fact := range chatFacts(message) {
+		fmt.Fprintf(&table, "| %s | %s |\n", fact[0], strings.ReplaceAll(fact[1], "|", `\|`))
+	}
+
+	return map[string]any{
+		"username": "KubeTag",
+		"text":     fmt.Sprintf("#### %s\n%s\n\n%s", chatTitles[message.Type], text, table.String()),
+	}
+}
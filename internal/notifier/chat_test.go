@@ -0,0 +1,185 @@
+package notifier
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+func TestRenderText(t *testing.T) {
+	base := models.ImageEvent{ResourceType: "Deployment", ResourceName: "web", Namespace: "production", ContainerName: "app"}
+
+	withImages := func(eventType, oldImage, newImage string) Message {
+		event := base
+		event.Type = eventType
+		return Message{ImageEvent: event, Image: newImage, OldImage: oldImage}
+	}
+
+	tests := []struct {
+		name      string
+		templates map[string]string
+		message   Message
+		expected  string
+	}{
+		{
+			name:     "added resource",
+			message:  withImages("ADD", "", "docker.io/nginx:1.25"),
+			expected: "Deployment production/web runs docker.io/nginx:1.25 in container app",
+		},
+		{
+			name:     "updated container",
+			message:  withImages("UPDATE", "docker.io/nginx:1.24", "docker.io/nginx:1.25"),
+			expected: "Deployment production/web updated container app from docker.io/nginx:1.24 to docker.io/nginx:1.25",
+		},
+		{
+			name:     "added container",
+			message:  withImages("UPDATE", "", "docker.io/envoy:1.30"),
+			expected: "Deployment production/web added container app running docker.io/envoy:1.30",
+		},
+		{
+			name:     "removed container",
+			message:  withImages("UPDATE", "docker.io/envoy:1.30", ""),
+			expected: "Deployment production/web removed container app, which ran docker.io/envoy:1.30",
+		},
+		{
+			name:     "deleted resource",
+			message:  withImages("DELETE", "docker.io/nginx:1.25", ""),
+			expected: "Deployment production/web was deleted, container app ran docker.io/nginx:1.25",
+		},
+		{
+			name:      "custom template",
+			templates: map[string]string{"UPDATE": ":rocket: {{.ResourceName}} now on {{.NewTag}} in {{.Cluster}}"},
+			message:   Message{ImageEvent: models.ImageEvent{Type: "UPDATE", ResourceName: "web", NewTag: "1.25"}, Cluster: "prod"},
+			expected:  ":rocket: web now on 1.25 in prod",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := NewTarget(Target{Name: "chat", URL: "https://hooks.example.com", Format: FormatSlack, Templates: tt.templates})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			text, err := target.renderText(tt.message)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if text != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, text)
+			}
+		})
+	}
+
+	target, err := NewTarget(Target{Name: "chat", URL: "https://hooks.example.com", Format: FormatSlack, Templates: map[string]string{"ADD": "{{.Missing}}"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := target.renderText(withImages("ADD", "", "nginx")); err == nil {
+		t.Error("Expected an error for a template referring to an unknown field")
+	}
+
+	if _, err := NewTarget(Target{Name: "chat", URL: "https://hooks.example.com", Format: FormatSlack, Templates: map[string]string{"ADD": "{{.Image"}}); err == nil {
+		t.Error("Expected an error for a template that does not parse")
+	}
+}
+
+func TestEncodeChat(t *testing.T) {
+	event := models.ImageEvent{
+		Type:          "UPDATE",
+		ResourceType:  "Deployment",
+		ResourceName:  "web",
+		Namespace:     "production",
+		ContainerName: "app",
+		ImageName:     "nginx",
+		Repository:    "docker.io",
+		NewTag:        "1.25",
+		OldImageName:  "nginx",
+		OldRepository: "docker.io",
+		OldTag:        "1.24",
+		ObservedAt:    time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+	text := "Deployment production/web updated container app from docker.io/nginx:1.24 to docker.io/nginx:1.25"
+
+	tests := []struct {
+		format string
+		check  func(t *testing.T, payload map[string]any, body string)
+	}{
+		{
+			format: FormatSlack,
+			check: func(t *testing.T, payload map[string]any, body string) {
+				if payload["text"] != text {
+					t.Errorf("Expected the fallback text %q, got %v", text, payload["text"])
+				}
+				blocks, _ := payload["blocks"].([]any)
+				if len(blocks) != 4 || blocks[0].(map[string]any)["type"] != "header" {
+					t.Errorf("Expected header, text, fields and context blocks, got %v", payload["blocks"])
+				}
+				if !strings.Contains(body, `*Previous image*\ndocker.io/nginx:1.24`) {
+					t.Errorf("Expected the previous image as a field, got %s", body)
+				}
+			},
+		},
+		{
+			format: FormatTeams,
+			check: func(t *testing.T, payload map[string]any, body string) {
+				attachments, _ := payload["attachments"].([]any)
+				if payload["type"] != "message" || len(attachments) != 1 {
+					t.Fatalf("Expected a message with one attachment, got %s", body)
+				}
+				attachment := attachments[0].(map[string]any)
+				card := attachment["content"].(map[string]any)
+				if attachment["contentType"] != "application/vnd.microsoft.card.adaptive" || card["type"] != "AdaptiveCard" {
+					t.Errorf("Expected an Adaptive Card, got %s", body)
+				}
+				if !strings.Contains(body, `{"title":"Cluster","value":"prod"}`) || !strings.Contains(body, text) {
+					t.Errorf("Expected the text and a cluster fact, got %s", body)
+				}
+			},
+		},
+		{
+			format: FormatTeamsMessageCard,
+			check: func(t *testing.T, payload map[string]any, body string) {
+				if payload["@type"] != "MessageCard" || payload["title"] != "Image updated" || payload["summary"] != text {
+					t.Errorf("Expected a MessageCard titled Image updated, got %s", body)
+				}
+				if !strings.Contains(body, `{"name":"Image","value":"docker.io/nginx:1.25"}`) {
+					t.Errorf("Expected the image as a fact, got %s", body)
+				}
+			},
+		},
+		{
+			format: FormatMattermost,
+			check: func(t *testing.T, payload map[string]any, body string) {
+				message, _ := payload["text"].(string)
+				if payload["username"] != "KubeTag" || !strings.HasPrefix(message, "#### Image updated\n"+text) {
+					t.Errorf("Expected a titled message from KubeTag, got %s", body)
+				}
+				if !strings.Contains(message, "| Resource | Deployment/web |") {
+					t.Errorf("Expected the resource in the table, got %q", message)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Cluster = "prod"
+
+			body, err := New(config, &recordedDeliveries{}).encodeChat(Target{Name: "chat", Format: tt.format}, event)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			var payload map[string]any
+			if err := json.Unmarshal(body, &payload); err != nil {
+				t.Fatalf("Failed to parse payload: %v", err)
+			}
+			tt.check(t, payload, string(body))
+		})
+	}
+}
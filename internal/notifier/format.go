@@ -0,0 +1,56 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/huseyinbabal/kubetag/internal/cloudevents"
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+// Payload formats a target can receive
+const (
+	FormatJSON              = "json"               // The image event as returned by /api/events
+	FormatCloudEvents       = "cloudevents"        // CloudEvents structured content mode
+	FormatCloudEventsBinary = "cloudevents-binary" // CloudEvents binary content mode
+	FormatSlack             = "slack"              // Slack incoming webhook with Block Kit
+	FormatTeams             = "teams"              // Microsoft Teams Adaptive Card
+	FormatTeamsMessageCard  = "teams-messagecard"  // Microsoft Teams legacy MessageCard
+	FormatMattermost        = "mattermost"         // Mattermost incoming webhook
+)
+
+// formats are the supported payload formats
+var formats = []string{
+	FormatJSON, FormatCloudEvents, FormatCloudEventsBinary,
+	FormatSlack, FormatTeams, FormatTeamsMessageCard, FormatMattermost,
+}
+
+// chatFormats render human readable messages from the target's templates
+var chatFormats = []string{FormatSlack, FormatTeams, FormatTeamsMessageCard, FormatMattermost}
+
+// encode renders an event in the target's payload format and returns the headers describing the body
+func (n *Notifier) encode(target Target, event models.ImageEvent) (http.Header, []byte, error) {
+	switch target.Format {
+	case FormatCloudEvents:
+		body, err := cloudevents.FromImageEvent(n.config.Cluster, event).EncodeStructured()
+		if err != nil {
+			return nil, nil, err
+		}
+		return http.Header{"Content-Type": {cloudevents.ContentTypeStructured}}, body, nil
+	case FormatCloudEventsBinary:
+		return cloudevents.FromImageEvent(n.config.Cluster, event).EncodeBinary()
+	case FormatSlack, FormatTeams, FormatTeamsMessageCard, FormatMattermost:
+		body, err := n.encodeChat(target, event)
+		if err != nil {
+			return nil, nil, err
+		}
+		return http.Header{"Content-Type": {"application/json"}}, body, nil
+	default:
+		body, err := json.Marshal(event)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode image event: %w", err)
+		}
+		return http.Header{"Content-Type": {"application/json"}}, body, nil
+	}
+}
@@ -0,0 +1,252 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-KubeTag-Event"         // ADD, UPDATE or DELETE
+	HeaderDelivery  = "X-KubeTag-Delivery"      // ID of the image event, the same for every retry
+	HeaderSignature = "X-KubeTag-Signature-256" // sha256=<hex HMAC of the body>, only with a secret
+)
+
+// Config configures webhook delivery
+type Config struct {
+	Targets    []Target
+	Cluster    string        // Names the cluster in the source of CloudEvents payloads
+	QueueSize  int           // Events buffered per target before new ones are dropped
+	MaxRetries int           // Retries per delivery after the first attempt
+	BaseDelay  time.Duration // First retry delay, doubled on every retry
+	MaxDelay   time.Duration // Upper bound for the retry delay
+	Timeout    time.Duration // Per attempt
+}
+
+// DefaultConfig returns the webhook delivery defaults, without targets
+func DefaultConfig() Config {
+	return Config{
+		QueueSize:  1000,
+		MaxRetries: 5,
+		BaseDelay:  time.Second,
+		MaxDelay:   time.Minute,
+		Timeout:    10 * time.Second,
+	}
+}
+
+// DeliveryLog persists webhook delivery attempts
+type DeliveryLog interface {
+	AppendWebhookDelivery(delivery *models.WebhookDelivery) error
+}
+
+// Notifier POSTs image events to webhook targets
+// Every target has its own queue and worker, so events reach it in order and a slow target does not hold up the others
+type Notifier struct {
+	config      Config
+	client      *http.Client
+	deliveryLog DeliveryLog
+	queues      []*targetQueue
+
+	dropped atomic.Int64
+}
+
+// targetQueue buffers the events of one target
+type targetQueue struct {
+	target Target
+	events chan models.ImageEvent
+}
+
+// New creates a notifier for the configured targets; call Start to begin delivering
+func New(config Config, deliveryLog DeliveryLog) *Notifier {
+	n := &Notifier{
+		config:      config,
+		client:      &http.Client{},
+		deliveryLog: deliveryLog,
+	}
+
+	for _, target := range config.Targets {
+		n.queues = append(n.queues, &targetQueue{
+			target: target,
+			events: make(chan models.ImageEvent, config.QueueSize),
+		})
+	}
+
+	return n
+}
+
+// Notify queues the events each target subscribed to, without blocking
+// Events for a target whose queue is full are dropped
+func (n *Notifier) Notify(events []models.ImageEvent) {
+	for _, queue := range n.queues {
+		for _, event := range events {
+			if !queue.target.Matches(event) {
+				continue
+			}
+
+			select {
+			case queue.events <- event:
+			default:
+				n.dropped.Add(1)
+				log.Printf("Webhook %s queue full, dropping %s event %d", queue.target.Name, event.Type, event.ID)
+			}
+		}
+	}
+}
+
+// Dropped returns how many events were dropped because a target's queue was full
+func (n *Notifier) Dropped() int64 {
+	return n.dropped.Load()
+}
+
+// Start delivers queued events until the context is cancelled
+func (n *Notifier) Start(ctx context.Context) {
+	for _, queue := range n.queues {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event := <-queue.events:
+					n.deliver(ctx, queue.target, event)
+				}
+			}
+		}()
+	}
+}
+
+// deliver POSTs an event to a target, retrying with exponential backoff
+// It stops once the target accepts the event, rejects it permanently or retries run out, and reports success
+func (n *Notifier) deliver(ctx context.Context, target Target, event models.ImageEvent) bool {
+	header, body, err := n.encode(target, event)
+	if err != nil {
+		log.Printf("Failed to encode image event %d for webhook %s: %v", event.ID, target.Name, err)
+		return false
+	}
+
+	delay := n.config.BaseDelay
+	for attempt := 1; ; attempt++ {
+		statusCode, err := n.attempt(ctx, target, event, header, body, attempt)
+		if err == nil {
+			return true
+		}
+
+		if !retryable(statusCode) || attempt > n.config.MaxRetries {
+			log.Printf("Giving up delivering %s event %d to webhook %s after %d attempts: %v",
+				event.Type, event.ID, target.Name, attempt, err)
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, n.config.MaxDelay)
+	}
+}
+
+// attempt makes one delivery attempt and records it in the delivery log
+func (n *Notifier) attempt(ctx context.Context, target Target, event models.ImageEvent, header http.Header, body []byte, attempt int) (int, error) {
+	start := time.Now()
+	statusCode, err := n.post(ctx, target, event, header, body)
+
+	delivery := &models.WebhookDelivery{
+		Target:     target.Name,
+		URL:        redactURL(target.urlFor(event.Namespace)),
+		EventID:    event.ID,
+		EventType:  event.Type,
+		Attempt:    attempt,
+		Success:    err == nil,
+		StatusCode: statusCode,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	if logErr := n.deliveryLog.AppendWebhookDelivery(delivery); logErr != nil {
+		log.Printf("Failed to record delivery to webhook %s: %v", target.Name, logErr)
+	}
+
+	return statusCode, err
+}
+
+// post sends the encoded event to the target and returns the response status, zero when there was no response
+func (n *Notifier) post(ctx context.Context, target Target, event models.ImageEvent, header http.Header, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, n.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.urlFor(event.Namespace), bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", "KubeTag-Webhook")
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(event.ID), 10))
+	if target.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign([]byte(target.Secret), body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// Leave out the URL the client error repeats, it is recorded
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return 0, fmt.Errorf("failed to post to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// redactURL drops everything but the scheme and host from a URL before it is recorded,
+// as the path or query of chat webhooks carries their token
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// retryable reports whether a failed attempt may succeed later: no response, 408, 429 or a server error
+func retryable(statusCode int) bool {
+	return statusCode == 0 ||
+		statusCode == http.StatusRequestTimeout ||
+		statusCode == http.StatusTooManyRequests ||
+		statusCode >= 500
+}
+
+// Sign returns the signature header value for a body, the hex HMAC-SHA256 prefixed with sha256=
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
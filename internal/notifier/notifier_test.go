@@ -0,0 +1,352 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+// recordedDeliveries is an in-memory DeliveryLog
+type recordedDeliveries struct {
+	mu         sync.Mutex
+	deliveries []models.WebhookDelivery
+}
+
+func (r *recordedDeliveries) AppendWebhookDelivery(delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery.ID = uint(len(r.deliveries) + 1)
+	r.deliveries = append(r.deliveries, *delivery)
+	return nil
+}
+
+func (r *recordedDeliveries) list() []models.WebhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]models.WebhookDelivery(nil), r.deliveries...)
+}
+
+// receivedRequest is what the test receiver saw of one delivery
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newReceiver starts a webhook receiver answering with the given statuses in turn, the last one repeating
+func newReceiver(t *testing.T, statuses ...int) (*httptest.Server, <-chan receivedRequest) {
+	received := make(chan receivedRequest, 16)
+
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedRequest{header: r.Header.Clone(), body: body}
+
+		mu.Lock()
+		status := statuses[min(calls, len(statuses)-1)]
+		calls++
+		mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, received
+}
+
+// testConfig delivers to target with retries that do not slow the tests down
+func testConfig(target Target) Config {
+	config := DefaultConfig()
+	config.Targets = []Target{target}
+	config.MaxRetries = 2
+	config.BaseDelay = time.Millisecond
+	config.MaxDelay = 2 * time.Millisecond
+	return config
+}
+
+var updateEvent = models.ImageEvent{
+	ID:           42,
+	Type:         "UPDATE",
+	Change:       "CHANGED",
+	ResourceType: "Deployment",
+	ResourceName: "web",
+	Namespace:    "production",
+	ImageName:    "nginx",
+	NewTag:       "1.25",
+	OldImageName: "nginx",
+	OldTag:       "1.24",
+}
+
+func TestDeliverSignsPayload(t *testing.T) {
+	server, received := newReceiver(t, http.StatusOK)
+	target := Target{Name: "deploy-bot", URL: server.URL, Secret: "s3cret"}
+
+	deliveries := &recordedDeliveries{}
+	n := New(testConfig(target), deliveries)
+
+	if !n.deliver(context.Background(), target, updateEvent) {
+		t.Fatal("Expected the delivery to succeed")
+	}
+
+	request := <-received
+	if signature := request.header.Get(HeaderSignature); signature != Sign([]byte("s3cret"), request.body) {
+		t.Errorf("Expected signature of the body, got %q", signature)
+	}
+	if request.header.Get(HeaderEvent) != "UPDATE" || request.header.Get(HeaderDelivery) != "42" {
+		t.Errorf("Expected event UPDATE delivery 42, got %q delivery %q", request.header.Get(HeaderEvent), request.header.Get(HeaderDelivery))
+	}
+	if contentType := request.header.Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", contentType)
+	}
+
+	var event models.ImageEvent
+	if err := json.Unmarshal(request.body, &event); err != nil {
+		t.Fatalf("Failed to parse payload: %v", err)
+	}
+	if event.ID != 42 || event.OldTag != "1.24" || event.NewTag != "1.25" {
+		t.Errorf("Expected event 42 from 1.24 to 1.25, got %+v", event)
+	}
+
+	logged := deliveries.list()
+	if len(logged) != 1 {
+		t.Fatalf("Expected 1 logged delivery, got %d", len(logged))
+	}
+	if !logged[0].Success || logged[0].StatusCode != http.StatusOK || logged[0].Attempt != 1 || logged[0].Target != "deploy-bot" || logged[0].EventID != 42 {
+		t.Errorf("Expected a successful first attempt for event 42 to deploy-bot, got %+v", logged[0])
+	}
+}
+
+func TestDeliverWithoutSecretIsUnsigned(t *testing.T) {
+	server, received := newReceiver(t, http.StatusNoContent)
+	target := Target{Name: "unsigned", URL: server.URL}
+
+	if !New(testConfig(target), &recordedDeliveries{}).deliver(context.Background(), target, updateEvent) {
+		t.Fatal("Expected the delivery to succeed")
+	}
+
+	if signature := (<-received).header.Get(HeaderSignature); signature != "" {
+		t.Errorf("Expected no signature without a secret, got %q", signature)
+	}
+}
+
+func TestDeliverFormats(t *testing.T) {
+	tests := []struct {
+		format              string
+		expectedContentType string
+		expectedHeader      map[string]string
+		expectedBody        string // Top level JSON field the body must contain
+	}{
+		{format: "", expectedContentType: "application/json", expectedBody: "new_tag"},
+		{format: FormatJSON, expectedContentType: "application/json", expectedBody: "new_tag"},
+		{
+			format:              FormatCloudEvents,
+			expectedContentType: "application/cloudevents+json",
+			expectedBody:        "specversion",
+		},
+		{
+			format:              FormatCloudEventsBinary,
+			expectedContentType: "application/json",
+			expectedHeader: map[string]string{
+				"ce-id":      "42",
+				"ce-type":    "io.kubetag.image.updated",
+				"ce-source":  "/clusters/prod/namespaces/production/Deployment/web",
+				"ce-subject": "nginx:1.25",
+			},
+			expectedBody: "new_tag",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			server, received := newReceiver(t, http.StatusOK)
+			target := Target{Name: "bus", URL: server.URL, Format: tt.format, Secret: "s3cret"}
+
+			config := testConfig(target)
+			config.Cluster = "prod"
+			if !New(config, &recordedDeliveries{}).deliver(context.Background(), target, updateEvent) {
+				t.Fatal("Expected the delivery to succeed")
+			}
+
+			request := <-received
+			if contentType := request.header.Get("Content-Type"); contentType != tt.expectedContentType {
+				t.Errorf("Expected Content-Type %s, got %s", tt.expectedContentType, contentType)
+			}
+			for key, value := range tt.expectedHeader {
+				if request.header.Get(key) != value {
+					t.Errorf("Expected header %s %q, got %q", key, value, request.header.Get(key))
+				}
+			}
+			if request.header.Get(HeaderSignature) != Sign([]byte("s3cret"), request.body) {
+				t.Error("Expected every format to be signed")
+			}
+
+			var body map[string]any
+			if err := json.Unmarshal(request.body, &body); err != nil {
+				t.Fatalf("Failed to parse payload: %v", err)
+			}
+			if _, found := body[tt.expectedBody]; !found {
+				t.Errorf("Expected %s in the body, got %s", tt.expectedBody, request.body)
+			}
+		})
+	}
+}
+
+func TestDeliverRoutesChannels(t *testing.T) {
+	fallback, fallbackReceived := newReceiver(t, http.StatusOK)
+	production, productionReceived := newReceiver(t, http.StatusOK)
+	target := Target{
+		Name:     "chat",
+		URL:      fallback.URL + "/hooks/fallback-token",
+		Format:   FormatMattermost,
+		Channels: map[string]string{"production": production.URL + "/hooks/production-token"},
+	}
+
+	deliveries := &recordedDeliveries{}
+	n := New(testConfig(target), deliveries)
+
+	staging := updateEvent
+	staging.Namespace = "staging"
+	for _, event := range []models.ImageEvent{updateEvent, staging} {
+		if !n.deliver(context.Background(), target, event) {
+			t.Fatalf("Expected the %s delivery to succeed", event.Namespace)
+		}
+	}
+
+	if request := <-productionReceived; !strings.Contains(string(request.body), "production/web") {
+		t.Errorf("Expected the production event on the production channel, got %s", request.body)
+	}
+	if request := <-fallbackReceived; !strings.Contains(string(request.body), "staging/web") {
+		t.Errorf("Expected the staging event on the fallback channel, got %s", request.body)
+	}
+
+	for _, delivery := range deliveries.list() {
+		if strings.Contains(delivery.URL, "token") {
+			t.Errorf("Expected the recorded URL to leave out the token, got %s", delivery.URL)
+		}
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	tests := []struct {
+		name             string
+		statuses         []int
+		expectedSuccess  bool
+		expectedAttempts int
+	}{
+		{name: "retries server errors until accepted", statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK}, expectedSuccess: true, expectedAttempts: 3},
+		{name: "retries rate limiting", statuses: []int{http.StatusTooManyRequests, http.StatusAccepted}, expectedSuccess: true, expectedAttempts: 2},
+		{name: "gives up once retries run out", statuses: []int{http.StatusInternalServerError}, expectedSuccess: false, expectedAttempts: 3},
+		{name: "does not retry client errors", statuses: []int{http.StatusBadRequest}, expectedSuccess: false, expectedAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newReceiver(t, tt.statuses...)
+			target := Target{Name: "flaky", URL: server.URL}
+
+			deliveries := &recordedDeliveries{}
+			if success := New(testConfig(target), deliveries).deliver(context.Background(), target, updateEvent); success != tt.expectedSuccess {
+				t.Errorf("Expected success %v, got %v", tt.expectedSuccess, success)
+			}
+
+			logged := deliveries.list()
+			if len(logged) != tt.expectedAttempts {
+				t.Fatalf("Expected %d logged attempts, got %d", tt.expectedAttempts, len(logged))
+			}
+			for i, delivery := range logged {
+				if delivery.Attempt != i+1 || delivery.StatusCode != tt.statuses[min(i, len(tt.statuses)-1)] {
+					t.Errorf("Expected attempt %d with status %d, got %+v", i+1, tt.statuses[min(i, len(tt.statuses)-1)], delivery)
+				}
+				if last := i == len(logged)-1; delivery.Success != (last && tt.expectedSuccess) || (delivery.Error == "") != delivery.Success {
+					t.Errorf("Expected only a final successful attempt to succeed, got %+v", delivery)
+				}
+			}
+		})
+	}
+}
+
+func TestDeliverUnreachableTarget(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	target := Target{Name: "down", URL: server.URL}
+
+	deliveries := &recordedDeliveries{}
+	if New(testConfig(target), deliveries).deliver(context.Background(), target, updateEvent) {
+		t.Fatal("Expected the delivery to fail")
+	}
+
+	logged := deliveries.list()
+	if len(logged) != 3 {
+		t.Fatalf("Expected the first attempt and 2 retries, got %d", len(logged))
+	}
+	if logged[0].StatusCode != 0 || logged[0].Error == "" {
+		t.Errorf("Expected an attempt without response carrying the error, got %+v", logged[0])
+	}
+	if strings.Contains(logged[0].Error, server.URL) {
+		t.Errorf("Expected the recorded error to leave out the URL, got %s", logged[0].Error)
+	}
+}
+
+func TestNotifierDeliversMatchingEvents(t *testing.T) {
+	server, received := newReceiver(t, http.StatusOK)
+	target := Target{Name: "production", URL: server.URL, Namespaces: []string{"production"}, EventTypes: []string{"UPDATE"}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	n := New(testConfig(target), &recordedDeliveries{})
+	n.Start(ctx)
+
+	staging := updateEvent
+	staging.ID, staging.Namespace = 43, "staging"
+	added := updateEvent
+	added.ID, added.Type = 44, "ADD"
+	n.Notify([]models.ImageEvent{staging, added, updateEvent})
+
+	select {
+	case request := <-received:
+		if id := request.header.Get(HeaderDelivery); id != "42" {
+			t.Errorf("Expected only event 42 to be delivered, got %s", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the webhook delivery")
+	}
+
+	select {
+	case request := <-received:
+		t.Errorf("Expected no further deliveries, got event %s", request.header.Get(HeaderDelivery))
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNotifyDropsWhenQueueFull(t *testing.T) {
+	config := testConfig(Target{Name: "slow", URL: "http://localhost"})
+	config.QueueSize = 1
+
+	// Not started, so nothing drains the queue
+	n := New(config, &recordedDeliveries{})
+	n.Notify([]models.ImageEvent{updateEvent, updateEvent, updateEvent})
+
+	if dropped := n.Dropped(); dropped != 2 {
+		t.Errorf("Expected 2 dropped events, got %d", dropped)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Reference value from: echo -n 'Hello, World!' | openssl dgst -sha256 -hmac "It's a Secret to Everybody"
+	signature := Sign([]byte("It's a Secret to Everybody"), []byte("Hello, World!"))
+	expected := "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+	if signature != expected {
+		t.Errorf("Expected %s, got %s", expected, signature)
+	}
+}
@@ -0,0 +1,162 @@
+package notifier
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"text/template"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+	"sigs.k8s.io/yaml"
+)
+
+// eventTypes are the image event types a target can subscribe to
+var eventTypes = []string{"ADD", "UPDATE", "DELETE"}
+
+// Target is a webhook endpoint and the image events it subscribes to
+type Target struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Format string `json:"format,omitempty"` // Payload format, json when empty
+
+	// Key the body is signed with using HMAC-SHA256, unsigned when empty
+	Secret    string `json:"secret,omitempty"`
+	SecretEnv string `json:"secretEnv,omitempty"` // Environment variable to read Secret from
+
+	// Filters, an empty list matches everything
+	Namespaces []string `json:"namespaces,omitempty"`
+	Images     []string `json:"images,omitempty"`     // Image names, matching the new or the old image
+	EventTypes []string `json:"eventTypes,omitempty"` // ADD, UPDATE or DELETE
+
+	// URLs events of a namespace are posted to instead of URL, e.g. a chat channel per team
+	Channels map[string]string `json:"channels,omitempty"`
+
+	// Chat message text/template per event type, overriding the default; executed with a Message
+	Templates map[string]string `json:"templates,omitempty"`
+
+	templates map[string]*template.Template // Templates parsed over the defaults by NewTarget
+}
+
+// NewTarget resolves the target's secret, validates it and parses its chat message templates
+func NewTarget(target Target) (Target, error) {
+	if target.SecretEnv != "" {
+		target.Secret = os.Getenv(target.SecretEnv)
+		if target.Secret == "" {
+			return Target{}, fmt.Errorf("webhook %q: environment variable %s is empty", target.Name, target.SecretEnv)
+		}
+	}
+
+	if err := target.validate(); err != nil {
+		return Target{}, err
+	}
+
+	templates, err := target.parseTemplates()
+	if err != nil {
+		return Target{}, err
+	}
+	target.templates = templates
+
+	return target, nil
+}
+
+// urlFor returns the URL an event of the namespace is posted to
+func (t Target) urlFor(namespace string) string {
+	if channelURL, found := t.Channels[namespace]; found {
+		return channelURL
+	}
+	return t.URL
+}
+
+// Matches reports whether the target subscribed to an event
+func (t Target) Matches(event models.ImageEvent) bool {
+	switch {
+	case len(t.Namespaces) > 0 && !slices.Contains(t.Namespaces, event.Namespace):
+		return false
+	case len(t.Images) > 0 && !slices.Contains(t.Images, event.ImageName) && !slices.Contains(t.Images, event.OldImageName):
+		return false
+	case len(t.EventTypes) > 0 && !slices.Contains(t.EventTypes, event.Type):
+		return false
+	}
+
+	return true
+}
+
+// targetsFile is the layout of the webhook configuration file
+type targetsFile struct {
+	Targets []Target `json:"targets"`
+}
+
+// LoadTargets reads webhook targets from a YAML or JSON file with a top level targets list
+func LoadTargets(path string) ([]Target, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook config: %w", err)
+	}
+
+	var file targetsFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse webhook config: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i, target := range file.Targets {
+		target, err := NewTarget(target)
+		if err != nil {
+			return nil, err
+		}
+		file.Targets[i] = target
+
+		if names[target.Name] {
+			return nil, fmt.Errorf("webhook %q is configured twice", target.Name)
+		}
+		names[target.Name] = true
+	}
+
+	return file.Targets, nil
+}
+
+// validate checks that the target can be delivered to
+func (t Target) validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("webhook %s has no name", t.URL)
+	}
+
+	if !validURL(t.URL) {
+		return fmt.Errorf("webhook %q: invalid url %q", t.Name, t.URL)
+	}
+	for namespace, channelURL := range t.Channels {
+		if !validURL(channelURL) {
+			return fmt.Errorf("webhook %q: invalid url %q for namespace %s", t.Name, channelURL, namespace)
+		}
+	}
+
+	if t.Format != "" && !slices.Contains(formats, t.Format) {
+		return fmt.Errorf("webhook %q: unsupported format %s", t.Name, t.Format)
+	}
+
+	if len(t.Templates) > 0 {
+		if !slices.Contains(chatFormats, t.Format) {
+			return fmt.Errorf("webhook %q: templates need a chat format", t.Name)
+		}
+		for eventType := range t.Templates {
+			if !slices.Contains(eventTypes, eventType) {
+				return fmt.Errorf("webhook %q: template for unsupported event type %s", t.Name, eventType)
+			}
+		}
+	}
+
+	for _, eventType := range t.EventTypes {
+		if !slices.Contains(eventTypes, eventType) {
+			return fmt.Errorf("webhook %q: unsupported event type %s", t.Name, eventType)
+		}
+	}
+
+	return nil
+}
+
+// validURL reports whether a webhook URL is an absolute http or https URL
+func validURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
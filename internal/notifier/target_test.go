@@ -0,0 +1,118 @@
+package notifier
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+func TestTargetMatches(t *testing.T) {
+	event := models.ImageEvent{Type: "UPDATE", Namespace: "production", ImageName: "nginx", OldImageName: "httpd"}
+
+	tests := []struct {
+		name     string
+		target   Target
+		expected bool
+	}{
+		{name: "no filters", target: Target{}, expected: true},
+		{name: "namespace matches", target: Target{Namespaces: []string{"staging", "production"}}, expected: true},
+		{name: "namespace differs", target: Target{Namespaces: []string{"staging"}}, expected: false},
+		{name: "new image matches", target: Target{Images: []string{"nginx"}}, expected: true},
+		{name: "old image matches", target: Target{Images: []string{"httpd"}}, expected: true},
+		{name: "image differs", target: Target{Images: []string{"redis"}}, expected: false},
+		{name: "event type matches", target: Target{EventTypes: []string{"UPDATE", "DELETE"}}, expected: true},
+		{name: "event type differs", target: Target{EventTypes: []string{"ADD"}}, expected: false},
+		{name: "all filters must match", target: Target{Namespaces: []string{"production"}, Images: []string{"redis"}}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if matches := tt.target.Matches(event); matches != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, matches)
+			}
+		})
+	}
+}
+
+func TestLoadTargets(t *testing.T) {
+	t.Setenv("KUBETAG_TEST_WEBHOOK_SECRET", "from-env")
+
+	tests := []struct {
+		name          string
+		config        string
+		expectedError string
+		expectedCount int
+	}{
+		{
+			name: "valid targets",
+			config: `
+targets:
+  - name: deploy-bot
+    url: https://hooks.example.com/kubetag
+    secretEnv: KUBETAG_TEST_WEBHOOK_SECRET
+    namespaces: [production]
+    eventTypes: [UPDATE, DELETE]
+  - name: audit
+    url: http://audit.internal:8080/events
+    secret: inline
+    format: cloudevents-binary
+  - name: team-chat
+    url: https://hooks.slack.com/services/T0/B0/fallback
+    format: slack
+    channels:
+      production: https://hooks.slack.com/services/T0/B1/production
+    templates:
+      UPDATE: "{{.ResourceName}} is now on {{.Image}}"
+`,
+			expectedCount: 3,
+		},
+		{name: "no targets", config: "targets: []\n", expectedCount: 0},
+		{name: "missing name", config: "targets:\n  - url: https://hooks.example.com\n", expectedError: "has no name"},
+		{name: "invalid scheme", config: "targets:\n  - name: a\n    url: ftp://hooks.example.com\n", expectedError: "invalid url"},
+		{name: "missing host", config: "targets:\n  - name: a\n    url: https://\n", expectedError: "invalid url"},
+		{name: "unknown event type", config: "targets:\n  - name: a\n    url: https://hooks.example.com\n    eventTypes: [CHANGE]\n", expectedError: "unsupported event type CHANGE"},
+		{name: "unknown format", config: "targets:\n  - name: a\n    url: https://hooks.example.com\n    format: xml\n", expectedError: "unsupported format xml"},
+		{name: "invalid channel url", config: "targets:\n  - name: a\n    url: https://hooks.example.com\n    channels:\n      production: hooks.example.com/prod\n", expectedError: "for namespace production"},
+		{name: "templates without chat format", config: "targets:\n  - name: a\n    url: https://hooks.example.com\n    templates:\n      ADD: hi\n", expectedError: "templates need a chat format"},
+		{name: "template for unknown event type", config: "targets:\n  - name: a\n    url: https://hooks.example.com\n    format: slack\n    templates:\n      CHANGE: hi\n", expectedError: "unsupported event type CHANGE"},
+		{name: "invalid template", config: "targets:\n  - name: a\n    url: https://hooks.example.com\n    format: slack\n    templates:\n      ADD: \"{{.Image\"\n", expectedError: "invalid ADD template"},
+		{name: "duplicate name", config: "targets:\n  - name: a\n    url: https://a.example.com\n  - name: a\n    url: https://b.example.com\n", expectedError: "configured twice"},
+		{name: "empty secret variable", config: "targets:\n  - name: a\n    url: https://hooks.example.com\n    secretEnv: KUBETAG_TEST_UNSET\n", expectedError: "KUBETAG_TEST_UNSET is empty"},
+		{name: "unknown field", config: "targets:\n  - name: a\n    url: https://hooks.example.com\n    namespace: production\n", expectedError: "failed to parse webhook config"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "webhooks.yaml")
+			if err := os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			targets, err := LoadTargets(path)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(targets) != tt.expectedCount {
+				t.Fatalf("Expected %d targets, got %d", tt.expectedCount, len(targets))
+			}
+			if tt.expectedCount > 0 && (targets[0].Secret != "from-env" || targets[1].Secret != "inline") {
+				t.Errorf("Expected secrets from-env and inline, got %q and %q", targets[0].Secret, targets[1].Secret)
+			}
+		})
+	}
+}
+
+func TestLoadTargetsMissingFile(t *testing.T) {
+	if _, err := LoadTargets(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
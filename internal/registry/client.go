@@ -0,0 +1,350 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dockerHub is the registry of image references without a registry host
+const dockerHub = "docker.io"
+
+// dockerHubEndpoint serves the distribution API of Docker Hub
+const dockerHubEndpoint = "registry-1.docker.io"
+
+// tagsPageSize is how many tags are asked for per page, registries may return fewer
+const tagsPageSize = 1000
+
+// maxTagPages stops following pagination links of a registry that never ends them
+const maxTagPages = 100
+
+// Config configures the registry client
+type Config struct {
+	Keychain  Keychain      // Credentials per registry, anonymous when missing
+	PlainHTTP []string      // Registry hosts reached over plain HTTP, e.g. localhost:5000
+	Timeout   time.Duration // Per request
+}
+
+// Client lists image tags with the OCI Distribution API, authenticating with bearer tokens or basic auth
+type Client struct {
+	config     Config
+	httpClient *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]bearerToken // By registry and scope
+}
+
+// bearerToken is a token issued by a registry's token service
+type bearerToken struct {
+	value     string
+	expiresAt time.Time
+}
+
+// NewClient creates a registry client
+func NewClient(config Config) *Client {
+	return &Client{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+		tokens:     make(map[string]bearerToken),
+	}
+}
+
+// ListTags returns every tag of an image, e.g. repository docker.io and name nginx
+func (c *Client) ListTags(ctx context.Context, repository, name string) ([]string, error) {
+	registry, path := locate(repository, name)
+
+	next := &url.URL{
+		Scheme:   c.scheme(registry),
+		Host:     endpoint(registry),
+		Path:     "/v2/" + path + "/tags/list",
+		RawQuery: url.Values{"n": {fmt.Sprint(tagsPageSize)}}.Encode(),
+	}
+
+	var tags []string
+	for page := 0; next != nil; page++ {
+		if page == maxTagPages {
+			return nil, fmt.Errorf("failed to list tags of %s: more than %d pages", path, maxTagPages)
+		}
+
+		resp, err := c.get(ctx, registry, "repository:"+path+":pull", next.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", path, err)
+		}
+
+		var body struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode tags of %s: %w", path, err)
+		}
+		tags = append(tags, body.Tags...)
+
+		next, err = nextPage(next, resp.Header.Get("Link"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", path, err)
+		}
+	}
+
+	return tags, nil
+}
+
+// locate splits an image repository and name into the registry and the repository path on it,
+// e.g. docker.io and nginx are library/nginx on Docker Hub
+func locate(repository, name string) (registry, path string) {
+	registry, namespace, _ := strings.Cut(repository, "/")
+
+	path = name
+	if namespace != "" {
+		path = namespace + "/" + name
+	}
+	if registry == dockerHub && namespace == "" {
+		path = "library/" + name
+	}
+
+	return registry, path
+}
+
+// endpoint returns the host serving a registry's API
+func endpoint(registry string) string {
+	if registry == dockerHub {
+		return dockerHubEndpoint
+	}
+	return registry
+}
+
+// scheme returns how a registry is reached
+func (c *Client) scheme(registry string) string {
+	if slices.Contains(c.config.PlainHTTP, registry) {
+		return "http"
+	}
+	return "https"
+}
+
+// get requests a registry URL, answering an authentication challenge once
+// The caller closes the body of the returned response, which always has status 200
+func (c *Client) get(ctx context.Context, registry, scope, rawURL string) (*http.Response, error) {
+	tokenKey := registry + "|" + scope
+
+	resp, err := c.do(ctx, rawURL, c.cachedToken(tokenKey))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		authorization, err := c.authorize(ctx, registry, scope, tokenKey, challenge)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp, err = c.do(ctx, rawURL, authorization); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("registry responded with status %d", resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// do sends a GET request with an optional Authorization header
+func (c *Client) do(ctx context.Context, rawURL, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "KubeTag")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach registry: %w", err)
+	}
+
+	return resp, nil
+}
+
+// authorize answers an authentication challenge and returns the Authorization header to retry with
+func (c *Client) authorize(ctx context.Context, registry, scope, tokenKey, challenge string) (string, error) {
+	credentials, hasCredentials := c.config.Keychain.Lookup(registry)
+
+	authScheme, params := parseChallenge(challenge)
+	switch strings.ToLower(authScheme) {
+	case "bearer":
+		token, err := c.fetchToken(ctx, registry, params, scope, credentials, hasCredentials)
+		if err != nil {
+			return "", err
+		}
+
+		c.mu.Lock()
+		c.tokens[tokenKey] = token
+		c.mu.Unlock()
+
+		return "Bearer " + token.value, nil
+	case "basic":
+		if !hasCredentials {
+			return "", fmt.Errorf("registry %s requires credentials", registry)
+		}
+
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(credentials.Username, credentials.Password)
+		return req.Header.Get("Authorization"), nil
+	default:
+		return "", fmt.Errorf("registry %s requires unsupported authentication %q", registry, authScheme)
+	}
+}
+
+// fetchToken asks the token service named by a bearer challenge for a token
+// The registry credentials are sent along, so a realm over plain HTTP is only accepted for
+// registries that are themselves reached over plain HTTP
+func (c *Client) fetchToken(ctx context.Context, registry string, params map[string]string, scope string, credentials Credentials, hasCredentials bool) (bearerToken, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" || (realm.Scheme != "https" && realm.Scheme != "http") {
+		return bearerToken{}, fmt.Errorf("invalid token realm %q", params["realm"])
+	}
+	if realm.Scheme == "http" && c.scheme(registry) != "http" {
+		return bearerToken{}, fmt.Errorf("refusing token realm %s over plain HTTP for registry %s", realm.Host, registry)
+	}
+
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	if challengeScope := params["scope"]; challengeScope != "" {
+		scope = challengeScope
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return bearerToken{}, fmt.Errorf("failed to create token request: %w", err)
+	}
+	if hasCredentials {
+		req.SetBasicAuth(credentials.Username, credentials.Password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return bearerToken{}, fmt.Errorf("failed to reach token service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return bearerToken{}, fmt.Errorf("token service responded with status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return bearerToken{}, fmt.Errorf("failed to decode token: %w", err)
+	}
+
+	token := bearerToken{value: body.Token}
+	if token.value == "" {
+		token.value = body.AccessToken
+	}
+	if token.value == "" {
+		return bearerToken{}, fmt.Errorf("token service returned no token")
+	}
+
+	// Tokens without an expiry are valid for 60 seconds, renew a little early
+	expiresIn := 60
+	if body.ExpiresIn > 0 {
+		expiresIn = body.ExpiresIn
+	}
+	token.expiresAt = time.Now().Add(time.Duration(expiresIn)*time.Second - 10*time.Second)
+
+	return token, nil
+}
+
+// cachedToken returns the Authorization header of an unexpired token, empty when there is none
+func (c *Client) cachedToken(tokenKey string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	token, found := c.tokens[tokenKey]
+	if !found || time.Now().After(token.expiresAt) {
+		return ""
+	}
+	return "Bearer " + token.value
+}
+
+// parseChallenge splits a WWW-Authenticate header into its scheme and parameters,
+// e.g. Bearer realm="https://auth.docker.io/token",service="registry.docker.io"
+func parseChallenge(header string) (string, map[string]string) {
+	authScheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		key, value, found := strings.Cut(rest, "=")
+		if !found {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			rest = strings.TrimPrefix(strings.TrimSpace(value[end+2:]), ",")
+			continue
+		}
+
+		value, rest, _ = strings.Cut(value, ",")
+		params[key] = strings.TrimSpace(value)
+	}
+
+	return authScheme, params
+}
+
+// nextPage resolves the next page from a Link header, e.g. </v2/app/tags/list?n=2&last=b>; rel="next"
+// It returns nil on the last page, and an error for a link to another scheme or host
+func nextPage(current *url.URL, link string) (*url.URL, error) {
+	if link == "" {
+		return nil, nil
+	}
+
+	target, params, _ := strings.Cut(link, ";")
+	if !strings.Contains(params, `rel="next"`) && !strings.Contains(params, "rel=next") {
+		return nil, nil
+	}
+
+	target = strings.Trim(strings.TrimSpace(target), "<>")
+	next, err := current.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pagination link %q: %w", link, err)
+	}
+
+	// Every page is requested with the registry's credentials, never follow a link elsewhere
+	if next.Scheme != current.Scheme || next.Host != current.Host {
+		return nil, fmt.Errorf("pagination link %q leaves %s://%s", link, current.Scheme, current.Host)
+	}
+
+	return next, nil
+}
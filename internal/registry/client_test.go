@@ -0,0 +1,348 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testRegistry is a stand-in for a registry implementing the tags/list endpoint of the distribution API
+type testRegistry struct {
+	server *httptest.Server
+	host   string
+
+	repositories map[string][]string // Tags by repository path
+	auth         string              // "bearer", "basic" or "" for anonymous access
+	username     string
+	password     string
+
+	tokenRequests atomic.Int32
+	tagRequests   atomic.Int32
+}
+
+const testToken = "t0k3n"
+
+// newTestRegistry starts a registry serving the given repositories
+func newTestRegistry(t *testing.T, auth string, repositories map[string][]string) *testRegistry {
+	r := &testRegistry{repositories: repositories, auth: auth, username: "robot", password: "s3cret"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", r.serveToken)
+	mux.HandleFunc("/v2/", r.serveTags)
+
+	r.server = httptest.NewServer(mux)
+	t.Cleanup(r.server.Close)
+
+	u, _ := url.Parse(r.server.URL)
+	r.host = u.Host
+	return r
+}
+
+// serveToken issues a token to clients presenting the registry credentials
+func (r *testRegistry) serveToken(w http.ResponseWriter, req *http.Request) {
+	r.tokenRequests.Add(1)
+
+	username, password, ok := req.BasicAuth()
+	if !ok || username != r.username || password != r.password {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if req.URL.Query().Get("service") != "test-registry" || !strings.HasPrefix(req.URL.Query().Get("scope"), "repository:") {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"token": testToken, "expires_in": 300})
+}
+
+// serveTags serves GET /v2/<name>/tags/list, paginated with n and last like the distribution spec
+func (r *testRegistry) serveTags(w http.ResponseWriter, req *http.Request) {
+	r.tagRequests.Add(1)
+
+	path, found := strings.CutSuffix(strings.TrimPrefix(req.URL.Path, "/v2/"), "/tags/list")
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.auth {
+	case "bearer":
+		if req.Header.Get("Authorization") != "Bearer "+testToken {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				`Bearer realm="%s/token",service="test-registry",scope="repository:%s:pull"`, r.server.URL, path))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	case "basic":
+		if username, password, ok := req.BasicAuth(); !ok || username != r.username || password != r.password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test-registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	tags, found := r.repositories[path]
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if last := req.URL.Query().Get("last"); last != "" {
+		tags = tags[slices.Index(tags, last)+1:]
+	}
+	if n, err := strconv.Atoi(req.URL.Query().Get("n")); err == nil && n < len(tags) {
+		tags = tags[:n]
+		w.Header().Set("Link", fmt.Sprintf(`</v2/%s/tags/list?n=%d&last=%s>; rel="next"`, path, n, tags[n-1]))
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"name": path, "tags": tags})
+}
+
+func TestListTags(t *testing.T) {
+	repositories := map[string][]string{"team/app": {"1.0.0", "1.1.0", "latest"}}
+
+	tests := []struct {
+		name          string
+		auth          string
+		keychain      Keychain
+		expectedError string
+	}{
+		{name: "anonymous", auth: ""},
+		{name: "bearer token", auth: "bearer", keychain: Keychain{}},
+		{name: "basic auth", auth: "basic", keychain: Keychain{}},
+		{name: "basic auth without credentials", auth: "basic", expectedError: "requires credentials"},
+		{name: "bearer token without credentials", auth: "bearer", expectedError: "token service responded with status 401"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newTestRegistry(t, tt.auth, repositories)
+			if tt.keychain != nil {
+				tt.keychain[registry.host] = Credentials{Username: "robot", Password: "s3cret"}
+			}
+
+			client := NewClient(Config{Keychain: tt.keychain, PlainHTTP: []string{registry.host}, Timeout: 5 * time.Second})
+			tags, err := client.ListTags(context.Background(), registry.host+"/team", "app")
+
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(tags, repositories["team/app"]) {
+				t.Errorf("Expected tags %v, got %v", repositories["team/app"], tags)
+			}
+		})
+	}
+}
+
+func TestListTagsPaginates(t *testing.T) {
+	var tags []string
+	for i := range 2500 {
+		tags = append(tags, fmt.Sprintf("1.0.%d", i))
+	}
+	registry := newTestRegistry(t, "bearer", map[string][]string{"app": tags})
+
+	client := NewClient(Config{
+		Keychain:  Keychain{registry.host: {Username: "robot", Password: "s3cret"}},
+		PlainHTTP: []string{registry.host},
+	})
+
+	listed, err := client.ListTags(context.Background(), registry.host, "app")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(listed, tags) {
+		t.Errorf("Expected all %d tags, got %d", len(tags), len(listed))
+	}
+	if requests := registry.tagRequests.Load(); requests != 4 {
+		t.Errorf("Expected one challenged and three authorized page requests, got %d", requests)
+	}
+
+	// The token is reused until it expires
+	if _, err := client.ListTags(context.Background(), registry.host, "app"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if requests := registry.tokenRequests.Load(); requests != 1 {
+		t.Errorf("Expected the token to be fetched once, got %d requests", requests)
+	}
+}
+
+func TestListTagsKeepsCredentialsOnTheRegistry(t *testing.T) {
+	var leaked atomic.Int32
+	elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		leaked.Add(1)
+	}))
+	t.Cleanup(elsewhere.Close)
+
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if username, password, ok := req.BasicAuth(); !ok || username != "robot" || password != "s3cret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test-registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/v2/app/tags/list?last=1.0>; rel="next"`, elsewhere.URL))
+		json.NewEncoder(w).Encode(map[string]any{"name": "app", "tags": []string{"1.0"}})
+	}))
+	t.Cleanup(registry.Close)
+
+	u, _ := url.Parse(registry.URL)
+	client := NewClient(Config{
+		Keychain:  Keychain{u.Host: {Username: "robot", Password: "s3cret"}},
+		PlainHTTP: []string{u.Host},
+	})
+
+	if _, err := client.ListTags(context.Background(), u.Host, "app"); err == nil {
+		t.Error("Expected error for a pagination link to another host, got nil")
+	}
+	if requests := leaked.Load(); requests != 0 {
+		t.Errorf("Expected no request to the other host, got %d", requests)
+	}
+}
+
+func TestListTagsRefusesPlainHTTPTokenRealm(t *testing.T) {
+	var leaked atomic.Int32
+	tokenService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		leaked.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{"token": testToken})
+	}))
+	t.Cleanup(tokenService.Close)
+
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer "+testToken {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test-registry"`, tokenService.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"name": "app", "tags": []string{"1.0"}})
+	}))
+	t.Cleanup(registry.Close)
+
+	u, _ := url.Parse(registry.URL)
+	client := NewClient(Config{Keychain: Keychain{u.Host: {Username: "robot", Password: "s3cret"}}})
+	client.httpClient = registry.Client()
+
+	if _, err := client.ListTags(context.Background(), u.Host, "app"); err == nil {
+		t.Error("Expected error for a token realm over plain HTTP, got nil")
+	}
+	if requests := leaked.Load(); requests != 0 {
+		t.Errorf("Expected no request to the token service, got %d", requests)
+	}
+}
+
+func TestListTagsUnknownRepository(t *testing.T) {
+	registry := newTestRegistry(t, "", map[string][]string{})
+
+	client := NewClient(Config{PlainHTTP: []string{registry.host}})
+	if _, err := client.ListTags(context.Background(), registry.host, "missing"); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("Expected a 404 error, got %v", err)
+	}
+}
+
+func TestLocate(t *testing.T) {
+	tests := []struct {
+		repository, name           string
+		expectedRegistry, expected string
+	}{
+		{repository: "docker.io", name: "nginx", expectedRegistry: "docker.io", expected: "library/nginx"},
+		{repository: "docker.io/bitnami", name: "redis", expectedRegistry: "docker.io", expected: "bitnami/redis"},
+		{repository: "gcr.io/my-project/team", name: "app", expectedRegistry: "gcr.io", expected: "my-project/team/app"},
+		{repository: "localhost:5000", name: "app", expectedRegistry: "localhost:5000", expected: "app"},
+	}
+
+	for _, tt := range tests {
+		registry, path := locate(tt.repository, tt.name)
+		if registry != tt.expectedRegistry || path != tt.expected {
+			t.Errorf("Expected %s and %s for %s/%s, got %s and %s", tt.expectedRegistry, tt.expected, tt.repository, tt.name, registry, path)
+		}
+	}
+
+	if host := endpoint("docker.io"); host != "registry-1.docker.io" {
+		t.Errorf("Expected Docker Hub to be served by registry-1.docker.io, got %s", host)
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	tests := []struct {
+		header         string
+		expectedScheme string
+		expectedParams map[string]string
+	}{
+		{
+			header:         `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`,
+			expectedScheme: "Bearer",
+			expectedParams: map[string]string{"realm": "https://auth.docker.io/token", "service": "registry.docker.io", "scope": "repository:library/nginx:pull"},
+		},
+		{
+			header:         `Bearer realm="https://ghcr.io/token", service="ghcr.io", scope="repository:a/b:pull,push"`,
+			expectedScheme: "Bearer",
+			expectedParams: map[string]string{"realm": "https://ghcr.io/token", "service": "ghcr.io", "scope": "repository:a/b:pull,push"},
+		},
+		{
+			header:         `Basic realm=registry`,
+			expectedScheme: "Basic",
+			expectedParams: map[string]string{"realm": "registry"},
+		},
+		{header: "", expectedScheme: "", expectedParams: map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		scheme, params := parseChallenge(tt.header)
+		if scheme != tt.expectedScheme || !reflect.DeepEqual(params, tt.expectedParams) {
+			t.Errorf("Expected %s %v for %q, got %s %v", tt.expectedScheme, tt.expectedParams, tt.header, scheme, params)
+		}
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	current, _ := url.Parse("https://registry.example.com/v2/app/tags/list?n=2")
+
+	tests := []struct {
+		link     string
+		expected string
+		err      bool
+	}{
+		{link: "", expected: ""},
+		{link: `</v2/app/tags/list?n=2&last=b>; rel="next"`, expected: "https://registry.example.com/v2/app/tags/list?n=2&last=b"},
+		{link: `<https://registry.example.com/v2/app/tags/list?last=b>; rel=next`, expected: "https://registry.example.com/v2/app/tags/list?last=b"},
+		{link: `</v2/app/tags/list?n=2>; rel="prev"`, expected: ""},
+		{link: `<https://mirror.example.com/v2/app/tags/list?last=b>; rel=next`, err: true},
+		{link: `<http://registry.example.com/v2/app/tags/list?last=b>; rel=next`, err: true},
+		{link: `<https://registry.example.com:8443/v2/app/tags/list?last=b>; rel=next`, err: true},
+	}
+
+	for _, tt := range tests {
+		next, err := nextPage(current, tt.link)
+		if tt.err {
+			if err == nil {
+				t.Errorf("Expected error for %q, got %v", tt.link, next)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Expected no error for %q, got %v", tt.link, err)
+		}
+
+		result := ""
+		if next != nil {
+			result = next.String()
+		}
+		if result != tt.expected {
+			t.Errorf("Expected %q for %q, got %q", tt.expected, tt.link, result)
+		}
+	}
+}
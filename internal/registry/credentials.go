@@ -0,0 +1,146 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Credentials authenticate with a registry
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Keychain holds the credentials of registries, keyed by registry host as written in image references
+type Keychain map[string]Credentials
+
+// Lookup returns the credentials of a registry
+func (k Keychain) Lookup(registry string) (Credentials, bool) {
+	credentials, found := k[registryKey(registry)]
+	return credentials, found
+}
+
+// Merge adds the credentials of other, replacing those of the same registry
+func (k Keychain) Merge(other Keychain) {
+	for registry, credentials := range other {
+		k[registry] = credentials
+	}
+}
+
+// registryKey normalizes a registry as written in a Docker config or image reference,
+// e.g. https://index.docker.io/v1/ and docker.io are the same registry
+func registryKey(registry string) string {
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	if i := strings.Index(registry, "/"); i >= 0 {
+		registry = registry[:i]
+	}
+
+	switch registry {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return dockerHub
+	}
+	return registry
+}
+
+// dockerConfigEntry is the credentials of one registry in a Docker config
+type dockerConfigEntry struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     string `json:"auth"` // base64 of username:password
+}
+
+// ParseDockerConfig reads the registry credentials of a Docker config.json or pull secret,
+// either with a top level auths object or in the legacy .dockercfg layout without one
+func ParseDockerConfig(data []byte) (Keychain, error) {
+	var config struct {
+		Auths map[string]dockerConfigEntry `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse docker config: %w", err)
+	}
+
+	entries := config.Auths
+	if entries == nil {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse docker config: %w", err)
+		}
+	}
+
+	keychain := make(Keychain)
+	for registry, entry := range entries {
+		credentials := Credentials{Username: entry.Username, Password: entry.Password}
+
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("invalid auth for registry %s: %w", registry, err)
+			}
+			username, password, found := strings.Cut(string(decoded), ":")
+			if !found {
+				return nil, fmt.Errorf("invalid auth for registry %s: missing password", registry)
+			}
+			credentials = Credentials{Username: username, Password: password}
+		}
+
+		if credentials.Username == "" {
+			continue
+		}
+		keychain[registryKey(registry)] = credentials
+	}
+
+	return keychain, nil
+}
+
+// LoadDockerConfig reads the registry credentials of a Docker config.json file
+func LoadDockerConfig(path string) (Keychain, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read docker config: %w", err)
+	}
+
+	return ParseDockerConfig(data)
+}
+
+// LoadPullSecrets reads the registry credentials of image pull secrets, given as namespace/name
+func LoadPullSecrets(ctx context.Context, clientset kubernetes.Interface, secrets []string) (Keychain, error) {
+	keychain := make(Keychain)
+
+	for _, ref := range secrets {
+		namespace, name, found := strings.Cut(ref, "/")
+		if !found || namespace == "" || name == "" {
+			return nil, fmt.Errorf("invalid pull secret %q, expected namespace/name", ref)
+		}
+
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pull secret %s: %w", ref, err)
+		}
+
+		var data []byte
+		switch secret.Type {
+		case corev1.SecretTypeDockerConfigJson:
+			data = secret.Data[corev1.DockerConfigJsonKey]
+		case corev1.SecretTypeDockercfg:
+			data = secret.Data[corev1.DockerConfigKey]
+		default:
+			return nil, fmt.Errorf("pull secret %s has type %s, expected %s", ref, secret.Type, corev1.SecretTypeDockerConfigJson)
+		}
+
+		credentials, err := ParseDockerConfig(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pull secret %s: %w", ref, err)
+		}
+		keychain.Merge(credentials)
+	}
+
+	return keychain, nil
+}
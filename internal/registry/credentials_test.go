@@ -0,0 +1,151 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func basicAuth(username, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+}
+
+func TestParseDockerConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        string
+		expected      Keychain
+		expectedError string
+	}{
+		{
+			name:   "auths with encoded credentials",
+			config: `{"auths":{"https://index.docker.io/v1/":{"auth":"` + basicAuth("hub", "pw") + `"},"ghcr.io":{"auth":"` + basicAuth("gh", "to:ken") + `"}}}`,
+			expected: Keychain{
+				"docker.io": {Username: "hub", Password: "pw"},
+				"ghcr.io":   {Username: "gh", Password: "to:ken"},
+			},
+		},
+		{
+			name:     "auths with plain credentials",
+			config:   `{"auths":{"registry.example.com:5000":{"username":"robot","password":"s3cret"}}}`,
+			expected: Keychain{"registry.example.com:5000": {Username: "robot", Password: "s3cret"}},
+		},
+		{
+			name:     "legacy dockercfg",
+			config:   `{"quay.io":{"auth":"` + basicAuth("q", "pw") + `","email":"q@example.com"}}`,
+			expected: Keychain{"quay.io": {Username: "q", Password: "pw"}},
+		},
+		{
+			name:     "entries without credentials are skipped",
+			config:   `{"auths":{"gcr.io":{}},"credHelpers":{"gcr.io":"gcloud"}}`,
+			expected: Keychain{},
+		},
+		{name: "invalid json", config: `{`, expectedError: "failed to parse docker config"},
+		{name: "invalid base64", config: `{"auths":{"ghcr.io":{"auth":"!!"}}}`, expectedError: "invalid auth for registry ghcr.io"},
+		{name: "auth without password", config: `{"auths":{"ghcr.io":{"auth":"` + base64.StdEncoding.EncodeToString([]byte("gh")) + `"}}}`, expectedError: "missing password"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keychain, err := ParseDockerConfig([]byte(tt.config))
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(keychain, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, keychain)
+			}
+		})
+	}
+}
+
+func TestKeychainLookup(t *testing.T) {
+	keychain := Keychain{"docker.io": {Username: "hub", Password: "pw"}}
+
+	for _, registry := range []string{"docker.io", "index.docker.io", "registry-1.docker.io"} {
+		if _, found := keychain.Lookup(registry); !found {
+			t.Errorf("Expected credentials for %s", registry)
+		}
+	}
+	if _, found := keychain.Lookup("ghcr.io"); found {
+		t.Errorf("Expected no credentials for ghcr.io")
+	}
+}
+
+func TestLoadDockerConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"auths":{"ghcr.io":{"auth":"`+basicAuth("gh", "pw")+`"}}}`), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	keychain, err := LoadDockerConfig(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if credentials, _ := keychain.Lookup("ghcr.io"); credentials.Username != "gh" {
+		t.Errorf("Expected credentials of gh, got %+v", credentials)
+	}
+
+	if _, err := LoadDockerConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("Expected error for a missing file")
+	}
+}
+
+func TestLoadPullSecrets(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ghcr", Namespace: "default"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"ghcr.io":{"auth":"` + basicAuth("gh", "pw") + `"}}}`)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "quay", Namespace: "staging"},
+			Type:       corev1.SecretTypeDockercfg,
+			Data:       map[string][]byte{corev1.DockerConfigKey: []byte(`{"quay.io":{"username":"q","password":"pw"}}`)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "opaque", Namespace: "default"},
+			Type:       corev1.SecretTypeOpaque,
+		},
+	)
+
+	keychain, err := LoadPullSecrets(context.Background(), clientset, []string{"default/ghcr", "staging/quay"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := Keychain{
+		"ghcr.io": {Username: "gh", Password: "pw"},
+		"quay.io": {Username: "q", Password: "pw"},
+	}
+	if !reflect.DeepEqual(keychain, expected) {
+		t.Errorf("Expected %v, got %v", expected, keychain)
+	}
+
+	errorTests := []struct {
+		secret        string
+		expectedError string
+	}{
+		{secret: "ghcr", expectedError: "expected namespace/name"},
+		{secret: "default/missing", expectedError: "failed to get pull secret default/missing"},
+		{secret: "default/opaque", expectedError: "has type Opaque"},
+	}
+
+	for _, tt := range errorTests {
+		if _, err := LoadPullSecrets(context.Background(), clientset, []string{tt.secret}); err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+			t.Errorf("Expected error containing %q for %s, got %v", tt.expectedError, tt.secret, err)
+		}
+	}
+}
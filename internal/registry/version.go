@@ -0,0 +1,117 @@
+package registry
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxVersionNumbers caps the dotted numbers of a version tag, so long numeric tags like dates are not split apart
+const maxVersionNumbers = 4
+
+// Version is an image tag read as a version, e.g. v1.25.3-alpine
+type Version struct {
+	Prefix  string // "v" or empty
+	Numbers []int  // Dotted numbers, e.g. [1 25 3]
+	Suffix  string // Everything after the first dash, e.g. alpine; empty for plain versions
+}
+
+// ParseVersion reads a tag as a version, reporting false for tags like latest or a commit hash
+func ParseVersion(tag string) (Version, bool) {
+	var v Version
+
+	rest := tag
+	if strings.HasPrefix(rest, "v") {
+		v.Prefix = "v"
+		rest = rest[1:]
+	}
+
+	if i := strings.Index(rest, "-"); i >= 0 {
+		v.Suffix = rest[i+1:]
+		rest = rest[:i]
+		if v.Suffix == "" {
+			return Version{}, false
+		}
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) > maxVersionNumbers {
+		return Version{}, false
+	}
+	for _, part := range parts {
+		if part == "" || strings.TrimLeft(part, "0123456789") != "" {
+			return Version{}, false
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return Version{}, false
+		}
+		v.Numbers = append(v.Numbers, n)
+	}
+
+	return v, true
+}
+
+// Compare returns -1, 0 or 1 as v is older than, the same as or newer than other
+func (v Version) Compare(other Version) int {
+	for i := 0; i < min(len(v.Numbers), len(other.Numbers)); i++ {
+		switch {
+		case v.Numbers[i] < other.Numbers[i]:
+			return -1
+		case v.Numbers[i] > other.Numbers[i]:
+			return 1
+		}
+	}
+
+	switch {
+	case len(v.Numbers) < len(other.Numbers):
+		return -1
+	case len(v.Numbers) > len(other.Numbers):
+		return 1
+	}
+	return 0
+}
+
+// sameShape reports whether two versions are tagged alike, with the same prefix, count of numbers and suffix
+// Images publish 1, 1.25 and 1.25.3 side by side and variants like -alpine, which are only compared among themselves
+func (v Version) sameShape(other Version) bool {
+	return v.Prefix == other.Prefix && len(v.Numbers) == len(other.Numbers) && v.Suffix == other.Suffix
+}
+
+// compatible reports whether other is a semver compatible upgrade target for v:
+// the same major version, and the same minor version while the major version is 0
+func (v Version) compatible(other Version) bool {
+	if v.Numbers[0] != other.Numbers[0] {
+		return false
+	}
+	if v.Numbers[0] == 0 && len(v.Numbers) > 1 {
+		return v.Numbers[1] == other.Numbers[1]
+	}
+	return true
+}
+
+// NewerTags finds the newest tags shaped like current that are newer than it:
+// the newest with a compatible version and the newest overall, empty when there is none
+// It reports false when current is not a version
+func NewerTags(current string, tags []string) (compatible, latest string, ok bool) {
+	currentVersion, ok := ParseVersion(current)
+	if !ok {
+		return "", "", false
+	}
+
+	var newestCompatible, newest *Version
+	for _, tag := range tags {
+		version, ok := ParseVersion(tag)
+		if !ok || !currentVersion.sameShape(version) || version.Compare(currentVersion) <= 0 {
+			continue
+		}
+
+		if newest == nil || version.Compare(*newest) > 0 {
+			newest, latest = &version, tag
+		}
+		if currentVersion.compatible(version) && (newestCompatible == nil || version.Compare(*newestCompatible) > 0) {
+			newestCompatible, compatible = &version, tag
+		}
+	}
+
+	return compatible, latest, true
+}
@@ -0,0 +1,104 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected Version
+		ok       bool
+	}{
+		{tag: "1.25.3", expected: Version{Numbers: []int{1, 25, 3}}, ok: true},
+		{tag: "v2.0", expected: Version{Prefix: "v", Numbers: []int{2, 0}}, ok: true},
+		{tag: "16", expected: Version{Numbers: []int{16}}, ok: true},
+		{tag: "1.25-alpine", expected: Version{Numbers: []int{1, 25}, Suffix: "alpine"}, ok: true},
+		{tag: "3.12.1-slim-bookworm", expected: Version{Numbers: []int{3, 12, 1}, Suffix: "slim-bookworm"}, ok: true},
+		{tag: "2024.01.15", expected: Version{Numbers: []int{2024, 1, 15}}, ok: true},
+		{tag: "latest", ok: false},
+		{tag: "stable-alpine", ok: false},
+		{tag: "a1b2c3d", ok: false},
+		{tag: "v", ok: false},
+		{tag: "1..2", ok: false},
+		{tag: "1.2.3.4.5", ok: false},
+		{tag: "1.25-", ok: false},
+		{tag: "+1.2", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			version, ok := ParseVersion(tt.tag)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok %v, got %v", tt.ok, ok)
+			}
+			if ok && !reflect.DeepEqual(version, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, version)
+			}
+		})
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "1.25.3", b: "1.25.3", expected: 0},
+		{a: "1.25.3", b: "1.25.10", expected: -1},
+		{a: "1.26.0", b: "1.25.10", expected: 1},
+		{a: "2", b: "10", expected: -1},
+		{a: "1.25", b: "1.25.0", expected: -1},
+	}
+
+	for _, tt := range tests {
+		a, _ := ParseVersion(tt.a)
+		b, _ := ParseVersion(tt.b)
+		if result := a.Compare(b); result != tt.expected {
+			t.Errorf("Expected %s compared to %s to be %d, got %d", tt.a, tt.b, tt.expected, result)
+		}
+	}
+}
+
+func TestNewerTags(t *testing.T) {
+	nginx := []string{
+		"latest", "stable", "mainline", "alpine",
+		"1", "1.24", "1.25", "1.27", "2", "2.0",
+		"1.24.0", "1.25.3", "1.25.4", "1.27.2", "2.0.1",
+		"1.25.3-alpine", "1.25.4-alpine", "1.27.2-alpine",
+		"2.1.0-rc1",
+	}
+
+	tests := []struct {
+		name               string
+		current            string
+		tags               []string
+		expectedCompatible string
+		expectedLatest     string
+		expectedOK         bool
+	}{
+		{name: "patch and major updates", current: "1.25.3", tags: nginx, expectedCompatible: "1.27.2", expectedLatest: "2.0.1", expectedOK: true},
+		{name: "same shape only", current: "1.25", tags: nginx, expectedCompatible: "1.27", expectedLatest: "2.0", expectedOK: true},
+		{name: "major only tags", current: "1", tags: nginx, expectedCompatible: "", expectedLatest: "2", expectedOK: true},
+		{name: "variant stays a variant", current: "1.25.3-alpine", tags: nginx, expectedCompatible: "1.27.2-alpine", expectedLatest: "1.27.2-alpine", expectedOK: true},
+		{name: "pre-releases are not upgrades", current: "2.0.1", tags: nginx, expectedOK: true},
+		{name: "already newest", current: "2.0.1", tags: []string{"2.0.1", "1.27.2"}, expectedOK: true},
+		{name: "current tag gone from the registry", current: "1.25.5", tags: nginx, expectedCompatible: "1.27.2", expectedLatest: "2.0.1", expectedOK: true},
+		{name: "prefix must match", current: "v1.0.0", tags: []string{"1.1.0", "v1.0.1"}, expectedCompatible: "v1.0.1", expectedLatest: "v1.0.1", expectedOK: true},
+		{name: "zero major needs same minor", current: "0.3.1", tags: []string{"0.3.2", "0.4.0"}, expectedCompatible: "0.3.2", expectedLatest: "0.4.0", expectedOK: true},
+		{name: "not a version", current: "latest", tags: nginx, expectedOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compatible, latest, ok := NewerTags(tt.current, tt.tags)
+			if ok != tt.expectedOK {
+				t.Fatalf("Expected ok %v, got %v", tt.expectedOK, ok)
+			}
+			if compatible != tt.expectedCompatible || latest != tt.expectedLatest {
+				t.Errorf("Expected compatible %q and latest %q, got %q and %q", tt.expectedCompatible, tt.expectedLatest, compatible, latest)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/huseyinbabal/kubetag/internal/models"
@@ -11,10 +13,31 @@ import (
 
 // ImageRepositoryInterface defines the methods for image repository operations
 type ImageRepositoryInterface interface {
-	UpsertImageTag(imageName, repository, tag, resourceType, resourceName, namespace, containerName string) error
-	DeleteImageTag(resourceType, resourceName, namespace string) error
+	UpsertImageTag(imageName, repository, tag, digest, resourceType, resourceName, namespace, containerName string) (bool, error)
+	UpsertImageTags(tags []models.ImageTagUpsert) ([]bool, error)
+	ReplaceImageTag(imageName, repository, tag, digest, resourceType, resourceName, namespace, containerName string) error
+	DeleteImageTag(resourceType, resourceName, namespace, containerName string) error
+	ListActiveImageTags() ([]models.ImageTag, error)
+	DeleteImageTagsByID(ids []uint) error
+	ListPrunableImageTags(query models.PruneQuery) ([]uint, error)
+	PurgeImageTags(ids []uint) error
 	GetAllImages(namespace string) ([]models.ImageInfo, error)
+	QueryImages(query models.ImageQuery) ([]models.ImageInfo, int, error)
+	GetImagesAt(namespace string, at time.Time) ([]models.ImageInfo, error)
 	GetImageTagHistory(imageName, namespace string) (*models.ImageTagHistory, error)
+	GetResourceImageTags(resourceType, resourceName, namespace string) ([]models.ImageTag, error)
+	SearchImageTags(query models.SearchQuery) ([]models.ImageTag, error)
+	UpsertRunningImage(imageName, repository, tag, resourceType, resourceName, namespace, containerName, podName, digest string) error
+	DeleteRunningImages(namespace, podName string) error
+	ListRunningImages() ([]models.RunningImage, error)
+	AppendImageEvents(events []models.ImageEvent) error
+	ListImageEvents(filter models.ImageEventFilter) ([]models.ImageEvent, error)
+	PurgeImageEvents(before time.Time, dryRun bool) (int64, error)
+	AppendWebhookDelivery(delivery *models.WebhookDelivery) error
+	ListWebhookDeliveries(filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error)
+	PurgeWebhookDeliveries(before time.Time, dryRun bool) (int64, error)
+	UpsertImageUpdates(updates []models.ImageUpdate) error
+	ListImageUpdates() ([]models.ImageUpdate, error)
 }
 
 // ImageRepository handles database operations for images
@@ -28,29 +51,90 @@ func NewImageRepository(db *gorm.DB) *ImageRepository {
 }
 
 // UpsertImageTag creates or updates an image tag record
+// It reports whether a row was created, i.e. the container is new or its tag or digest changed
 func (r *ImageRepository) UpsertImageTag(
-	imageName, repository, tag, resourceType, resourceName, namespace, containerName string,
+	imageName, repository, tag, digest, resourceType, resourceName, namespace, containerName string,
+) (bool, error) {
+	var created bool
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		created, err = upsertImageTag(tx, time.Now().UTC(),
+			imageName, repository, tag, digest, resourceType, resourceName, namespace, containerName)
+		return err
+	})
+
+	return created, err
+}
+
+// ReplaceImageTag upserts the new tag of a container and closes out the tag it replaces
+// Both happen in one transaction so the old row's RemovedAt marks exactly when the new one took over
+func (r *ImageRepository) ReplaceImageTag(
+	imageName, repository, tag, digest, resourceType, resourceName, namespace, containerName string,
 ) error {
+	now := time.Now().UTC()
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := upsertImageTag(tx, now,
+			imageName, repository, tag, digest, resourceType, resourceName, namespace, containerName); err != nil {
+			return err
+		}
+
+		var image models.Image
+		if err := tx.Where("full_name = ?", fmt.Sprintf("%s/%s", repository, imageName)).First(&image).Error; err != nil {
+			return fmt.Errorf("failed to fetch image: %w", err)
+		}
+
+		// Every other active row of the same container has been replaced
+		err := closeImageTags(tx.Where(
+			"resource_type = ? AND resource_name = ? AND namespace = ? AND container_name = ?",
+			resourceType, resourceName, namespace, containerName,
+		).Where(
+			"NOT (image_id = ? AND tag = ? AND digest = ?)",
+			image.ID, tag, digest,
+		), now)
+		if err != nil {
+			return fmt.Errorf("failed to close out replaced image tag: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// upsertImageTag creates an image tag record or refreshes the LastSeen of the active one
+// It reports whether a row was created
+func upsertImageTag(
+	db *gorm.DB, now time.Time, imageName, repository, tag, digest, resourceType, resourceName, namespace, containerName string,
+) (bool, error) {
 	// First, get or create the image
 	fullName := fmt.Sprintf("%s/%s", repository, imageName)
 
 	var image models.Image
-	err := r.db.Where("full_name = ?", fullName).FirstOrCreate(&image, models.Image{
+	err := db.Where("full_name = ?", fullName).FirstOrCreate(&image, models.Image{
 		Name:       imageName,
 		Repository: repository,
 		FullName:   fullName,
 	}).Error
 
 	if err != nil {
-		return fmt.Errorf("failed to upsert image: %w", err)
+		return false, fmt.Errorf("failed to upsert image: %w", err)
 	}
 
-	// Now upsert the image tag
-	now := time.Now().UTC()
+	var existing int64
+	err = db.Model(&models.ImageTag{}).
+		Where("image_id = ? AND tag = ? AND digest = ? AND resource_type = ? AND resource_name = ? AND namespace = ? AND container_name = ?",
+			image.ID, tag, digest, resourceType, resourceName, namespace, containerName).
+		Where("removed_at IS NULL").
+		Count(&existing).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to look up image tag: %w", err)
+	}
 
+	// Now upsert the image tag
 	imageTag := models.ImageTag{
 		ImageID:       image.ID,
 		Tag:           tag,
+		Digest:        digest,
 		ResourceType:  resourceType,
 		ResourceName:  resourceName,
 		Namespace:     namespace,
@@ -60,40 +144,566 @@ func (r *ImageRepository) UpsertImageTag(
 	}
 
 	// Use ON CONFLICT to update LastSeen if record exists
-	err = r.db.Clauses(clause.OnConflict{
+	err = db.Clauses(imageTagConflict()).Create(&imageTag).Error
+
+	if err != nil {
+		return false, fmt.Errorf("failed to upsert image tag: %w", err)
+	}
+
+	return existing == 0, nil
+}
+
+// imageTagConflict refreshes LastSeen of an active image tag row instead of inserting a duplicate
+// Closed out rows are not matched, a tag coming back after a rollback starts a new row
+func imageTagConflict() clause.OnConflict {
+	return clause.OnConflict{
 		Columns: []clause.Column{
 			{Name: "image_id"},
 			{Name: "tag"},
+			{Name: "digest"},
 			{Name: "resource_type"},
 			{Name: "resource_name"},
 			{Name: "namespace"},
 			{Name: "container_name"},
 		},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "deleted_at IS NULL AND removed_at IS NULL"},
+		}},
 		DoUpdates: clause.AssignmentColumns([]string{"last_seen", "updated_at"}),
-	}).Create(&imageTag).Error
+	}
+}
+
+// upsertBatchSize keeps multi-row statements well below the PostgreSQL bind parameter limit
+const upsertBatchSize = 500
+
+// UpsertImageTags creates or updates many image tag records in one transaction
+// Images and tags are written with multi-row ON CONFLICT statements instead of one round trip per container.
+// It reports for each tag whether it created a row; of duplicates in the batch only the first can.
+// The Event of every tag that created a row is appended to the event log in the same transaction.
+func (r *ImageRepository) UpsertImageTags(tags []models.ImageTagUpsert) ([]bool, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now().UTC()
+	created := make([]bool, len(tags))
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Create missing images first, then read back every ID in one query
+		var images []models.Image
+		var fullNames []string
+		seenImages := make(map[string]bool)
+		for _, t := range tags {
+			fullName := fmt.Sprintf("%s/%s", t.Repository, t.ImageName)
+			if seenImages[fullName] {
+				continue
+			}
+			seenImages[fullName] = true
+			fullNames = append(fullNames, fullName)
+			images = append(images, models.Image{Name: t.ImageName, Repository: t.Repository, FullName: fullName})
+		}
+
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "full_name"}},
+			DoNothing: true,
+		}).CreateInBatches(&images, upsertBatchSize).Error
+		if err != nil {
+			return fmt.Errorf("failed to upsert images: %w", err)
+		}
+
+		imageIDs := make(map[string]uint)
+		for start := 0; start < len(fullNames); start += upsertBatchSize {
+			end := min(start+upsertBatchSize, len(fullNames))
+
+			var existing []models.Image
+			if err := tx.Where("full_name IN ?", fullNames[start:end]).Find(&existing).Error; err != nil {
+				return fmt.Errorf("failed to fetch images: %w", err)
+			}
+			for _, image := range existing {
+				imageIDs[image.FullName] = image.ID
+			}
+		}
+
+		// One statement must not touch the same row twice, so duplicates are dropped
+		var imageTags []models.ImageTag
+		var keys []string
+		seenTags := make(map[string]bool)
+		for _, t := range tags {
+			imageID := imageIDs[fmt.Sprintf("%s/%s", t.Repository, t.ImageName)]
+			key := imageTagKey(imageID, t.Tag, t.Digest, t.ResourceType, t.ResourceName, t.Namespace, t.ContainerName)
+			keys = append(keys, key)
+			if seenTags[key] {
+				continue
+			}
+			seenTags[key] = true
+
+			imageTags = append(imageTags, models.ImageTag{
+				ImageID:       imageID,
+				Tag:           t.Tag,
+				Digest:        t.Digest,
+				ResourceType:  t.ResourceType,
+				ResourceName:  t.ResourceName,
+				Namespace:     t.Namespace,
+				ContainerName: t.ContainerName,
+				FirstSeen:     now,
+				LastSeen:      now,
+			})
+		}
 
+		active, err := activeImageTagKeys(tx, imageTags)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(imageTagConflict()).CreateInBatches(&imageTags, upsertBatchSize).Error; err != nil {
+			return fmt.Errorf("failed to upsert image tags: %w", err)
+		}
+
+		for i, key := range keys {
+			created[i] = !active[key]
+			active[key] = true
+		}
+
+		// Logged with the rows, so a failed append is retried as a whole instead of finding the rows recorded
+		var events []*models.ImageEvent
+		for i, t := range tags {
+			if created[i] && t.Event != nil {
+				events = append(events, t.Event)
+			}
+		}
+		if len(events) > 0 {
+			if err := tx.CreateInBatches(events, upsertBatchSize).Error; err != nil {
+				return fmt.Errorf("failed to append image events: %w", err)
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to upsert image tag: %w", err)
+		return nil, err
 	}
 
-	return nil
+	return created, nil
+}
+
+// activeImageTagKeys returns the imageTagKey of the active rows among the given image tags
+func activeImageTagKeys(tx *gorm.DB, imageTags []models.ImageTag) (map[string]bool, error) {
+	active := make(map[string]bool)
+
+	for start := 0; start < len(imageTags); start += upsertBatchSize {
+		end := min(start+upsertBatchSize, len(imageTags))
+
+		imageIDs := make([]uint, 0, end-start)
+		resourceNames := make([]string, 0, end-start)
+		for _, it := range imageTags[start:end] {
+			imageIDs = append(imageIDs, it.ImageID)
+			resourceNames = append(resourceNames, it.ResourceName)
+		}
+
+		// Narrowed by image and resource name, the exact rows are matched by key below
+		var existing []models.ImageTag
+		err := tx.Select("image_id", "tag", "digest", "resource_type", "resource_name", "namespace", "container_name").
+			Where("image_id IN ? AND resource_name IN ? AND removed_at IS NULL", imageIDs, resourceNames).
+			Find(&existing).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up image tags: %w", err)
+		}
+
+		for _, it := range existing {
+			active[imageTagKey(it.ImageID, it.Tag, it.Digest, it.ResourceType, it.ResourceName, it.Namespace, it.ContainerName)] = true
+		}
+	}
+
+	return active, nil
 }
 
-// DeleteImageTag soft deletes an image tag
+// closeImageTags marks the active rows matched by query as removed at the given time and soft deletes them
+func closeImageTags(query *gorm.DB, now time.Time) error {
+	return query.Model(&models.ImageTag{}).Updates(map[string]interface{}{
+		"removed_at": now,
+		"deleted_at": now,
+	}).Error
+}
+
+// DeleteImageTag closes out the image tags of a resource
+// containerName limits it to a single container, e.g. a removed sidecar; empty closes out every container
 func (r *ImageRepository) DeleteImageTag(
-	resourceType, resourceName, namespace string,
+	resourceType, resourceName, namespace, containerName string,
 ) error {
-	return r.db.Where(
+	query := r.db.Where(
 		"resource_type = ? AND resource_name = ? AND namespace = ?",
 		resourceType, resourceName, namespace,
-	).Delete(&models.ImageTag{}).Error
+	)
+
+	if containerName != "" {
+		query = query.Where("container_name = ?", containerName)
+	}
+
+	return closeImageTags(query, time.Now().UTC())
+}
+
+// ListActiveImageTags returns every non-deleted image tag with its image preloaded
+func (r *ImageRepository) ListActiveImageTags() ([]models.ImageTag, error) {
+	var imageTags []models.ImageTag
+
+	if err := r.db.Preload("Image").Find(&imageTags).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch active image tags: %w", err)
+	}
+
+	return imageTags, nil
+}
+
+// DeleteImageTagsByID closes out the given image tag rows
+func (r *ImageRepository) DeleteImageTagsByID(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := closeImageTags(r.db.Where("id IN ?", ids), time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to delete image tags: %w", err)
+	}
+
+	return nil
+}
+
+// ListPrunableImageTags returns the IDs of the closed out image tags query selects, in ID order
+// Rows are ranked in the database, so a pruning run never loads the whole history
+func (r *ImageRepository) ListPrunableImageTags(query models.PruneQuery) ([]uint, error) {
+	var limits []string
+	var vars []any
+	if query.MaxInactive > 0 {
+		limits = append(limits, "position > ?")
+		vars = append(vars, query.MaxInactive)
+	}
+	if !query.ClosedBefore.IsZero() {
+		limits = append(limits, "deleted_at < ?")
+		vars = append(vars, query.ClosedBefore)
+	}
+	if len(limits) == 0 {
+		return nil, nil
+	}
+
+	// RemovedAt and DeletedAt are set together, DeletedAt also covers older rows
+	ranked := r.db.Unscoped().Model(&models.ImageTag{}).
+		Select("id, deleted_at, ROW_NUMBER() OVER (" +
+			"PARTITION BY image_id, resource_type, resource_name, namespace ORDER BY deleted_at DESC, id DESC) AS position").
+		Where("deleted_at IS NOT NULL")
+
+	stmt := r.db.Table("(?) AS ranked", ranked).
+		Where("position > ? AND id > ?", query.KeepLast, query.AfterID).
+		Where("("+strings.Join(limits, " OR ")+")", vars...).
+		Order("id")
+	if query.Limit > 0 {
+		stmt = stmt.Limit(query.Limit)
+	}
+
+	var ids []uint
+	if err := stmt.Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch prunable image tags: %w", err)
+	}
+
+	return ids, nil
+}
+
+// PurgeImageTags permanently deletes the given image tag rows, active rows are left alone
+func (r *ImageRepository) PurgeImageTags(ids []uint) error {
+	for start := 0; start < len(ids); start += upsertBatchSize {
+		end := min(start+upsertBatchSize, len(ids))
+
+		err := r.db.Unscoped().
+			Where("id IN ? AND deleted_at IS NOT NULL", ids[start:end]).
+			Delete(&models.ImageTag{}).Error
+		if err != nil {
+			return fmt.Errorf("failed to purge image tags: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UpsertRunningImage records the digest a Pod actually runs for a container of a workload
+func (r *ImageRepository) UpsertRunningImage(
+	imageName, repository, tag, resourceType, resourceName, namespace, containerName, podName, digest string,
+) error {
+	runningImage := models.RunningImage{
+		ImageName:     imageName,
+		Repository:    repository,
+		Tag:           tag,
+		ResourceType:  resourceType,
+		ResourceName:  resourceName,
+		Namespace:     namespace,
+		PodName:       podName,
+		ContainerName: containerName,
+		Digest:        digest,
+		LastSeen:      time.Now().UTC(),
+	}
+
+	// A Pod container runs one image at a time, so the latest report wins
+	err := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "namespace"},
+			{Name: "pod_name"},
+			{Name: "container_name"},
+		},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"image_name", "repository", "tag", "resource_type", "resource_name", "digest", "last_seen", "updated_at",
+		}),
+	}).Create(&runningImage).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to upsert running image: %w", err)
+	}
+
+	return nil
 }
 
-// GetAllImages returns all active images grouped by image name, showing only the latest tag per resource
+// DeleteRunningImages removes the running digests reported by a Pod
+func (r *ImageRepository) DeleteRunningImages(namespace, podName string) error {
+	return r.db.Where("namespace = ? AND pod_name = ?", namespace, podName).
+		Delete(&models.RunningImage{}).Error
+}
+
+// ListRunningImages returns every recorded running digest
+func (r *ImageRepository) ListRunningImages() ([]models.RunningImage, error) {
+	var runningImages []models.RunningImage
+
+	if err := r.db.Find(&runningImages).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch running images: %w", err)
+	}
+
+	return runningImages, nil
+}
+
+// getRunningDigests returns the distinct running digests keyed by runningKey
+func (r *ImageRepository) getRunningDigests(namespace string) (map[string][]string, error) {
+	var runningImages []models.RunningImage
+
+	query := r.db.Order("digest")
+	if namespace != "" {
+		query = query.Where("namespace = ?", namespace)
+	}
+
+	if err := query.Find(&runningImages).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch running images: %w", err)
+	}
+
+	return groupRunningDigests(runningImages), nil
+}
+
+// groupRunningDigests returns the distinct digests of runningImages keyed by runningKey, in the given order
+func groupRunningDigests(runningImages []models.RunningImage) map[string][]string {
+	digests := make(map[string][]string)
+	for _, ri := range runningImages {
+		key := runningKey(ri.Repository, ri.ImageName, ri.Tag, ri.ResourceType, ri.ResourceName, ri.Namespace, ri.ContainerName)
+		digests[key] = appendUnique(digests[key], ri.Digest)
+	}
+
+	return digests
+}
+
+// runningKey identifies the image tag row a running digest belongs to
+func runningKey(repository, imageName, tag, resourceType, resourceName, namespace, containerName string) string {
+	return fmt.Sprintf("%s/%s:%s|%s|%s|%s|%s", repository, imageName, tag, resourceType, resourceName, namespace, containerName)
+}
+
+// appendUnique appends values that are not already present, keeping order
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, existing := range list {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// GetAllImages retrieves all images with their latest tags
 func (r *ImageRepository) GetAllImages(namespace string) ([]models.ImageInfo, error) {
+	images, _, err := r.QueryImages(models.ImageQuery{Namespace: namespace})
+	return images, err
+}
+
+// QueryImages returns a page of the image inventory and the number of images matching the query
+// Filtering, sorting and paging run in SQL; only the containers of the returned page are loaded
+func (r *ImageRepository) QueryImages(query models.ImageQuery) ([]models.ImageInfo, int, error) {
+	orderBy, err := imageSortColumn(query.Sort)
+	if err != nil {
+		return nil, 0, err
+	}
+	if query.Descending {
+		orderBy += " DESC"
+	}
+
+	groups := r.imageGroups(query)
+
+	page := groups.Order(orderBy).Order(imageSortTieBreak)
+	if query.Limit > 0 {
+		page = page.Limit(query.Limit)
+	}
+	if query.Offset > 0 {
+		page = page.Offset(query.Offset)
+	}
+
+	var keys []imageGroupKey
+	if err := page.Scan(&keys).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch images: %w", err)
+	}
+
+	total := len(keys)
+	if query.Limit > 0 || query.Offset > 0 {
+		var count int64
+		if err := r.db.Table("(?) AS image_groups", r.imageGroups(query)).Count(&count).Error; err != nil {
+			return nil, 0, fmt.Errorf("failed to count images: %w", err)
+		}
+		total = int(count)
+	}
+
+	if len(keys) == 0 {
+		return nil, total, nil
+	}
+
+	// Load the rows of the page's groups to collect their containers and timestamps
+	imageIDs := make([]uint, 0, len(keys))
+	namespaces := make([]string, 0, len(keys))
+	for _, key := range keys {
+		imageIDs = append(imageIDs, key.ImageID)
+		namespaces = append(namespaces, key.Namespace)
+	}
+
+	var imageTags []models.ImageTag
+	err = r.activeImageTags(query.Namespace).
+		Select("image_tags.*").
+		Preload("Image").
+		Where("image_tags.image_id IN ? AND image_tags.namespace IN ?", imageIDs, namespaces).
+		Find(&imageTags).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch image tags: %w", err)
+	}
+
+	runningDigests, err := r.getRunningDigests(query.Namespace)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	groupsByKey := make(map[string]imageGroup)
+	for _, group := range groupImageTags(imageTags, runningDigests) {
+		groupsByKey[group.key] = group
+	}
+
+	images := make([]models.ImageInfo, 0, len(keys))
+	for _, key := range keys {
+		if group, found := groupsByKey[key.String()]; found {
+			images = append(images, group.imageInfo())
+		}
+	}
+
+	return images, total, nil
+}
+
+// imageGroupKey identifies one row of the image inventory
+type imageGroupKey struct {
+	ImageID      uint
+	Tag          string
+	Digest       string
+	ResourceType string
+	ResourceName string
+	Namespace    string
+}
+
+// String returns the key groupImageTags uses for the same row
+func (k imageGroupKey) String() string {
+	return fmt.Sprintf("%d|%s|%s|%s|%s|%s", k.ImageID, k.Tag, k.Digest, k.ResourceType, k.ResourceName, k.Namespace)
+}
+
+// imageSortTieBreak keeps pages stable when the sort key has duplicates
+const imageSortTieBreak = "images.name, images.repository, image_tags.tag, image_tags.digest, " +
+	"image_tags.resource_type, image_tags.resource_name, image_tags.namespace"
+
+// imageSortColumn maps an ImageQuery sort key to the expression grouped rows are ordered by
+func imageSortColumn(sort string) (string, error) {
+	switch sort {
+	case "", models.ImageSortName:
+		return "images.name", nil
+	case models.ImageSortTag:
+		return "image_tags.tag", nil
+	case models.ImageSortNamespace:
+		return "image_tags.namespace", nil
+	case models.ImageSortFirstSeen:
+		return "MIN(image_tags.first_seen)", nil
+	case models.ImageSortLastSeen:
+		return "MAX(image_tags.last_seen)", nil
+	default:
+		return "", fmt.Errorf("unsupported sort key: %s", sort)
+	}
+}
+
+// activeImageTags selects the active image tag rows joined with their image
+// ReplaceImageTag closes out superseded rows, so these are exactly the tags in use
+func (r *ImageRepository) activeImageTags(namespace string) *gorm.DB {
+	query := r.db.Table("image_tags").
+		Joins("JOIN images ON images.id = image_tags.image_id").
+		Where("image_tags.removed_at IS NULL AND image_tags.deleted_at IS NULL")
+
+	if namespace != "" {
+		query = query.Where("image_tags.namespace = ?", namespace)
+	}
+
+	return query
+}
+
+// imageGroups selects the keys of the inventory rows matching the query's filters
+func (r *ImageRepository) imageGroups(query models.ImageQuery) *gorm.DB {
+	groups := r.activeImageTags(query.Namespace)
+
+	if query.Repository != "" {
+		groups = groups.Where("images.repository = ?", query.Repository)
+	}
+	if query.Name != "" {
+		groups = groups.Where("LOWER(images.name) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(query.Name))+"%")
+	}
+	if query.NamePrefix != "" {
+		groups = groups.Where("LOWER(images.name) LIKE ? ESCAPE '\\'", escapeLike(strings.ToLower(query.NamePrefix))+"%")
+	}
+	if query.Tag != "" {
+		groups = groups.Where("image_tags.tag = ?", query.Tag)
+	}
+	if query.ResourceType != "" {
+		groups = groups.Where("image_tags.resource_type = ?", query.ResourceType)
+	}
+
+	groups = groups.
+		Select("image_tags.image_id, image_tags.tag, image_tags.digest, " +
+			"image_tags.resource_type, image_tags.resource_name, image_tags.namespace").
+		Group("image_tags.image_id, images.name, images.repository, image_tags.tag, image_tags.digest, " +
+			"image_tags.resource_type, image_tags.resource_name, image_tags.namespace")
+
+	if query.Container != "" {
+		groups = groups.Having("SUM(CASE WHEN image_tags.container_name = ? THEN 1 ELSE 0 END) > 0", query.Container)
+	}
+
+	return groups
+}
+
+// escapeLike escapes the LIKE wildcards in a user supplied pattern
+func escapeLike(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(value)
+}
+
+// GetImagesAt reconstructs the inventory as it was at the given instant
+// Running digests are not kept historically, so they are left out
+func (r *ImageRepository) GetImagesAt(namespace string, at time.Time) ([]models.ImageInfo, error) {
 	var imageTags []models.ImageTag
 
-	query := r.db.Preload("Image").Where("deleted_at IS NULL")
+	at = at.UTC()
+	query := r.db.Unscoped().Preload("Image").
+		Where("first_seen <= ?", at).
+		Where("deleted_at IS NULL OR deleted_at > ?", at)
 
 	if namespace != "" {
 		query = query.Where("namespace = ?", namespace)
@@ -103,103 +713,471 @@ func (r *ImageRepository) GetAllImages(namespace string) ([]models.ImageInfo, er
 		return nil, fmt.Errorf("failed to fetch image tags: %w", err)
 	}
 
-	// Group by image name+resource to find the latest tag
-	// Key: image_name|resource_type|resource_name|namespace
-	latestTagMap := make(map[string]*models.ImageTag)
+	return aggregateImages(tagsActiveAt(imageTags, at), nil), nil
+}
 
-	for i := range imageTags {
-		it := &imageTags[i]
-		resourceKey := fmt.Sprintf("%s|%s|%s|%s",
-			it.Image.Name, it.ResourceType, it.ResourceName, it.Namespace)
-
-		if existing, found := latestTagMap[resourceKey]; found {
-			// Keep the tag with the most recent LastSeen
-			if it.LastSeen.After(existing.LastSeen) {
-				latestTagMap[resourceKey] = it
-			}
-		} else {
-			latestTagMap[resourceKey] = it
+// tagsActiveAt keeps the image tag rows that were active at the given instant, one per container
+// Every row is one interval, so normally one row per container matches; should a row have been
+// left open when it was replaced, the one that started last is what the container ran
+func tagsActiveAt(imageTags []models.ImageTag, at time.Time) []models.ImageTag {
+	latest := make(map[string]models.ImageTag)
+
+	for _, it := range imageTags {
+		if it.FirstSeen.After(at) || (it.DeletedAt.Valid && !it.DeletedAt.Time.After(at)) {
+			continue
+		}
+
+		key := fmt.Sprintf("%s|%s|%s|%s", it.ResourceType, it.ResourceName, it.Namespace, it.ContainerName)
+		existing, found := latest[key]
+		if !found || it.FirstSeen.After(existing.FirstSeen) ||
+			(it.FirstSeen.Equal(existing.FirstSeen) && it.ID > existing.ID) {
+			latest[key] = it
 		}
 	}
 
-	// Now aggregate containers for each unique image+tag+resource combination
-	imageMap := make(map[string]*models.ImageInfo)
+	result := make([]models.ImageTag, 0, len(latest))
+	for _, it := range latest {
+		result = append(result, it)
+	}
 
-	for _, it := range latestTagMap {
-		key := fmt.Sprintf("%s|%s|%s|%s|%s",
-			it.Image.Name, it.Tag, it.ResourceType, it.ResourceName, it.Namespace)
+	return result
+}
+
+// aggregateImages groups active image tags into ImageInfo rows
+func aggregateImages(imageTags []models.ImageTag, runningDigests map[string][]string) []models.ImageInfo {
+	var result []models.ImageInfo
+	for _, group := range groupImageTags(imageTags, runningDigests) {
+		result = append(result, group.imageInfo())
+	}
+
+	return result
+}
 
-		if existing, found := imageMap[key]; found {
-			existing.Containers = append(existing.Containers, it.ContainerName)
-		} else {
-			imageMap[key] = &models.ImageInfo{
+// imageGroup is an inventory row before it is rendered as ImageInfo
+type imageGroup struct {
+	key       string
+	info      models.ImageInfo
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// imageInfo renders the group with its timestamps
+func (g imageGroup) imageInfo() models.ImageInfo {
+	info := g.info
+	info.FirstSeen = g.firstSeen.Format(time.RFC3339)
+	info.LastSeen = g.lastSeen.Format(time.RFC3339)
+	info.DigestMismatch = len(info.RunningDigests) > 1
+	return info
+}
+
+// groupImageTags aggregates the containers of each image, tag, digest and resource, in order of appearance
+func groupImageTags(imageTags []models.ImageTag, runningDigests map[string][]string) []imageGroup {
+	var groups []imageGroup
+	indexes := make(map[string]int)
+
+	for _, it := range imageTags {
+		key := imageGroupKey{
+			ImageID: it.ImageID, Tag: it.Tag, Digest: it.Digest,
+			ResourceType: it.ResourceType, ResourceName: it.ResourceName, Namespace: it.Namespace,
+		}.String()
+		running := runningDigests[runningKey(it.Image.Repository, it.Image.Name, it.Tag,
+			it.ResourceType, it.ResourceName, it.Namespace, it.ContainerName)]
+
+		if i, found := indexes[key]; found {
+			group := &groups[i]
+			group.info.Containers = append(group.info.Containers, it.ContainerName)
+			group.info.RunningDigests = appendUnique(group.info.RunningDigests, running...)
+			if it.FirstSeen.Before(group.firstSeen) {
+				group.firstSeen = it.FirstSeen
+			}
+			if it.LastSeen.After(group.lastSeen) {
+				group.lastSeen = it.LastSeen
+			}
+			continue
+		}
+
+		indexes[key] = len(groups)
+		groups = append(groups, imageGroup{
+			key: key,
+			info: models.ImageInfo{
 				Name:         it.Image.Name,
+				Repository:   it.Image.Repository,
 				Tag:          it.Tag,
+				Digest:       it.Digest,
 				ResourceType: it.ResourceType,
 				ResourceName: it.ResourceName,
 				Namespace:    it.Namespace,
 				Containers:   []string{it.ContainerName},
-				FirstSeen:    it.FirstSeen.Format(time.RFC3339),
-				LastSeen:     it.LastSeen.Format(time.RFC3339),
-			}
-		}
-	}
 
-	// Convert map to slice
-	var result []models.ImageInfo
-	for _, img := range imageMap {
-		result = append(result, *img)
+				RunningDigests: appendUnique(nil, running...),
+			},
+			firstSeen: it.FirstSeen,
+			lastSeen:  it.LastSeen,
+		})
 	}
 
-	return result, nil
+	return groups
 }
 
 // GetImageTagHistory returns the history of all tags for a specific image
-func (r *ImageRepository) GetImageTagHistory(imageName, namespace string) (*models.ImageTagHistory, error) {
-	var image models.Image
+// reference is either a full reference such as ghcr.io/bitnami/redis, or a bare name matching every repository
+func (r *ImageRepository) GetImageTagHistory(reference, namespace string) (*models.ImageTagHistory, error) {
+	var images []models.Image
 
-	// Find image by name (could be from multiple repositories)
-	if err := r.db.Where("name = ?", imageName).First(&image).Error; err != nil {
-		return nil, fmt.Errorf("image not found: %w", err)
+	query := r.db.Order("full_name")
+	if isFullReference(reference) {
+		query = query.Where("full_name = ?", reference)
+	} else {
+		query = query.Where("name = ?", reference)
+	}
+
+	if err := query.Find(&images).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch image: %w", err)
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("image not found: %w", gorm.ErrRecordNotFound)
+	}
+
+	imagesByID := make(map[uint]models.Image, len(images))
+	ids := make([]uint, 0, len(images))
+	for _, image := range images {
+		imagesByID[image.ID] = image
+		ids = append(ids, image.ID)
 	}
 
 	var imageTags []models.ImageTag
-	query := r.db.Unscoped().Where("image_id = ?", image.ID)
+	tagQuery := r.db.Unscoped().Where("image_id IN ?", ids)
 
 	if namespace != "" {
-		query = query.Where("namespace = ?", namespace)
+		tagQuery = tagQuery.Where("namespace = ?", namespace)
 	}
 
-	if err := query.Order("first_seen DESC").Find(&imageTags).Error; err != nil {
+	if err := tagQuery.Order("first_seen DESC").Find(&imageTags).Error; err != nil {
 		return nil, fmt.Errorf("failed to fetch image tag history: %w", err)
 	}
 
-	// Group by tag to find which tags are currently active
+	for i := range imageTags {
+		imageTags[i].Image = imagesByID[imageTags[i].ImageID]
+	}
+
+	runningDigests, err := r.getRunningDigests(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildTagHistory(images, imageTags, runningDigests), nil
+}
+
+// isFullReference reports whether an image reference includes its repository
+// Image names are the last path component, so only a full reference contains a slash
+func isFullReference(reference string) bool {
+	return strings.Contains(reference, "/")
+}
+
+// GetResourceImageTags returns every image tag row of a workload, closed out ones included, oldest first
+func (r *ImageRepository) GetResourceImageTags(resourceType, resourceName, namespace string) ([]models.ImageTag, error) {
+	var imageTags []models.ImageTag
+
+	err := r.db.Unscoped().Preload("Image").
+		Where("resource_type = ? AND resource_name = ? AND namespace = ?", resourceType, resourceName, namespace).
+		Order("first_seen ASC").Order("id ASC").
+		Find(&imageTags).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch resource image tags: %w", err)
+	}
+
+	return imageTags, nil
+}
+
+// searchCandidateLimit caps the rows one search returns for ranking, best matches first
+const searchCandidateLimit = 1000
+
+// searchColumns are the columns search terms are matched against, with the field they rank as
+var searchColumns = []struct {
+	column, field string
+}{
+	{"images.name", models.SearchFieldName},
+	{"images.repository", models.SearchFieldRepository},
+	{"image_tags.tag", models.SearchFieldTag},
+	{"image_tags.resource_name", models.SearchFieldResource},
+	{"image_tags.namespace", models.SearchFieldNamespace},
+	{"image_tags.container_name", models.SearchFieldContainer},
+}
+
+// SearchImageTags returns the image tag rows, closed out ones included, where every term matches a searched column
+// On PostgreSQL a term also matches trigram-similar values, served by the indexes of the search_indexes migration.
+// Rows are ordered by the score the search service ranks them by, so the candidate limit keeps the best matches.
+func (r *ImageRepository) SearchImageTags(query models.SearchQuery) ([]models.ImageTag, error) {
+	fuzzy := r.db.Dialector.Name() == "postgres"
+
+	db := r.db.Unscoped().Table("image_tags").
+		Joins("JOIN images ON images.id = image_tags.image_id").
+		Select("image_tags.*").
+		Preload("Image")
+
+	var scores []string
+	var scoreArgs []any
+	for _, term := range query.Terms() {
+		pattern := "%" + escapeLike(term) + "%"
+
+		var conditions, columnScores []string
+		var args []any
+		for _, search := range searchColumns {
+			column := search.column
+			if fuzzy {
+				conditions = append(conditions, column+" ILIKE ? ESCAPE '\\'", column+" % ?")
+				args = append(args, pattern, term)
+			} else {
+				conditions = append(conditions, "LOWER("+column+") LIKE ? ESCAPE '\\'")
+				args = append(args, pattern)
+			}
+
+			// Mirrors matchScore of the search service: exact, prefix, substring, then trigram similarity
+			fallback := "0"
+			if fuzzy {
+				fallback = "0.5 * similarity(" + column + ", ?)"
+			}
+			columnScores = append(columnScores, fmt.Sprintf(
+				"%g * CASE WHEN LOWER(%s) = ? THEN 1 WHEN LOWER(%s) LIKE ? ESCAPE '\\' THEN 0.75 "+
+					"WHEN LOWER(%s) LIKE ? ESCAPE '\\' THEN 0.5 ELSE %s END",
+				models.SearchFieldWeights[search.field], column, column, column, fallback))
+			scoreArgs = append(scoreArgs, term, escapeLike(term)+"%", pattern)
+			if fuzzy {
+				scoreArgs = append(scoreArgs, term)
+			}
+		}
+		db = db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+
+		// The best matching column counts per term, SQLite's scalar MAX is PostgreSQL's GREATEST
+		greatest := "MAX"
+		if fuzzy {
+			greatest = "GREATEST"
+		}
+		scores = append(scores, greatest+"("+strings.Join(columnScores, ", ")+")")
+	}
+
+	if query.Namespace != "" {
+		db = db.Where("image_tags.namespace = ?", query.Namespace)
+	}
+
+	// One expression, GORM drops an ordering expression once plain columns are added
+	order := "CASE WHEN image_tags.deleted_at IS NULL THEN 0 ELSE 1 END, image_tags.last_seen DESC, image_tags.id DESC"
+	if len(scores) > 0 {
+		order = "(" + strings.Join(scores, " + ") + ") DESC, " + order
+	}
+
+	var imageTags []models.ImageTag
+	err := db.Order(clause.OrderBy{Expression: clause.Expr{SQL: order, Vars: scoreArgs}}).
+		Limit(searchCandidateLimit).
+		Find(&imageTags).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to search image tags: %w", err)
+	}
+
+	return imageTags, nil
+}
+
+// buildTagHistory converts the tag rows of an image, newest first, into its history response
+func buildTagHistory(
+	images []models.Image, imageTags []models.ImageTag, runningDigests map[string][]string,
+) *models.ImageTagHistory {
+	// Group by image and tag to find which tags are currently active
 	// A tag is active if it has at least one non-deleted record
 	tagActiveMap := make(map[string]bool)
 	for _, it := range imageTags {
 		if it.DeletedAt.Time.IsZero() {
-			tagActiveMap[it.Tag] = true
+			tagActiveMap[fmt.Sprintf("%d|%s", it.ImageID, it.Tag)] = true
 		}
 	}
 
 	// Convert to response format
 	var tagDetails []models.ImageTagDetails
 	for _, it := range imageTags {
+		var running []string
+		if it.DeletedAt.Time.IsZero() {
+			running = runningDigests[runningKey(it.Image.Repository, it.Image.Name, it.Tag,
+				it.ResourceType, it.ResourceName, it.Namespace, it.ContainerName)]
+		}
+
 		tagDetails = append(tagDetails, models.ImageTagDetails{
+			Repository:   it.Image.Repository,
 			Tag:          it.Tag,
+			Digest:       it.Digest,
 			FirstSeen:    it.FirstSeen,
 			LastSeen:     it.LastSeen,
 			ResourceType: it.ResourceType,
 			ResourceName: it.ResourceName,
 			Namespace:    it.Namespace,
 			Container:    it.ContainerName,
-			Active:       tagActiveMap[it.Tag], // Active if any instance of this tag is not deleted
+			Active:       tagActiveMap[fmt.Sprintf("%d|%s", it.ImageID, it.Tag)], // Active if any instance of this tag is not deleted
+
+			RunningDigests: running,
+			RemovedAt:      it.RemovedAt,
 		})
 	}
 
+	repositories := make([]string, 0, len(images))
+	for _, image := range images {
+		repositories = append(repositories, image.Repository)
+	}
+	sort.Strings(repositories)
+
 	return &models.ImageTagHistory{
-		ImageName: imageName,
-		Tags:      tagDetails,
-	}, nil
+		ImageName:    images[0].Name,
+		Repositories: repositories,
+		Tags:         tagDetails,
+	}
+}
+
+// AppendImageEvents adds entries to the append-only image event log, setting their IDs
+func (r *ImageRepository) AppendImageEvents(events []models.ImageEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	if err := r.db.CreateInBatches(&events, upsertBatchSize).Error; err != nil {
+		return fmt.Errorf("failed to append image events: %w", err)
+	}
+
+	return nil
+}
+
+// ListImageEvents returns the image event log entries matching filter, newest first
+func (r *ImageRepository) ListImageEvents(filter models.ImageEventFilter) ([]models.ImageEvent, error) {
+	query := r.db.Order("observed_at DESC").Order("id DESC")
+
+	if filter.Namespace != "" {
+		query = query.Where("namespace = ?", filter.Namespace)
+	}
+	if filter.ImageName != "" {
+		query = query.Where("image_name = ? OR old_image_name = ?", filter.ImageName, filter.ImageName)
+	}
+	if filter.ResourceType != "" {
+		query = query.Where("resource_type = ?", filter.ResourceType)
+	}
+	if filter.ResourceName != "" {
+		query = query.Where("resource_name = ?", filter.ResourceName)
+	}
+	if filter.Since != nil {
+		query = query.Where("observed_at >= ?", filter.Since.UTC())
+	}
+	if filter.Until != nil {
+		query = query.Where("observed_at < ?", filter.Until.UTC())
+	}
+	if filter.AfterID > 0 {
+		query = query.Where("id > ?", filter.AfterID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var events []models.ImageEvent
+	if err := query.Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch image events: %w", err)
+	}
+
+	return events, nil
+}
+
+// PurgeImageEvents permanently deletes the event log entries observed before the given time
+// In dry-run mode the entries are only counted
+func (r *ImageRepository) PurgeImageEvents(before time.Time, dryRun bool) (int64, error) {
+	query := r.db.Model(&models.ImageEvent{}).Where("observed_at < ?", before.UTC())
+
+	if dryRun {
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return 0, fmt.Errorf("failed to count image events: %w", err)
+		}
+		return count, nil
+	}
+
+	result := query.Delete(&models.ImageEvent{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge image events: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// AppendWebhookDelivery records a webhook delivery attempt, setting its ID
+func (r *ImageRepository) AppendWebhookDelivery(delivery *models.WebhookDelivery) error {
+	if err := r.db.Create(delivery).Error; err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// ListWebhookDeliveries returns the webhook delivery attempts matching filter, newest first
+func (r *ImageRepository) ListWebhookDeliveries(filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	query := r.db.Order("id DESC")
+
+	if filter.Target != "" {
+		query = query.Where("target = ?", filter.Target)
+	}
+	if filter.EventID > 0 {
+		query = query.Where("event_id = ?", filter.EventID)
+	}
+	if filter.Failed {
+		query = query.Where("success = ?", false)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// PurgeWebhookDeliveries permanently deletes the delivery attempts made before the given time
+// In dry-run mode the attempts are only counted
+func (r *ImageRepository) PurgeWebhookDeliveries(before time.Time, dryRun bool) (int64, error) {
+	query := r.db.Model(&models.WebhookDelivery{}).Where("created_at < ?", before.UTC())
+
+	if dryRun {
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+		}
+		return count, nil
+	}
+
+	result := query.Delete(&models.WebhookDelivery{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge webhook deliveries: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// UpsertImageUpdates records registry checks of image tags, replacing earlier checks of the same tags
+func (r *ImageRepository) UpsertImageUpdates(updates []models.ImageUpdate) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "image_name"}, {Name: "repository"}, {Name: "tag"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "latest_compatible", "latest", "outdated", "checked_at", "error"}),
+	}).CreateInBatches(&updates, upsertBatchSize).Error
+	if err != nil {
+		return fmt.Errorf("failed to upsert image updates: %w", err)
+	}
+
+	return nil
+}
+
+// ListImageUpdates returns the latest registry check of every image tag, ordered by image and tag
+func (r *ImageRepository) ListImageUpdates() ([]models.ImageUpdate, error) {
+	var updates []models.ImageUpdate
+	if err := r.db.Order("repository, image_name, tag").Find(&updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch image updates: %w", err)
+	}
+
+	return updates, nil
 }
@@ -2,11 +2,17 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
 	"testing"
 	"time"
 
+	"github.com/huseyinbabal/kubetag/internal/database"
 	"github.com/huseyinbabal/kubetag/internal/models"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
@@ -24,8 +30,54 @@ func isDockerAvailable() bool {
 	return err == nil
 }
 
+// testBackends lists the storage drivers the repository suite runs against
+var testBackends = []struct {
+	name  string
+	setup func(t testing.TB) (*gorm.DB, func())
+}{
+	{name: "postgres", setup: setupTestDB},
+	{name: "sqlite", setup: setupSQLiteFileDB},
+}
+
+// forEachBackend runs a test against a fresh database of every storage driver
+func forEachBackend(t *testing.T, run func(t *testing.T, db *gorm.DB)) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			db, cleanup := backend.setup(t)
+			defer cleanup()
+
+			run(t, db)
+		})
+	}
+}
+
+// setupSQLiteFileDB opens a file backed SQLite database the way the server does with DB_DRIVER=sqlite
+func setupSQLiteFileDB(t testing.TB) (*gorm.DB, func()) {
+	db, err := database.Connect(&database.Config{
+		Driver: database.DriverSQLite,
+		Path:   filepath.Join(t.TempDir(), "kubetag.db"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open SQLite database: %v", err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	cleanup := func() {
+		sqlDB, _ := db.DB()
+		if sqlDB != nil {
+			sqlDB.Close()
+		}
+	}
+
+	return db, cleanup
+}
+
 // setupTestDB creates a PostgreSQL testcontainer for testing
-func setupTestDB(t *testing.T) (*gorm.DB, func()) {
+func setupTestDB(t testing.TB) (*gorm.DB, func()) {
 	// Skip if Docker is not available
 	if testing.Short() {
 		t.Skip("Skipping testcontainer tests in short mode")
@@ -67,7 +119,7 @@ func setupTestDB(t *testing.T) (*gorm.DB, func()) {
 	}
 
 	// Run migrations
-	err = db.AutoMigrate(&models.Image{}, &models.ImageTag{})
+	err = db.AutoMigrate(&models.Image{}, &models.ImageTag{}, &models.RunningImage{}, &models.ImageEvent{}, &models.WebhookDelivery{}, &models.ImageUpdate{})
 	if err != nil {
 		postgresContainer.Terminate(ctx)
 		t.Fatalf("Failed to run migrations: %v", err)
@@ -98,400 +150,401 @@ func TestNewImageRepository(t *testing.T) {
 }
 
 func TestUpsertImageTag(t *testing.T) {
-	db, cleanup := setupTestDB(t)
-	defer cleanup()
-
-	repo := NewImageRepository(db)
-
-	t.Run("Create new image and tag", func(t *testing.T) {
-		err := repo.UpsertImageTag(
-			"nginx",
-			"docker.io",
-			"1.19",
-			"Deployment",
-			"web-server",
-			"default",
-			"nginx",
-		)
-
-		if err != nil {
-			t.Fatalf("Expected no error, got %v", err)
-		}
-
-		// Verify image was created
-		var image models.Image
-		err = db.Where("name = ?", "nginx").First(&image).Error
-		if err != nil {
-			t.Fatalf("Failed to find image: %v", err)
-		}
-
-		if image.Name != "nginx" {
-			t.Errorf("Expected image name 'nginx', got '%s'", image.Name)
-		}
-		if image.Repository != "docker.io" {
-			t.Errorf("Expected repository 'docker.io', got '%s'", image.Repository)
-		}
-		if image.FullName != "docker.io/nginx" {
-			t.Errorf("Expected full name 'docker.io/nginx', got '%s'", image.FullName)
-		}
-
-		// Verify image tag was created
-		var imageTag models.ImageTag
-		err = db.Where("image_id = ? AND tag = ?", image.ID, "1.19").First(&imageTag).Error
-		if err != nil {
-			t.Fatalf("Failed to find image tag: %v", err)
-		}
+	forEachBackend(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewImageRepository(db)
 
-		if imageTag.Tag != "1.19" {
-			t.Errorf("Expected tag '1.19', got '%s'", imageTag.Tag)
-		}
-		if imageTag.ResourceType != "Deployment" {
-			t.Errorf("Expected resource type 'Deployment', got '%s'", imageTag.ResourceType)
-		}
-		if imageTag.ResourceName != "web-server" {
-			t.Errorf("Expected resource name 'web-server', got '%s'", imageTag.ResourceName)
-		}
-		if imageTag.Namespace != "default" {
-			t.Errorf("Expected namespace 'default', got '%s'", imageTag.Namespace)
-		}
-		if imageTag.ContainerName != "nginx" {
-			t.Errorf("Expected container name 'nginx', got '%s'", imageTag.ContainerName)
-		}
-	})
+		t.Run("Create new image and tag", func(t *testing.T) {
+			_, err := repo.UpsertImageTag(
+				"nginx",
+				"docker.io",
+				"1.19",
+				"",
+				"Deployment",
+				"web-server",
+				"default",
+				"nginx",
+			)
+
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
 
-	t.Run("Update existing tag", func(t *testing.T) {
-		// First insert
-		err := repo.UpsertImageTag(
-			"redis",
-			"docker.io",
-			"6.0",
-			"Deployment",
-			"cache",
-			"default",
-			"redis",
-		)
-		if err != nil {
-			t.Fatalf("Failed to create initial tag: %v", err)
-		}
+			// Verify image was created
+			var image models.Image
+			err = db.Where("name = ?", "nginx").First(&image).Error
+			if err != nil {
+				t.Fatalf("Failed to find image: %v", err)
+			}
 
-		// Get first seen time
-		var imageTag models.ImageTag
-		db.Joins("JOIN images ON images.id = image_tags.image_id").
-			Where("images.name = ? AND image_tags.tag = ?", "redis", "6.0").
-			First(&imageTag)
-		firstSeenTime := imageTag.FirstSeen
+			if image.Name != "nginx" {
+				t.Errorf("Expected image name 'nginx', got '%s'", image.Name)
+			}
+			if image.Repository != "docker.io" {
+				t.Errorf("Expected repository 'docker.io', got '%s'", image.Repository)
+			}
+			if image.FullName != "docker.io/nginx" {
+				t.Errorf("Expected full name 'docker.io/nginx', got '%s'", image.FullName)
+			}
 
-		// Wait a bit and upsert again
-		time.Sleep(100 * time.Millisecond)
+			// Verify image tag was created
+			var imageTag models.ImageTag
+			err = db.Where("image_id = ? AND tag = ?", image.ID, "1.19").First(&imageTag).Error
+			if err != nil {
+				t.Fatalf("Failed to find image tag: %v", err)
+			}
 
-		err = repo.UpsertImageTag(
-			"redis",
-			"docker.io",
-			"6.0",
-			"Deployment",
-			"cache",
-			"default",
-			"redis",
-		)
-		if err != nil {
-			t.Fatalf("Failed to update tag: %v", err)
-		}
+			if imageTag.Tag != "1.19" {
+				t.Errorf("Expected tag '1.19', got '%s'", imageTag.Tag)
+			}
+			if imageTag.ResourceType != "Deployment" {
+				t.Errorf("Expected resource type 'Deployment', got '%s'", imageTag.ResourceType)
+			}
+			if imageTag.ResourceName != "web-server" {
+				t.Errorf("Expected resource name 'web-server', got '%s'", imageTag.ResourceName)
+			}
+			if imageTag.Namespace != "default" {
+				t.Errorf("Expected namespace 'default', got '%s'", imageTag.Namespace)
+			}
+			if imageTag.ContainerName != "nginx" {
+				t.Errorf("Expected container name 'nginx', got '%s'", imageTag.ContainerName)
+			}
+		})
+
+		t.Run("Update existing tag", func(t *testing.T) {
+			// First insert
+			_, err := repo.UpsertImageTag(
+				"redis",
+				"docker.io",
+				"6.0",
+				"",
+				"Deployment",
+				"cache",
+				"default",
+				"redis",
+			)
+			if err != nil {
+				t.Fatalf("Failed to create initial tag: %v", err)
+			}
 
-		// Verify FirstSeen didn't change but LastSeen did
-		var updatedTag models.ImageTag
-		db.Joins("JOIN images ON images.id = image_tags.image_id").
-			Where("images.name = ? AND image_tags.tag = ?", "redis", "6.0").
-			First(&updatedTag)
+			// Get first seen time
+			var imageTag models.ImageTag
+			db.Joins("JOIN images ON images.id = image_tags.image_id").
+				Where("images.name = ? AND image_tags.tag = ?", "redis", "6.0").
+				First(&imageTag)
+			firstSeenTime := imageTag.FirstSeen
+
+			// Wait a bit and upsert again
+			time.Sleep(100 * time.Millisecond)
+
+			_, err = repo.UpsertImageTag(
+				"redis",
+				"docker.io",
+				"6.0",
+				"",
+				"Deployment",
+				"cache",
+				"default",
+				"redis",
+			)
+			if err != nil {
+				t.Fatalf("Failed to update tag: %v", err)
+			}
 
-		if !updatedTag.FirstSeen.Equal(firstSeenTime) {
-			t.Error("FirstSeen should not change on update")
-		}
-		if !updatedTag.LastSeen.After(firstSeenTime) {
-			t.Error("LastSeen should be updated on upsert")
-		}
-	})
+			// Verify FirstSeen didn't change but LastSeen did
+			var updatedTag models.ImageTag
+			db.Joins("JOIN images ON images.id = image_tags.image_id").
+				Where("images.name = ? AND image_tags.tag = ?", "redis", "6.0").
+				First(&updatedTag)
 
-	t.Run("Same image in different resources", func(t *testing.T) {
-		err := repo.UpsertImageTag(
-			"busybox",
-			"docker.io",
-			"latest",
-			"Deployment",
-			"app1",
-			"default",
-			"init",
-		)
-		if err != nil {
-			t.Fatalf("Failed to create first tag: %v", err)
-		}
+			if !updatedTag.FirstSeen.Equal(firstSeenTime) {
+				t.Error("FirstSeen should not change on update")
+			}
+			if !updatedTag.LastSeen.After(firstSeenTime) {
+				t.Error("LastSeen should be updated on upsert")
+			}
+		})
+
+		t.Run("Same image in different resources", func(t *testing.T) {
+			_, err := repo.UpsertImageTag(
+				"busybox",
+				"docker.io",
+				"latest",
+				"",
+				"Deployment",
+				"app1",
+				"default",
+				"init",
+			)
+			if err != nil {
+				t.Fatalf("Failed to create first tag: %v", err)
+			}
 
-		err = repo.UpsertImageTag(
-			"busybox",
-			"docker.io",
-			"latest",
-			"DaemonSet",
-			"app2",
-			"default",
-			"init",
-		)
-		if err != nil {
-			t.Fatalf("Failed to create second tag: %v", err)
-		}
+			_, err = repo.UpsertImageTag(
+				"busybox",
+				"docker.io",
+				"latest",
+				"",
+				"DaemonSet",
+				"app2",
+				"default",
+				"init",
+			)
+			if err != nil {
+				t.Fatalf("Failed to create second tag: %v", err)
+			}
 
-		// Should have 2 distinct image tags
-		var count int64
-		db.Model(&models.ImageTag{}).Where("tag = ?", "latest").Count(&count)
-		if count != 2 {
-			t.Errorf("Expected 2 image tags, got %d", count)
-		}
+			// Should have 2 distinct image tags
+			var count int64
+			db.Model(&models.ImageTag{}).Where("tag = ?", "latest").Count(&count)
+			if count != 2 {
+				t.Errorf("Expected 2 image tags, got %d", count)
+			}
+		})
 	})
 }
 
 func TestDeleteImageTag(t *testing.T) {
-	db, cleanup := setupTestDB(t)
-	defer cleanup()
-
-	repo := NewImageRepository(db)
+	forEachBackend(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewImageRepository(db)
 
-	// Create some test data
-	repo.UpsertImageTag("nginx", "docker.io", "1.19", "Deployment", "web", "default", "nginx")
-	repo.UpsertImageTag("nginx", "docker.io", "1.20", "Deployment", "web", "default", "nginx")
-	repo.UpsertImageTag("redis", "docker.io", "6.0", "Deployment", "cache", "default", "redis")
+		// Create some test data
+		repo.UpsertImageTag("nginx", "docker.io", "1.19", "", "Deployment", "web", "default", "nginx")
+		repo.UpsertImageTag("nginx", "docker.io", "1.20", "", "Deployment", "web", "default", "nginx")
+		repo.UpsertImageTag("redis", "docker.io", "6.0", "", "Deployment", "cache", "default", "redis")
 
-	t.Run("Delete specific resource tags", func(t *testing.T) {
-		err := repo.DeleteImageTag("Deployment", "web", "default")
-		if err != nil {
-			t.Fatalf("Failed to delete tags: %v", err)
-		}
+		t.Run("Delete specific resource tags", func(t *testing.T) {
+			err := repo.DeleteImageTag("Deployment", "web", "default", "")
+			if err != nil {
+				t.Fatalf("Failed to delete tags: %v", err)
+			}
 
-		// Should soft delete nginx tags
-		var count int64
-		db.Model(&models.ImageTag{}).
-			Where("resource_type = ? AND resource_name = ? AND namespace = ?", "Deployment", "web", "default").
-			Count(&count)
-		if count != 0 {
-			t.Errorf("Expected 0 tags after deletion, got %d", count)
-		}
+			// Should soft delete nginx tags
+			var count int64
+			db.Model(&models.ImageTag{}).
+				Where("resource_type = ? AND resource_name = ? AND namespace = ?", "Deployment", "web", "default").
+				Count(&count)
+			if count != 0 {
+				t.Errorf("Expected 0 tags after deletion, got %d", count)
+			}
 
-		// Redis tag should still exist
-		db.Model(&models.ImageTag{}).
-			Where("resource_type = ? AND resource_name = ?", "Deployment", "cache").
-			Count(&count)
-		if count != 1 {
-			t.Errorf("Expected redis tag to still exist, got count %d", count)
-		}
-	})
+			// Redis tag should still exist
+			db.Model(&models.ImageTag{}).
+				Where("resource_type = ? AND resource_name = ?", "Deployment", "cache").
+				Count(&count)
+			if count != 1 {
+				t.Errorf("Expected redis tag to still exist, got count %d", count)
+			}
+		})
 
-	t.Run("Delete non-existent resource", func(t *testing.T) {
-		err := repo.DeleteImageTag("Deployment", "nonexistent", "default")
-		if err != nil {
-			t.Errorf("Deleting non-existent resource should not error, got %v", err)
-		}
+		t.Run("Delete non-existent resource", func(t *testing.T) {
+			err := repo.DeleteImageTag("Deployment", "nonexistent", "default", "")
+			if err != nil {
+				t.Errorf("Deleting non-existent resource should not error, got %v", err)
+			}
+		})
 	})
 }
 
 func TestGetAllImages(t *testing.T) {
-	db, cleanup := setupTestDB(t)
-	defer cleanup()
-
-	repo := NewImageRepository(db)
+	forEachBackend(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewImageRepository(db)
 
-	// Create test data
-	repo.UpsertImageTag("nginx", "docker.io", "1.19", "Deployment", "web", "default", "nginx")
-	repo.UpsertImageTag("nginx", "docker.io", "1.19", "Deployment", "web", "default", "sidecar")
-	repo.UpsertImageTag("redis", "docker.io", "6.0", "DaemonSet", "cache", "production", "redis")
+		// Create test data
+		repo.UpsertImageTag("nginx", "docker.io", "1.19", "", "Deployment", "web", "default", "nginx")
+		repo.UpsertImageTag("nginx", "docker.io", "1.19", "", "Deployment", "web", "default", "sidecar")
+		repo.UpsertImageTag("redis", "docker.io", "6.0", "", "DaemonSet", "cache", "production", "redis")
 
-	t.Run("Get all images without namespace filter", func(t *testing.T) {
-		images, err := repo.GetAllImages("")
-		if err != nil {
-			t.Fatalf("Failed to get images: %v", err)
-		}
+		t.Run("Get all images without namespace filter", func(t *testing.T) {
+			images, err := repo.GetAllImages("")
+			if err != nil {
+				t.Fatalf("Failed to get images: %v", err)
+			}
 
-		if len(images) != 2 {
-			t.Errorf("Expected 2 images, got %d", len(images))
-			for i, img := range images {
-				t.Logf("Image %d: %+v", i, img)
+			if len(images) != 2 {
+				t.Errorf("Expected 2 images, got %d", len(images))
+				for i, img := range images {
+					t.Logf("Image %d: %+v", i, img)
+				}
 			}
-		}
 
-		// Find nginx image
-		var nginxImg *models.ImageInfo
-		for i := range images {
-			if images[i].Name == "nginx" {
-				nginxImg = &images[i]
-				break
+			// Find nginx image
+			var nginxImg *models.ImageInfo
+			for i := range images {
+				if images[i].Name == "nginx" {
+					nginxImg = &images[i]
+					break
+				}
 			}
-		}
 
-		if nginxImg == nil {
-			t.Fatal("Expected to find nginx image")
-		}
+			if nginxImg == nil {
+				t.Fatal("Expected to find nginx image")
+			}
 
-		if nginxImg.Name != "nginx" {
-			t.Errorf("Expected image name nginx, got %s", nginxImg.Name)
-		}
-	})
+			if nginxImg.Name != "nginx" {
+				t.Errorf("Expected image name nginx, got %s", nginxImg.Name)
+			}
+		})
 
-	t.Run("Get images with namespace filter", func(t *testing.T) {
-		images, err := repo.GetAllImages("default")
-		if err != nil {
-			t.Fatalf("Failed to get images: %v", err)
-		}
+		t.Run("Get images with namespace filter", func(t *testing.T) {
+			images, err := repo.GetAllImages("default")
+			if err != nil {
+				t.Fatalf("Failed to get images: %v", err)
+			}
 
-		if len(images) != 1 {
-			t.Errorf("Expected 1 image in default namespace, got %d", len(images))
-		}
+			if len(images) != 1 {
+				t.Errorf("Expected 1 image in default namespace, got %d", len(images))
+			}
 
-		if images[0].Name != "nginx" {
-			t.Errorf("Expected nginx image, got %s", images[0].Name)
-		}
-	})
+			if images[0].Name != "nginx" {
+				t.Errorf("Expected nginx image, got %s", images[0].Name)
+			}
+		})
 
-	t.Run("Get images excludes deleted tags", func(t *testing.T) {
-		// Delete the redis tag
-		repo.DeleteImageTag("DaemonSet", "cache", "production")
+		t.Run("Get images excludes deleted tags", func(t *testing.T) {
+			// Delete the redis tag
+			repo.DeleteImageTag("DaemonSet", "cache", "production", "")
 
-		images, err := repo.GetAllImages("")
-		if err != nil {
-			t.Fatalf("Failed to get images: %v", err)
-		}
+			images, err := repo.GetAllImages("")
+			if err != nil {
+				t.Fatalf("Failed to get images: %v", err)
+			}
 
-		// Should only have nginx now
-		if len(images) != 1 {
-			t.Errorf("Expected 1 active image, got %d", len(images))
-		}
-		if images[0].Name != "nginx" {
-			t.Errorf("Expected nginx, got %s", images[0].Name)
-		}
+			// Should only have nginx now
+			if len(images) != 1 {
+				t.Errorf("Expected 1 active image, got %d", len(images))
+			}
+			if images[0].Name != "nginx" {
+				t.Errorf("Expected nginx, got %s", images[0].Name)
+			}
+		})
 	})
 }
 
 func TestGetImageTagHistory(t *testing.T) {
-	db, cleanup := setupTestDB(t)
-	defer cleanup()
-
-	repo := NewImageRepository(db)
-
-	// Create test data with version history
-	repo.UpsertImageTag("myapp", "gcr.io", "v1.0", "Deployment", "api", "production", "app")
-	time.Sleep(50 * time.Millisecond)
-	repo.UpsertImageTag("myapp", "gcr.io", "v1.1", "Deployment", "api", "production", "app")
-	time.Sleep(50 * time.Millisecond)
-	repo.UpsertImageTag("myapp", "gcr.io", "v1.2", "Deployment", "api", "production", "app")
+	forEachBackend(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewImageRepository(db)
 
-	t.Run("Get history without namespace filter", func(t *testing.T) {
-		history, err := repo.GetImageTagHistory("myapp", "")
-		if err != nil {
-			t.Fatalf("Failed to get history: %v", err)
-		}
+		// Create test data with version history
+		repo.UpsertImageTag("myapp", "gcr.io", "v1.0", "", "Deployment", "api", "production", "app")
+		time.Sleep(50 * time.Millisecond)
+		repo.UpsertImageTag("myapp", "gcr.io", "v1.1", "", "Deployment", "api", "production", "app")
+		time.Sleep(50 * time.Millisecond)
+		repo.UpsertImageTag("myapp", "gcr.io", "v1.2", "", "Deployment", "api", "production", "app")
+
+		t.Run("Get history without namespace filter", func(t *testing.T) {
+			history, err := repo.GetImageTagHistory("myapp", "")
+			if err != nil {
+				t.Fatalf("Failed to get history: %v", err)
+			}
 
-		if history.ImageName != "myapp" {
-			t.Errorf("Expected image name 'myapp', got '%s'", history.ImageName)
-		}
+			if history.ImageName != "myapp" {
+				t.Errorf("Expected image name 'myapp', got '%s'", history.ImageName)
+			}
 
-		if len(history.Tags) != 3 {
-			t.Errorf("Expected 3 tags, got %d", len(history.Tags))
-		}
+			if len(history.Tags) != 3 {
+				t.Errorf("Expected 3 tags, got %d", len(history.Tags))
+			}
 
-		// Tags should be ordered by FirstSeen DESC
-		if history.Tags[0].Tag != "v1.2" {
-			t.Errorf("Expected first tag to be 'v1.2', got '%s'", history.Tags[0].Tag)
-		}
+			// Tags should be ordered by FirstSeen DESC
+			if history.Tags[0].Tag != "v1.2" {
+				t.Errorf("Expected first tag to be 'v1.2', got '%s'", history.Tags[0].Tag)
+			}
 
-		// All tags should be active
-		for _, tag := range history.Tags {
-			if !tag.Active {
-				t.Errorf("Expected tag %s to be active", tag.Tag)
+			// All tags should be active
+			for _, tag := range history.Tags {
+				if !tag.Active {
+					t.Errorf("Expected tag %s to be active", tag.Tag)
+				}
 			}
-		}
-	})
+		})
 
-	t.Run("Get history with namespace filter", func(t *testing.T) {
-		history, err := repo.GetImageTagHistory("myapp", "production")
-		if err != nil {
-			t.Fatalf("Failed to get history: %v", err)
-		}
+		t.Run("Get history with namespace filter", func(t *testing.T) {
+			history, err := repo.GetImageTagHistory("myapp", "production")
+			if err != nil {
+				t.Fatalf("Failed to get history: %v", err)
+			}
 
-		if len(history.Tags) != 3 {
-			t.Errorf("Expected 3 tags, got %d", len(history.Tags))
-		}
+			if len(history.Tags) != 3 {
+				t.Errorf("Expected 3 tags, got %d", len(history.Tags))
+			}
 
-		for _, tag := range history.Tags {
-			if tag.Namespace != "production" {
-				t.Errorf("Expected namespace 'production', got '%s'", tag.Namespace)
+			for _, tag := range history.Tags {
+				if tag.Namespace != "production" {
+					t.Errorf("Expected namespace 'production', got '%s'", tag.Namespace)
+				}
 			}
-		}
-	})
+		})
 
-	t.Run("Get history includes deleted tags", func(t *testing.T) {
-		// Delete v1.0, v1.1, v1.2
-		repo.DeleteImageTag("Deployment", "api", "production")
+		t.Run("Get history includes deleted tags", func(t *testing.T) {
+			// Delete v1.0, v1.1, v1.2
+			repo.DeleteImageTag("Deployment", "api", "production", "")
 
-		// Create new version
-		repo.UpsertImageTag("myapp", "gcr.io", "v1.3", "Deployment", "api", "production", "app")
+			// Create new version
+			repo.UpsertImageTag("myapp", "gcr.io", "v1.3", "", "Deployment", "api", "production", "app")
 
-		history, err := repo.GetImageTagHistory("myapp", "")
-		if err != nil {
-			t.Fatalf("Failed to get history: %v", err)
-		}
+			history, err := repo.GetImageTagHistory("myapp", "")
+			if err != nil {
+				t.Fatalf("Failed to get history: %v", err)
+			}
 
-		// Should still see v1.0, v1.1, v1.2 (deleted), v1.3 (active)
-		if len(history.Tags) < 3 {
-			t.Errorf("Expected at least 3 tags including deleted ones, got %d", len(history.Tags))
-		}
-	})
+			// Should still see v1.0, v1.1, v1.2 (deleted), v1.3 (active)
+			if len(history.Tags) < 3 {
+				t.Errorf("Expected at least 3 tags including deleted ones, got %d", len(history.Tags))
+			}
+		})
 
-	t.Run("Non-existent image returns error", func(t *testing.T) {
-		_, err := repo.GetImageTagHistory("nonexistent", "")
-		if err == nil {
-			t.Error("Expected error for non-existent image")
-		}
+		t.Run("Non-existent image returns error", func(t *testing.T) {
+			_, err := repo.GetImageTagHistory("nonexistent", "")
+			if err == nil {
+				t.Error("Expected error for non-existent image")
+			}
+		})
 	})
 }
 
 func TestConcurrentUpserts(t *testing.T) {
-	db, cleanup := setupTestDB(t)
-	defer cleanup()
-
-	repo := NewImageRepository(db)
+	forEachBackend(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewImageRepository(db)
 
-	// Test concurrent upserts to ensure database handles them correctly
-	t.Run("Concurrent upserts of same image", func(t *testing.T) {
-		done := make(chan bool, 10)
-
-		for i := 0; i < 10; i++ {
-			go func(idx int) {
-				err := repo.UpsertImageTag(
-					"concurrent-test",
-					"docker.io",
-					"v1.0",
-					"Deployment",
-					fmt.Sprintf("deployment-%d", idx),
-					"default",
-					"app",
-				)
-				if err != nil {
-					t.Errorf("Failed to upsert image tag: %v", err)
-				}
-				done <- true
-			}(i)
-		}
+		// Test concurrent upserts to ensure database handles them correctly
+		t.Run("Concurrent upserts of same image", func(t *testing.T) {
+			done := make(chan bool, 10)
+
+			for i := 0; i < 10; i++ {
+				go func(idx int) {
+					_, err := repo.UpsertImageTag(
+						"concurrent-test",
+						"docker.io",
+						"v1.0",
+						"",
+						"Deployment",
+						fmt.Sprintf("deployment-%d", idx),
+						"default",
+						"app",
+					)
+					if err != nil {
+						t.Errorf("Failed to upsert image tag: %v", err)
+					}
+					done <- true
+				}(i)
+			}
 
-		// Wait for all goroutines
-		for i := 0; i < 10; i++ {
-			<-done
-		}
+			// Wait for all goroutines
+			for i := 0; i < 10; i++ {
+				<-done
+			}
 
-		// Should have 10 distinct image tags (different resource names)
-		var count int64
-		db.Model(&models.ImageTag{}).
-			Joins("JOIN images ON images.id = image_tags.image_id").
-			Where("images.name = ?", "concurrent-test").
-			Count(&count)
+			// Should have 10 distinct image tags (different resource names)
+			var count int64
+			db.Model(&models.ImageTag{}).
+				Joins("JOIN images ON images.id = image_tags.image_id").
+				Where("images.name = ?", "concurrent-test").
+				Count(&count)
 
-		if count != 10 {
-			t.Errorf("Expected 10 image tags, got %d", count)
-		}
+			if count != 10 {
+				t.Errorf("Expected 10 image tags, got %d", count)
+			}
+		})
 	})
 }
 
@@ -503,7 +556,7 @@ func TestPostgreSQLSpecificFeatures(t *testing.T) {
 
 	t.Run("Upsert with conflict resolution", func(t *testing.T) {
 		// Create initial tag
-		err := repo.UpsertImageTag("postgres-test", "docker.io", "v1.0", "Deployment", "test", "default", "app")
+		_, err := repo.UpsertImageTag("postgres-test", "docker.io", "v1.0", "", "Deployment", "test", "default", "app")
 		if err != nil {
 			t.Fatalf("Failed to create initial tag: %v", err)
 		}
@@ -518,7 +571,7 @@ func TestPostgreSQLSpecificFeatures(t *testing.T) {
 		time.Sleep(100 * time.Millisecond)
 
 		// Upsert again - should update LastSeen
-		err = repo.UpsertImageTag("postgres-test", "docker.io", "v1.0", "Deployment", "test", "default", "app")
+		_, err = repo.UpsertImageTag("postgres-test", "docker.io", "v1.0", "", "Deployment", "test", "default", "app")
 		if err != nil {
 			t.Fatalf("Failed to upsert tag: %v", err)
 		}
@@ -546,7 +599,7 @@ func setupSQLiteDB(t *testing.T) (*gorm.DB, func()) {
 	}
 
 	// Run migrations
-	err = db.AutoMigrate(&models.Image{}, &models.ImageTag{})
+	err = db.AutoMigrate(&models.Image{}, &models.ImageTag{}, &models.RunningImage{}, &models.ImageEvent{}, &models.WebhookDelivery{}, &models.ImageUpdate{})
 	if err != nil {
 		t.Fatalf("Failed to run migrations: %v", err)
 	}
@@ -585,7 +638,7 @@ func TestUpsertImageTagUnit(t *testing.T) {
 
 		repo := NewImageRepository(db)
 
-		err := repo.UpsertImageTag("nginx", "docker.io", "latest", "Deployment", "nginx-deploy", "default", "nginx")
+		_, err := repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "nginx-deploy", "default", "nginx")
 		if err != nil {
 			t.Fatalf("Failed to upsert: %v", err)
 		}
@@ -628,7 +681,7 @@ func TestUpsertImageTagUnit(t *testing.T) {
 		repo := NewImageRepository(db)
 
 		// First insert
-		err := repo.UpsertImageTag("redis", "docker.io", "7.0", "Deployment", "redis-deploy", "default", "redis")
+		_, err := repo.UpsertImageTag("redis", "docker.io", "7.0", "", "Deployment", "redis-deploy", "default", "redis")
 		if err != nil {
 			t.Fatalf("Failed to upsert: %v", err)
 		}
@@ -643,7 +696,7 @@ func TestUpsertImageTagUnit(t *testing.T) {
 		time.Sleep(10 * time.Millisecond)
 
 		// Second insert (should update)
-		err = repo.UpsertImageTag("redis", "docker.io", "7.0", "Deployment", "redis-deploy", "default", "redis")
+		_, err = repo.UpsertImageTag("redis", "docker.io", "7.0", "", "Deployment", "redis-deploy", "default", "redis")
 		if err != nil {
 			t.Fatalf("Failed to upsert: %v", err)
 		}
@@ -666,12 +719,12 @@ func TestUpsertImageTagUnit(t *testing.T) {
 		repo := NewImageRepository(db)
 
 		// Insert same image for different containers
-		err := repo.UpsertImageTag("nginx", "docker.io", "latest", "Deployment", "nginx-deploy", "default", "nginx")
+		_, err := repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "nginx-deploy", "default", "nginx")
 		if err != nil {
 			t.Fatalf("Failed to upsert: %v", err)
 		}
 
-		err = repo.UpsertImageTag("nginx", "docker.io", "latest", "Deployment", "nginx-deploy", "default", "sidecar")
+		_, err = repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "nginx-deploy", "default", "sidecar")
 		if err != nil {
 			t.Fatalf("Failed to upsert: %v", err)
 		}
@@ -685,77 +738,145 @@ func TestUpsertImageTagUnit(t *testing.T) {
 	})
 }
 
-func TestDeleteImageTagUnit(t *testing.T) {
-	t.Run("successfully deletes image tag", func(t *testing.T) {
+func TestUpsertImageTagDigestUnit(t *testing.T) {
+	t.Run("stores digest on the image tag", func(t *testing.T) {
 		db, cleanup := setupSQLiteDB(t)
 		defer cleanup()
 
 		repo := NewImageRepository(db)
 
-		// Insert tag
-		err := repo.UpsertImageTag("nginx", "docker.io", "latest", "Deployment", "nginx-deploy", "default", "nginx")
+		_, err := repo.UpsertImageTag("app", "localhost:5000/team", "v1", "sha256:aaa", "Deployment", "app", "default", "app")
 		if err != nil {
 			t.Fatalf("Failed to upsert: %v", err)
 		}
 
-		// Delete tag
-		err = repo.DeleteImageTag("Deployment", "nginx-deploy", "default")
-		if err != nil {
-			t.Fatalf("Failed to delete: %v", err)
+		var tag models.ImageTag
+		if err := db.Where("tag = ?", "v1").First(&tag).Error; err != nil {
+			t.Fatalf("Failed to find tag: %v", err)
 		}
 
-		// Verify tag is soft deleted
-		var count int64
-		db.Model(&models.ImageTag{}).Where("deleted_at IS NULL").Count(&count)
-		if count != 0 {
-			t.Errorf("Expected 0 active tags, got %d", count)
+		if tag.Digest != "sha256:aaa" {
+			t.Errorf("Expected digest 'sha256:aaa', got '%s'", tag.Digest)
 		}
 	})
 
-	t.Run("deletes multiple tags for same resource", func(t *testing.T) {
+	t.Run("new digest for the same tag creates a new record", func(t *testing.T) {
 		db, cleanup := setupSQLiteDB(t)
 		defer cleanup()
 
 		repo := NewImageRepository(db)
 
-		// Insert multiple tags for same resource
-		repo.UpsertImageTag("nginx", "docker.io", "latest", "Deployment", "nginx-deploy", "default", "nginx")
-		repo.UpsertImageTag("nginx", "docker.io", "latest", "Deployment", "nginx-deploy", "default", "sidecar")
-
-		// Delete all tags for resource
-		err := repo.DeleteImageTag("Deployment", "nginx-deploy", "default")
-		if err != nil {
-			t.Fatalf("Failed to delete: %v", err)
-		}
+		repo.UpsertImageTag("app", "docker.io", "v1", "sha256:aaa", "Deployment", "app", "default", "app")
+		repo.UpsertImageTag("app", "docker.io", "v1", "sha256:aaa", "Deployment", "app", "default", "app")
+		repo.UpsertImageTag("app", "docker.io", "v1", "sha256:bbb", "Deployment", "app", "default", "app")
 
-		// Verify all tags are deleted
 		var count int64
-		db.Model(&models.ImageTag{}).Where("deleted_at IS NULL").Count(&count)
-		if count != 0 {
-			t.Errorf("Expected 0 active tags, got %d", count)
+		db.Model(&models.ImageTag{}).Count(&count)
+		if count != 2 {
+			t.Errorf("Expected 2 tags (one per digest), got %d", count)
 		}
 	})
-}
 
-func TestGetAllImagesUnit(t *testing.T) {
-	t.Run("returns all images", func(t *testing.T) {
+	t.Run("digest is returned by GetAllImages and history", func(t *testing.T) {
 		db, cleanup := setupSQLiteDB(t)
 		defer cleanup()
 
 		repo := NewImageRepository(db)
 
-		// Insert test data
-		repo.UpsertImageTag("nginx", "docker.io", "latest", "Deployment", "nginx-deploy", "default", "nginx")
-		repo.UpsertImageTag("redis", "docker.io", "7.0", "Deployment", "redis-deploy", "default", "redis")
+		repo.UpsertImageTag("nginx", "docker.io", "", "sha256:ccc", "Deployment", "web", "default", "nginx")
 
-		// Get all images
 		images, err := repo.GetAllImages("")
 		if err != nil {
 			t.Fatalf("Failed to get images: %v", err)
 		}
+		if len(images) != 1 {
+			t.Fatalf("Expected 1 image, got %d", len(images))
+		}
+		if images[0].Digest != "sha256:ccc" {
+			t.Errorf("Expected digest 'sha256:ccc', got '%s'", images[0].Digest)
+		}
 
-		if len(images) != 2 {
-			t.Errorf("Expected 2 images, got %d", len(images))
+		history, err := repo.GetImageTagHistory("nginx", "")
+		if err != nil {
+			t.Fatalf("Failed to get history: %v", err)
+		}
+		if len(history.Tags) != 1 || history.Tags[0].Digest != "sha256:ccc" {
+			t.Errorf("Expected history to carry digest 'sha256:ccc', got %+v", history.Tags)
+		}
+	})
+}
+
+func TestDeleteImageTagUnit(t *testing.T) {
+	t.Run("successfully deletes image tag", func(t *testing.T) {
+		db, cleanup := setupSQLiteDB(t)
+		defer cleanup()
+
+		repo := NewImageRepository(db)
+
+		// Insert tag
+		_, err := repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "nginx-deploy", "default", "nginx")
+		if err != nil {
+			t.Fatalf("Failed to upsert: %v", err)
+		}
+
+		// Delete tag
+		err = repo.DeleteImageTag("Deployment", "nginx-deploy", "default", "")
+		if err != nil {
+			t.Fatalf("Failed to delete: %v", err)
+		}
+
+		// Verify tag is soft deleted
+		var count int64
+		db.Model(&models.ImageTag{}).Where("deleted_at IS NULL").Count(&count)
+		if count != 0 {
+			t.Errorf("Expected 0 active tags, got %d", count)
+		}
+	})
+
+	t.Run("deletes multiple tags for same resource", func(t *testing.T) {
+		db, cleanup := setupSQLiteDB(t)
+		defer cleanup()
+
+		repo := NewImageRepository(db)
+
+		// Insert multiple tags for same resource
+		repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "nginx-deploy", "default", "nginx")
+		repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "nginx-deploy", "default", "sidecar")
+
+		// Delete all tags for resource
+		err := repo.DeleteImageTag("Deployment", "nginx-deploy", "default", "")
+		if err != nil {
+			t.Fatalf("Failed to delete: %v", err)
+		}
+
+		// Verify all tags are deleted
+		var count int64
+		db.Model(&models.ImageTag{}).Where("deleted_at IS NULL").Count(&count)
+		if count != 0 {
+			t.Errorf("Expected 0 active tags, got %d", count)
+		}
+	})
+}
+
+func TestGetAllImagesUnit(t *testing.T) {
+	t.Run("returns all images", func(t *testing.T) {
+		db, cleanup := setupSQLiteDB(t)
+		defer cleanup()
+
+		repo := NewImageRepository(db)
+
+		// Insert test data
+		repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "nginx-deploy", "default", "nginx")
+		repo.UpsertImageTag("redis", "docker.io", "7.0", "", "Deployment", "redis-deploy", "default", "redis")
+
+		// Get all images
+		images, err := repo.GetAllImages("")
+		if err != nil {
+			t.Fatalf("Failed to get images: %v", err)
+		}
+
+		if len(images) != 2 {
+			t.Errorf("Expected 2 images, got %d", len(images))
 		}
 	})
 
@@ -766,8 +887,8 @@ func TestGetAllImagesUnit(t *testing.T) {
 		repo := NewImageRepository(db)
 
 		// Insert test data in different namespaces
-		repo.UpsertImageTag("nginx", "docker.io", "latest", "Deployment", "nginx-deploy", "default", "nginx")
-		repo.UpsertImageTag("redis", "docker.io", "7.0", "Deployment", "redis-deploy", "production", "redis")
+		repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "nginx-deploy", "default", "nginx")
+		repo.UpsertImageTag("redis", "docker.io", "7.0", "", "Deployment", "redis-deploy", "production", "redis")
 
 		// Get images for specific namespace
 		images, err := repo.GetAllImages("default")
@@ -784,18 +905,18 @@ func TestGetAllImagesUnit(t *testing.T) {
 		}
 	})
 
-	t.Run("returns latest tag per resource", func(t *testing.T) {
+	t.Run("returns the active tag per resource", func(t *testing.T) {
 		db, cleanup := setupSQLiteDB(t)
 		defer cleanup()
 
 		repo := NewImageRepository(db)
 
-		// Insert same image with different tags for same resource
-		repo.UpsertImageTag("nginx", "docker.io", "1.20", "Deployment", "nginx-deploy", "default", "nginx")
+		// The container moves from 1.20 to 1.21
+		repo.UpsertImageTag("nginx", "docker.io", "1.20", "", "Deployment", "nginx-deploy", "default", "nginx")
 		time.Sleep(10 * time.Millisecond)
-		repo.UpsertImageTag("nginx", "docker.io", "1.21", "Deployment", "nginx-deploy", "default", "nginx")
+		repo.ReplaceImageTag("nginx", "docker.io", "1.21", "", "Deployment", "nginx-deploy", "default", "nginx")
 
-		// Get all images - should return only the latest tag
+		// Get all images - should return only the active tag
 		images, err := repo.GetAllImages("")
 		if err != nil {
 			t.Fatalf("Failed to get images: %v", err)
@@ -811,6 +932,27 @@ func TestGetAllImagesUnit(t *testing.T) {
 		}
 	})
 
+	t.Run("keeps every container of an image written at different times", func(t *testing.T) {
+		db, cleanup := setupSQLiteDB(t)
+		defer cleanup()
+
+		repo := NewImageRepository(db)
+
+		repo.UpsertImageTag("nginx", "docker.io", "1.25", "", "Deployment", "web", "default", "nginx")
+		time.Sleep(10 * time.Millisecond)
+		repo.UpsertImageTag("nginx", "docker.io", "1.25", "", "Deployment", "web", "default", "sidecar")
+		time.Sleep(10 * time.Millisecond)
+		repo.UpsertImageTag("nginx", "docker.io", "1.25", "", "Deployment", "web", "default", "sidecar")
+
+		images, err := repo.GetAllImages("")
+		if err != nil {
+			t.Fatalf("Failed to get images: %v", err)
+		}
+		if len(images) != 1 || !reflect.DeepEqual(images[0].Containers, []string{"nginx", "sidecar"}) {
+			t.Errorf("Expected nginx:1.25 with both containers, got %+v", images)
+		}
+	})
+
 	t.Run("handles multiple resources with same image", func(t *testing.T) {
 		db, cleanup := setupSQLiteDB(t)
 		defer cleanup()
@@ -818,8 +960,8 @@ func TestGetAllImagesUnit(t *testing.T) {
 		repo := NewImageRepository(db)
 
 		// Insert same image in different resources
-		repo.UpsertImageTag("nginx", "docker.io", "latest", "Deployment", "nginx-deploy-1", "default", "nginx")
-		repo.UpsertImageTag("nginx", "docker.io", "latest", "Deployment", "nginx-deploy-2", "default", "nginx")
+		repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "nginx-deploy-1", "default", "nginx")
+		repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "nginx-deploy-2", "default", "nginx")
 
 		// Get all images - should return 2 separate entries (one per resource)
 		images, err := repo.GetAllImages("")
@@ -857,9 +999,9 @@ func TestGetImageTagHistoryUnit(t *testing.T) {
 		repo := NewImageRepository(db)
 
 		// Insert multiple tags
-		repo.UpsertImageTag("nginx", "docker.io", "1.20", "Deployment", "nginx-v1", "default", "nginx")
+		repo.UpsertImageTag("nginx", "docker.io", "1.20", "", "Deployment", "nginx-v1", "default", "nginx")
 		time.Sleep(10 * time.Millisecond)
-		repo.UpsertImageTag("nginx", "docker.io", "1.21", "Deployment", "nginx-v2", "default", "nginx")
+		repo.UpsertImageTag("nginx", "docker.io", "1.21", "", "Deployment", "nginx-v2", "default", "nginx")
 
 		// Get history
 		history, err := repo.GetImageTagHistory("nginx", "")
@@ -883,8 +1025,8 @@ func TestGetImageTagHistoryUnit(t *testing.T) {
 		repo := NewImageRepository(db)
 
 		// Insert tags in different namespaces
-		repo.UpsertImageTag("nginx", "docker.io", "latest", "Deployment", "nginx-deploy", "default", "nginx")
-		repo.UpsertImageTag("nginx", "docker.io", "latest", "Deployment", "nginx-deploy", "production", "nginx")
+		repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "nginx-deploy", "default", "nginx")
+		repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "nginx-deploy", "production", "nginx")
 
 		// Get history for specific namespace
 		history, err := repo.GetImageTagHistory("nginx", "default")
@@ -908,11 +1050,11 @@ func TestGetImageTagHistoryUnit(t *testing.T) {
 		repo := NewImageRepository(db)
 
 		// Insert and then delete a tag
-		repo.UpsertImageTag("nginx", "docker.io", "old", "Deployment", "nginx-old", "default", "nginx")
-		repo.DeleteImageTag("Deployment", "nginx-old", "default")
+		repo.UpsertImageTag("nginx", "docker.io", "old", "", "Deployment", "nginx-old", "default", "nginx")
+		repo.DeleteImageTag("Deployment", "nginx-old", "default", "")
 
 		// Insert an active tag
-		repo.UpsertImageTag("nginx", "docker.io", "latest", "Deployment", "nginx-new", "default", "nginx")
+		repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "nginx-new", "default", "nginx")
 
 		// Get history
 		history, err := repo.GetImageTagHistory("nginx", "")
@@ -952,3 +1094,1412 @@ func TestGetImageTagHistoryUnit(t *testing.T) {
 		}
 	})
 }
+
+func TestRunningImagesUnit(t *testing.T) {
+	t.Run("attaches running digests to the declared tag", func(t *testing.T) {
+		db, cleanup := setupSQLiteDB(t)
+		defer cleanup()
+
+		repo := NewImageRepository(db)
+
+		repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "web", "default", "nginx")
+		repo.UpsertRunningImage("nginx", "docker.io", "latest", "Deployment", "web", "default", "nginx", "web-abc-1", "sha256:aaa")
+		repo.UpsertRunningImage("nginx", "docker.io", "latest", "Deployment", "web", "default", "nginx", "web-abc-2", "sha256:aaa")
+
+		images, err := repo.GetAllImages("")
+		if err != nil {
+			t.Fatalf("Failed to get images: %v", err)
+		}
+		if len(images) != 1 {
+			t.Fatalf("Expected 1 image, got %d", len(images))
+		}
+		if len(images[0].RunningDigests) != 1 || images[0].RunningDigests[0] != "sha256:aaa" {
+			t.Errorf("Expected running digests [sha256:aaa], got %v", images[0].RunningDigests)
+		}
+		if images[0].DigestMismatch {
+			t.Error("Expected no digest mismatch when all replicas run the same digest")
+		}
+	})
+
+	t.Run("flags replicas running different digests", func(t *testing.T) {
+		db, cleanup := setupSQLiteDB(t)
+		defer cleanup()
+
+		repo := NewImageRepository(db)
+
+		repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "web", "default", "nginx")
+		repo.UpsertRunningImage("nginx", "docker.io", "latest", "Deployment", "web", "default", "nginx", "web-abc-1", "sha256:aaa")
+		repo.UpsertRunningImage("nginx", "docker.io", "latest", "Deployment", "web", "default", "nginx", "web-abc-2", "sha256:bbb")
+
+		images, err := repo.GetAllImages("default")
+		if err != nil {
+			t.Fatalf("Failed to get images: %v", err)
+		}
+		if len(images[0].RunningDigests) != 2 {
+			t.Errorf("Expected 2 running digests, got %v", images[0].RunningDigests)
+		}
+		if !images[0].DigestMismatch {
+			t.Error("Expected digest mismatch to be flagged")
+		}
+
+		history, err := repo.GetImageTagHistory("nginx", "")
+		if err != nil {
+			t.Fatalf("Failed to get history: %v", err)
+		}
+		if len(history.Tags[0].RunningDigests) != 2 {
+			t.Errorf("Expected history to show 2 running digests, got %v", history.Tags[0].RunningDigests)
+		}
+	})
+
+	t.Run("latest report for a pod container wins", func(t *testing.T) {
+		db, cleanup := setupSQLiteDB(t)
+		defer cleanup()
+
+		repo := NewImageRepository(db)
+
+		repo.UpsertRunningImage("nginx", "docker.io", "latest", "Pod", "debug", "default", "nginx", "debug", "sha256:aaa")
+		repo.UpsertRunningImage("nginx", "docker.io", "latest", "Pod", "debug", "default", "nginx", "debug", "sha256:bbb")
+
+		var runningImages []models.RunningImage
+		db.Find(&runningImages)
+		if len(runningImages) != 1 {
+			t.Fatalf("Expected 1 running image, got %d", len(runningImages))
+		}
+		if runningImages[0].Digest != "sha256:bbb" {
+			t.Errorf("Expected digest 'sha256:bbb', got '%s'", runningImages[0].Digest)
+		}
+	})
+
+	t.Run("deleting a pod forgets its digests", func(t *testing.T) {
+		db, cleanup := setupSQLiteDB(t)
+		defer cleanup()
+
+		repo := NewImageRepository(db)
+
+		repo.UpsertImageTag("nginx", "docker.io", "latest", "", "Deployment", "web", "default", "nginx")
+		repo.UpsertRunningImage("nginx", "docker.io", "latest", "Deployment", "web", "default", "nginx", "web-abc-1", "sha256:aaa")
+		repo.UpsertRunningImage("nginx", "docker.io", "latest", "Deployment", "web", "default", "nginx", "web-abc-2", "sha256:bbb")
+
+		if err := repo.DeleteRunningImages("default", "web-abc-1"); err != nil {
+			t.Fatalf("Failed to delete running images: %v", err)
+		}
+
+		images, err := repo.GetAllImages("")
+		if err != nil {
+			t.Fatalf("Failed to get images: %v", err)
+		}
+		if len(images[0].RunningDigests) != 1 || images[0].RunningDigests[0] != "sha256:bbb" {
+			t.Errorf("Expected running digests [sha256:bbb], got %v", images[0].RunningDigests)
+		}
+		if images[0].DigestMismatch {
+			t.Error("Expected mismatch to clear once the stale replica is gone")
+		}
+	})
+}
+
+func TestReconcileQueriesUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repo := NewImageRepository(db)
+
+	repo.UpsertImageTag("nginx", "docker.io", "1.25", "", "Deployment", "web", "default", "nginx")
+	repo.UpsertImageTag("redis", "docker.io", "7", "", "Deployment", "cache", "default", "redis")
+	repo.UpsertRunningImage("nginx", "docker.io", "1.25", "Deployment", "web", "default", "nginx", "web-abc-1", "sha256:aaa")
+
+	imageTags, err := repo.ListActiveImageTags()
+	if err != nil {
+		t.Fatalf("Failed to list active image tags: %v", err)
+	}
+	if len(imageTags) != 2 {
+		t.Fatalf("Expected 2 active image tags, got %d", len(imageTags))
+	}
+
+	var staleID uint
+	for _, it := range imageTags {
+		if it.Image.Name == "" {
+			t.Error("Expected image to be preloaded")
+		}
+		if it.Image.Name == "redis" {
+			staleID = it.ID
+		}
+	}
+
+	if err := repo.DeleteImageTagsByID([]uint{staleID}); err != nil {
+		t.Fatalf("Failed to delete image tags: %v", err)
+	}
+	if err := repo.DeleteImageTagsByID(nil); err != nil {
+		t.Errorf("Expected no error for empty id list, got %v", err)
+	}
+
+	imageTags, _ = repo.ListActiveImageTags()
+	if len(imageTags) != 1 || imageTags[0].Image.Name != "nginx" {
+		t.Errorf("Expected only nginx to remain active, got %v", imageTags)
+	}
+
+	// Soft deleted rows stay in the history
+	history, err := repo.GetImageTagHistory("redis", "")
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history.Tags) != 1 || history.Tags[0].Active {
+		t.Errorf("Expected 1 inactive redis tag in history, got %v", history.Tags)
+	}
+
+	runningImages, err := repo.ListRunningImages()
+	if err != nil {
+		t.Fatalf("Failed to list running images: %v", err)
+	}
+	if len(runningImages) != 1 || runningImages[0].PodName != "web-abc-1" {
+		t.Errorf("Expected running image for web-abc-1, got %v", runningImages)
+	}
+}
+
+func TestPruneQueriesUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repos := map[string]ImageRepositoryInterface{
+		"database": NewImageRepository(db),
+		"memory":   NewMemoryImageRepository(),
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			repo.UpsertImageTag("nginx", "docker.io", "1.24", "", "Deployment", "web", "default", "nginx")
+			repo.ReplaceImageTag("nginx", "docker.io", "1.25", "", "Deployment", "web", "default", "nginx")
+			repo.ReplaceImageTag("nginx", "docker.io", "1.26", "", "Deployment", "web", "default", "nginx")
+
+			ids, err := repo.ListPrunableImageTags(models.PruneQuery{ClosedBefore: time.Now().UTC().Add(time.Hour)})
+			if err != nil {
+				t.Fatalf("Failed to list prunable image tags: %v", err)
+			}
+			if len(ids) != 2 {
+				t.Fatalf("Expected 2 prunable image tags, got %v", ids)
+			}
+
+			// 1.24 comes back before the purge runs and must survive it
+			repo.ReplaceImageTag("nginx", "docker.io", "1.24", "", "Deployment", "web", "default", "nginx")
+
+			if err := repo.PurgeImageTags(ids); err != nil {
+				t.Fatalf("Failed to purge image tags: %v", err)
+			}
+
+			history, err := repo.GetImageTagHistory("nginx", "")
+			if err != nil {
+				t.Fatalf("Failed to get history: %v", err)
+			}
+
+			tags := make(map[string]bool)
+			for _, tag := range history.Tags {
+				tags[tag.Tag] = tag.Active
+			}
+			if len(tags) != 2 || !tags["1.24"] {
+				t.Errorf("Expected active 1.24 and inactive 1.26 to remain, got %v", tags)
+			}
+			if _, found := tags["1.25"]; found {
+				t.Error("Expected 1.25 to be purged")
+			}
+
+			// A purged row can be recorded again from scratch
+			if _, err := repo.UpsertImageTag("nginx", "docker.io", "1.25", "", "Deployment", "web", "default", "nginx"); err != nil {
+				t.Errorf("Failed to upsert purged tag again: %v", err)
+			}
+		})
+	}
+}
+
+func TestListPrunableImageTagsUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	now := time.Now().UTC()
+	day := 24 * time.Hour
+
+	// closedTag builds a closed out row of a resource in default
+	closedTag := func(id, imageID uint, resourceName string, removedAt time.Time) models.ImageTag {
+		return models.ImageTag{
+			ID: id, ImageID: imageID, Tag: fmt.Sprint(id), ResourceType: "Deployment", ResourceName: resourceName,
+			Namespace: "default", ContainerName: "app", FirstSeen: removedAt.Add(-time.Hour), LastSeen: removedAt,
+			DeletedAt: gorm.DeletedAt{Time: removedAt, Valid: true}, RemovedAt: &removedAt,
+		}
+	}
+
+	// Four generations of web, newest last, an old row of another resource and one of another image
+	rows := []models.ImageTag{
+		closedTag(1, 1, "web", now.Add(-40*day)),
+		closedTag(2, 1, "web", now.Add(-20*day)),
+		closedTag(3, 1, "web", now.Add(-10*day)),
+		closedTag(4, 1, "web", now.Add(-1*day)),
+		closedTag(5, 1, "api", now.Add(-50*day)),
+		closedTag(6, 2, "web", now.Add(-50*day)),
+	}
+
+	images := []models.Image{
+		{ID: 1, Name: "nginx", Repository: "docker.io/library", FullName: "docker.io/library/nginx"},
+		{ID: 2, Name: "redis", Repository: "docker.io/library", FullName: "docker.io/library/redis"},
+	}
+	if err := db.Create(&images).Error; err != nil {
+		t.Fatalf("Failed to create images: %v", err)
+	}
+	if err := db.Create(&rows).Error; err != nil {
+		t.Fatalf("Failed to create image tags: %v", err)
+	}
+
+	memory := NewMemoryImageRepository()
+	memory.imageTags = append([]models.ImageTag(nil), rows...)
+	memory.indexImageTags()
+
+	repos := map[string]ImageRepositoryInterface{
+		"database": NewImageRepository(db),
+		"memory":   memory,
+	}
+
+	tests := []struct {
+		name     string
+		query    models.PruneQuery
+		expected []uint
+	}{
+		{
+			name:     "no limits keeps everything",
+			query:    models.PruneQuery{KeepLast: 1},
+			expected: nil,
+		},
+		{
+			name:     "max age prunes old rows",
+			query:    models.PruneQuery{ClosedBefore: now.Add(-15 * day)},
+			expected: []uint{1, 2, 5, 6},
+		},
+		{
+			name:     "keep last protects the newest rows of each resource from max age",
+			query:    models.PruneQuery{ClosedBefore: now.Add(-15 * day), KeepLast: 1},
+			expected: []uint{1, 2},
+		},
+		{
+			name:     "max inactive caps the rows per image and resource",
+			query:    models.PruneQuery{MaxInactive: 2},
+			expected: []uint{1, 2},
+		},
+		{
+			name:     "max inactive and max age combine",
+			query:    models.PruneQuery{ClosedBefore: now.Add(-5 * day), MaxInactive: 3, KeepLast: 2},
+			expected: []uint{1, 2},
+		},
+		{
+			name:     "pages by ID",
+			query:    models.PruneQuery{ClosedBefore: now.Add(-15 * day), AfterID: 1, Limit: 2},
+			expected: []uint{2, 5},
+		},
+	}
+
+	for name, repo := range repos {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				ids, err := repo.ListPrunableImageTags(tt.query)
+				if err != nil {
+					t.Fatalf("Failed to list prunable image tags: %v", err)
+				}
+				if len(ids) != len(tt.expected) || (len(ids) > 0 && !reflect.DeepEqual(ids, tt.expected)) {
+					t.Errorf("Expected %v, got %v", tt.expected, ids)
+				}
+			})
+		}
+	}
+}
+
+func TestPruneEventLogUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repos := map[string]ImageRepositoryInterface{
+		"database": NewImageRepository(db),
+		"memory":   NewMemoryImageRepository(),
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now().UTC()
+			events := []models.ImageEvent{
+				{Type: "ADD", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", ContainerName: "nginx", ObservedAt: now.Add(-48 * time.Hour)},
+				{Type: "ADD", ResourceType: "Deployment", ResourceName: "api", Namespace: "default", ContainerName: "api", ObservedAt: now.Add(-time.Minute)},
+			}
+			if err := repo.AppendImageEvents(events); err != nil {
+				t.Fatalf("Failed to append image events: %v", err)
+			}
+
+			old := models.WebhookDelivery{Target: "audit", URL: "https://audit.example.com", EventID: 1, EventType: "ADD", Attempt: 1, Success: true}
+			if err := repo.AppendWebhookDelivery(&old); err != nil {
+				t.Fatalf("Failed to append webhook delivery: %v", err)
+			}
+			time.Sleep(5 * time.Millisecond)
+			cutoff := time.Now().UTC()
+			recent := models.WebhookDelivery{Target: "audit", URL: "https://audit.example.com", EventID: 2, EventType: "ADD", Attempt: 1, Success: true}
+			if err := repo.AppendWebhookDelivery(&recent); err != nil {
+				t.Fatalf("Failed to append webhook delivery: %v", err)
+			}
+
+			// A dry run only counts
+			pruned, err := repo.PurgeImageEvents(now.Add(-time.Hour), true)
+			if err != nil || pruned != 1 {
+				t.Fatalf("Expected 1 event in dry run, got %d and %v", pruned, err)
+			}
+			pruned, err = repo.PurgeWebhookDeliveries(cutoff, true)
+			if err != nil || pruned != 1 {
+				t.Fatalf("Expected 1 delivery in dry run, got %d and %v", pruned, err)
+			}
+			if remaining, _ := repo.ListImageEvents(models.ImageEventFilter{}); len(remaining) != 2 {
+				t.Errorf("Expected a dry run to keep every event, got %d", len(remaining))
+			}
+
+			pruned, err = repo.PurgeImageEvents(now.Add(-time.Hour), false)
+			if err != nil || pruned != 1 {
+				t.Fatalf("Expected 1 event pruned, got %d and %v", pruned, err)
+			}
+			pruned, err = repo.PurgeWebhookDeliveries(cutoff, false)
+			if err != nil || pruned != 1 {
+				t.Fatalf("Expected 1 delivery pruned, got %d and %v", pruned, err)
+			}
+
+			remaining, err := repo.ListImageEvents(models.ImageEventFilter{})
+			if err != nil {
+				t.Fatalf("Failed to list image events: %v", err)
+			}
+			if len(remaining) != 1 || remaining[0].ResourceName != "api" {
+				t.Errorf("Expected only the recent api event to remain, got %+v", remaining)
+			}
+
+			deliveries, err := repo.ListWebhookDeliveries(models.WebhookDeliveryFilter{})
+			if err != nil {
+				t.Fatalf("Failed to list webhook deliveries: %v", err)
+			}
+			if len(deliveries) != 1 || deliveries[0].ID != recent.ID {
+				t.Errorf("Expected only delivery %d to remain, got %+v", recent.ID, deliveries)
+			}
+
+			// IDs keep counting after a purge
+			next := models.WebhookDelivery{Target: "audit", URL: "https://audit.example.com", EventID: 2, EventType: "ADD", Attempt: 2, Success: true}
+			if err := repo.AppendWebhookDelivery(&next); err != nil {
+				t.Fatalf("Failed to append webhook delivery: %v", err)
+			}
+			if next.ID <= recent.ID {
+				t.Errorf("Expected a new delivery ID after %d, got %d", recent.ID, next.ID)
+			}
+		})
+	}
+}
+
+func TestQueryImagesUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repos := map[string]ImageRepositoryInterface{
+		"database": NewImageRepository(db),
+		"memory":   NewMemoryImageRepository(),
+	}
+
+	tests := []struct {
+		name     string
+		query    models.ImageQuery
+		expected []string
+		total    int
+	}{
+		{name: "sorted by name", query: models.ImageQuery{}, expected: []string{"my_app@api", "myxapp@job", "nginx@web", "redis@cache", "redis@queue"}, total: 5},
+		{name: "sorted by first seen descending", query: models.ImageQuery{Sort: models.ImageSortFirstSeen, Descending: true}, expected: []string{"myxapp@job", "my_app@api", "redis@queue", "redis@cache", "nginx@web"}, total: 5},
+		{name: "sorted by tag", query: models.ImageQuery{Sort: models.ImageSortTag}, expected: []string{"my_app@api", "nginx@web", "myxapp@job", "redis@queue", "redis@cache"}, total: 5},
+		{name: "sorted by namespace", query: models.ImageQuery{Sort: models.ImageSortNamespace}, expected: []string{"my_app@api", "redis@queue", "myxapp@job", "nginx@web", "redis@cache"}, total: 5},
+		{name: "name substring ignores case", query: models.ImageQuery{Name: "REDIS"}, expected: []string{"redis@cache", "redis@queue"}, total: 2},
+		{name: "name substring", query: models.ImageQuery{Name: "app"}, expected: []string{"my_app@api", "myxapp@job"}, total: 2},
+		{name: "name prefix escapes wildcards", query: models.ImageQuery{NamePrefix: "my_"}, expected: []string{"my_app@api"}, total: 1},
+		{name: "repository", query: models.ImageQuery{Repository: "ghcr.io/bitnami"}, expected: []string{"redis@queue"}, total: 1},
+		{name: "tag", query: models.ImageQuery{Tag: "7.2"}, expected: []string{"redis@cache"}, total: 1},
+		{name: "resource type", query: models.ImageQuery{ResourceType: "CronJob"}, expected: []string{"myxapp@job"}, total: 1},
+		{name: "container", query: models.ImageQuery{Container: "proxy"}, expected: []string{"nginx@web"}, total: 1},
+		{name: "namespace", query: models.ImageQuery{Namespace: "data"}, expected: []string{"my_app@api", "redis@queue"}, total: 2},
+		{name: "page", query: models.ImageQuery{Limit: 2, Offset: 1}, expected: []string{"myxapp@job", "nginx@web"}, total: 5},
+		{name: "filtered page", query: models.ImageQuery{Name: "redis", Limit: 1, Offset: 1}, expected: []string{"redis@queue"}, total: 2},
+		{name: "offset past the end", query: models.ImageQuery{Limit: 2, Offset: 10}, expected: nil, total: 5},
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			repo.UpsertImageTags([]models.ImageTagUpsert{
+				{ImageName: "nginx", Repository: "docker.io", Tag: "1.25", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", ContainerName: "nginx"},
+				{ImageName: "nginx", Repository: "docker.io", Tag: "1.25", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", ContainerName: "proxy"},
+			})
+			for _, upsert := range []models.ImageTagUpsert{
+				{ImageName: "redis", Repository: "docker.io", Tag: "7.2", ResourceType: "Deployment", ResourceName: "cache", Namespace: "default", ContainerName: "redis"},
+				{ImageName: "redis", Repository: "ghcr.io/bitnami", Tag: "7.0", ResourceType: "StatefulSet", ResourceName: "queue", Namespace: "data", ContainerName: "redis"},
+				{ImageName: "my_app", Repository: "registry.local/team", Tag: "1.0", ResourceType: "Deployment", ResourceName: "api", Namespace: "data", ContainerName: "app"},
+				{ImageName: "myxapp", Repository: "docker.io", Tag: "2.0", ResourceType: "CronJob", ResourceName: "job", Namespace: "default", ContainerName: "worker"},
+			} {
+				time.Sleep(5 * time.Millisecond)
+				repo.UpsertImageTags([]models.ImageTagUpsert{upsert})
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					images, total, err := repo.QueryImages(tt.query)
+					if err != nil {
+						t.Fatalf("Failed to query images: %v", err)
+					}
+
+					var refs []string
+					for _, img := range images {
+						refs = append(refs, img.Name+"@"+img.ResourceName)
+						if img.Name == "nginx" && len(img.Containers) != 2 {
+							t.Errorf("Expected nginx to list both containers, got %v", img.Containers)
+						}
+					}
+
+					if !reflect.DeepEqual(refs, tt.expected) {
+						t.Errorf("Expected images %v, got %v", tt.expected, refs)
+					}
+					if total != tt.total {
+						t.Errorf("Expected total %d, got %d", tt.total, total)
+					}
+				})
+			}
+
+			if _, _, err := repo.QueryImages(models.ImageQuery{Sort: "size"}); err == nil {
+				t.Error("Expected error for an unsupported sort key, got nil")
+			}
+		})
+	}
+}
+
+func TestImageReferencesUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repos := map[string]ImageRepositoryInterface{
+		"database": NewImageRepository(db),
+		"memory":   NewMemoryImageRepository(),
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			repo.UpsertImageTag("redis", "docker.io", "7.2", "", "Deployment", "cache", "default", "redis")
+			repo.UpsertImageTag("redis", "ghcr.io/bitnami", "7.0", "", "Deployment", "cache", "default", "replica")
+			repo.UpsertImageTag("redis", "ghcr.io/bitnami", "6.2", "", "StatefulSet", "queue", "default", "redis")
+
+			t.Run("bare name matches every repository", func(t *testing.T) {
+				history, err := repo.GetImageTagHistory("redis", "")
+				if err != nil {
+					t.Fatalf("Failed to get history: %v", err)
+				}
+
+				if !reflect.DeepEqual(history.Repositories, []string{"docker.io", "ghcr.io/bitnami"}) {
+					t.Errorf("Expected both repositories, got %v", history.Repositories)
+				}
+				if len(history.Tags) != 3 {
+					t.Fatalf("Expected 3 tags, got %d", len(history.Tags))
+				}
+
+				repositories := make(map[string]string)
+				for _, tag := range history.Tags {
+					repositories[tag.Tag] = tag.Repository
+					if !tag.Active {
+						t.Errorf("Expected tag %s to be active", tag.Tag)
+					}
+				}
+				expected := map[string]string{"7.2": "docker.io", "7.0": "ghcr.io/bitnami", "6.2": "ghcr.io/bitnami"}
+				if !reflect.DeepEqual(repositories, expected) {
+					t.Errorf("Expected tag repositories %v, got %v", expected, repositories)
+				}
+			})
+
+			t.Run("full reference matches one repository", func(t *testing.T) {
+				history, err := repo.GetImageTagHistory("ghcr.io/bitnami/redis", "")
+				if err != nil {
+					t.Fatalf("Failed to get history: %v", err)
+				}
+
+				if history.ImageName != "redis" || !reflect.DeepEqual(history.Repositories, []string{"ghcr.io/bitnami"}) {
+					t.Errorf("Expected redis from ghcr.io/bitnami, got %s from %v", history.ImageName, history.Repositories)
+				}
+				if len(history.Tags) != 2 {
+					t.Errorf("Expected 2 tags, got %d", len(history.Tags))
+				}
+			})
+
+			t.Run("unknown reference", func(t *testing.T) {
+				if _, err := repo.GetImageTagHistory("quay.io/redis", ""); err == nil {
+					t.Error("Expected error for an unknown reference, got nil")
+				}
+			})
+
+			t.Run("images include their repository", func(t *testing.T) {
+				images, err := repo.GetAllImages("default")
+				if err != nil {
+					t.Fatalf("Failed to get images: %v", err)
+				}
+
+				var refs []string
+				for _, img := range images {
+					refs = append(refs, img.Repository+"/"+img.Name+":"+img.Tag+" "+img.ResourceName)
+				}
+				sort.Strings(refs)
+
+				expected := []string{
+					"docker.io/redis:7.2 cache",
+					"ghcr.io/bitnami/redis:6.2 queue",
+					"ghcr.io/bitnami/redis:7.0 cache",
+				}
+				if !reflect.DeepEqual(refs, expected) {
+					t.Errorf("Expected images %v, got %v", expected, refs)
+				}
+			})
+		})
+	}
+}
+
+func TestGetImagesAtUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repos := map[string]ImageRepositoryInterface{
+		"database": NewImageRepository(db),
+		"memory":   NewMemoryImageRepository(),
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			// mark returns an instant strictly between two repository writes
+			mark := func() time.Time {
+				time.Sleep(5 * time.Millisecond)
+				at := time.Now()
+				time.Sleep(5 * time.Millisecond)
+				return at
+			}
+
+			beforeAll := mark()
+			repo.UpsertImageTag("nginx", "docker.io", "1.24", "", "Deployment", "web", "default", "nginx")
+			repo.UpsertImageTag("postgres", "docker.io", "16", "", "StatefulSet", "db", "data", "postgres")
+			firstRelease := mark()
+			repo.ReplaceImageTag("nginx", "docker.io", "1.25", "", "Deployment", "web", "default", "nginx")
+			secondRelease := mark()
+			repo.ReplaceImageTag("nginx", "docker.io", "1.24", "", "Deployment", "web", "default", "nginx")
+			rolledBack := mark()
+			repo.ReplaceImageTag("nginx", "docker.io", "1.25", "", "Deployment", "web", "default", "nginx")
+			upgradedAgain := mark()
+			repo.DeleteImageTag("StatefulSet", "db", "data", "")
+			dbDeleted := mark()
+
+			tests := []struct {
+				name      string
+				namespace string
+				at        time.Time
+				expected  []string
+			}{
+				{name: "before anything was deployed", at: beforeAll, expected: nil},
+				{name: "first release", at: firstRelease, expected: []string{"nginx:1.24", "postgres:16"}},
+				{name: "second release", at: secondRelease, expected: []string{"nginx:1.25", "postgres:16"}},
+				{name: "after the rollback", at: rolledBack, expected: []string{"nginx:1.24", "postgres:16"}},
+				{name: "after upgrading again", at: upgradedAgain, expected: []string{"nginx:1.25", "postgres:16"}},
+				{name: "namespace filter", namespace: "data", at: secondRelease, expected: []string{"postgres:16"}},
+				{name: "after the resource was deleted", at: dbDeleted, expected: []string{"nginx:1.25"}},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					images, err := repo.GetImagesAt(tt.namespace, tt.at)
+					if err != nil {
+						t.Fatalf("Failed to get images: %v", err)
+					}
+
+					var refs []string
+					for _, img := range images {
+						refs = append(refs, img.Name+":"+img.Tag)
+						if len(img.RunningDigests) != 0 {
+							t.Errorf("Expected no running digests for %s, got %v", img.Name, img.RunningDigests)
+						}
+					}
+					sort.Strings(refs)
+
+					if !reflect.DeepEqual(refs, tt.expected) {
+						t.Errorf("Expected images %v, got %v", tt.expected, refs)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestGetResourceImageTagsUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repos := map[string]ImageRepositoryInterface{
+		"database": NewImageRepository(db),
+		"memory":   NewMemoryImageRepository(),
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			repo.UpsertImageTag("nginx", "docker.io", "1.24", "", "Deployment", "web", "default", "nginx")
+			time.Sleep(5 * time.Millisecond)
+			repo.ReplaceImageTag("nginx", "docker.io", "1.25", "", "Deployment", "web", "default", "nginx")
+			repo.UpsertImageTag("nginx", "docker.io", "1.25", "", "Deployment", "web", "staging", "nginx")
+			repo.UpsertImageTag("nginx", "docker.io", "1.25", "", "Deployment", "api", "default", "nginx")
+
+			imageTags, err := repo.GetResourceImageTags("Deployment", "web", "default")
+			if err != nil {
+				t.Fatalf("Failed to get resource image tags: %v", err)
+			}
+
+			if len(imageTags) != 2 {
+				t.Fatalf("Expected 2 image tags, got %d", len(imageTags))
+			}
+			if imageTags[0].Tag != "1.24" || imageTags[1].Tag != "1.25" {
+				t.Errorf("Expected 1.24 then 1.25, got %s then %s", imageTags[0].Tag, imageTags[1].Tag)
+			}
+			if imageTags[0].RemovedAt == nil {
+				t.Error("Expected the replaced tag to be included with RemovedAt set")
+			}
+			if imageTags[1].Image.Name != "nginx" {
+				t.Errorf("Expected image to be loaded, got %q", imageTags[1].Image.Name)
+			}
+
+			imageTags, err = repo.GetResourceImageTags("Deployment", "missing", "default")
+			if err != nil {
+				t.Fatalf("Failed to get resource image tags: %v", err)
+			}
+			if len(imageTags) != 0 {
+				t.Errorf("Expected no image tags for an unknown resource, got %d", len(imageTags))
+			}
+		})
+	}
+}
+
+func TestSearchImageTagsUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repos := map[string]ImageRepositoryInterface{
+		"database": NewImageRepository(db),
+		"memory":   NewMemoryImageRepository(),
+	}
+
+	tests := []struct {
+		name     string
+		query    models.SearchQuery
+		expected []string
+	}{
+		{name: "image name ignores case", query: models.SearchQuery{Text: "NGINX"}, expected: []string{"nginx:1.25@api", "nginx:1.25@web", "nginx:1.24@web"}},
+		{name: "every term must match", query: models.SearchQuery{Text: "nginx api"}, expected: []string{"nginx:1.25@api"}},
+		{name: "tag of a replaced version", query: models.SearchQuery{Text: "1.24"}, expected: []string{"nginx:1.24@web"}},
+		{name: "repository", query: models.SearchQuery{Text: "bitnami"}, expected: []string{"redis:7.2@cache"}},
+		{name: "container name", query: models.SearchQuery{Text: "sidecar"}, expected: []string{"nginx:1.25@api"}},
+		{name: "namespace filter", query: models.SearchQuery{Text: "nginx", Namespace: "staging"}, expected: []string{"nginx:1.25@api"}},
+		{name: "wildcards are literal", query: models.SearchQuery{Text: "%"}, expected: nil},
+		{name: "no match", query: models.SearchQuery{Text: "postgres"}, expected: nil},
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			repo.UpsertImageTag("nginx", "docker.io", "1.24", "", "Deployment", "web", "default", "nginx")
+			time.Sleep(5 * time.Millisecond)
+			repo.ReplaceImageTag("nginx", "docker.io", "1.25", "", "Deployment", "web", "default", "nginx")
+			time.Sleep(5 * time.Millisecond)
+			repo.UpsertImageTag("nginx", "docker.io", "1.25", "", "Deployment", "api", "staging", "sidecar")
+			repo.UpsertImageTag("redis", "ghcr.io/bitnami", "7.2", "", "StatefulSet", "cache", "default", "redis")
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					imageTags, err := repo.SearchImageTags(tt.query)
+					if err != nil {
+						t.Fatalf("Failed to search image tags: %v", err)
+					}
+
+					var refs []string
+					for _, it := range imageTags {
+						refs = append(refs, it.Image.Name+":"+it.Tag+"@"+it.ResourceName)
+					}
+
+					if !reflect.DeepEqual(refs, tt.expected) {
+						t.Errorf("Expected image tags %v, got %v", tt.expected, refs)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestSearchImageTagsCandidateLimitUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repos := map[string]ImageRepositoryInterface{
+		"database": NewImageRepository(db),
+		"memory":   NewMemoryImageRepository(),
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			// The exact match was closed out before more than a limit of weak matches were seen
+			repo.UpsertImageTag("web", "docker.io", "1.0", "", "Deployment", "legacy", "default", "web")
+			repo.DeleteImageTag("Deployment", "legacy", "default", "")
+
+			tags := make([]models.ImageTagUpsert, searchCandidateLimit)
+			for i := range tags {
+				tags[i] = models.ImageTagUpsert{
+					ImageName: "app", Repository: "docker.io", Tag: "v1", ResourceType: "Deployment",
+					ResourceName: fmt.Sprintf("webapp-%d", i), Namespace: "default", ContainerName: "app",
+				}
+			}
+			if _, err := repo.UpsertImageTags(tags); err != nil {
+				t.Fatalf("Failed to upsert batch: %v", err)
+			}
+
+			imageTags, err := repo.SearchImageTags(models.SearchQuery{Text: "web"})
+			if err != nil {
+				t.Fatalf("Failed to search image tags: %v", err)
+			}
+
+			found := false
+			for _, it := range imageTags {
+				found = found || it.ResourceName == "legacy"
+			}
+			if !found {
+				t.Errorf("Expected the exact image name match among %d candidates", len(imageTags))
+			}
+		})
+	}
+}
+
+func TestImageEventsQueriesUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repos := map[string]ImageRepositoryInterface{
+		"database": NewImageRepository(db),
+		"memory":   NewMemoryImageRepository(),
+	}
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []models.ImageEvent{
+		{Type: "ADD", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", ContainerName: "nginx", ImageName: "nginx", NewTag: "1.24", ObservedAt: base},
+		{Type: "UPDATE", Change: "CHANGED", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", ContainerName: "nginx", ImageName: "nginx", NewTag: "1.25", OldImageName: "nginx", OldTag: "1.24", ObservedAt: base.Add(time.Minute)},
+		{Type: "ADD", ResourceType: "StatefulSet", ResourceName: "db", Namespace: "data", ContainerName: "postgres", ImageName: "postgres", NewTag: "16", ObservedAt: base.Add(2 * time.Minute)},
+		{Type: "DELETE", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", ContainerName: "nginx", OldImageName: "nginx", OldTag: "1.25", ObservedAt: base.Add(3 * time.Minute)},
+	}
+	since := base.Add(time.Minute)
+	until := base.Add(3 * time.Minute)
+
+	tests := []struct {
+		name     string
+		filter   models.ImageEventFilter
+		expected []string
+	}{
+		{name: "all events newest first", filter: models.ImageEventFilter{}, expected: []string{"DELETE", "ADD", "UPDATE", "ADD"}},
+		{name: "by namespace", filter: models.ImageEventFilter{Namespace: "data"}, expected: []string{"ADD"}},
+		{name: "by image matches old image", filter: models.ImageEventFilter{ImageName: "nginx"}, expected: []string{"DELETE", "UPDATE", "ADD"}},
+		{name: "by image and namespace", filter: models.ImageEventFilter{ImageName: "nginx", Namespace: "data"}, expected: nil},
+		{name: "by resource", filter: models.ImageEventFilter{ResourceType: "StatefulSet", ResourceName: "db"}, expected: []string{"ADD"}},
+		{name: "since inclusive until exclusive", filter: models.ImageEventFilter{Since: &since, Until: &until}, expected: []string{"ADD", "UPDATE"}},
+		{name: "limit", filter: models.ImageEventFilter{Limit: 2}, expected: []string{"DELETE", "ADD"}},
+		{name: "after id", filter: models.ImageEventFilter{AfterID: 2}, expected: []string{"DELETE", "ADD"}},
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			appended := slices.Clone(events)
+			if err := repo.AppendImageEvents(appended); err != nil {
+				t.Fatalf("Failed to append image events: %v", err)
+			}
+			for i, event := range appended {
+				if event.ID != uint(i+1) {
+					t.Errorf("Expected appended event %d to get ID %d, got %d", i, i+1, event.ID)
+				}
+			}
+			if err := repo.AppendImageEvents(nil); err != nil {
+				t.Errorf("Expected appending no events to succeed, got %v", err)
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					result, err := repo.ListImageEvents(tt.filter)
+					if err != nil {
+						t.Fatalf("Failed to list image events: %v", err)
+					}
+
+					var types []string
+					for _, event := range result {
+						if event.ID == 0 {
+							t.Error("Expected event to have an ID")
+						}
+						types = append(types, event.Type)
+					}
+					if !reflect.DeepEqual(types, tt.expected) {
+						t.Errorf("Expected events %v, got %v", tt.expected, types)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestWebhookDeliveriesUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repos := map[string]ImageRepositoryInterface{
+		"database": NewImageRepository(db),
+		"memory":   NewMemoryImageRepository(),
+	}
+
+	deliveries := []models.WebhookDelivery{
+		{Target: "deploy-bot", URL: "https://hooks.example.com", EventID: 1, EventType: "ADD", Attempt: 1, Success: true, StatusCode: 200},
+		{Target: "audit", URL: "https://audit.example.com", EventID: 1, EventType: "ADD", Attempt: 1, StatusCode: 503, Error: "webhook responded with status 503"},
+		{Target: "audit", URL: "https://audit.example.com", EventID: 1, EventType: "ADD", Attempt: 2, Success: true, StatusCode: 204},
+		{Target: "deploy-bot", URL: "https://hooks.example.com", EventID: 2, EventType: "UPDATE", Attempt: 1, Error: "connection refused"},
+	}
+
+	tests := []struct {
+		name     string
+		filter   models.WebhookDeliveryFilter
+		expected []uint
+	}{
+		{name: "all deliveries newest first", filter: models.WebhookDeliveryFilter{}, expected: []uint{4, 3, 2, 1}},
+		{name: "by target", filter: models.WebhookDeliveryFilter{Target: "audit"}, expected: []uint{3, 2}},
+		{name: "by event", filter: models.WebhookDeliveryFilter{EventID: 1}, expected: []uint{3, 2, 1}},
+		{name: "failed only", filter: models.WebhookDeliveryFilter{Failed: true}, expected: []uint{4, 2}},
+		{name: "failed for target", filter: models.WebhookDeliveryFilter{Target: "deploy-bot", Failed: true}, expected: []uint{4}},
+		{name: "limit", filter: models.WebhookDeliveryFilter{Limit: 1}, expected: []uint{4}},
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			for i := range deliveries {
+				delivery := deliveries[i]
+				if err := repo.AppendWebhookDelivery(&delivery); err != nil {
+					t.Fatalf("Failed to append webhook delivery: %v", err)
+				}
+				if delivery.ID != uint(i+1) {
+					t.Errorf("Expected delivery %d to get ID %d, got %d", i, i+1, delivery.ID)
+				}
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					result, err := repo.ListWebhookDeliveries(tt.filter)
+					if err != nil {
+						t.Fatalf("Failed to list webhook deliveries: %v", err)
+					}
+
+					var ids []uint
+					for _, delivery := range result {
+						if delivery.CreatedAt.IsZero() {
+							t.Error("Expected delivery to have a creation time")
+						}
+						ids = append(ids, delivery.ID)
+					}
+					if !reflect.DeepEqual(ids, tt.expected) {
+						t.Errorf("Expected deliveries %v, got %v", tt.expected, ids)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestReplaceImageTagUnit(t *testing.T) {
+	t.Run("closes out the previous tag of the container", func(t *testing.T) {
+		db, cleanup := setupSQLiteDB(t)
+		defer cleanup()
+
+		repo := NewImageRepository(db)
+
+		repo.UpsertImageTag("app", "docker.io", "v1", "", "Deployment", "app", "default", "app")
+		repo.UpsertImageTag("envoy", "docker.io", "1.30", "", "Deployment", "app", "default", "proxy")
+
+		if err := repo.ReplaceImageTag("app", "docker.io", "v2", "", "Deployment", "app", "default", "app"); err != nil {
+			t.Fatalf("Failed to replace: %v", err)
+		}
+
+		var v1, v2 models.ImageTag
+		db.Unscoped().Where("tag = ?", "v1").First(&v1)
+		db.Where("tag = ?", "v2").First(&v2)
+
+		if v1.RemovedAt == nil {
+			t.Fatal("Expected v1 to have a removed_at timestamp")
+		}
+		if !v1.DeletedAt.Valid {
+			t.Error("Expected v1 to be inactive")
+		}
+		if v1.RemovedAt.Before(v1.FirstSeen) || v1.RemovedAt.After(v2.FirstSeen) {
+			t.Errorf("Expected v1 to end when v2 started, got removed_at %v and v2 first_seen %v", v1.RemovedAt, v2.FirstSeen)
+		}
+		if v2.RemovedAt != nil {
+			t.Error("Expected v2 to be active")
+		}
+
+		// Other containers of the same resource are untouched
+		var count int64
+		db.Model(&models.ImageTag{}).Where("container_name = ?", "proxy").Count(&count)
+		if count != 1 {
+			t.Errorf("Expected proxy container to stay active, got %d active rows", count)
+		}
+
+		history, err := repo.GetImageTagHistory("app", "")
+		if err != nil {
+			t.Fatalf("Failed to get history: %v", err)
+		}
+		for _, tag := range history.Tags {
+			if tag.Tag == "v1" && (tag.Active || tag.RemovedAt == nil) {
+				t.Error("Expected v1 to be inactive with a removed_at timestamp in history")
+			}
+			if tag.Tag == "v2" && !tag.Active {
+				t.Error("Expected v2 to be active in history")
+			}
+		}
+	})
+
+	t.Run("replacing with the same tag keeps it active", func(t *testing.T) {
+		db, cleanup := setupSQLiteDB(t)
+		defer cleanup()
+
+		repo := NewImageRepository(db)
+
+		repo.UpsertImageTag("app", "docker.io", "v1", "", "Deployment", "app", "default", "app")
+		if err := repo.ReplaceImageTag("app", "docker.io", "v1", "", "Deployment", "app", "default", "app"); err != nil {
+			t.Fatalf("Failed to replace: %v", err)
+		}
+
+		var count int64
+		db.Model(&models.ImageTag{}).Where("removed_at IS NULL").Count(&count)
+		if count != 1 {
+			t.Errorf("Expected 1 active tag, got %d", count)
+		}
+	})
+
+	t.Run("deleting a resource records when its tags were removed", func(t *testing.T) {
+		db, cleanup := setupSQLiteDB(t)
+		defer cleanup()
+
+		repo := NewImageRepository(db)
+
+		repo.UpsertImageTag("app", "docker.io", "v1", "", "Deployment", "app", "default", "app")
+		if err := repo.DeleteImageTag("Deployment", "app", "default", ""); err != nil {
+			t.Fatalf("Failed to delete: %v", err)
+		}
+
+		var v1 models.ImageTag
+		db.Unscoped().Where("tag = ?", "v1").First(&v1)
+		if v1.RemovedAt == nil || !v1.DeletedAt.Valid {
+			t.Error("Expected deleted tag to be inactive with a removed_at timestamp")
+		}
+	})
+}
+
+func TestImageTagRollbackKeepsIntervalsUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repos := map[string]ImageRepositoryInterface{
+		"database": NewImageRepository(db),
+		"memory":   NewMemoryImageRepository(),
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			// v1 -> v2 -> v1
+			for _, tag := range []string{"v1", "v2", "v1"} {
+				if err := repo.ReplaceImageTag("app", "docker.io", tag, "", "Deployment", "app", "default", "app"); err != nil {
+					t.Fatalf("Failed to replace with %s: %v", tag, err)
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			// Seeing the current tag again refreshes its row instead of starting another one
+			if _, err := repo.UpsertImageTag("app", "docker.io", "v1", "", "Deployment", "app", "default", "app"); err != nil {
+				t.Fatalf("Failed to upsert: %v", err)
+			}
+
+			rows, err := repo.GetResourceImageTags("Deployment", "app", "default")
+			if err != nil {
+				t.Fatalf("Failed to get resource image tags: %v", err)
+			}
+
+			var tags []string
+			for _, row := range rows {
+				tags = append(tags, row.Tag)
+			}
+			if expected := []string{"v1", "v2", "v1"}; !reflect.DeepEqual(tags, expected) {
+				t.Fatalf("Expected one row per interval %v, got %v", expected, tags)
+			}
+
+			first, v2, second := rows[0], rows[1], rows[2]
+			if first.RemovedAt == nil || !first.DeletedAt.Valid {
+				t.Fatal("Expected the first v1 interval to stay closed out")
+			}
+			if first.RemovedAt.After(v2.FirstSeen) {
+				t.Errorf("Expected the first v1 interval to end when v2 started, got %v and %v", first.RemovedAt, v2.FirstSeen)
+			}
+			if v2.RemovedAt == nil || v2.RemovedAt.After(second.FirstSeen) {
+				t.Errorf("Expected v2 to end when v1 came back, got %v and %v", v2.RemovedAt, second.FirstSeen)
+			}
+			if second.RemovedAt != nil || second.DeletedAt.Valid || !second.FirstSeen.After(*first.RemovedAt) {
+				t.Errorf("Expected the second v1 interval to be active and start after the first ended, got %+v", second)
+			}
+
+			active, err := repo.ListActiveImageTags()
+			if err != nil {
+				t.Fatalf("Failed to list active image tags: %v", err)
+			}
+			if len(active) != 1 || active[0].ID != second.ID {
+				t.Errorf("Expected only the second v1 interval to be active, got %+v", active)
+			}
+		})
+	}
+}
+
+func TestDeleteContainerImageTagUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repo := NewImageRepository(db)
+
+	repo.UpsertImageTag("app", "docker.io", "v1", "", "Deployment", "web", "default", "app")
+	repo.UpsertImageTag("fluent-bit", "docker.io", "3.0", "", "Deployment", "web", "default", "sidecar")
+
+	if err := repo.DeleteImageTag("Deployment", "web", "default", "sidecar"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+
+	var active []models.ImageTag
+	db.Find(&active)
+	if len(active) != 1 || active[0].ContainerName != "app" {
+		t.Errorf("Expected only the app container to stay active, got %v", active)
+	}
+
+	var sidecar models.ImageTag
+	db.Unscoped().Where("container_name = ?", "sidecar").First(&sidecar)
+	if sidecar.RemovedAt == nil {
+		t.Error("Expected sidecar to have a removed_at timestamp")
+	}
+}
+
+func TestUpsertImageTagsUnit(t *testing.T) {
+	t.Run("writes every tag in one call", func(t *testing.T) {
+		db, cleanup := setupSQLiteDB(t)
+		defer cleanup()
+
+		repo := NewImageRepository(db)
+
+		// nginx already exists and web-1 is already tracked
+		repo.UpsertImageTag("nginx", "docker.io", "1.25", "", "Deployment", "web-1", "default", "nginx")
+
+		_, err := repo.UpsertImageTags([]models.ImageTagUpsert{
+			{ImageName: "nginx", Repository: "docker.io", Tag: "1.25", ResourceType: "Deployment", ResourceName: "web-1", Namespace: "default", ContainerName: "nginx"},
+			{ImageName: "nginx", Repository: "docker.io", Tag: "1.25", ResourceType: "Deployment", ResourceName: "web-2", Namespace: "default", ContainerName: "nginx"},
+			{ImageName: "nginx", Repository: "docker.io", Tag: "1.25", ResourceType: "Deployment", ResourceName: "web-2", Namespace: "default", ContainerName: "nginx"},
+			{ImageName: "redis", Repository: "docker.io", Tag: "7", ResourceType: "StatefulSet", ResourceName: "cache", Namespace: "default", ContainerName: "redis"},
+			{ImageName: "app", Repository: "ghcr.io/acme", Tag: "v1", Digest: "sha256:abc", ResourceType: "Deployment", ResourceName: "app", Namespace: "prod", ContainerName: "app"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to upsert batch: %v", err)
+		}
+
+		var imageCount, tagCount int64
+		db.Model(&models.Image{}).Count(&imageCount)
+		db.Model(&models.ImageTag{}).Count(&tagCount)
+		if imageCount != 3 {
+			t.Errorf("Expected 3 images, got %d", imageCount)
+		}
+		if tagCount != 4 {
+			t.Errorf("Expected 4 image tags, got %d", tagCount)
+		}
+
+		var app models.ImageTag
+		db.Preload("Image").Where("resource_name = ?", "app").First(&app)
+		if app.Image.FullName != "ghcr.io/acme/app" || app.Digest != "sha256:abc" {
+			t.Errorf("Expected ghcr.io/acme/app pinned to sha256:abc, got %s pinned to %s", app.Image.FullName, app.Digest)
+		}
+	})
+
+	t.Run("closed out tags come back as a new row", func(t *testing.T) {
+		db, cleanup := setupSQLiteDB(t)
+		defer cleanup()
+
+		repo := NewImageRepository(db)
+
+		repo.UpsertImageTag("nginx", "docker.io", "1.25", "", "Deployment", "web", "default", "nginx")
+		repo.DeleteImageTag("Deployment", "web", "default", "")
+
+		_, err := repo.UpsertImageTags([]models.ImageTagUpsert{
+			{ImageName: "nginx", Repository: "docker.io", Tag: "1.25", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", ContainerName: "nginx"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to upsert batch: %v", err)
+		}
+
+		images, _ := repo.GetAllImages("")
+		if len(images) != 1 {
+			t.Errorf("Expected the tag to be active again, got %d images", len(images))
+		}
+
+		var rows int64
+		db.Unscoped().Model(&models.ImageTag{}).Count(&rows)
+		if rows != 2 {
+			t.Errorf("Expected the closed out interval to be kept next to the new one, got %d rows", rows)
+		}
+	})
+
+	t.Run("empty batch is a no-op", func(t *testing.T) {
+		db, cleanup := setupSQLiteDB(t)
+		defer cleanup()
+
+		if _, err := NewImageRepository(db).UpsertImageTags(nil); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+}
+
+func TestUpsertImageTagCreatedUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repos := map[string]ImageRepositoryInterface{
+		"database": NewImageRepository(db),
+		"memory":   NewMemoryImageRepository(),
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			steps := []struct {
+				tag, digest string
+				expected    bool
+			}{
+				{"1.25", "", true},
+				{"1.25", "", false},
+				{"1.25", "sha256:aaa", true},
+			}
+			for _, step := range steps {
+				created, err := repo.UpsertImageTag("nginx", "docker.io", step.tag, step.digest, "Deployment", "web", "default", "nginx")
+				if err != nil {
+					t.Fatalf("Failed to upsert: %v", err)
+				}
+				if created != step.expected {
+					t.Errorf("Expected created %v for %s@%s, got %v", step.expected, step.tag, step.digest, created)
+				}
+			}
+
+			// A closed out tag seen again starts a new interval
+			if err := repo.DeleteImageTag("Deployment", "web", "default", ""); err != nil {
+				t.Fatalf("Failed to delete: %v", err)
+			}
+
+			created, err := repo.UpsertImageTags([]models.ImageTagUpsert{
+				{ImageName: "nginx", Repository: "docker.io", Tag: "1.25", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", ContainerName: "nginx"},
+				{ImageName: "nginx", Repository: "docker.io", Tag: "1.25", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", ContainerName: "nginx"},
+				{ImageName: "redis", Repository: "docker.io", Tag: "7", ResourceType: "StatefulSet", ResourceName: "cache", Namespace: "default", ContainerName: "redis"},
+			})
+			if err != nil {
+				t.Fatalf("Failed to upsert batch: %v", err)
+			}
+			if expected := []bool{true, false, true}; !reflect.DeepEqual(created, expected) {
+				t.Errorf("Expected created %v, got %v", expected, created)
+			}
+
+			created, err = repo.UpsertImageTags([]models.ImageTagUpsert{
+				{ImageName: "redis", Repository: "docker.io", Tag: "7", ResourceType: "StatefulSet", ResourceName: "cache", Namespace: "default", ContainerName: "redis"},
+			})
+			if err != nil {
+				t.Fatalf("Failed to upsert batch: %v", err)
+			}
+			if expected := []bool{false}; !reflect.DeepEqual(created, expected) {
+				t.Errorf("Expected created %v, got %v", expected, created)
+			}
+		})
+	}
+}
+
+func TestUpsertImageTagsLogsEventsUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repos := map[string]ImageRepositoryInterface{
+		"database": NewImageRepository(db),
+		"memory":   NewMemoryImageRepository(),
+	}
+
+	// batch pairs two containers with the event log entries of their ADD events
+	batch := func() []models.ImageTagUpsert {
+		return []models.ImageTagUpsert{
+			{ImageName: "nginx", Repository: "docker.io/library", Tag: "1.25", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", ContainerName: "nginx",
+				Event: &models.ImageEvent{Type: "ADD", ImageName: "nginx", Repository: "docker.io/library", NewTag: "1.25", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", ContainerName: "nginx", ObservedAt: time.Now().UTC()}},
+			{ImageName: "redis", Repository: "docker.io/library", Tag: "7", ResourceType: "StatefulSet", ResourceName: "cache", Namespace: "default", ContainerName: "redis",
+				Event: &models.ImageEvent{Type: "ADD", ImageName: "redis", Repository: "docker.io/library", NewTag: "7", ResourceType: "StatefulSet", ResourceName: "cache", Namespace: "default", ContainerName: "redis", ObservedAt: time.Now().UTC()}},
+		}
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			if _, err := repo.UpsertImageTag("nginx", "docker.io/library", "1.25", "", "Deployment", "web", "default", "nginx"); err != nil {
+				t.Fatalf("Failed to upsert: %v", err)
+			}
+
+			tags := batch()
+			if _, err := repo.UpsertImageTags(tags); err != nil {
+				t.Fatalf("Failed to upsert batch: %v", err)
+			}
+			if tags[0].Event.ID != 0 || tags[1].Event.ID == 0 {
+				t.Errorf("Expected only the event of the new redis tag to get an ID, got %d and %d", tags[0].Event.ID, tags[1].Event.ID)
+			}
+
+			events, err := repo.ListImageEvents(models.ImageEventFilter{})
+			if err != nil {
+				t.Fatalf("Failed to list events: %v", err)
+			}
+			if len(events) != 1 || events[0].ImageName != "redis" {
+				t.Errorf("Expected the redis ADD event to be logged, got %+v", events)
+			}
+		})
+	}
+
+	t.Run("a failed append rolls the tags back", func(t *testing.T) {
+		db, cleanup := setupSQLiteDB(t)
+		defer cleanup()
+
+		failed := false
+		err := db.Callback().Create().Before("gorm:create").Register("fail_image_events", func(tx *gorm.DB) {
+			if tx.Statement.Table == "image_events" && !failed {
+				failed = true
+				tx.AddError(errors.New("disk full"))
+			}
+		})
+		if err != nil {
+			t.Fatalf("Failed to register callback: %v", err)
+		}
+
+		repo := NewImageRepository(db)
+		if _, err := repo.UpsertImageTags(batch()); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+
+		// Written again, the tags are new and their events are logged this time
+		created, err := repo.UpsertImageTags(batch())
+		if err != nil {
+			t.Fatalf("Failed to upsert batch: %v", err)
+		}
+		if expected := []bool{true, true}; !reflect.DeepEqual(created, expected) {
+			t.Errorf("Expected created %v, got %v", expected, created)
+		}
+
+		var count int64
+		db.Model(&models.ImageEvent{}).Count(&count)
+		if count != 2 {
+			t.Errorf("Expected 2 logged events, got %d", count)
+		}
+	})
+}
+
+// benchmarkTags builds one tag per workload, spread over a handful of images
+func benchmarkTags(workloads int) []models.ImageTagUpsert {
+	tags := make([]models.ImageTagUpsert, workloads)
+	for i := range tags {
+		tags[i] = models.ImageTagUpsert{
+			ImageName:     fmt.Sprintf("app-%d", i%50),
+			Repository:    "docker.io",
+			Tag:           "v1",
+			ResourceType:  "Deployment",
+			ResourceName:  fmt.Sprintf("workload-%d", i),
+			Namespace:     "default",
+			ContainerName: "main",
+		}
+	}
+	return tags
+}
+
+// BenchmarkUpsertImageTagPostgres writes a startup-sized listing one container at a time
+func BenchmarkUpsertImageTagPostgres(b *testing.B) {
+	db, cleanup := setupTestDB(b)
+	defer cleanup()
+
+	repo := NewImageRepository(db)
+	tags := benchmarkTags(4000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, t := range tags {
+			_, err := repo.UpsertImageTag(t.ImageName, t.Repository, t.Tag, t.Digest, t.ResourceType, t.ResourceName, t.Namespace, t.ContainerName)
+			if err != nil {
+				b.Fatalf("Failed to upsert: %v", err)
+			}
+		}
+	}
+}
+
+// BenchmarkUpsertImageTagsPostgres writes the same listing with the batch API
+func BenchmarkUpsertImageTagsPostgres(b *testing.B) {
+	db, cleanup := setupTestDB(b)
+	defer cleanup()
+
+	repo := NewImageRepository(db)
+	tags := benchmarkTags(4000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.UpsertImageTags(tags); err != nil {
+			b.Fatalf("Failed to upsert batch: %v", err)
+		}
+	}
+}
+
+func TestImageUpdatesUnit(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	repos := map[string]ImageRepositoryInterface{
+		"database": NewImageRepository(db),
+		"memory":   NewMemoryImageRepository(),
+	}
+
+	checkedAt := time.Now().UTC().Truncate(time.Second)
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			err := repo.UpsertImageUpdates([]models.ImageUpdate{
+				{ImageName: "redis", Repository: "docker.io", Tag: "7.0", LatestCompatible: "7.2", Latest: "7.2", Outdated: true, CheckedAt: checkedAt},
+				{ImageName: "nginx", Repository: "docker.io", Tag: "1.25.3", LatestCompatible: "1.25.4", Latest: "1.25.4", Outdated: true, CheckedAt: checkedAt},
+				{ImageName: "nginx", Repository: "docker.io", Tag: "latest", Error: "tag is not a version", CheckedAt: checkedAt},
+			})
+			if err != nil {
+				t.Fatalf("Failed to upsert image updates: %v", err)
+			}
+
+			// A later check replaces the result of the same tag
+			err = repo.UpsertImageUpdates([]models.ImageUpdate{
+				{ImageName: "nginx", Repository: "docker.io", Tag: "1.25.3", LatestCompatible: "1.27.2", Latest: "2.0.1", Outdated: true, CheckedAt: checkedAt.Add(time.Hour)},
+			})
+			if err != nil {
+				t.Fatalf("Failed to upsert image updates: %v", err)
+			}
+
+			updates, err := repo.ListImageUpdates()
+			if err != nil {
+				t.Fatalf("Failed to list image updates: %v", err)
+			}
+
+			var tags []string
+			for _, update := range updates {
+				tags = append(tags, update.ImageName+":"+update.Tag)
+			}
+			if expected := []string{"nginx:1.25.3", "nginx:latest", "redis:7.0"}; !reflect.DeepEqual(tags, expected) {
+				t.Fatalf("Expected updates %v, got %v", expected, tags)
+			}
+
+			nginx := updates[0]
+			if nginx.LatestCompatible != "1.27.2" || nginx.Latest != "2.0.1" || !nginx.CheckedAt.Equal(checkedAt.Add(time.Hour)) {
+				t.Errorf("Expected the later check to replace the earlier one, got %+v", nginx)
+			}
+			if updates[1].Outdated || updates[1].Error != "tag is not a version" {
+				t.Errorf("Expected the failed check to be recorded, got %+v", updates[1])
+			}
+
+			if err := repo.UpsertImageUpdates(nil); err != nil {
+				t.Errorf("Expected no error for no updates, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,1004 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+	"gorm.io/gorm"
+)
+
+// Entries kept by the in-memory event log and delivery history, the oldest are dropped first
+const (
+	maxMemoryImageEvents = 10000
+	maxMemoryDeliveries  = 10000
+)
+
+// MemoryImageRepository keeps images in memory for deployments without a database
+// It follows the same soft delete and history semantics as ImageRepository
+type MemoryImageRepository struct {
+	mu sync.RWMutex
+
+	images        []models.Image
+	imageTags     []models.ImageTag // Image is not set, it is attached on read
+	runningImages map[string]models.RunningImage
+	imageEvents   []models.ImageEvent
+	deliveries    []models.WebhookDelivery
+	imageUpdates  map[string]models.ImageUpdate // Keyed by repository, image name and tag
+
+	// Lookups by the unique columns, pointing into images and the active rows of imageTags
+	imageIDs   map[string]uint
+	tagIndexes map[string]int
+
+	nextImageID    uint
+	nextTagID      uint
+	nextRunningID  uint
+	nextEventID    uint
+	nextDeliveryID uint
+	nextUpdateID   uint
+}
+
+// NewMemoryImageRepository creates a new empty in-memory image repository
+func NewMemoryImageRepository() *MemoryImageRepository {
+	return &MemoryImageRepository{
+		runningImages: make(map[string]models.RunningImage),
+		imageUpdates:  make(map[string]models.ImageUpdate),
+		imageIDs:      make(map[string]uint),
+		tagIndexes:    make(map[string]int),
+	}
+}
+
+// UpsertImageTag creates or updates an image tag record and reports whether a row was created
+func (r *MemoryImageRepository) UpsertImageTag(
+	imageName, repository, tag, digest, resourceType, resourceName, namespace, containerName string,
+) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, created := r.upsertImageTag(time.Now().UTC(), models.ImageTagUpsert{
+		ImageName:     imageName,
+		Repository:    repository,
+		Tag:           tag,
+		Digest:        digest,
+		ResourceType:  resourceType,
+		ResourceName:  resourceName,
+		Namespace:     namespace,
+		ContainerName: containerName,
+	})
+
+	return created, nil
+}
+
+// UpsertImageTags creates or updates many image tag records at once and reports which created a row
+// The Event of every tag that created a row is appended to the event log
+func (r *MemoryImageRepository) UpsertImageTags(tags []models.ImageTagUpsert) ([]bool, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	created := make([]bool, len(tags))
+	for i, t := range tags {
+		_, created[i] = r.upsertImageTag(now, t)
+		if created[i] && t.Event != nil {
+			r.appendImageEvent(now, t.Event)
+		}
+	}
+
+	return created, nil
+}
+
+// ReplaceImageTag upserts the new tag of a container and closes out the tag it replaces
+func (r *MemoryImageRepository) ReplaceImageTag(
+	imageName, repository, tag, digest, resourceType, resourceName, namespace, containerName string,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	current, _ := r.upsertImageTag(now, models.ImageTagUpsert{
+		ImageName:     imageName,
+		Repository:    repository,
+		Tag:           tag,
+		Digest:        digest,
+		ResourceType:  resourceType,
+		ResourceName:  resourceName,
+		Namespace:     namespace,
+		ContainerName: containerName,
+	})
+
+	// Every other active row of the same container has been replaced
+	r.closeImageTags(now, func(it *models.ImageTag) bool {
+		return it.ID != current &&
+			it.ResourceType == resourceType &&
+			it.ResourceName == resourceName &&
+			it.Namespace == namespace &&
+			it.ContainerName == containerName
+	})
+
+	return nil
+}
+
+// upsertImageTag creates an image tag record or refreshes the active one and returns its ID
+// and whether it was created, the caller holds the lock
+func (r *MemoryImageRepository) upsertImageTag(now time.Time, t models.ImageTagUpsert) (uint, bool) {
+	fullName := fmt.Sprintf("%s/%s", t.Repository, t.ImageName)
+
+	imageID, found := r.imageIDs[fullName]
+	if !found {
+		r.nextImageID++
+		imageID = r.nextImageID
+		r.imageIDs[fullName] = imageID
+		r.images = append(r.images, models.Image{
+			ID:         imageID,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			Name:       t.ImageName,
+			Repository: t.Repository,
+			FullName:   fullName,
+		})
+	}
+
+	// Active rows are unique on the same columns as idx_image_tag_resource,
+	// a tag coming back after a rollback starts a new row
+	key := imageTagKey(imageID, t.Tag, t.Digest, t.ResourceType, t.ResourceName, t.Namespace, t.ContainerName)
+	if index, found := r.tagIndexes[key]; found {
+		it := &r.imageTags[index]
+		it.LastSeen = now
+		it.UpdatedAt = now
+		return it.ID, false
+	}
+
+	r.nextTagID++
+	r.tagIndexes[key] = len(r.imageTags)
+	r.imageTags = append(r.imageTags, models.ImageTag{
+		ID:            r.nextTagID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		ImageID:       imageID,
+		Tag:           t.Tag,
+		Digest:        t.Digest,
+		ResourceType:  t.ResourceType,
+		ResourceName:  t.ResourceName,
+		Namespace:     t.Namespace,
+		ContainerName: t.ContainerName,
+		FirstSeen:     now,
+		LastSeen:      now,
+	})
+
+	return r.nextTagID, true
+}
+
+// imageTagKey identifies an image tag row by the columns of idx_image_tag_resource
+func imageTagKey(imageID uint, tag, digest, resourceType, resourceName, namespace, containerName string) string {
+	return fmt.Sprintf("%d|%s|%s|%s|%s|%s|%s", imageID, tag, digest, resourceType, resourceName, namespace, containerName)
+}
+
+// indexImageTags rebuilds tagIndexes after imageTags was rewritten, the caller holds the lock
+func (r *MemoryImageRepository) indexImageTags() {
+	r.tagIndexes = make(map[string]int, len(r.imageTags))
+	for i, it := range r.imageTags {
+		if it.DeletedAt.Valid {
+			continue
+		}
+		r.tagIndexes[imageTagKey(it.ImageID, it.Tag, it.Digest, it.ResourceType, it.ResourceName, it.Namespace, it.ContainerName)] = i
+	}
+}
+
+// closeImageTags marks the active rows matched by match as removed at the given time and soft deletes them
+func (r *MemoryImageRepository) closeImageTags(now time.Time, match func(it *models.ImageTag) bool) {
+	for i := range r.imageTags {
+		it := &r.imageTags[i]
+		if it.DeletedAt.Valid || !match(it) {
+			continue
+		}
+
+		removedAt := now
+		it.RemovedAt = &removedAt
+		it.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		it.UpdatedAt = now
+		delete(r.tagIndexes, imageTagKey(it.ImageID, it.Tag, it.Digest, it.ResourceType, it.ResourceName, it.Namespace, it.ContainerName))
+	}
+}
+
+// DeleteImageTag closes out the image tags of a resource
+// containerName limits it to a single container; empty closes out every container
+func (r *MemoryImageRepository) DeleteImageTag(
+	resourceType, resourceName, namespace, containerName string,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closeImageTags(time.Now().UTC(), func(it *models.ImageTag) bool {
+		return it.ResourceType == resourceType &&
+			it.ResourceName == resourceName &&
+			it.Namespace == namespace &&
+			(containerName == "" || it.ContainerName == containerName)
+	})
+
+	return nil
+}
+
+// ListActiveImageTags returns every non-deleted image tag with its image attached
+func (r *MemoryImageRepository) ListActiveImageTags() ([]models.ImageTag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.activeImageTags(""), nil
+}
+
+// activeImageTags returns copies of the non-deleted image tags with their image attached, the caller holds the lock
+func (r *MemoryImageRepository) activeImageTags(namespace string) []models.ImageTag {
+	var imageTags []models.ImageTag
+	for _, it := range r.imageTags {
+		if it.DeletedAt.Valid || it.RemovedAt != nil || (namespace != "" && it.Namespace != namespace) {
+			continue
+		}
+
+		it.Image = r.images[it.ImageID-1]
+		imageTags = append(imageTags, it)
+	}
+
+	return imageTags
+}
+
+// DeleteImageTagsByID closes out the given image tag rows
+func (r *MemoryImageRepository) DeleteImageTagsByID(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	closed := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		closed[id] = true
+	}
+
+	r.closeImageTags(time.Now().UTC(), func(it *models.ImageTag) bool {
+		return closed[it.ID]
+	})
+
+	return nil
+}
+
+// ListPrunableImageTags returns the IDs of the closed out image tags query selects, in ID order
+func (r *MemoryImageRepository) ListPrunableImageTags(query models.PruneQuery) ([]uint, error) {
+	if query.MaxInactive <= 0 && query.ClosedBefore.IsZero() {
+		return nil, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	byResource := make(map[string][]models.ImageTag)
+	for _, it := range r.imageTags {
+		if it.DeletedAt.Valid {
+			key := fmt.Sprintf("%d|%s|%s|%s", it.ImageID, it.ResourceType, it.ResourceName, it.Namespace)
+			byResource[key] = append(byResource[key], it)
+		}
+	}
+
+	var ids []uint
+	for _, rows := range byResource {
+		// Newest first; RemovedAt and DeletedAt are set together, DeletedAt also covers older rows
+		sort.Slice(rows, func(i, j int) bool {
+			if !rows[i].DeletedAt.Time.Equal(rows[j].DeletedAt.Time) {
+				return rows[i].DeletedAt.Time.After(rows[j].DeletedAt.Time)
+			}
+			return rows[i].ID > rows[j].ID
+		})
+
+		for i, it := range rows {
+			position := i + 1
+			if position <= query.KeepLast || it.ID <= query.AfterID {
+				continue
+			}
+
+			tooMany := query.MaxInactive > 0 && position > query.MaxInactive
+			tooOld := !query.ClosedBefore.IsZero() && it.DeletedAt.Time.Before(query.ClosedBefore)
+			if tooMany || tooOld {
+				ids = append(ids, it.ID)
+			}
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if query.Limit > 0 && len(ids) > query.Limit {
+		ids = ids[:query.Limit]
+	}
+
+	return ids, nil
+}
+
+// PurgeImageTags permanently deletes the given image tag rows, active rows are left alone
+func (r *MemoryImageRepository) PurgeImageTags(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	purged := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		purged[id] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.imageTags[:0]
+	for _, it := range r.imageTags {
+		if it.DeletedAt.Valid && purged[it.ID] {
+			continue
+		}
+		kept = append(kept, it)
+	}
+	r.imageTags = kept
+	r.indexImageTags()
+
+	return nil
+}
+
+// UpsertRunningImage records the digest a Pod actually runs for a container of a workload
+func (r *MemoryImageRepository) UpsertRunningImage(
+	imageName, repository, tag, resourceType, resourceName, namespace, containerName, podName, digest string,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	key := fmt.Sprintf("%s|%s|%s", namespace, podName, containerName)
+
+	// A Pod container runs one image at a time, so the latest report wins
+	runningImage, found := r.runningImages[key]
+	if !found {
+		r.nextRunningID++
+		runningImage = models.RunningImage{
+			ID:            r.nextRunningID,
+			CreatedAt:     now,
+			Namespace:     namespace,
+			PodName:       podName,
+			ContainerName: containerName,
+		}
+	}
+
+	runningImage.ImageName = imageName
+	runningImage.Repository = repository
+	runningImage.Tag = tag
+	runningImage.ResourceType = resourceType
+	runningImage.ResourceName = resourceName
+	runningImage.Digest = digest
+	runningImage.LastSeen = now
+	runningImage.UpdatedAt = now
+	r.runningImages[key] = runningImage
+
+	return nil
+}
+
+// DeleteRunningImages removes the running digests reported by a Pod
+func (r *MemoryImageRepository) DeleteRunningImages(namespace, podName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, ri := range r.runningImages {
+		if ri.Namespace == namespace && ri.PodName == podName {
+			delete(r.runningImages, key)
+		}
+	}
+
+	return nil
+}
+
+// ListRunningImages returns every recorded running digest
+func (r *MemoryImageRepository) ListRunningImages() ([]models.RunningImage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.listRunningImages(""), nil
+}
+
+// listRunningImages returns the running digests ordered by digest, the caller holds the lock
+func (r *MemoryImageRepository) listRunningImages(namespace string) []models.RunningImage {
+	var runningImages []models.RunningImage
+	for _, ri := range r.runningImages {
+		if namespace == "" || ri.Namespace == namespace {
+			runningImages = append(runningImages, ri)
+		}
+	}
+
+	sort.Slice(runningImages, func(i, j int) bool {
+		if runningImages[i].Digest != runningImages[j].Digest {
+			return runningImages[i].Digest < runningImages[j].Digest
+		}
+		return runningImages[i].ID < runningImages[j].ID
+	})
+
+	return runningImages
+}
+
+// GetAllImages retrieves all images with their latest tags
+func (r *MemoryImageRepository) GetAllImages(namespace string) ([]models.ImageInfo, error) {
+	images, _, err := r.QueryImages(models.ImageQuery{Namespace: namespace})
+	return images, err
+}
+
+// QueryImages returns a page of the image inventory and the number of images matching the query
+// Matches, order and pages are the same as the database repository's
+func (r *MemoryImageRepository) QueryImages(query models.ImageQuery) ([]models.ImageInfo, int, error) {
+	less, err := imageGroupLess(query.Sort)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	r.mu.RLock()
+	runningDigests := groupRunningDigests(r.listRunningImages(query.Namespace))
+	groups := groupImageTags(r.activeImageTags(query.Namespace), runningDigests)
+	r.mu.RUnlock()
+
+	var matches []imageGroup
+	for _, group := range groups {
+		if matchesImageQuery(group.info, query) {
+			matches = append(matches, group)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if query.Descending {
+			a, b = b, a
+		}
+		if c := less(a, b); c != 0 {
+			return c < 0
+		}
+		return compareImageGroups(matches[i], matches[j]) < 0
+	})
+
+	total := len(matches)
+	start := min(query.Offset, total)
+	end := total
+	if query.Limit > 0 {
+		end = min(start+query.Limit, total)
+	}
+
+	var images []models.ImageInfo
+	for _, group := range matches[start:end] {
+		images = append(images, group.imageInfo())
+	}
+
+	return images, total, nil
+}
+
+// matchesImageQuery applies the filters of a query to an inventory row
+func matchesImageQuery(img models.ImageInfo, query models.ImageQuery) bool {
+	name := strings.ToLower(img.Name)
+
+	switch {
+	case query.Repository != "" && img.Repository != query.Repository:
+		return false
+	case query.Name != "" && !strings.Contains(name, strings.ToLower(query.Name)):
+		return false
+	case query.NamePrefix != "" && !strings.HasPrefix(name, strings.ToLower(query.NamePrefix)):
+		return false
+	case query.Tag != "" && img.Tag != query.Tag:
+		return false
+	case query.ResourceType != "" && img.ResourceType != query.ResourceType:
+		return false
+	case query.Container != "" && !slices.Contains(img.Containers, query.Container):
+		return false
+	}
+
+	return true
+}
+
+// imageGroupLess compares inventory rows by an ImageQuery sort key, like imageSortColumn
+func imageGroupLess(sortKey string) (func(a, b imageGroup) int, error) {
+	switch sortKey {
+	case "", models.ImageSortName:
+		return func(a, b imageGroup) int { return strings.Compare(a.info.Name, b.info.Name) }, nil
+	case models.ImageSortTag:
+		return func(a, b imageGroup) int { return strings.Compare(a.info.Tag, b.info.Tag) }, nil
+	case models.ImageSortNamespace:
+		return func(a, b imageGroup) int { return strings.Compare(a.info.Namespace, b.info.Namespace) }, nil
+	case models.ImageSortFirstSeen:
+		return func(a, b imageGroup) int { return a.firstSeen.Compare(b.firstSeen) }, nil
+	case models.ImageSortLastSeen:
+		return func(a, b imageGroup) int { return a.lastSeen.Compare(b.lastSeen) }, nil
+	default:
+		return nil, fmt.Errorf("unsupported sort key: %s", sortKey)
+	}
+}
+
+// compareImageGroups breaks sort ties in the order of imageSortTieBreak
+func compareImageGroups(a, b imageGroup) int {
+	for _, pair := range [][2]string{
+		{a.info.Name, b.info.Name},
+		{a.info.Repository, b.info.Repository},
+		{a.info.Tag, b.info.Tag},
+		{a.info.Digest, b.info.Digest},
+		{a.info.ResourceType, b.info.ResourceType},
+		{a.info.ResourceName, b.info.ResourceName},
+		{a.info.Namespace, b.info.Namespace},
+	} {
+		if c := strings.Compare(pair[0], pair[1]); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// GetImagesAt reconstructs the inventory as it was at the given instant
+func (r *MemoryImageRepository) GetImagesAt(namespace string, at time.Time) ([]models.ImageInfo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var imageTags []models.ImageTag
+	for _, it := range r.imageTags {
+		if namespace != "" && it.Namespace != namespace {
+			continue
+		}
+
+		it.Image = r.images[it.ImageID-1]
+		imageTags = append(imageTags, it)
+	}
+
+	return aggregateImages(tagsActiveAt(imageTags, at.UTC()), nil), nil
+}
+
+// GetImageTagHistory returns the history of all tags for a specific image
+// reference is either a full reference such as ghcr.io/bitnami/redis, or a bare name matching every repository
+func (r *MemoryImageRepository) GetImageTagHistory(reference, namespace string) (*models.ImageTagHistory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var images []models.Image
+	for _, image := range r.images {
+		if image.FullName == reference || (!isFullReference(reference) && image.Name == reference) {
+			images = append(images, image)
+		}
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("image not found: %w", gorm.ErrRecordNotFound)
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].FullName < images[j].FullName })
+
+	var imageTags []models.ImageTag
+	for _, it := range r.imageTags {
+		image := r.images[it.ImageID-1]
+		if !slices.ContainsFunc(images, func(match models.Image) bool { return match.ID == image.ID }) {
+			continue
+		}
+		if namespace == "" || it.Namespace == namespace {
+			it.Image = image
+			imageTags = append(imageTags, it)
+		}
+	}
+
+	sort.SliceStable(imageTags, func(i, j int) bool {
+		return imageTags[i].FirstSeen.After(imageTags[j].FirstSeen)
+	})
+
+	runningDigests := groupRunningDigests(r.listRunningImages(namespace))
+
+	return buildTagHistory(images, imageTags, runningDigests), nil
+}
+
+// GetResourceImageTags returns every image tag row of a workload, closed out ones included, oldest first
+func (r *MemoryImageRepository) GetResourceImageTags(resourceType, resourceName, namespace string) ([]models.ImageTag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var imageTags []models.ImageTag
+	for _, it := range r.imageTags {
+		if it.ResourceType != resourceType || it.ResourceName != resourceName || it.Namespace != namespace {
+			continue
+		}
+
+		it.Image = r.images[it.ImageID-1]
+		imageTags = append(imageTags, it)
+	}
+
+	sort.Slice(imageTags, func(i, j int) bool {
+		if !imageTags[i].FirstSeen.Equal(imageTags[j].FirstSeen) {
+			return imageTags[i].FirstSeen.Before(imageTags[j].FirstSeen)
+		}
+		return imageTags[i].ID < imageTags[j].ID
+	})
+
+	return imageTags, nil
+}
+
+// SearchImageTags returns the image tag rows, closed out ones included, where every term is a substring of a searched field
+// Every match is returned for ranking, they are in memory already
+func (r *MemoryImageRepository) SearchImageTags(query models.SearchQuery) ([]models.ImageTag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	terms := query.Terms()
+
+	var imageTags []models.ImageTag
+	for _, it := range r.imageTags {
+		if query.Namespace != "" && it.Namespace != query.Namespace {
+			continue
+		}
+
+		it.Image = r.images[it.ImageID-1]
+		if !matchesSearchTerms(it, terms) {
+			continue
+		}
+		imageTags = append(imageTags, it)
+	}
+
+	sort.Slice(imageTags, func(i, j int) bool {
+		if active := !imageTags[i].DeletedAt.Valid; active != !imageTags[j].DeletedAt.Valid {
+			return active
+		}
+		if !imageTags[i].LastSeen.Equal(imageTags[j].LastSeen) {
+			return imageTags[i].LastSeen.After(imageTags[j].LastSeen)
+		}
+		return imageTags[i].ID > imageTags[j].ID
+	})
+
+	return imageTags, nil
+}
+
+// matchesSearchTerms reports whether every term is a substring of one of the fields of searchColumns
+func matchesSearchTerms(it models.ImageTag, terms []string) bool {
+	fields := []string{it.Image.Name, it.Image.Repository, it.Tag, it.ResourceName, it.Namespace, it.ContainerName}
+
+	for _, term := range terms {
+		if !slices.ContainsFunc(fields, func(field string) bool {
+			return strings.Contains(strings.ToLower(field), term)
+		}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AppendImageEvents adds entries to the append-only image event log, setting their IDs like the database does
+func (r *MemoryImageRepository) AppendImageEvents(events []models.ImageEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	for i := range events {
+		r.appendImageEvent(now, &events[i])
+	}
+
+	return nil
+}
+
+// appendImageEvent adds an entry to the image event log, dropping the oldest beyond the cap, the caller holds the lock
+func (r *MemoryImageRepository) appendImageEvent(now time.Time, event *models.ImageEvent) {
+	r.nextEventID++
+	event.ID = r.nextEventID
+	event.CreatedAt = now
+	r.imageEvents = append(r.imageEvents, *event)
+
+	if len(r.imageEvents) > maxMemoryImageEvents {
+		r.imageEvents = r.imageEvents[len(r.imageEvents)-maxMemoryImageEvents:]
+	}
+}
+
+// ListImageEvents returns the image event log entries matching filter, newest first
+func (r *MemoryImageRepository) ListImageEvents(filter models.ImageEventFilter) ([]models.ImageEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []models.ImageEvent
+	for _, event := range r.imageEvents {
+		if filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].ObservedAt.Equal(events[j].ObservedAt) {
+			return events[i].ObservedAt.After(events[j].ObservedAt)
+		}
+		return events[i].ID > events[j].ID
+	})
+
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[:filter.Limit]
+	}
+
+	return events, nil
+}
+
+// PurgeImageEvents permanently deletes the event log entries observed before the given time
+// In dry-run mode the entries are only counted
+func (r *MemoryImageRepository) PurgeImageEvents(before time.Time, dryRun bool) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := make([]models.ImageEvent, 0, len(r.imageEvents))
+	for _, event := range r.imageEvents {
+		if !event.ObservedAt.Before(before) {
+			kept = append(kept, event)
+		}
+	}
+
+	purged := int64(len(r.imageEvents) - len(kept))
+	if !dryRun {
+		r.imageEvents = kept
+	}
+
+	return purged, nil
+}
+
+// AppendWebhookDelivery records a webhook delivery attempt, setting its ID
+func (r *MemoryImageRepository) AppendWebhookDelivery(delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextDeliveryID++
+	delivery.ID = r.nextDeliveryID
+	delivery.CreatedAt = time.Now().UTC()
+	r.deliveries = append(r.deliveries, *delivery)
+
+	if len(r.deliveries) > maxMemoryDeliveries {
+		r.deliveries = r.deliveries[len(r.deliveries)-maxMemoryDeliveries:]
+	}
+
+	return nil
+}
+
+// ListWebhookDeliveries returns the webhook delivery attempts matching filter, newest first
+func (r *MemoryImageRepository) ListWebhookDeliveries(filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var deliveries []models.WebhookDelivery
+	for i := len(r.deliveries) - 1; i >= 0; i-- {
+		delivery := r.deliveries[i]
+		switch {
+		case filter.Target != "" && delivery.Target != filter.Target:
+			continue
+		case filter.EventID > 0 && delivery.EventID != filter.EventID:
+			continue
+		case filter.Failed && delivery.Success:
+			continue
+		}
+
+		deliveries = append(deliveries, delivery)
+		if filter.Limit > 0 && len(deliveries) == filter.Limit {
+			break
+		}
+	}
+
+	return deliveries, nil
+}
+
+// PurgeWebhookDeliveries permanently deletes the delivery attempts made before the given time
+// In dry-run mode the attempts are only counted
+func (r *MemoryImageRepository) PurgeWebhookDeliveries(before time.Time, dryRun bool) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := make([]models.WebhookDelivery, 0, len(r.deliveries))
+	for _, delivery := range r.deliveries {
+		if !delivery.CreatedAt.Before(before) {
+			kept = append(kept, delivery)
+		}
+	}
+
+	purged := int64(len(r.deliveries) - len(kept))
+	if !dryRun {
+		r.deliveries = kept
+	}
+
+	return purged, nil
+}
+
+// UpsertImageUpdates records registry checks of image tags, replacing earlier checks of the same tags
+func (r *MemoryImageRepository) UpsertImageUpdates(updates []models.ImageUpdate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, update := range updates {
+		key := fmt.Sprintf("%s|%s|%s", update.Repository, update.ImageName, update.Tag)
+
+		if existing, found := r.imageUpdates[key]; found {
+			update.ID = existing.ID
+			update.CreatedAt = existing.CreatedAt
+		} else {
+			r.nextUpdateID++
+			update.ID = r.nextUpdateID
+			update.CreatedAt = now
+		}
+		update.UpdatedAt = now
+
+		r.imageUpdates[key] = update
+	}
+
+	return nil
+}
+
+// ListImageUpdates returns the latest registry check of every image tag, ordered by image and tag
+func (r *MemoryImageRepository) ListImageUpdates() ([]models.ImageUpdate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.listImageUpdates(), nil
+}
+
+// listImageUpdates returns the image updates ordered by image and tag, the caller holds mu
+func (r *MemoryImageRepository) listImageUpdates() []models.ImageUpdate {
+	updates := make([]models.ImageUpdate, 0, len(r.imageUpdates))
+	for _, update := range r.imageUpdates {
+		updates = append(updates, update)
+	}
+
+	sort.Slice(updates, func(i, j int) bool {
+		a, b := updates[i], updates[j]
+		if a.Repository != b.Repository {
+			return a.Repository < b.Repository
+		}
+		if a.ImageName != b.ImageName {
+			return a.ImageName < b.ImageName
+		}
+		return a.Tag < b.Tag
+	})
+
+	return updates
+}
+
+// memorySnapshot is the on-disk format of a MemoryImageRepository
+type memorySnapshot struct {
+	Images        []models.Image           `json:"images"`
+	ImageTags     []memorySnapshotTag      `json:"image_tags"`
+	RunningImages []models.RunningImage    `json:"running_images"`
+	ImageEvents   []models.ImageEvent      `json:"image_events"`
+	Deliveries    []models.WebhookDelivery `json:"webhook_deliveries,omitempty"`
+	ImageUpdates  []models.ImageUpdate     `json:"image_updates,omitempty"`
+}
+
+// memorySnapshotTag keeps DeletedAt, which models.ImageTag leaves out of its JSON
+type memorySnapshotTag struct {
+	models.ImageTag
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// SaveSnapshot writes the repository contents to path, replacing the file atomically
+func (r *MemoryImageRepository) SaveSnapshot(path string) error {
+	r.mu.RLock()
+	snapshot := memorySnapshot{
+		Images:        r.images,
+		RunningImages: r.listRunningImages(""),
+		ImageEvents:   r.imageEvents,
+		Deliveries:    r.deliveries,
+		ImageUpdates:  r.listImageUpdates(),
+	}
+	for _, it := range r.imageTags {
+		tag := memorySnapshotTag{ImageTag: it}
+		if it.DeletedAt.Valid {
+			deletedAt := it.DeletedAt.Time
+			tag.DeletedAt = &deletedAt
+		}
+		snapshot.ImageTags = append(snapshot.ImageTags, tag)
+	}
+	data, err := json.Marshal(snapshot)
+	r.mu.RUnlock()
+
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// LoadSnapshot replaces the repository contents with the snapshot at path
+// A missing file is not an error, the repository simply starts empty
+func (r *MemoryImageRepository) LoadSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snapshot memorySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	// Images are looked up by ID-1, so IDs must be dense and in order
+	for i, image := range snapshot.Images {
+		if image.ID != uint(i+1) {
+			return fmt.Errorf("invalid snapshot: image %s has ID %d at position %d", image.FullName, image.ID, i+1)
+		}
+	}
+
+	imageTags := make([]models.ImageTag, 0, len(snapshot.ImageTags))
+	for _, tag := range snapshot.ImageTags {
+		if tag.ImageID == 0 || tag.ImageID > uint(len(snapshot.Images)) {
+			return fmt.Errorf("invalid snapshot: image tag %d references unknown image %d", tag.ID, tag.ImageID)
+		}
+
+		it := tag.ImageTag
+		it.Image = models.Image{}
+		if tag.DeletedAt != nil {
+			it.DeletedAt = gorm.DeletedAt{Time: *tag.DeletedAt, Valid: true}
+		}
+		imageTags = append(imageTags, it)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.images = snapshot.Images
+	r.nextImageID = uint(len(snapshot.Images))
+	r.imageIDs = make(map[string]uint)
+	for _, image := range r.images {
+		r.imageIDs[image.FullName] = image.ID
+	}
+
+	r.imageTags = imageTags
+	r.nextTagID = 0
+	for _, it := range r.imageTags {
+		r.nextTagID = max(r.nextTagID, it.ID)
+	}
+	r.indexImageTags()
+
+	r.imageEvents = snapshot.ImageEvents
+	r.nextEventID = 0
+	for _, event := range r.imageEvents {
+		r.nextEventID = max(r.nextEventID, event.ID)
+	}
+
+	r.deliveries = snapshot.Deliveries
+	r.nextDeliveryID = 0
+	for _, delivery := range r.deliveries {
+		r.nextDeliveryID = max(r.nextDeliveryID, delivery.ID)
+	}
+
+	r.imageUpdates = make(map[string]models.ImageUpdate)
+	r.nextUpdateID = 0
+	for _, update := range snapshot.ImageUpdates {
+		r.imageUpdates[fmt.Sprintf("%s|%s|%s", update.Repository, update.ImageName, update.Tag)] = update
+		r.nextUpdateID = max(r.nextUpdateID, update.ID)
+	}
+
+	r.runningImages = make(map[string]models.RunningImage)
+	r.nextRunningID = 0
+	for _, ri := range snapshot.RunningImages {
+		r.runningImages[fmt.Sprintf("%s|%s|%s", ri.Namespace, ri.PodName, ri.ContainerName)] = ri
+		r.nextRunningID = max(r.nextRunningID, ri.ID)
+	}
+
+	return nil
+}
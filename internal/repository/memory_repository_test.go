@@ -0,0 +1,311 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+	"gorm.io/gorm"
+)
+
+// applyParityScript runs the same sequence of writes against any repository implementation
+func applyParityScript(t *testing.T, repo ImageRepositoryInterface) {
+	steps := []func() error{
+		func() error {
+			_, err := repo.UpsertImageTag("nginx", "docker.io", "1.21", "", "Deployment", "web", "default", "nginx")
+			return err
+		},
+		func() error {
+			_, err := repo.UpsertImageTag("redis", "docker.io", "7", "sha256:abc", "StatefulSet", "cache", "default", "redis")
+			return err
+		},
+		func() error {
+			_, err := repo.UpsertImageTag("envoy", "docker.io", "1.30", "", "Deployment", "web", "default", "envoy")
+			return err
+		},
+		func() error {
+			return repo.UpsertRunningImage("nginx", "docker.io", "1.21", "Deployment", "web", "default", "nginx", "web-1", "sha256:111")
+		},
+		func() error {
+			return repo.UpsertRunningImage("nginx", "docker.io", "1.21", "Deployment", "web", "default", "nginx", "web-2", "sha256:222")
+		},
+		func() error {
+			return repo.ReplaceImageTag("nginx", "docker.io", "1.22", "", "Deployment", "web", "default", "nginx")
+		},
+		func() error {
+			return repo.UpsertRunningImage("nginx", "docker.io", "1.22", "Deployment", "web", "default", "nginx", "web-1", "sha256:333")
+		},
+		func() error { return repo.DeleteImageTag("Deployment", "web", "default", "envoy") },
+		func() error {
+			_, err := repo.UpsertImageTags([]models.ImageTagUpsert{
+				{ImageName: "app", Repository: "ghcr.io/acme", Tag: "v1", ResourceType: "Deployment", ResourceName: "app", Namespace: "prod", ContainerName: "app"},
+				{ImageName: "app", Repository: "ghcr.io/acme", Tag: "v1", ResourceType: "Deployment", ResourceName: "worker", Namespace: "prod", ContainerName: "app"},
+				{ImageName: "nginx", Repository: "docker.io", Tag: "1.22", ResourceType: "Deployment", ResourceName: "edge", Namespace: "prod", ContainerName: "nginx"},
+			})
+			return err
+		},
+		func() error { return repo.DeleteImageTag("StatefulSet", "cache", "default", "") },
+		func() error { return repo.DeleteRunningImages("default", "web-2") },
+		func() error {
+			_, err := repo.UpsertImageTag("redis", "docker.io", "7", "sha256:abc", "StatefulSet", "cache", "default", "redis")
+			return err
+		},
+		func() error {
+			tags, err := repo.ListActiveImageTags()
+			if err != nil {
+				return err
+			}
+			var ids []uint
+			for _, it := range tags {
+				if it.ResourceName == "worker" {
+					ids = append(ids, it.ID)
+				}
+			}
+			return repo.DeleteImageTagsByID(ids)
+		},
+	}
+
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("Step %d failed: %v", i, err)
+		}
+	}
+}
+
+// comparableImages drops timestamps and fixes the order so two implementations can be compared
+func comparableImages(images []models.ImageInfo) []models.ImageInfo {
+	var result []models.ImageInfo
+	for _, img := range images {
+		img.FirstSeen, img.LastSeen = "", ""
+		sort.Strings(img.Containers)
+		result = append(result, img)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return fmt.Sprint(result[i]) < fmt.Sprint(result[j])
+	})
+
+	return result
+}
+
+// comparableHistory drops timestamps, keeping whether each row was closed out
+func comparableHistory(history *models.ImageTagHistory) []string {
+	var result []string
+	for _, tag := range history.Tags {
+		result = append(result, fmt.Sprintf("%s|%s|%s|%s|%s|%s|%t|%v|%t",
+			tag.Tag, tag.Digest, tag.ResourceType, tag.ResourceName, tag.Namespace, tag.Container,
+			tag.Active, tag.RunningDigests, tag.RemovedAt != nil))
+	}
+
+	return result
+}
+
+func TestMemoryImageRepositoryParity(t *testing.T) {
+	db, cleanup := setupSQLiteDB(t)
+	defer cleanup()
+
+	dbRepo := NewImageRepository(db)
+	memRepo := NewMemoryImageRepository()
+
+	applyParityScript(t, dbRepo)
+	applyParityScript(t, memRepo)
+
+	for _, namespace := range []string{"", "default", "prod", "missing"} {
+		t.Run("images in namespace "+namespace, func(t *testing.T) {
+			expected, err := dbRepo.GetAllImages(namespace)
+			if err != nil {
+				t.Fatalf("Failed to get images from database: %v", err)
+			}
+			got, err := memRepo.GetAllImages(namespace)
+			if err != nil {
+				t.Fatalf("Failed to get images from memory: %v", err)
+			}
+
+			if !reflect.DeepEqual(comparableImages(expected), comparableImages(got)) {
+				t.Errorf("Expected %+v, got %+v", comparableImages(expected), comparableImages(got))
+			}
+		})
+	}
+
+	for _, imageName := range []string{"nginx", "redis", "envoy", "app"} {
+		for _, namespace := range []string{"", "default", "prod"} {
+			t.Run("history of "+imageName+" in namespace "+namespace, func(t *testing.T) {
+				expected, err := dbRepo.GetImageTagHistory(imageName, namespace)
+				if err != nil {
+					t.Fatalf("Failed to get history from database: %v", err)
+				}
+				got, err := memRepo.GetImageTagHistory(imageName, namespace)
+				if err != nil {
+					t.Fatalf("Failed to get history from memory: %v", err)
+				}
+
+				if !reflect.DeepEqual(comparableHistory(expected), comparableHistory(got)) {
+					t.Errorf("Expected %v, got %v", comparableHistory(expected), comparableHistory(got))
+				}
+			})
+		}
+	}
+
+	t.Run("active tags and running images", func(t *testing.T) {
+		expectedTags, _ := dbRepo.ListActiveImageTags()
+		gotTags, _ := memRepo.ListActiveImageTags()
+		if len(expectedTags) != len(gotTags) {
+			t.Errorf("Expected %d active tags, got %d", len(expectedTags), len(gotTags))
+		}
+		for _, it := range gotTags {
+			if it.Image.Name == "" {
+				t.Errorf("Expected image to be attached to tag %d", it.ID)
+			}
+		}
+
+		expectedRunning, _ := dbRepo.ListRunningImages()
+		gotRunning, _ := memRepo.ListRunningImages()
+		if len(expectedRunning) != len(gotRunning) {
+			t.Errorf("Expected %d running images, got %d", len(expectedRunning), len(gotRunning))
+		}
+	})
+
+	t.Run("missing image", func(t *testing.T) {
+		_, err := memRepo.GetImageTagHistory("missing", "")
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Expected record not found error, got %v", err)
+		}
+	})
+}
+
+func TestMemoryImageRepositoryConcurrency(t *testing.T) {
+	repo := NewMemoryImageRepository()
+	for i := 0; i < 5; i++ {
+		repo.UpsertImageTag("nginx", "docker.io", "1.25", "", "Deployment", fmt.Sprintf("web-%d", i), "default", "nginx")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			name := fmt.Sprintf("web-%d", i%5)
+			repo.UpsertImageTag("nginx", "docker.io", "1.26", "", "Deployment", name, "default", "nginx")
+			repo.ReplaceImageTag("nginx", "docker.io", "1.26", "", "Deployment", name, "default", "nginx")
+			repo.UpsertRunningImage("nginx", "docker.io", "1.26", "Deployment", name, "default", "nginx", name+"-pod", "sha256:abc")
+			repo.GetAllImages("")
+			repo.GetImageTagHistory("nginx", "default")
+		}(i)
+	}
+	wg.Wait()
+
+	images, err := repo.GetAllImages("")
+	if err != nil {
+		t.Fatalf("Failed to get images: %v", err)
+	}
+	if len(images) != 5 {
+		t.Errorf("Expected 5 images, got %d", len(images))
+	}
+
+	history, _ := repo.GetImageTagHistory("nginx", "")
+	if len(history.Tags) != 10 {
+		t.Errorf("Expected 10 history rows, got %d", len(history.Tags))
+	}
+}
+
+func TestMemoryImageRepositoryCapsLogs(t *testing.T) {
+	repo := NewMemoryImageRepository()
+
+	events := make([]models.ImageEvent, maxMemoryImageEvents+5)
+	for i := range events {
+		events[i] = models.ImageEvent{Type: "ADD", ResourceName: fmt.Sprintf("web-%d", i), ObservedAt: time.Now().UTC()}
+	}
+	if err := repo.AppendImageEvents(events); err != nil {
+		t.Fatalf("Failed to append image events: %v", err)
+	}
+	for i := 0; i < maxMemoryDeliveries+5; i++ {
+		if err := repo.AppendWebhookDelivery(&models.WebhookDelivery{Target: "audit", EventID: uint(i + 1)}); err != nil {
+			t.Fatalf("Failed to append webhook delivery: %v", err)
+		}
+	}
+
+	kept, _ := repo.ListImageEvents(models.ImageEventFilter{})
+	if len(kept) != maxMemoryImageEvents {
+		t.Errorf("Expected %d events, got %d", maxMemoryImageEvents, len(kept))
+	}
+	if oldest := kept[len(kept)-1]; oldest.ID != 6 {
+		t.Errorf("Expected the 5 oldest events to be dropped, oldest kept is %d", oldest.ID)
+	}
+
+	deliveries, _ := repo.ListWebhookDeliveries(models.WebhookDeliveryFilter{})
+	if len(deliveries) != maxMemoryDeliveries {
+		t.Errorf("Expected %d deliveries, got %d", maxMemoryDeliveries, len(deliveries))
+	}
+	if newest := deliveries[0]; newest.ID != uint(maxMemoryDeliveries+5) {
+		t.Errorf("Expected the newest delivery to keep ID %d, got %d", maxMemoryDeliveries+5, newest.ID)
+	}
+}
+
+func TestMemoryImageRepositorySnapshot(t *testing.T) {
+	t.Run("round trips every row", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snapshot.json")
+
+		repo := NewMemoryImageRepository()
+		applyParityScript(t, repo)
+		if err := repo.SaveSnapshot(path); err != nil {
+			t.Fatalf("Failed to save snapshot: %v", err)
+		}
+
+		restored := NewMemoryImageRepository()
+		if err := restored.LoadSnapshot(path); err != nil {
+			t.Fatalf("Failed to load snapshot: %v", err)
+		}
+
+		expected, _ := repo.GetAllImages("")
+		got, _ := restored.GetAllImages("")
+		if !reflect.DeepEqual(comparableImages(expected), comparableImages(got)) {
+			t.Errorf("Expected %+v, got %+v", comparableImages(expected), comparableImages(got))
+		}
+
+		expectedHistory, _ := repo.GetImageTagHistory("nginx", "")
+		gotHistory, _ := restored.GetImageTagHistory("nginx", "")
+		if !reflect.DeepEqual(comparableHistory(expectedHistory), comparableHistory(gotHistory)) {
+			t.Errorf("Expected %v, got %v", comparableHistory(expectedHistory), comparableHistory(gotHistory))
+		}
+
+		// New rows must not reuse restored IDs, and existing rows must still be found
+		restored.UpsertImageTag("nginx", "docker.io", "1.22", "", "Deployment", "web", "default", "nginx")
+		restored.UpsertImageTag("busybox", "docker.io", "1.36", "", "Job", "once", "default", "busybox")
+		tags, _ := restored.ListActiveImageTags()
+		seen := make(map[uint]bool)
+		for _, it := range tags {
+			if seen[it.ID] {
+				t.Errorf("Expected unique tag IDs, got %d twice", it.ID)
+			}
+			seen[it.ID] = true
+		}
+		if len(tags) != len(seen) || len(tags) != 5 {
+			t.Errorf("Expected 5 active tags, got %d", len(tags))
+		}
+	})
+
+	t.Run("missing file starts empty", func(t *testing.T) {
+		repo := NewMemoryImageRepository()
+		if err := repo.LoadSnapshot(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("corrupt file is rejected", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snapshot.json")
+		os.WriteFile(path, []byte(`{"images":[{"id":2,"full_name":"docker.io/nginx"}]}`), 0o600)
+
+		repo := NewMemoryImageRepository()
+		if err := repo.LoadSnapshot(path); err == nil {
+			t.Error("Expected error for snapshot with sparse image IDs, got nil")
+		}
+	})
+}
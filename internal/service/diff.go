@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+// DiffImages compares the inventories picked by two selectors
+// Containers are matched by resource and container name; across namespaces the namespace is ignored
+func (s *ImageService) DiffImages(ctx context.Context, left, right models.InventorySelector) (*models.ImageDiff, error) {
+	leftImages, err := s.selectInventory(ctx, left)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff images: %w", err)
+	}
+
+	rightImages, err := s.selectInventory(ctx, right)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff images: %w", err)
+	}
+
+	diff := diffImages(leftImages, rightImages, left.Namespace == right.Namespace)
+	diff.Left = left
+	diff.Right = right
+
+	return diff, nil
+}
+
+// selectInventory fetches the current inventory, or the one at the selector's instant
+func (s *ImageService) selectInventory(ctx context.Context, selector models.InventorySelector) ([]models.ImageInfo, error) {
+	if selector.At != nil {
+		return s.repo.GetImagesAt(selector.Namespace, *selector.At)
+	}
+
+	return s.repo.GetAllImages(selector.Namespace)
+}
+
+// diffImages matches the containers of both inventories and collects what was added, removed or changed
+func diffImages(left, right []models.ImageInfo, matchNamespace bool) *models.ImageDiff {
+	leftSides := diffSides(left, matchNamespace)
+	rightSides := diffSides(right, matchNamespace)
+
+	diff := &models.ImageDiff{
+		Added:   []models.ImageDiffEntry{},
+		Removed: []models.ImageDiffEntry{},
+		Changed: []models.ImageDiffEntry{},
+	}
+
+	for key, l := range leftSides {
+		r, found := rightSides[key]
+		switch {
+		case !found:
+			diff.Removed = append(diff.Removed, models.ImageDiffEntry{
+				ResourceType: l.resourceType, ResourceName: l.resourceName, Container: l.container, Left: &l.side,
+			})
+		case l.side.Repository != r.side.Repository || l.side.Name != r.side.Name ||
+			l.side.Tag != r.side.Tag || l.side.Digest != r.side.Digest:
+			diff.Changed = append(diff.Changed, models.ImageDiffEntry{
+				ResourceType: l.resourceType, ResourceName: l.resourceName, Container: l.container, Left: &l.side, Right: &r.side,
+			})
+		}
+	}
+
+	for key, r := range rightSides {
+		if _, found := leftSides[key]; !found {
+			diff.Added = append(diff.Added, models.ImageDiffEntry{
+				ResourceType: r.resourceType, ResourceName: r.resourceName, Container: r.container, Right: &r.side,
+			})
+		}
+	}
+
+	sortDiffEntries(diff.Added)
+	sortDiffEntries(diff.Removed)
+	sortDiffEntries(diff.Changed)
+
+	return diff
+}
+
+// diffContainer is one container of an inventory with the image it runs
+type diffContainer struct {
+	resourceType string
+	resourceName string
+	container    string
+	side         models.ImageDiffSide
+}
+
+// diffSides flattens an inventory into one entry per container, keyed by its identity
+func diffSides(images []models.ImageInfo, matchNamespace bool) map[string]diffContainer {
+	sides := make(map[string]diffContainer)
+
+	for _, img := range images {
+		for _, container := range img.Containers {
+			key := fmt.Sprintf("%s|%s|%s", img.ResourceType, img.ResourceName, container)
+			if matchNamespace {
+				key = img.Namespace + "|" + key
+			}
+
+			sides[key] = diffContainer{
+				resourceType: img.ResourceType,
+				resourceName: img.ResourceName,
+				container:    container,
+				side: models.ImageDiffSide{
+					Namespace:  img.Namespace,
+					Name:       img.Name,
+					Repository: img.Repository,
+					Tag:        img.Tag,
+					Digest:     img.Digest,
+				},
+			}
+		}
+	}
+
+	return sides
+}
+
+// sortDiffEntries orders entries by resource, namespace and container for a stable response
+func sortDiffEntries(entries []models.ImageDiffEntry) {
+	namespace := func(e models.ImageDiffEntry) string {
+		if e.Left != nil {
+			return e.Left.Namespace
+		}
+		return e.Right.Namespace
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.ResourceType != b.ResourceType {
+			return a.ResourceType < b.ResourceType
+		}
+		if a.ResourceName != b.ResourceName {
+			return a.ResourceName < b.ResourceName
+		}
+		if namespace(a) != namespace(b) {
+			return namespace(a) < namespace(b)
+		}
+		return a.Container < b.Container
+	})
+}
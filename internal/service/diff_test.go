@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/mocks"
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+func TestDiffImages(t *testing.T) {
+	web := func(namespace, tag string, containers ...string) models.ImageInfo {
+		return models.ImageInfo{
+			Name: "nginx", Tag: tag, ResourceType: "Deployment", ResourceName: "web",
+			Namespace: namespace, Containers: containers,
+		}
+	}
+	db := func(namespace, tag string) models.ImageInfo {
+		return models.ImageInfo{
+			Name: "postgres", Tag: tag, ResourceType: "StatefulSet", ResourceName: "db",
+			Namespace: namespace, Containers: []string{"postgres"},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		left           []models.ImageInfo
+		right          []models.ImageInfo
+		matchNamespace bool
+		added          []string
+		removed        []string
+		changed        []string
+	}{
+		{
+			name:           "identical inventories",
+			left:           []models.ImageInfo{web("default", "1.24", "nginx"), db("default", "16")},
+			right:          []models.ImageInfo{web("default", "1.24", "nginx"), db("default", "16")},
+			matchNamespace: true,
+		},
+		{
+			name:           "release window",
+			left:           []models.ImageInfo{web("default", "1.24", "nginx", "proxy"), db("default", "15")},
+			right:          []models.ImageInfo{web("default", "1.25", "nginx"), web("default", "1.24", "proxy"), web("default", "1.25", "metrics")},
+			matchNamespace: true,
+			added:          []string{"web/metrics"},
+			removed:        []string{"db/postgres"},
+			changed:        []string{"web/nginx"},
+		},
+		{
+			name:           "same resource in another namespace is a different container",
+			left:           []models.ImageInfo{web("staging", "1.25", "nginx")},
+			right:          []models.ImageInfo{web("production", "1.25", "nginx")},
+			matchNamespace: true,
+			added:          []string{"web/nginx"},
+			removed:        []string{"web/nginx"},
+		},
+		{
+			name:           "staging against production",
+			left:           []models.ImageInfo{web("staging", "1.25", "nginx"), db("staging", "16")},
+			right:          []models.ImageInfo{web("production", "1.24", "nginx"), db("production", "16")},
+			matchNamespace: false,
+			changed:        []string{"web/nginx"},
+		},
+		{
+			name: "same tag from another repository",
+			left: []models.ImageInfo{{Name: "redis", Repository: "docker.io", Tag: "7.2", ResourceType: "Deployment",
+				ResourceName: "cache", Namespace: "default", Containers: []string{"redis"}}},
+			right: []models.ImageInfo{{Name: "redis", Repository: "ghcr.io/bitnami", Tag: "7.2", ResourceType: "Deployment",
+				ResourceName: "cache", Namespace: "default", Containers: []string{"redis"}}},
+			matchNamespace: true,
+			changed:        []string{"cache/redis"},
+		},
+		{
+			name: "digest change on the same tag",
+			left: []models.ImageInfo{{Name: "nginx", Tag: "latest", Digest: "sha256:aaa", ResourceType: "Deployment",
+				ResourceName: "web", Namespace: "default", Containers: []string{"nginx"}}},
+			right: []models.ImageInfo{{Name: "nginx", Tag: "latest", Digest: "sha256:bbb", ResourceType: "Deployment",
+				ResourceName: "web", Namespace: "default", Containers: []string{"nginx"}}},
+			matchNamespace: true,
+			changed:        []string{"web/nginx"},
+		},
+	}
+
+	names := func(entries []models.ImageDiffEntry) []string {
+		var result []string
+		for _, e := range entries {
+			result = append(result, e.ResourceName+"/"+e.Container)
+		}
+		return result
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := diffImages(tt.left, tt.right, tt.matchNamespace)
+
+			for _, check := range []struct {
+				kind     string
+				got      []string
+				expected []string
+			}{
+				{"added", names(diff.Added), tt.added},
+				{"removed", names(diff.Removed), tt.removed},
+				{"changed", names(diff.Changed), tt.changed},
+			} {
+				if len(check.got) != len(check.expected) {
+					t.Errorf("Expected %s %v, got %v", check.kind, check.expected, check.got)
+					continue
+				}
+				for i := range check.got {
+					if check.got[i] != check.expected[i] {
+						t.Errorf("Expected %s %v, got %v", check.kind, check.expected, check.got)
+						break
+					}
+				}
+			}
+
+			for _, e := range diff.Changed {
+				if e.Left == nil || e.Right == nil {
+					t.Fatalf("Expected both sides for changed %s/%s", e.ResourceName, e.Container)
+				}
+			}
+			for _, e := range diff.Added {
+				if e.Left != nil || e.Right == nil {
+					t.Errorf("Expected only the right side for added %s/%s", e.ResourceName, e.Container)
+				}
+			}
+			for _, e := range diff.Removed {
+				if e.Left == nil || e.Right != nil {
+					t.Errorf("Expected only the left side for removed %s/%s", e.ResourceName, e.Container)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffImagesSelectors(t *testing.T) {
+	from := time.Date(2024, 1, 2, 14, 0, 0, 0, time.UTC)
+
+	t.Run("past instant against the current inventory", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().GetImagesAt("default", from).Return([]models.ImageInfo{
+			{Name: "nginx", Tag: "1.24", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", Containers: []string{"nginx"}},
+		}, nil).Once()
+		mockRepo.EXPECT().GetAllImages("default").Return([]models.ImageInfo{
+			{Name: "nginx", Tag: "1.25", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", Containers: []string{"nginx"}},
+		}, nil).Once()
+
+		left := models.InventorySelector{Namespace: "default", At: &from}
+		right := models.InventorySelector{Namespace: "default"}
+
+		diff, err := NewImageService(mockRepo, nil).DiffImages(context.Background(), left, right)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(diff.Changed) != 1 || diff.Changed[0].Left.Tag != "1.24" || diff.Changed[0].Right.Tag != "1.25" {
+			t.Errorf("Expected nginx 1.24 -> 1.25, got %+v", diff.Changed)
+		}
+		if diff.Left.At == nil || !diff.Left.At.Equal(from) {
+			t.Errorf("Expected left selector to be echoed, got %+v", diff.Left)
+		}
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().GetAllImages("staging").Return(nil, errors.New("database error")).Once()
+
+		_, err := NewImageService(mockRepo, nil).DiffImages(context.Background(),
+			models.InventorySelector{Namespace: "staging"}, models.InventorySelector{Namespace: "production"})
+		if err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+}
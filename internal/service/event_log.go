@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/huseyinbabal/kubetag/internal/k8s"
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+// imageEventRecord converts an informer event into an image event log entry
+// The image of DELETE and REMOVED events is the one that went away, so it is recorded as the old image
+func imageEventRecord(event k8s.ImageEvent) models.ImageEvent {
+	record := models.ImageEvent{
+		Type:          string(event.Type),
+		Change:        string(event.Change),
+		ResourceType:  event.ResourceType,
+		ResourceName:  event.ResourceName,
+		Namespace:     event.Namespace,
+		ContainerName: event.ContainerName,
+		ObservedAt:    event.Timestamp.UTC(),
+	}
+
+	if event.Type == k8s.EventTypeDelete || event.Change == k8s.ContainerRemoved {
+		record.OldImageName = event.ImageName
+		record.OldRepository = event.Repository
+		record.OldTag = event.ImageTag
+		record.OldDigest = event.ImageDigest
+		return record
+	}
+
+	record.ImageName = event.ImageName
+	record.Repository = event.Repository
+	record.NewTag = event.ImageTag
+	record.NewDigest = event.ImageDigest
+
+	if event.Previous != nil {
+		record.OldImageName = event.Previous.Name
+		record.OldRepository = event.Previous.Repository
+		record.OldTag = event.Previous.Tag
+		record.OldDigest = event.Previous.Digest
+	}
+
+	return record
+}
+
+// appendImageEvents records image changes in the event log, then streams and notifies them
+func (s *ImageService) appendImageEvents(events ...k8s.ImageEvent) error {
+	records := make([]models.ImageEvent, 0, len(events))
+	for _, event := range events {
+		records = append(records, imageEventRecord(event))
+	}
+
+	if err := s.repo.AppendImageEvents(records); err != nil {
+		return fmt.Errorf("failed to append image events: %w", err)
+	}
+
+	s.publishImageEvents(records)
+	return nil
+}
+
+// publishImageEvents streams and notifies image events written to the event log
+// Only persisted events are published, so a resuming client finds them in the log
+func (s *ImageService) publishImageEvents(records []models.ImageEvent) {
+	s.broker.publish(records)
+	if s.notifier != nil {
+		s.notifier.Notify(records)
+	}
+}
+
+// GetImageEvents retrieves image event log entries, newest first
+func (s *ImageService) GetImageEvents(ctx context.Context, filter models.ImageEventFilter) (*models.ImageEventsResponse, error) {
+	events, err := s.repo.ListImageEvents(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image events: %w", err)
+	}
+
+	return &models.ImageEventsResponse{
+		Events: events,
+		Total:  len(events),
+	}, nil
+}
+
+// SetNotifier registers the notifier told about persisted image events
+// It must be called before the event queue starts
+func (s *ImageService) SetNotifier(notifier ImageEventNotifier) {
+	s.notifier = notifier
+}
+
+// GetWebhookDeliveries retrieves webhook delivery attempts, newest first
+func (s *ImageService) GetWebhookDeliveries(ctx context.Context, filter models.WebhookDeliveryFilter) (*models.WebhookDeliveriesResponse, error) {
+	deliveries, err := s.repo.ListWebhookDeliveries(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+
+	return &models.WebhookDeliveriesResponse{
+		Deliveries: deliveries,
+		Total:      len(deliveries),
+	}, nil
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/k8s"
+	"github.com/huseyinbabal/kubetag/internal/mocks"
+	"github.com/huseyinbabal/kubetag/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestImageEventRecord(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		event    k8s.ImageEvent
+		newTag   string
+		oldTag   string
+		oldImage string
+	}{
+		{
+			name:   "added container has no old image",
+			event:  k8s.ImageEvent{Type: k8s.EventTypeAdd, ImageName: "nginx", ImageTag: "1.24", Timestamp: now},
+			newTag: "1.24",
+		},
+		{
+			name: "changed container records both images",
+			event: k8s.ImageEvent{
+				Type: k8s.EventTypeUpdate, Change: k8s.ContainerChanged, ImageName: "nginx", ImageTag: "1.25", Timestamp: now,
+				Previous: &k8s.ImageReference{Name: "nginx", Tag: "1.24"},
+			},
+			newTag:   "1.25",
+			oldTag:   "1.24",
+			oldImage: "nginx",
+		},
+		{
+			name:     "removed container records the image as old",
+			event:    k8s.ImageEvent{Type: k8s.EventTypeUpdate, Change: k8s.ContainerRemoved, ImageName: "sidecar", ImageTag: "v1", Timestamp: now},
+			oldTag:   "v1",
+			oldImage: "sidecar",
+		},
+		{
+			name:     "deleted resource records the image as old",
+			event:    k8s.ImageEvent{Type: k8s.EventTypeDelete, ImageName: "nginx", ImageTag: "1.25", Timestamp: now},
+			oldTag:   "1.25",
+			oldImage: "nginx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := imageEventRecord(tt.event)
+
+			if record.NewTag != tt.newTag {
+				t.Errorf("Expected new tag %q, got %q", tt.newTag, record.NewTag)
+			}
+			if record.OldTag != tt.oldTag {
+				t.Errorf("Expected old tag %q, got %q", tt.oldTag, record.OldTag)
+			}
+			if record.OldImageName != tt.oldImage {
+				t.Errorf("Expected old image %q, got %q", tt.oldImage, record.OldImageName)
+			}
+			if record.Type != string(tt.event.Type) {
+				t.Errorf("Expected type %s, got %s", tt.event.Type, record.Type)
+			}
+			if !record.ObservedAt.Equal(now) {
+				t.Errorf("Expected observed at %v, got %v", now, record.ObservedAt)
+			}
+		})
+	}
+}
+
+// recordingNotifier records the events it is told about
+type recordingNotifier struct {
+	events []models.ImageEvent
+}
+
+func (n *recordingNotifier) Notify(events []models.ImageEvent) {
+	n.events = append(n.events, events...)
+}
+
+func TestAppendImageEventsNotifies(t *testing.T) {
+	event := k8s.ImageEvent{Type: k8s.EventTypeAdd, ImageName: "nginx", ImageTag: "1.25", Namespace: "default"}
+
+	t.Run("notifies persisted events with their IDs", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().AppendImageEvents(mock.Anything).
+			Run(func(events []models.ImageEvent) { events[0].ID = 7 }).
+			Return(nil).Once()
+
+		notifier := &recordingNotifier{}
+		svc := NewImageService(mockRepo, nil)
+		svc.SetNotifier(notifier)
+
+		if err := svc.appendImageEvents(event); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(notifier.events) != 1 || notifier.events[0].ID != 7 || notifier.events[0].NewTag != "1.25" {
+			t.Errorf("Expected event 7 for 1.25, got %+v", notifier.events)
+		}
+	})
+
+	t.Run("does not notify events that failed to persist", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().AppendImageEvents(mock.Anything).Return(errors.New("database error")).Once()
+
+		notifier := &recordingNotifier{}
+		svc := NewImageService(mockRepo, nil)
+		svc.SetNotifier(notifier)
+
+		if err := svc.appendImageEvents(event); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+
+		if len(notifier.events) != 0 {
+			t.Errorf("Expected no notified events, got %+v", notifier.events)
+		}
+	})
+}
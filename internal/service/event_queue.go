@@ -0,0 +1,409 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/k8s"
+	"github.com/huseyinbabal/kubetag/internal/models"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// EventQueueConfig configures the queue between the informers and the repository
+type EventQueueConfig struct {
+	Workers    int           // Resources processed concurrently
+	MaxDepth   int           // Events buffered before new ones are dropped
+	MaxRetries int           // Retries per event before it is dropped
+	BaseDelay  time.Duration // First retry delay, doubled on every retry
+	MaxDelay   time.Duration // Upper bound for the retry delay
+
+	// ADD events are coalesced and written with one batch upsert once BatchSize events
+	// are buffered or BatchWindow has passed; a zero BatchSize disables batching
+	BatchSize   int
+	BatchWindow time.Duration
+}
+
+// DefaultEventQueueConfig returns the event queue defaults
+func DefaultEventQueueConfig() EventQueueConfig {
+	return EventQueueConfig{
+		Workers:    4,
+		MaxDepth:   10000,
+		MaxRetries: 5,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   30 * time.Second,
+
+		BatchSize:   500,
+		BatchWindow: time.Second,
+	}
+}
+
+// EventQueueStats reports the state of the event queue
+type EventQueueStats struct {
+	Depth   int   // Events waiting to be processed
+	Retries int64 // Failed attempts that were scheduled for a retry
+	Dropped int64 // Events dropped because the queue was full or retries ran out
+}
+
+// eventQueue buffers image events per resource and retries failed writes with backoff
+// A resource is only processed by one worker at a time, so its events stay in order.
+// ADD events of otherwise idle resources are written in batches; anything that follows
+// a batched event of the same resource waits until the batch has been written.
+type eventQueue struct {
+	config  EventQueueConfig
+	queue   workqueue.TypedRateLimitingInterface[string]
+	process func(event k8s.ImageEvent) error
+	flush   func(events []k8s.ImageEvent) error
+
+	mu      sync.Mutex
+	pending map[string][]k8s.ImageEvent
+	active  map[string]bool // Resources a worker is processing
+	batch   []k8s.ImageEvent
+	batched map[string]int // Events per resource in the batch or in a flush in progress
+	depth   int
+	full    chan struct{}
+
+	retries atomic.Int64
+	dropped atomic.Int64
+}
+
+// newEventQueue creates an event queue that hands events to process, and batches of ADD events to flush
+func newEventQueue(config EventQueueConfig, process func(event k8s.ImageEvent) error, flush func(events []k8s.ImageEvent) error) *eventQueue {
+	return &eventQueue{
+		config: config,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.NewTypedItemExponentialFailureRateLimiter[string](config.BaseDelay, config.MaxDelay),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "image-events"},
+		),
+		process: process,
+		flush:   flush,
+		pending: make(map[string][]k8s.ImageEvent),
+		active:  make(map[string]bool),
+		batched: make(map[string]int),
+		full:    make(chan struct{}, 1),
+	}
+}
+
+// batching reports whether ADD events are coalesced into batches
+func (q *eventQueue) batching() bool {
+	return q.flush != nil && q.config.BatchSize > 0 && q.config.BatchWindow > 0
+}
+
+// eventKey identifies the resource an event belongs to
+func eventKey(event k8s.ImageEvent) string {
+	return fmt.Sprintf("%s/%s/%s", event.Namespace, event.ResourceType, event.ResourceName)
+}
+
+// add buffers an event for its resource, dropping it when the queue is full
+func (q *eventQueue) add(event k8s.ImageEvent) {
+	key := eventKey(event)
+
+	q.mu.Lock()
+	if q.depth >= q.config.MaxDepth {
+		q.mu.Unlock()
+		q.dropped.Add(1)
+		log.Printf("Event queue full, dropping %s event for %s", event.Type, key)
+		return
+	}
+	q.depth++
+
+	if q.batching() && event.Type == k8s.EventTypeAdd && len(q.pending[key]) == 0 && !q.active[key] {
+		q.batch = append(q.batch, event)
+		q.batched[key]++
+		full := len(q.batch) >= q.config.BatchSize
+		q.mu.Unlock()
+
+		if full {
+			select {
+			case q.full <- struct{}{}:
+			default:
+			}
+		}
+		return
+	}
+
+	q.pending[key] = append(q.pending[key], event)
+	waiting := q.batched[key] > 0
+	q.mu.Unlock()
+
+	// The resource is queued once its batched events have been written
+	if !waiting {
+		q.queue.Add(key)
+	}
+}
+
+// run starts the workers and shuts the queue down when the context is cancelled, after writing the last batch
+func (q *eventQueue) run(ctx context.Context) {
+	for i := 0; i < q.config.Workers; i++ {
+		go func() {
+			for q.processNext() {
+			}
+		}()
+	}
+
+	if !q.batching() {
+		go func() {
+			<-ctx.Done()
+			q.queue.ShutDown()
+		}()
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(q.config.BatchWindow)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				// Events of a failed last batch are handed to the workers, which finish them before shutting down
+				q.flushBatch()
+				q.queue.ShutDown()
+				return
+			case <-ticker.C:
+				q.flushBatch()
+			case <-q.full:
+				q.flushBatch()
+			}
+		}
+	}()
+}
+
+// flushBatch writes the buffered ADD events with one batch upsert
+// On failure the events fall back to being processed one by one, with retries
+func (q *eventQueue) flushBatch() {
+	q.mu.Lock()
+	events := q.batch
+	q.batch = nil
+	q.mu.Unlock()
+
+	if len(events) == 0 {
+		return
+	}
+
+	err := q.flush(events)
+	if err != nil {
+		log.Printf("Error writing batch of %d events, retrying them one by one: %v", len(events), err)
+		q.retries.Add(int64(len(events)))
+	}
+
+	q.mu.Lock()
+	var keys []string
+	failed := make(map[string][]k8s.ImageEvent)
+	for _, event := range events {
+		key := eventKey(event)
+		if _, found := failed[key]; !found {
+			keys = append(keys, key)
+		}
+		failed[key] = append(failed[key], event)
+
+		q.batched[key]--
+		if q.batched[key] == 0 {
+			delete(q.batched, key)
+		}
+	}
+
+	if err == nil {
+		q.depth -= len(events)
+	} else {
+		// Batched events are older than anything that arrived for the resource meanwhile
+		for _, key := range keys {
+			q.pending[key] = append(failed[key], q.pending[key]...)
+		}
+	}
+
+	var ready []string
+	for _, key := range keys {
+		if q.batched[key] == 0 && len(q.pending[key]) > 0 {
+			ready = append(ready, key)
+		}
+	}
+	q.mu.Unlock()
+
+	for _, key := range ready {
+		q.queue.Add(key)
+	}
+}
+
+// processNext processes the buffered events of the next resource, returning false once shut down
+func (q *eventQueue) processNext() bool {
+	key, shutdown := q.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer q.queue.Done(key)
+
+	q.mu.Lock()
+	events := q.pending[key]
+	delete(q.pending, key)
+	q.depth -= len(events)
+	q.active[key] = true
+	q.mu.Unlock()
+
+	defer func() {
+		q.mu.Lock()
+		delete(q.active, key)
+		q.mu.Unlock()
+	}()
+
+	for i, event := range events {
+		err := q.process(event)
+		if err == nil {
+			continue
+		}
+
+		if q.queue.NumRequeues(key) < q.config.MaxRetries {
+			q.retries.Add(1)
+			log.Printf("Error processing %s event for %s, retrying: %v", event.Type, key, err)
+			q.requeue(key, events[i:])
+			q.queue.AddRateLimited(key)
+			return true
+		}
+
+		q.dropped.Add(1)
+		log.Printf("Error processing %s event for %s, dropping after %d retries: %v", event.Type, key, q.config.MaxRetries, err)
+		q.queue.Forget(key)
+
+		if rest := events[i+1:]; len(rest) > 0 {
+			q.requeue(key, rest)
+			q.queue.Add(key)
+		}
+		return true
+	}
+
+	q.queue.Forget(key)
+	return true
+}
+
+// requeue puts unprocessed events back in front of anything buffered for the resource meanwhile
+func (q *eventQueue) requeue(key string, events []k8s.ImageEvent) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pending[key] = append(append([]k8s.ImageEvent(nil), events...), q.pending[key]...)
+	q.depth += len(events)
+}
+
+// drain writes the buffered batch right away and waits until every queued event was written or dropped
+func (q *eventQueue) drain(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		q.mu.Lock()
+		idle := q.depth == 0 && len(q.active) == 0
+		batched := len(q.batch) > 0
+		q.mu.Unlock()
+
+		if idle {
+			return nil
+		}
+		if batched {
+			select {
+			case q.full <- struct{}{}:
+			default:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// stats returns the current queue depth and cumulative counters
+func (q *eventQueue) stats() EventQueueStats {
+	q.mu.Lock()
+	depth := q.depth
+	q.mu.Unlock()
+
+	return EventQueueStats{
+		Depth:   depth,
+		Retries: q.retries.Load(),
+		Dropped: q.dropped.Load(),
+	}
+}
+
+// StartEventQueue starts processing queued image events until the context is cancelled
+// It must be called before the informers start so no event bypasses the queue
+func (s *ImageService) StartEventQueue(ctx context.Context, config EventQueueConfig) {
+	s.queue = newEventQueue(config, s.processImageEvent, s.upsertImageTagBatch)
+	s.queue.run(ctx)
+}
+
+// upsertImageTagBatch writes a batch of ADD events with one repository call
+// The tags and the event log entries of the new ones are written together, so a failed batch
+// is retried without the entries going missing because the tags were already recorded
+func (s *ImageService) upsertImageTagBatch(events []k8s.ImageEvent) error {
+	tags := make([]models.ImageTagUpsert, 0, len(events))
+	records := make([]models.ImageEvent, len(events))
+	for i, event := range events {
+		records[i] = imageEventRecord(event)
+		tags = append(tags, models.ImageTagUpsert{
+			ImageName:     event.ImageName,
+			Repository:    event.Repository,
+			Tag:           event.ImageTag,
+			Digest:        event.ImageDigest,
+			ResourceType:  event.ResourceType,
+			ResourceName:  event.ResourceName,
+			Namespace:     event.Namespace,
+			ContainerName: event.ContainerName,
+			Event:         &records[i],
+		})
+	}
+
+	created, err := s.repo.UpsertImageTags(tags)
+	if err != nil {
+		return fmt.Errorf("failed to upsert image tag batch: %w", err)
+	}
+
+	// Tags that were already recorded, e.g. on every restart, are not changes
+	var changes []models.ImageEvent
+	for i, record := range records {
+		if created[i] {
+			changes = append(changes, record)
+		}
+	}
+	if len(changes) > 0 {
+		s.publishImageEvents(changes)
+	}
+
+	log.Printf("Upserted batch of %d image tags", len(tags))
+	return nil
+}
+
+// EnqueueImageEvent queues an image event for processing, or handles it inline when no queue is running
+func (s *ImageService) EnqueueImageEvent(event k8s.ImageEvent) {
+	if s.queue == nil {
+		s.HandleImageEvent(event)
+		return
+	}
+
+	s.queue.add(event)
+}
+
+// DrainEventQueue waits until the queued image events were written, or the context is done
+func (s *ImageService) DrainEventQueue(ctx context.Context) error {
+	if s.queue == nil {
+		return nil
+	}
+
+	if err := s.queue.drain(ctx); err != nil {
+		return fmt.Errorf("failed to drain event queue: %w", err)
+	}
+	return nil
+}
+
+// EventQueueStats returns the state of the event queue; zero when no queue is running
+func (s *ImageService) EventQueueStats() EventQueueStats {
+	if s.queue == nil {
+		return EventQueueStats{}
+	}
+
+	return s.queue.stats()
+}
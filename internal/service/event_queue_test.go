@@ -0,0 +1,520 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/k8s"
+	"github.com/huseyinbabal/kubetag/internal/mocks"
+	"github.com/huseyinbabal/kubetag/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// testQueueConfig retries quickly so tests do not wait on backoff
+func testQueueConfig() EventQueueConfig {
+	return EventQueueConfig{
+		Workers:    2,
+		MaxDepth:   100,
+		MaxRetries: 3,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   10 * time.Millisecond,
+	}
+}
+
+// waitFor polls until condition holds or fails the test after a few seconds
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatal("Timed out waiting for condition")
+}
+
+func TestEventQueue(t *testing.T) {
+	event := func(resourceName, tag string) k8s.ImageEvent {
+		return k8s.ImageEvent{
+			Type:         k8s.EventTypeUpdate,
+			ResourceType: "Deployment",
+			ResourceName: resourceName,
+			Namespace:    "default",
+			ImageName:    "app",
+			ImageTag:     tag,
+		}
+	}
+
+	t.Run("retries failed events in order", func(t *testing.T) {
+		var mu sync.Mutex
+		var processed []string
+		failures := 2
+
+		q := newEventQueue(testQueueConfig(), func(event k8s.ImageEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if event.ImageTag == "v1" && failures > 0 {
+				failures--
+				return errors.New("database unavailable")
+			}
+			processed = append(processed, event.ImageTag)
+			return nil
+		}, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		q.run(ctx)
+
+		q.add(event("web", "v1"))
+		q.add(event("web", "v2"))
+
+		waitFor(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(processed) == 2
+		})
+
+		mu.Lock()
+		defer mu.Unlock()
+		if processed[0] != "v1" || processed[1] != "v2" {
+			t.Errorf("Expected [v1 v2], got %v", processed)
+		}
+
+		stats := q.stats()
+		if stats.Retries != 2 {
+			t.Errorf("Expected 2 retries, got %d", stats.Retries)
+		}
+		if stats.Dropped != 0 || stats.Depth != 0 {
+			t.Errorf("Expected nothing dropped or pending, got %d dropped and depth %d", stats.Dropped, stats.Depth)
+		}
+	})
+
+	t.Run("drops events once retries run out", func(t *testing.T) {
+		var mu sync.Mutex
+		var processed []string
+
+		q := newEventQueue(testQueueConfig(), func(event k8s.ImageEvent) error {
+			if event.ImageTag == "broken" {
+				return errors.New("constraint violation")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			processed = append(processed, event.ImageTag)
+			return nil
+		}, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		q.run(ctx)
+
+		q.add(event("web", "broken"))
+		q.add(event("web", "v2"))
+
+		waitFor(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(processed) == 1
+		})
+
+		stats := q.stats()
+		if stats.Retries != 3 {
+			t.Errorf("Expected 3 retries, got %d", stats.Retries)
+		}
+		if stats.Dropped != 1 {
+			t.Errorf("Expected 1 dropped event, got %d", stats.Dropped)
+		}
+	})
+
+	t.Run("drops new events when the queue is full", func(t *testing.T) {
+		config := testQueueConfig()
+		config.MaxDepth = 2
+
+		// Not running, so nothing drains the queue
+		q := newEventQueue(config, func(event k8s.ImageEvent) error { return nil }, nil)
+		defer q.queue.ShutDown()
+
+		q.add(event("web", "v1"))
+		q.add(event("api", "v1"))
+		q.add(event("worker", "v1"))
+
+		stats := q.stats()
+		if stats.Depth != 2 {
+			t.Errorf("Expected depth 2, got %d", stats.Depth)
+		}
+		if stats.Dropped != 1 {
+			t.Errorf("Expected 1 dropped event, got %d", stats.Dropped)
+		}
+	})
+}
+
+func TestEnqueueImageEvent(t *testing.T) {
+	event := k8s.ImageEvent{
+		Type:         k8s.EventTypeDelete,
+		ResourceType: "Deployment",
+		ResourceName: "web",
+		Namespace:    "default",
+	}
+
+	t.Run("writes ADD events with the batch API", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+
+		done := make(chan struct{})
+		mockRepo.EXPECT().UpsertImageTags(mock.Anything).
+			Run(func(tags []models.ImageTagUpsert) {
+				if len(tags) != 1 || tags[0].ImageName != "nginx" || tags[0].Tag != "1.25" || tags[0].ContainerName != "nginx" {
+					t.Errorf("Expected the nginx tag, got %+v", tags)
+				} else if tags[0].Event == nil || tags[0].Event.Type != "ADD" || tags[0].Event.NewTag != "1.25" {
+					t.Errorf("Expected the ADD event to be logged with the tag, got %+v", tags[0].Event)
+				}
+				close(done)
+			}).
+			Return([]bool{true}, nil).
+			Once()
+
+		service := NewImageService(mockRepo, nil)
+
+		config := testQueueConfig()
+		config.BatchSize = 100
+		config.BatchWindow = 10 * time.Millisecond
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		service.StartEventQueue(ctx, config)
+
+		service.EnqueueImageEvent(k8s.ImageEvent{
+			Type:          k8s.EventTypeAdd,
+			ImageName:     "nginx",
+			Repository:    "docker.io",
+			ImageTag:      "1.25",
+			ResourceType:  "Deployment",
+			ResourceName:  "web",
+			Namespace:     "default",
+			ContainerName: "nginx",
+		})
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the batch")
+		}
+	})
+
+	t.Run("only logs batched tags that were not recorded yet", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().UpsertImageTags(mock.Anything).
+			Run(func(tags []models.ImageTagUpsert) { tags[1].Event.ID = 7 }).
+			Return([]bool{false, true}, nil).
+			Once()
+
+		notifier := &recordingNotifier{}
+		service := NewImageService(mockRepo, nil)
+		service.SetNotifier(notifier)
+		err := service.upsertImageTagBatch([]k8s.ImageEvent{
+			{Type: k8s.EventTypeAdd, ImageName: "nginx", ImageTag: "1.25", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", ContainerName: "nginx"},
+			{Type: k8s.EventTypeAdd, ImageName: "redis", ImageTag: "7", ResourceType: "StatefulSet", ResourceName: "cache", Namespace: "default", ContainerName: "redis"},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(notifier.events) != 1 || notifier.events[0].ResourceName != "cache" || notifier.events[0].ID != 7 {
+			t.Errorf("Expected only the logged event of the new cache tag, got %+v", notifier.events)
+		}
+	})
+
+	t.Run("nothing is logged when every batched tag was recorded", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().UpsertImageTags(mock.Anything).Return([]bool{false}, nil).Once()
+
+		service := NewImageService(mockRepo, nil)
+		err := service.upsertImageTagBatch([]k8s.ImageEvent{
+			{Type: k8s.EventTypeAdd, ImageName: "nginx", ImageTag: "1.25", ResourceType: "Deployment", ResourceName: "web", Namespace: "default", ContainerName: "nginx"},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("a batch whose event log append failed is written again", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+
+		// The append failing rolls back the batch, the event is then written on its own
+		mockRepo.EXPECT().UpsertImageTags(mock.Anything).Return(nil, errors.New("failed to append image events")).Once()
+		mockRepo.EXPECT().UpsertImageTag("nginx", "docker.io/library", "1.25", "", "Deployment", "web", "default", "nginx").Return(true, nil).Once()
+		mockRepo.EXPECT().AppendImageEvents(mock.Anything).Return(nil).Once()
+
+		notifier := &recordingNotifier{}
+		service := NewImageService(mockRepo, nil)
+		service.SetNotifier(notifier)
+
+		config := testQueueConfig()
+		config.BatchSize = 100
+		config.BatchWindow = time.Hour
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		service.StartEventQueue(ctx, config)
+
+		service.EnqueueImageEvent(k8s.ImageEvent{
+			Type:          k8s.EventTypeAdd,
+			ImageName:     "nginx",
+			Repository:    "docker.io/library",
+			ImageTag:      "1.25",
+			ResourceType:  "Deployment",
+			ResourceName:  "web",
+			Namespace:     "default",
+			ContainerName: "nginx",
+		})
+
+		if err := service.DrainEventQueue(ctx); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(notifier.events) != 1 || notifier.events[0].Type != "ADD" || notifier.events[0].NewTag != "1.25" {
+			t.Errorf("Expected the ADD event to be logged, got %+v", notifier.events)
+		}
+	})
+
+	t.Run("the last batch is written before shutting down", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+
+		done := make(chan struct{})
+		mockRepo.EXPECT().UpsertImageTags(mock.Anything).Return(nil, errors.New("database error")).Once()
+		mockRepo.EXPECT().UpsertImageTag("nginx", "docker.io/library", "1.25", "", "Deployment", "web", "default", "nginx").Return(true, nil).Once()
+		mockRepo.EXPECT().AppendImageEvents(mock.Anything).
+			Run(func(events []models.ImageEvent) { close(done) }).
+			Return(nil).
+			Once()
+
+		service := NewImageService(mockRepo, nil)
+
+		config := testQueueConfig()
+		config.BatchSize = 100
+		config.BatchWindow = time.Hour
+
+		ctx, cancel := context.WithCancel(context.Background())
+		service.StartEventQueue(ctx, config)
+
+		service.EnqueueImageEvent(k8s.ImageEvent{
+			Type:          k8s.EventTypeAdd,
+			ImageName:     "nginx",
+			Repository:    "docker.io/library",
+			ImageTag:      "1.25",
+			ResourceType:  "Deployment",
+			ResourceName:  "web",
+			Namespace:     "default",
+			ContainerName: "nginx",
+		})
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the failed batch to be processed by the workers")
+		}
+	})
+
+	t.Run("retries repository errors through the queue", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+
+		done := make(chan struct{})
+		mockRepo.EXPECT().DeleteImageTag("Deployment", "web", "default", "").Return(errors.New("database error")).Once()
+		mockRepo.EXPECT().DeleteImageTag("Deployment", "web", "default", "").Return(nil).Once()
+		mockRepo.EXPECT().AppendImageEvents(mock.Anything).
+			Run(func(events []models.ImageEvent) { close(done) }).
+			Return(nil).
+			Once()
+
+		service := NewImageService(mockRepo, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		service.StartEventQueue(ctx, testQueueConfig())
+
+		service.EnqueueImageEvent(event)
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the retry")
+		}
+
+		if retries := service.EventQueueStats().Retries; retries != 1 {
+			t.Errorf("Expected 1 retry, got %d", retries)
+		}
+	})
+
+	t.Run("handles events inline without a queue", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().DeleteImageTag("Deployment", "web", "default", "").Return(nil).Once()
+		mockRepo.EXPECT().AppendImageEvents(mock.Anything).Return(nil).Once()
+
+		service := NewImageService(mockRepo, nil)
+		service.EnqueueImageEvent(event)
+
+		if stats := service.EventQueueStats(); stats != (EventQueueStats{}) {
+			t.Errorf("Expected empty stats without a queue, got %+v", stats)
+		}
+	})
+}
+
+func TestEventQueueBatching(t *testing.T) {
+	addEvent := func(resourceName string) k8s.ImageEvent {
+		return k8s.ImageEvent{
+			Type:         k8s.EventTypeAdd,
+			ResourceType: "Deployment",
+			ResourceName: resourceName,
+			Namespace:    "default",
+			ImageName:    "app",
+			ImageTag:     "v1",
+		}
+	}
+	batchConfig := func() EventQueueConfig {
+		config := testQueueConfig()
+		config.BatchSize = 3
+		config.BatchWindow = time.Hour // Only a full batch triggers a flush
+		return config
+	}
+
+	t.Run("writes ADD events in batches before later events of the same resource", func(t *testing.T) {
+		var mu sync.Mutex
+		var order []string
+
+		q := newEventQueue(batchConfig(), func(event k8s.ImageEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, string(event.Type)+":"+event.ResourceName)
+			return nil
+		}, func(events []k8s.ImageEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, "BATCH:"+strconv.Itoa(len(events)))
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		q.run(ctx)
+
+		q.add(addEvent("web"))
+		q.add(k8s.ImageEvent{Type: k8s.EventTypeDelete, ResourceType: "Deployment", ResourceName: "web", Namespace: "default"})
+		q.add(addEvent("api"))
+		q.add(addEvent("worker"))
+
+		waitFor(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(order) == 2
+		})
+
+		mu.Lock()
+		defer mu.Unlock()
+		if order[0] != "BATCH:3" || order[1] != "DELETE:web" {
+			t.Errorf("Expected [BATCH:3 DELETE:web], got %v", order)
+		}
+		if depth := q.stats().Depth; depth != 0 {
+			t.Errorf("Expected depth 0, got %d", depth)
+		}
+	})
+
+	t.Run("falls back to single writes when the batch fails", func(t *testing.T) {
+		var mu sync.Mutex
+		var processed []string
+
+		q := newEventQueue(batchConfig(), func(event k8s.ImageEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			processed = append(processed, event.ResourceName)
+			return nil
+		}, func(events []k8s.ImageEvent) error {
+			return errors.New("database unavailable")
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		q.run(ctx)
+
+		q.add(addEvent("web"))
+		q.add(addEvent("api"))
+		q.add(addEvent("worker"))
+
+		waitFor(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(processed) == 3
+		})
+
+		if retries := q.stats().Retries; retries != 3 {
+			t.Errorf("Expected 3 retries, got %d", retries)
+		}
+	})
+
+	t.Run("drain writes a partial batch and waits for every event", func(t *testing.T) {
+		var mu sync.Mutex
+		var order []string
+
+		q := newEventQueue(batchConfig(), func(event k8s.ImageEvent) error {
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, string(event.Type)+":"+event.ResourceName)
+			return nil
+		}, func(events []k8s.ImageEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, "BATCH:"+strconv.Itoa(len(events)))
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		q.run(ctx)
+
+		q.add(addEvent("web"))
+		q.add(k8s.ImageEvent{Type: k8s.EventTypeDelete, ResourceType: "Deployment", ResourceName: "api", Namespace: "default"})
+
+		drainCtx, cancelDrain := context.WithTimeout(ctx, 5*time.Second)
+		defer cancelDrain()
+		if err := q.drain(drainCtx); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(order) != 2 {
+			t.Errorf("Expected the batch and the DELETE to be written, got %v", order)
+		}
+		if depth := q.stats().Depth; depth != 0 {
+			t.Errorf("Expected depth 0, got %d", depth)
+		}
+	})
+
+	t.Run("drain gives up when the context is done", func(t *testing.T) {
+		block := make(chan struct{})
+		defer close(block)
+
+		q := newEventQueue(batchConfig(), func(event k8s.ImageEvent) error {
+			<-block
+			return nil
+		}, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		q.run(ctx)
+
+		q.add(k8s.ImageEvent{Type: k8s.EventTypeDelete, ResourceType: "Deployment", ResourceName: "web", Namespace: "default"})
+
+		drainCtx, cancelDrain := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancelDrain()
+		if err := q.drain(drainCtx); err != context.DeadlineExceeded {
+			t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+		}
+	})
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/huseyinbabal/kubetag/internal/k8s"
 	"github.com/huseyinbabal/kubetag/internal/models"
@@ -13,7 +16,16 @@ import (
 // ImageServiceInterface defines the methods for image service operations
 type ImageServiceInterface interface {
 	GetImages(ctx context.Context, namespace string) (*models.ImagesResponse, error)
+	GetImagesAt(ctx context.Context, namespace string, at time.Time) (*models.ImagesResponse, error)
+	QueryImages(ctx context.Context, query models.ImageQuery) (*models.ImagesResponse, error)
 	GetImageTagHistory(ctx context.Context, imageName, namespace string) (*models.ImageTagHistory, error)
+	GetImageEvents(ctx context.Context, filter models.ImageEventFilter) (*models.ImageEventsResponse, error)
+	SubscribeImageEvents(filter models.ImageEventFilter) (<-chan models.ImageEvent, func())
+	GetWebhookDeliveries(ctx context.Context, filter models.WebhookDeliveryFilter) (*models.WebhookDeliveriesResponse, error)
+	DiffImages(ctx context.Context, left, right models.InventorySelector) (*models.ImageDiff, error)
+	GetResourceTimeline(ctx context.Context, namespace, resourceType, resourceName string) (*models.ResourceTimeline, error)
+	Search(ctx context.Context, query models.SearchQuery) (*models.SearchResponse, error)
+	GetOutdatedImages(ctx context.Context, namespace string, compatibleOnly bool) (*models.OutdatedImagesResponse, error)
 	HandleImageEvent(event k8s.ImageEvent)
 }
 
@@ -21,6 +33,32 @@ type ImageServiceInterface interface {
 type ImageService struct {
 	repo            repository.ImageRepositoryInterface
 	informerManager *k8s.InformerManager
+
+	// Cumulative corrections applied by Reconcile
+	reconcileUpserted atomic.Int64
+	reconcileDeleted  atomic.Int64
+
+	// Queue between the informers and the repository, nil until StartEventQueue
+	queue *eventQueue
+
+	// Cumulative rows removed by Prune, and selected by dry runs
+	pruned       atomic.Int64
+	prunedDryRun atomic.Int64
+
+	// Subscribers of the live image event stream
+	broker *eventBroker
+
+	// Told about persisted image events, nil when no webhooks are configured
+	notifier ImageEventNotifier
+
+	// Lists registry tags for update checks, nil when they are disabled
+	tagLister TagLister
+}
+
+// ImageEventNotifier is told about image events once they are written to the event log
+// Notify must not block, the events are handed over on the write path
+type ImageEventNotifier interface {
+	Notify(events []models.ImageEvent)
 }
 
 // NewImageService creates a new image service
@@ -28,34 +66,82 @@ func NewImageService(repo repository.ImageRepositoryInterface, informerManager *
 	return &ImageService{
 		repo:            repo,
 		informerManager: informerManager,
+		broker:          newEventBroker(),
 	}
 }
 
-// HandleImageEvent processes image events from Kubernetes informers
+// HandleImageEvent processes image events from Kubernetes informers, logging any error
 func (s *ImageService) HandleImageEvent(event k8s.ImageEvent) {
-	log.Printf("Image event: %s - %s/%s:%s in %s/%s/%s",
-		event.Type,
-		event.Repository,
-		event.ImageName,
-		event.ImageTag,
-		event.Namespace,
-		event.ResourceType,
-		event.ResourceName,
-	)
+	if err := s.processImageEvent(event); err != nil {
+		log.Printf("Error handling image event: %v", err)
+	}
+}
+
+// processImageEvent writes an image event to the repository
+func (s *ImageService) processImageEvent(event k8s.ImageEvent) error {
+	// Pods start and stop all the time, only workload spec changes are logged
+	if event.Type != k8s.EventTypeRunning && event.Type != k8s.EventTypeStopped {
+		log.Printf("Image event: %s - %s/%s:%s@%s in %s/%s/%s",
+			event.Type,
+			event.Repository,
+			event.ImageName,
+			event.ImageTag,
+			event.ImageDigest,
+			event.Namespace,
+			event.ResourceType,
+			event.ResourceName,
+		)
+	}
 
 	switch event.Type {
-	case k8s.EventTypeAdd, k8s.EventTypeUpdate:
-		err := s.repo.UpsertImageTag(
+	case k8s.EventTypeAdd:
+		created, err := s.repo.UpsertImageTag(
+			event.ImageName,
+			event.Repository,
+			event.ImageTag,
+			event.ImageDigest,
+			event.ResourceType,
+			event.ResourceName,
+			event.Namespace,
+			event.ContainerName,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to upsert image tag: %w", err)
+		}
+
+		// Informers report every workload as ADD on startup and resync, only new rows are changes
+		if !created {
+			return nil
+		}
+
+	case k8s.EventTypeUpdate:
+		if event.Change == k8s.ContainerRemoved {
+			// Only the removed container is closed out, the rest of the resource stays active
+			err := s.repo.DeleteImageTag(
+				event.ResourceType,
+				event.ResourceName,
+				event.Namespace,
+				event.ContainerName,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to delete container image tag: %w", err)
+			}
+			return s.appendImageEvents(event)
+		}
+
+		// The new tag replaces whatever the container ran before
+		err := s.repo.ReplaceImageTag(
 			event.ImageName,
 			event.Repository,
 			event.ImageTag,
+			event.ImageDigest,
 			event.ResourceType,
 			event.ResourceName,
 			event.Namespace,
 			event.ContainerName,
 		)
 		if err != nil {
-			log.Printf("Error upserting image tag: %v", err)
+			return fmt.Errorf("failed to replace image tag: %w", err)
 		}
 
 	case k8s.EventTypeDelete:
@@ -63,11 +149,44 @@ func (s *ImageService) HandleImageEvent(event k8s.ImageEvent) {
 			event.ResourceType,
 			event.ResourceName,
 			event.Namespace,
+			"",
 		)
 		if err != nil {
-			log.Printf("Error deleting image tag: %v", err)
+			return fmt.Errorf("failed to delete image tag: %w", err)
 		}
+
+	case k8s.EventTypeRunning:
+		err := s.repo.UpsertRunningImage(
+			event.ImageName,
+			event.Repository,
+			event.ImageTag,
+			event.ResourceType,
+			event.ResourceName,
+			event.Namespace,
+			event.ContainerName,
+			event.PodName,
+			event.RunningDigest,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record running image: %w", err)
+		}
+
+		return nil
+
+	case k8s.EventTypeStopped:
+		err := s.repo.DeleteRunningImages(event.Namespace, event.PodName)
+		if err != nil {
+			return fmt.Errorf("failed to delete running images: %w", err)
+		}
+
+		return nil
+
+	default:
+		return nil
 	}
+
+	// Image changes are also kept in the append-only event log, running digests are not
+	return s.appendImageEvents(event)
 }
 
 // GetImages retrieves all images from the database
@@ -83,8 +202,41 @@ func (s *ImageService) GetImages(ctx context.Context, namespace string) (*models
 	}, nil
 }
 
+// QueryImages retrieves a filtered, sorted page of the images
+func (s *ImageService) QueryImages(ctx context.Context, query models.ImageQuery) (*models.ImagesResponse, error) {
+	images, total, err := s.repo.QueryImages(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query images: %w", err)
+	}
+
+	return &models.ImagesResponse{
+		Images: images,
+		Total:  total,
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	}, nil
+}
+
+// GetImagesAt retrieves the images that were deployed at the given instant
+func (s *ImageService) GetImagesAt(ctx context.Context, namespace string, at time.Time) (*models.ImagesResponse, error) {
+	images, err := s.repo.GetImagesAt(namespace, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get images at %s: %w", at.Format(time.RFC3339), err)
+	}
+
+	return &models.ImagesResponse{
+		Images: images,
+		Total:  len(images),
+	}, nil
+}
+
 // GetImageTagHistory retrieves the tag history for a specific image
 func (s *ImageService) GetImageTagHistory(ctx context.Context, imageName, namespace string) (*models.ImageTagHistory, error) {
+	// Docker Hub images are stored normalized, e.g. docker.io/nginx as docker.io/library/nginx
+	if strings.HasPrefix(imageName, "docker.io/") {
+		imageName = k8s.ParseImageReference(imageName).FullName()
+	}
+
 	history, err := s.repo.GetImageTagHistory(imageName, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get image tag history: %w", err)
@@ -166,6 +166,67 @@ func TestGetImages(t *testing.T) {
 	}
 }
 
+func TestQueryImages(t *testing.T) {
+	query := models.ImageQuery{Name: "redis", Sort: models.ImageSortName, Limit: 1, Offset: 1}
+
+	t.Run("returns the page and the total", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().QueryImages(query).Return([]models.ImageInfo{{Name: "redis", Tag: "7.0"}}, 2, nil).Once()
+
+		result, err := NewImageService(mockRepo, nil).QueryImages(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(result.Images) != 1 || result.Total != 2 {
+			t.Errorf("Expected 1 image of 2, got %d of %d", len(result.Images), result.Total)
+		}
+		if result.Limit != 1 || result.Offset != 1 {
+			t.Errorf("Expected limit 1 and offset 1, got %d and %d", result.Limit, result.Offset)
+		}
+	})
+
+	t.Run("wraps repository errors", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().QueryImages(query).Return(nil, 0, errors.New("database error")).Once()
+
+		if _, err := NewImageService(mockRepo, nil).QueryImages(context.Background(), query); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+}
+
+func TestGetImagesAt(t *testing.T) {
+	at := time.Date(2024, 1, 2, 14, 32, 0, 0, time.UTC)
+
+	t.Run("returns the inventory at the instant", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().GetImagesAt("default", at).Return([]models.ImageInfo{
+			{Name: "nginx", Tag: "1.25", Namespace: "default"},
+		}, nil).Once()
+
+		result, err := NewImageService(mockRepo, nil).GetImagesAt(context.Background(), "default", at)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.Total != 1 || result.Images[0].Tag != "1.25" {
+			t.Errorf("Expected nginx:1.25, got %+v", result)
+		}
+	})
+
+	t.Run("wraps repository errors", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().GetImagesAt("", at).Return(nil, errors.New("database error")).Once()
+
+		result, err := NewImageService(mockRepo, nil).GetImagesAt(context.Background(), "", at)
+		if err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+		if result != nil {
+			t.Errorf("Expected nil result, got %+v", result)
+		}
+	})
+}
+
 func TestGetImageTagHistory(t *testing.T) {
 	now := time.Now()
 	tests := []struct {
@@ -319,12 +380,15 @@ func TestGetImageTagHistory(t *testing.T) {
 
 func TestHandleImageEvent(t *testing.T) {
 	tests := []struct {
-		name         string
-		event        k8s.ImageEvent
-		expectUpsert bool
-		expectDelete bool
-		upsertError  error
-		deleteError  error
+		name            string
+		event           k8s.ImageEvent
+		expectUpsert    bool
+		alreadyRecorded bool // The upsert only refreshes an existing row
+		expectReplace   bool
+		expectDelete    bool
+		deleteContainer string
+		upsertError     error
+		deleteError     error
 	}{
 		{
 			name: "add event calls upsert",
@@ -343,7 +407,22 @@ func TestHandleImageEvent(t *testing.T) {
 			upsertError:  nil,
 		},
 		{
-			name: "update event calls upsert",
+			name: "add event of a recorded tag is not logged",
+			event: k8s.ImageEvent{
+				Type:          k8s.EventTypeAdd,
+				ImageName:     "nginx",
+				Repository:    "docker.io",
+				ImageTag:      "latest",
+				ResourceType:  "Deployment",
+				ResourceName:  "nginx-deployment",
+				Namespace:     "default",
+				ContainerName: "nginx",
+			},
+			expectUpsert:    true,
+			alreadyRecorded: true,
+		},
+		{
+			name: "update event calls replace",
 			event: k8s.ImageEvent{
 				Type:          k8s.EventTypeUpdate,
 				ImageName:     "redis",
@@ -354,6 +433,23 @@ func TestHandleImageEvent(t *testing.T) {
 				Namespace:     "default",
 				ContainerName: "redis",
 			},
+			expectReplace: true,
+			expectDelete:  false,
+			upsertError:   nil,
+		},
+		{
+			name: "digest pinned event passes digest to upsert",
+			event: k8s.ImageEvent{
+				Type:          k8s.EventTypeAdd,
+				ImageName:     "app",
+				Repository:    "localhost:5000/team",
+				ImageTag:      "v1",
+				ImageDigest:   "sha256:abc123",
+				ResourceType:  "Deployment",
+				ResourceName:  "app-deployment",
+				Namespace:     "default",
+				ContainerName: "app",
+			},
 			expectUpsert: true,
 			expectDelete: false,
 			upsertError:  nil,
@@ -370,6 +466,22 @@ func TestHandleImageEvent(t *testing.T) {
 			expectDelete: true,
 			deleteError:  nil,
 		},
+		{
+			name: "removed container closes out only that container",
+			event: k8s.ImageEvent{
+				Type:          k8s.EventTypeUpdate,
+				Change:        k8s.ContainerRemoved,
+				ImageName:     "fluent-bit",
+				Repository:    "docker.io",
+				ImageTag:      "3.0",
+				ResourceType:  "Deployment",
+				ResourceName:  "web",
+				Namespace:     "default",
+				ContainerName: "sidecar",
+			},
+			expectDelete:    true,
+			deleteContainer: "sidecar",
+		},
 		{
 			name: "upsert error is logged but does not panic",
 			event: k8s.ImageEvent{
@@ -411,6 +523,23 @@ func TestHandleImageEvent(t *testing.T) {
 						tt.event.ImageName,
 						tt.event.Repository,
 						tt.event.ImageTag,
+						tt.event.ImageDigest,
+						tt.event.ResourceType,
+						tt.event.ResourceName,
+						tt.event.Namespace,
+						tt.event.ContainerName,
+					).
+					Return(tt.upsertError == nil && !tt.alreadyRecorded, tt.upsertError).
+					Once()
+			}
+
+			if tt.expectReplace {
+				mockRepo.EXPECT().
+					ReplaceImageTag(
+						tt.event.ImageName,
+						tt.event.Repository,
+						tt.event.ImageTag,
+						tt.event.ImageDigest,
 						tt.event.ResourceType,
 						tt.event.ResourceName,
 						tt.event.Namespace,
@@ -426,11 +555,20 @@ func TestHandleImageEvent(t *testing.T) {
 						tt.event.ResourceType,
 						tt.event.ResourceName,
 						tt.event.Namespace,
+						tt.deleteContainer,
 					).
 					Return(tt.deleteError).
 					Once()
 			}
 
+			// Successful writes that changed something are also recorded in the event log
+			if tt.upsertError == nil && tt.deleteError == nil && !tt.alreadyRecorded {
+				mockRepo.EXPECT().
+					AppendImageEvents([]models.ImageEvent{imageEventRecord(tt.event)}).
+					Return(nil).
+					Once()
+			}
+
 			service := NewImageService(mockRepo, nil)
 
 			// Execute - should not panic even on errors
@@ -523,4 +661,66 @@ func TestGetImageTagHistoryEdgeCases(t *testing.T) {
 
 		mockRepo.AssertExpectations(t)
 	})
+	t.Run("docker hub reference is looked up normalized", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+
+		mockRepo.EXPECT().
+			GetImageTagHistory("docker.io/library/nginx", "").
+			Return(&models.ImageTagHistory{ImageName: "nginx", Repositories: []string{"docker.io/library"}}, nil).
+			Once()
+
+		service := NewImageService(mockRepo, nil)
+		if _, err := service.GetImageTagHistory(context.Background(), "docker.io/nginx", ""); err != nil {
+			t.Errorf("Expected no error but got: %v", err)
+		}
+	})
+}
+
+func TestHandleRunningImageEvents(t *testing.T) {
+	t.Run("running event records the running digest", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+
+		event := k8s.ImageEvent{
+			Type:          k8s.EventTypeRunning,
+			ImageName:     "nginx",
+			Repository:    "docker.io",
+			ImageTag:      "latest",
+			ResourceType:  "Deployment",
+			ResourceName:  "web",
+			Namespace:     "default",
+			ContainerName: "nginx",
+			PodName:       "web-abc-1",
+			RunningDigest: "sha256:aaa",
+		}
+
+		mockRepo.EXPECT().
+			UpsertRunningImage("nginx", "docker.io", "latest", "Deployment", "web", "default", "nginx", "web-abc-1", "sha256:aaa").
+			Return(nil).
+			Once()
+
+		service := NewImageService(mockRepo, nil)
+		service.HandleImageEvent(event)
+	})
+
+	t.Run("stopped event forgets the pod digests", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+
+		event := k8s.ImageEvent{
+			Type:         k8s.EventTypeStopped,
+			ResourceType: "Deployment",
+			ResourceName: "web",
+			Namespace:    "default",
+			PodName:      "web-abc-1",
+		}
+
+		mockRepo.EXPECT().
+			DeleteRunningImages("default", "web-abc-1").
+			Return(errors.New("database error")).
+			Once()
+
+		service := NewImageService(mockRepo, nil)
+
+		// Errors are logged, not propagated
+		service.HandleImageEvent(event)
+	})
 }
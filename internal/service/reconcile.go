@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/k8s"
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+// ReconcileResult counts the corrections applied by a reconciliation run
+type ReconcileResult struct {
+	Upserted int64 // Rows added because the cluster had them but the database did not
+	Deleted  int64 // Rows closed out because the cluster no longer has them
+}
+
+// Reconcile compares the informer caches with the database and fixes any drift
+// Image tags no longer present in the cluster are soft deleted, missing ones replace the container's tag.
+// Running digests of Pods that are gone are removed and missing ones are recorded.
+func (s *ImageService) Reconcile(ctx context.Context) (*ReconcileResult, error) {
+	if s.informerManager == nil {
+		return nil, fmt.Errorf("failed to reconcile: informer manager is not configured")
+	}
+
+	// Read the database before the cluster so rows written by concurrent events are never closed out
+	imageTags, err := s.repo.ListActiveImageTags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile image tags: %w", err)
+	}
+
+	runningImages, err := s.repo.ListRunningImages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile running images: %w", err)
+	}
+
+	declared, err := s.informerManager.ListImages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile image tags: %w", err)
+	}
+
+	running, err := s.informerManager.ListRunningImages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile running images: %w", err)
+	}
+
+	result := &ReconcileResult{}
+
+	// Count corrections even when a later step fails, they were applied
+	defer func() {
+		s.reconcileUpserted.Add(result.Upserted)
+		s.reconcileDeleted.Add(result.Deleted)
+	}()
+
+	if err := s.reconcileImageTags(ctx, imageTags, declared, result); err != nil {
+		return result, err
+	}
+
+	if err := s.reconcileRunningImages(ctx, runningImages, running, result); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// reconcileImageTags closes out stale image tag rows and records missing ones
+func (s *ImageService) reconcileImageTags(ctx context.Context, imageTags []models.ImageTag, declared []k8s.ImageEvent, result *ReconcileResult) error {
+	existing := make(map[string]bool)
+	var staleIDs []uint
+
+	wanted := make(map[string]k8s.ImageEvent)
+	for _, event := range declared {
+		wanted[imageTagKey(event.Repository, event.ImageName, event.ImageTag, event.ImageDigest,
+			event.ResourceType, event.ResourceName, event.Namespace, event.ContainerName)] = event
+	}
+
+	for _, it := range imageTags {
+		// Rows of namespaces that are not watched cannot be verified, leave them alone
+		if !s.informerManager.WatchesNamespace(it.Namespace) {
+			continue
+		}
+
+		key := imageTagKey(it.Image.Repository, it.Image.Name, it.Tag, it.Digest,
+			it.ResourceType, it.ResourceName, it.Namespace, it.ContainerName)
+		if _, found := wanted[key]; found {
+			existing[key] = true
+		} else {
+			staleIDs = append(staleIDs, it.ID)
+		}
+	}
+
+	if err := s.repo.DeleteImageTagsByID(staleIDs); err != nil {
+		return fmt.Errorf("failed to close out stale image tags: %w", err)
+	}
+	result.Deleted += int64(len(staleIDs))
+
+	for key, event := range wanted {
+		if existing[key] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// The container may have switched tags while the event was missed, close out what it ran before
+		err := s.repo.ReplaceImageTag(
+			event.ImageName,
+			event.Repository,
+			event.ImageTag,
+			event.ImageDigest,
+			event.ResourceType,
+			event.ResourceName,
+			event.Namespace,
+			event.ContainerName,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to replace missing image tag: %w", err)
+		}
+		result.Upserted++
+	}
+
+	return nil
+}
+
+// reconcileRunningImages forgets Pods that are gone and records digests that were missed
+func (s *ImageService) reconcileRunningImages(ctx context.Context, runningImages []models.RunningImage, running []k8s.ImageEvent, result *ReconcileResult) error {
+	livePods := make(map[string]bool)
+	wanted := make(map[string]k8s.ImageEvent)
+	for _, event := range running {
+		livePods[event.Namespace+"/"+event.PodName] = true
+		wanted[event.Namespace+"/"+event.PodName+"/"+event.ContainerName] = event
+	}
+
+	existing := make(map[string]string)
+	stalePods := make(map[string]models.RunningImage)
+	for _, ri := range runningImages {
+		if !s.informerManager.WatchesNamespace(ri.Namespace) {
+			continue
+		}
+
+		pod := ri.Namespace + "/" + ri.PodName
+		if livePods[pod] {
+			existing[pod+"/"+ri.ContainerName] = ri.Digest
+		} else {
+			stalePods[pod] = ri
+		}
+	}
+
+	for _, ri := range stalePods {
+		if err := s.repo.DeleteRunningImages(ri.Namespace, ri.PodName); err != nil {
+			return fmt.Errorf("failed to delete stale running images: %w", err)
+		}
+		result.Deleted++
+	}
+
+	for key, event := range wanted {
+		if digest, found := existing[key]; found && digest == event.RunningDigest {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := s.repo.UpsertRunningImage(
+			event.ImageName,
+			event.Repository,
+			event.ImageTag,
+			event.ResourceType,
+			event.ResourceName,
+			event.Namespace,
+			event.ContainerName,
+			event.PodName,
+			event.RunningDigest,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to upsert missing running image: %w", err)
+		}
+		result.Upserted++
+	}
+
+	return nil
+}
+
+// imageTagKey identifies an image tag row independently of its database ID
+func imageTagKey(repository, imageName, tag, digest, resourceType, resourceName, namespace, containerName string) string {
+	return fmt.Sprintf("%s/%s:%s@%s|%s|%s|%s|%s", repository, imageName, tag, digest, resourceType, resourceName, namespace, containerName)
+}
+
+// StartReconciler reconciles once and then every interval until the context is cancelled
+// An interval of zero only runs the initial reconciliation
+func (s *ImageService) StartReconciler(ctx context.Context, interval time.Duration) {
+	s.runReconcile(ctx)
+
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.runReconcile(ctx)
+			}
+		}
+	}()
+}
+
+// runReconcile runs a reconciliation and logs its outcome
+func (s *ImageService) runReconcile(ctx context.Context) {
+	result, err := s.Reconcile(ctx)
+	if err != nil {
+		log.Printf("Error reconciling images: %v", err)
+		return
+	}
+
+	log.Printf("Reconciliation finished: %d upserted, %d deleted", result.Upserted, result.Deleted)
+}
+
+// ReconcileCorrections returns the corrections applied by all reconciliation runs so far
+func (s *ImageService) ReconcileCorrections() ReconcileResult {
+	return ReconcileResult{
+		Upserted: s.reconcileUpserted.Load(),
+		Deleted:  s.reconcileDeleted.Load(),
+	}
+}
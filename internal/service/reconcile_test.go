@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/huseyinbabal/kubetag/internal/k8s"
+	"github.com/huseyinbabal/kubetag/internal/mocks"
+	"github.com/huseyinbabal/kubetag/internal/models"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// startInformers runs an informer manager against a fake cluster and waits for its caches
+func startInformers(t *testing.T, namespaces []string, objects ...interface{}) *k8s.InformerManager {
+	t.Helper()
+
+	clientset := fake.NewSimpleClientset()
+	for _, obj := range objects {
+		var err error
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			_, err = clientset.AppsV1().Deployments(o.Namespace).Create(context.Background(), o, metav1.CreateOptions{})
+		case *corev1.Pod:
+			_, err = clientset.CoreV1().Pods(o.Namespace).Create(context.Background(), o, metav1.CreateOptions{})
+		}
+		if err != nil {
+			t.Fatalf("Failed to create object: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	im := k8s.NewInformerManager(clientset, nil, namespaces)
+	if err := im.Start(ctx); err != nil {
+		t.Fatalf("Failed to start informers: %v", err)
+	}
+
+	return im
+}
+
+func TestReconcile(t *testing.T) {
+	web := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "nginx", Image: "nginx:1.25"}},
+		}}},
+	}
+	debug := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "shell", Image: "busybox:1.36"}}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "shell", ImageID: "docker.io/library/busybox@sha256:bbb"},
+		}},
+	}
+
+	t.Run("closes out stale rows and upserts missing ones", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		im := startInformers(t, nil, web, debug)
+
+		mockRepo.EXPECT().ListActiveImageTags().Return([]models.ImageTag{
+			{
+				ID:            1,
+				Image:         models.Image{Name: "nginx", Repository: "docker.io/library"},
+				Tag:           "1.25",
+				ResourceType:  "Deployment",
+				ResourceName:  "web",
+				Namespace:     "default",
+				ContainerName: "nginx",
+			},
+			{
+				ID:            2,
+				Image:         models.Image{Name: "redis", Repository: "docker.io"},
+				Tag:           "7",
+				ResourceType:  "Deployment",
+				ResourceName:  "cache",
+				Namespace:     "default",
+				ContainerName: "redis",
+			},
+		}, nil).Once()
+		mockRepo.EXPECT().ListRunningImages().Return([]models.RunningImage{
+			{Namespace: "default", PodName: "cache-abc-1", ContainerName: "redis", Digest: "sha256:ccc"},
+		}, nil).Once()
+
+		mockRepo.EXPECT().DeleteImageTagsByID([]uint{2}).Return(nil).Once()
+		mockRepo.EXPECT().
+			ReplaceImageTag("busybox", "docker.io/library", "1.36", "", "Pod", "debug", "default", "shell").
+			Return(nil).
+			Once()
+		mockRepo.EXPECT().DeleteRunningImages("default", "cache-abc-1").Return(nil).Once()
+		mockRepo.EXPECT().
+			UpsertRunningImage("busybox", "docker.io/library", "1.36", "Pod", "debug", "default", "shell", "debug", "sha256:bbb").
+			Return(nil).
+			Once()
+
+		service := NewImageService(mockRepo, im)
+
+		result, err := service.Reconcile(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.Upserted != 2 || result.Deleted != 2 {
+			t.Errorf("Expected 2 upserted and 2 deleted, got %d upserted and %d deleted", result.Upserted, result.Deleted)
+		}
+
+		corrections := service.ReconcileCorrections()
+		if corrections.Upserted != 2 || corrections.Deleted != 2 {
+			t.Errorf("Expected cumulative corrections 2/2, got %d/%d", corrections.Upserted, corrections.Deleted)
+		}
+	})
+
+	t.Run("leaves rows of unwatched namespaces alone", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		im := startInformers(t, []string{"staging"})
+
+		mockRepo.EXPECT().ListActiveImageTags().Return([]models.ImageTag{
+			{
+				ID:            1,
+				Image:         models.Image{Name: "nginx", Repository: "docker.io"},
+				Tag:           "1.25",
+				ResourceType:  "Deployment",
+				ResourceName:  "web",
+				Namespace:     "default",
+				ContainerName: "nginx",
+			},
+		}, nil).Once()
+		mockRepo.EXPECT().ListRunningImages().Return([]models.RunningImage{}, nil).Once()
+		mockRepo.EXPECT().DeleteImageTagsByID([]uint(nil)).Return(nil).Once()
+
+		service := NewImageService(mockRepo, im)
+
+		result, err := service.Reconcile(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.Upserted != 0 || result.Deleted != 0 {
+			t.Errorf("Expected no corrections, got %d upserted and %d deleted", result.Upserted, result.Deleted)
+		}
+	})
+
+	t.Run("returns repository errors", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		im := startInformers(t, nil)
+
+		mockRepo.EXPECT().ListActiveImageTags().Return(nil, errors.New("database error")).Once()
+
+		service := NewImageService(mockRepo, im)
+
+		if _, err := service.Reconcile(context.Background()); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+
+	t.Run("requires an informer manager", func(t *testing.T) {
+		service := NewImageService(mocks.NewMockImageRepository(t), nil)
+
+		if _, err := service.Reconcile(context.Background()); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+// RetentionPolicy decides which closed out image tag rows are pruned from the history
+// Active rows are never pruned. Limits apply per image and resource; zero disables a limit.
+// MaxAge also prunes the image event log and the webhook delivery attempts.
+type RetentionPolicy struct {
+	MaxAge      time.Duration // Prune rows closed out, events observed and deliveries made longer ago than this
+	MaxInactive int           // Keep at most this many closed out rows, newest first
+	KeepLast    int           // Always keep this many newest closed out rows, whatever their age
+	DryRun      bool          // Only count and log what would be pruned
+}
+
+// Enabled reports whether the policy prunes anything at all
+func (p RetentionPolicy) Enabled() bool {
+	return p.MaxAge > 0 || p.MaxInactive > 0
+}
+
+// pruneBatchSize is the most image tag rows selected and purged at once
+const pruneBatchSize = 500
+
+// PruneResult counts the rows selected by a pruning run
+// Rows are deleted, or would have been deleted in dry-run mode
+type PruneResult struct {
+	Pruned     int64 // Image tag rows
+	Events     int64 // Image event log entries
+	Deliveries int64 // Webhook delivery attempts
+	DryRun     bool
+}
+
+// Prune permanently deletes the closed out image tags, image events and webhook deliveries
+// that fall outside the retention policy
+func (s *ImageService) Prune(ctx context.Context, policy RetentionPolicy) (*PruneResult, error) {
+	now := time.Now().UTC()
+
+	result := &PruneResult{DryRun: policy.DryRun}
+	query := models.PruneQuery{
+		MaxInactive: policy.MaxInactive,
+		KeepLast:    policy.KeepLast,
+		Limit:       pruneBatchSize,
+	}
+	if policy.MaxAge > 0 {
+		query.ClosedBefore = now.Add(-policy.MaxAge)
+	}
+
+	// Pruned rows rank behind every kept one, so paging by ID is not thrown off by the purges
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		ids, err := s.repo.ListPrunableImageTags(query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prune image tags: %w", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		if policy.DryRun {
+			s.prunedDryRun.Add(int64(len(ids)))
+		} else {
+			if err := s.repo.PurgeImageTags(ids); err != nil {
+				return nil, fmt.Errorf("failed to prune image tags: %w", err)
+			}
+			s.pruned.Add(int64(len(ids)))
+		}
+		result.Pruned += int64(len(ids))
+
+		if len(ids) < pruneBatchSize {
+			break
+		}
+		query.AfterID = ids[len(ids)-1]
+	}
+
+	// The event log and the delivery attempts only have an age limit
+	if policy.MaxAge <= 0 {
+		return result, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	before := now.Add(-policy.MaxAge)
+	result.Events, err = s.repo.PurgeImageEvents(before, policy.DryRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune image events: %w", err)
+	}
+	result.Deliveries, err = s.repo.PurgeWebhookDeliveries(before, policy.DryRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune webhook deliveries: %w", err)
+	}
+
+	return result, nil
+}
+
+// StartPruner prunes once and then every interval until the context is cancelled
+// Nothing runs when the policy has no limits
+func (s *ImageService) StartPruner(ctx context.Context, policy RetentionPolicy, interval time.Duration) {
+	if !policy.Enabled() {
+		return
+	}
+
+	s.runPrune(ctx, policy)
+
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.runPrune(ctx, policy)
+			}
+		}
+	}()
+}
+
+// runPrune runs a pruning pass and logs its outcome
+func (s *ImageService) runPrune(ctx context.Context, policy RetentionPolicy) {
+	result, err := s.Prune(ctx, policy)
+	if err != nil {
+		log.Printf("Error pruning image tag history: %v", err)
+		return
+	}
+
+	if result.DryRun {
+		log.Printf("Pruning dry run finished: %d image tags, %d image events and %d webhook deliveries would be pruned",
+			result.Pruned, result.Events, result.Deliveries)
+		return
+	}
+
+	log.Printf("Pruning finished: %d image tags, %d image events and %d webhook deliveries pruned",
+		result.Pruned, result.Events, result.Deliveries)
+}
+
+// PrunedImageTags returns the rows pruned by all runs so far, and those dry runs would have pruned
+func (s *ImageService) PrunedImageTags() (pruned, dryRun int64) {
+	return s.pruned.Load(), s.prunedDryRun.Load()
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/mocks"
+	"github.com/huseyinbabal/kubetag/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPrune(t *testing.T) {
+	policy := RetentionPolicy{MaxAge: time.Hour, KeepLast: 1}
+
+	t.Run("purges the selected rows", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		service := NewImageService(mockRepo, nil)
+
+		var query models.PruneQuery
+		mockRepo.EXPECT().ListPrunableImageTags(mock.Anything).
+			Run(func(q models.PruneQuery) { query = q }).
+			Return([]uint{1}, nil).Once()
+		mockRepo.EXPECT().PurgeImageTags([]uint{1}).Return(nil).Once()
+
+		var eventsBefore, deliveriesBefore time.Time
+		mockRepo.EXPECT().PurgeImageEvents(mock.Anything, false).
+			Run(func(before time.Time, dryRun bool) { eventsBefore = before }).
+			Return(3, nil).Once()
+		mockRepo.EXPECT().PurgeWebhookDeliveries(mock.Anything, false).
+			Run(func(before time.Time, dryRun bool) { deliveriesBefore = before }).
+			Return(5, nil).Once()
+
+		result, err := service.Prune(context.Background(), policy)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.Pruned != 1 || result.Events != 3 || result.Deliveries != 5 || result.DryRun {
+			t.Errorf("Expected 1 row, 3 events and 5 deliveries pruned, got %+v", result)
+		}
+
+		cutoff := time.Now().UTC().Add(-policy.MaxAge)
+		if cutoff.Sub(eventsBefore) > time.Minute || !eventsBefore.Equal(deliveriesBefore) {
+			t.Errorf("Expected events and deliveries older than %v to be pruned, got %v and %v", policy.MaxAge, eventsBefore, deliveriesBefore)
+		}
+		if !query.ClosedBefore.Equal(eventsBefore) || query.KeepLast != 1 || query.MaxInactive != 0 || query.Limit != pruneBatchSize {
+			t.Errorf("Expected rows closed out before %v to be selected keeping the last one, got %+v", eventsBefore, query)
+		}
+
+		pruned, dryRun := service.PrunedImageTags()
+		if pruned != 1 || dryRun != 0 {
+			t.Errorf("Expected 1 pruned and 0 dry run rows, got %d and %d", pruned, dryRun)
+		}
+	})
+
+	t.Run("dry run only counts", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		service := NewImageService(mockRepo, nil)
+
+		dryRunPolicy := policy
+		dryRunPolicy.DryRun = true
+		mockRepo.EXPECT().ListPrunableImageTags(mock.Anything).Return([]uint{1}, nil).Once()
+		mockRepo.EXPECT().PurgeImageEvents(mock.Anything, true).Return(3, nil).Once()
+		mockRepo.EXPECT().PurgeWebhookDeliveries(mock.Anything, true).Return(5, nil).Once()
+
+		result, err := service.Prune(context.Background(), dryRunPolicy)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.Pruned != 1 || result.Events != 3 || result.Deliveries != 5 || !result.DryRun {
+			t.Errorf("Expected 1 row, 3 events and 5 deliveries in dry run, got %+v", result)
+		}
+
+		pruned, dryRun := service.PrunedImageTags()
+		if pruned != 0 || dryRun != 1 {
+			t.Errorf("Expected 0 pruned and 1 dry run rows, got %d and %d", pruned, dryRun)
+		}
+	})
+
+	t.Run("repository errors are returned", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		service := NewImageService(mockRepo, nil)
+
+		mockRepo.EXPECT().ListPrunableImageTags(mock.Anything).Return(nil, errors.New("db down")).Once()
+
+		if _, err := service.Prune(context.Background(), policy); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+
+	t.Run("event log is kept without an age limit", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		service := NewImageService(mockRepo, nil)
+
+		mockRepo.EXPECT().ListPrunableImageTags(models.PruneQuery{MaxInactive: 1, Limit: pruneBatchSize}).Return([]uint{1}, nil).Once()
+		mockRepo.EXPECT().PurgeImageTags([]uint{1}).Return(nil).Once()
+
+		result, err := service.Prune(context.Background(), RetentionPolicy{MaxInactive: 1})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.Pruned != 1 || result.Events != 0 || result.Deliveries != 0 {
+			t.Errorf("Expected only 1 row pruned, got %+v", result)
+		}
+	})
+
+	t.Run("event log errors are returned", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		service := NewImageService(mockRepo, nil)
+
+		mockRepo.EXPECT().ListPrunableImageTags(mock.Anything).Return([]uint{1}, nil).Once()
+		mockRepo.EXPECT().PurgeImageTags([]uint{1}).Return(nil).Once()
+		mockRepo.EXPECT().PurgeImageEvents(mock.Anything, false).Return(0, errors.New("db down")).Once()
+
+		if _, err := service.Prune(context.Background(), policy); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+
+	t.Run("purges in batches", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		service := NewImageService(mockRepo, nil)
+
+		first := make([]uint, pruneBatchSize)
+		for i := range first {
+			first[i] = uint(i + 1)
+		}
+		query := models.PruneQuery{MaxInactive: 1, Limit: pruneBatchSize}
+		mockRepo.EXPECT().ListPrunableImageTags(query).Return(first, nil).Once()
+		mockRepo.EXPECT().PurgeImageTags(first).Return(nil).Once()
+
+		query.AfterID = pruneBatchSize
+		mockRepo.EXPECT().ListPrunableImageTags(query).Return([]uint{900}, nil).Once()
+		mockRepo.EXPECT().PurgeImageTags([]uint{900}).Return(nil).Once()
+
+		result, err := service.Prune(context.Background(), RetentionPolicy{MaxInactive: 1})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.Pruned != pruneBatchSize+1 {
+			t.Errorf("Expected %d rows pruned, got %+v", pruneBatchSize+1, result)
+		}
+	})
+
+	t.Run("pruner does not start without limits", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		service := NewImageService(mockRepo, nil)
+
+		// The mock fails the test on any unexpected call
+		service.StartPruner(context.Background(), RetentionPolicy{KeepLast: 5, DryRun: true}, time.Hour)
+	})
+}
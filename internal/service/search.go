@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+// Search ranks the image tag rows, current and historical, matching every term of the query
+func (s *ImageService) Search(ctx context.Context, query models.SearchQuery) (*models.SearchResponse, error) {
+	imageTags, err := s.repo.SearchImageTags(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search images: %w", err)
+	}
+
+	results := rankSearchResults(imageTags, query.Terms())
+	total := len(results)
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+
+	return &models.SearchResponse{
+		Query:   query.Text,
+		Results: results,
+		Total:   total,
+	}, nil
+}
+
+// rankSearchResults scores every row against the terms, best first
+// Ties keep active rows ahead of history, then the most recently seen
+func rankSearchResults(imageTags []models.ImageTag, terms []string) []models.SearchResult {
+	results := make([]models.SearchResult, 0, len(imageTags))
+
+	for _, it := range imageTags {
+		fields := map[string]string{
+			models.SearchFieldName:       it.Image.Name,
+			models.SearchFieldRepository: it.Image.Repository,
+			models.SearchFieldTag:        it.Tag,
+			models.SearchFieldResource:   it.ResourceName,
+			models.SearchFieldNamespace:  it.Namespace,
+			models.SearchFieldContainer:  it.ContainerName,
+		}
+
+		result := models.SearchResult{
+			Matches:      []string{},
+			Name:         it.Image.Name,
+			Repository:   it.Image.Repository,
+			Tag:          it.Tag,
+			Digest:       it.Digest,
+			ResourceType: it.ResourceType,
+			ResourceName: it.ResourceName,
+			Namespace:    it.Namespace,
+			Container:    it.ContainerName,
+			FirstSeen:    it.FirstSeen,
+			LastSeen:     it.LastSeen,
+			Active:       !it.DeletedAt.Valid,
+			RemovedAt:    it.RemovedAt,
+		}
+
+		for _, term := range terms {
+			bestField, bestScore := "", 0.0
+			for field, value := range fields {
+				score := models.SearchFieldWeights[field] * matchScore(value, term)
+				if score > bestScore || (score == bestScore && score > 0 && field < bestField) {
+					bestField, bestScore = field, score
+				}
+			}
+
+			result.Score += bestScore
+			if bestField != "" && !slices.Contains(result.Matches, bestField) {
+				result.Matches = append(result.Matches, bestField)
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Active != b.Active {
+			return a.Active
+		}
+		return a.LastSeen.After(b.LastSeen)
+	})
+
+	return results
+}
+
+// matchScore rates how well a term matches a value, from 1 for an exact match down to 0
+// Substring matches beat fuzzy ones, which score by trigram similarity like PostgreSQL's pg_trgm.
+// The repository orders search candidates by the same score, keep both in sync.
+func matchScore(value, term string) float64 {
+	value = strings.ToLower(value)
+
+	switch {
+	case value == "" || term == "":
+		return 0
+	case value == term:
+		return 1
+	case strings.HasPrefix(value, term):
+		return 0.75
+	case strings.Contains(value, term):
+		return 0.5
+	default:
+		return 0.5 * trigramSimilarity(value, term)
+	}
+}
+
+// trigramSimilarity is the share of trigrams two strings have in common, as computed by pg_trgm
+func trigramSimilarity(a, b string) float64 {
+	trigramsA, trigramsB := trigrams(a), trigrams(b)
+	if len(trigramsA) == 0 || len(trigramsB) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for trigram := range trigramsA {
+		if trigramsB[trigram] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(trigramsA)+len(trigramsB)-shared)
+}
+
+// trigrams splits the alphanumeric words of a string into padded three-character sequences
+func trigrams(value string) map[string]bool {
+	set := make(map[string]bool)
+
+	words := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+
+	return set
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/mocks"
+	"github.com/huseyinbabal/kubetag/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestMatchScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		term     string
+		expected float64
+	}{
+		{name: "exact match ignores case", value: "NGINX", term: "nginx", expected: 1},
+		{name: "prefix", value: "nginx-ingress", term: "nginx", expected: 0.75},
+		{name: "substring", value: "ingress-nginx", term: "nginx", expected: 0.5},
+		{name: "no shared trigrams", value: "redis", term: "nginx", expected: 0},
+		{name: "empty value", value: "", term: "nginx", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if score := matchScore(tt.value, tt.term); score != tt.expected {
+				t.Errorf("Expected score %v, got %v", tt.expected, score)
+			}
+		})
+	}
+
+	if score := matchScore("nginx", "ngix"); score <= 0 || score >= 0.5 {
+		t.Errorf("Expected a typo to score between 0 and a substring match, got %v", score)
+	}
+}
+
+func TestTrigramSimilarity(t *testing.T) {
+	if similarity := trigramSimilarity("word", "word"); similarity != 1 {
+		t.Errorf("Expected identical strings to have similarity 1, got %v", similarity)
+	}
+
+	// pg_trgm: similarity('word', 'two words') = 0.363636
+	if similarity := trigramSimilarity("word", "two words"); similarity < 0.36 || similarity > 0.37 {
+		t.Errorf("Expected similarity 0.3636, got %v", similarity)
+	}
+}
+
+func TestRankSearchResults(t *testing.T) {
+	now := time.Now().UTC()
+	removed := now.Add(-time.Hour)
+
+	row := func(name, tag, resource, namespace string, lastSeen time.Time, active bool) models.ImageTag {
+		it := models.ImageTag{
+			Image:         models.Image{Name: name, Repository: "docker.io"},
+			Tag:           tag,
+			ResourceType:  "Deployment",
+			ResourceName:  resource,
+			Namespace:     namespace,
+			ContainerName: "app",
+			LastSeen:      lastSeen,
+		}
+		if !active {
+			it.RemovedAt = &removed
+			it.DeletedAt = gorm.DeletedAt{Time: removed, Valid: true}
+		}
+		return it
+	}
+
+	results := rankSearchResults([]models.ImageTag{
+		row("nginx-exporter", "0.11", "metrics", "monitoring", now, true),
+		row("nginx", "1.24", "web", "default", removed, false),
+		row("nginx", "1.25", "web", "default", now, true),
+		row("app", "1.0", "nginx-proxy", "default", now, true),
+	}, []string{"nginx"})
+
+	var order []string
+	for _, result := range results {
+		order = append(order, result.Name+":"+result.Tag)
+	}
+
+	expected := []string{"nginx:1.25", "nginx:1.24", "nginx-exporter:0.11", "app:1.0"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected results %v, got %v", expected, order)
+	}
+
+	if results[1].Active || results[1].RemovedAt == nil {
+		t.Error("Expected the replaced tag to be reported as history")
+	}
+	if !reflect.DeepEqual(results[3].Matches, []string{models.SearchFieldResource}) {
+		t.Errorf("Expected app to match on the resource name, got %v", results[3].Matches)
+	}
+
+	results = rankSearchResults([]models.ImageTag{row("nginx", "1.25", "web", "default", now, true)}, []string{"nginx", "1.25"})
+	if results[0].Score != 1+models.SearchFieldWeights[models.SearchFieldTag] || !reflect.DeepEqual(results[0].Matches, []string{models.SearchFieldName, models.SearchFieldTag}) {
+		t.Errorf("Expected every term to add to the score, got %v matching %v", results[0].Score, results[0].Matches)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	query := models.SearchQuery{Text: "Nginx", Namespace: "default", Limit: 1}
+
+	t.Run("limits ranked results", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().SearchImageTags(query).Return([]models.ImageTag{
+			{Image: models.Image{Name: "nginx-exporter"}, ResourceName: "metrics"},
+			{Image: models.Image{Name: "nginx"}, ResourceName: "web"},
+		}, nil).Once()
+
+		response, err := NewImageService(mockRepo, nil).Search(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if response.Total != 2 || len(response.Results) != 1 {
+			t.Fatalf("Expected 1 of 2 results, got %d of %d", len(response.Results), response.Total)
+		}
+		if response.Results[0].Name != "nginx" || response.Query != "Nginx" {
+			t.Errorf("Expected the exact match for Nginx first, got %s for %s", response.Results[0].Name, response.Query)
+		}
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().SearchImageTags(query).Return(nil, errors.New("database error")).Once()
+
+		if _, err := NewImageService(mockRepo, nil).Search(context.Background(), query); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+}
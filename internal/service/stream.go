@@ -0,0 +1,110 @@
+package service
+
+import (
+	"log"
+	"sync"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+// eventSubscriberBuffer is how many events a stream subscriber may fall behind before it is disconnected
+// A disconnected client resumes from the event log with the ID of the last event it received
+const eventSubscriberBuffer = 256
+
+// eventBroker fans image events out to stream subscribers once they are persisted
+type eventBroker struct {
+	mu          sync.Mutex
+	subscribers map[*eventSubscriber]struct{}
+	closed      bool
+}
+
+// eventSubscriber is one open stream and the events it asked for
+type eventSubscriber struct {
+	filter models.ImageEventFilter
+	events chan models.ImageEvent
+}
+
+// newEventBroker creates a broker without subscribers
+func newEventBroker() *eventBroker {
+	return &eventBroker{subscribers: make(map[*eventSubscriber]struct{})}
+}
+
+// subscribe registers a subscriber for the events matching filter and returns its unsubscribe function
+// The channel is closed on unsubscribe, when the subscriber falls behind, or when the broker closes
+func (b *eventBroker) subscribe(filter models.ImageEventFilter) (<-chan models.ImageEvent, func()) {
+	subscriber := &eventSubscriber{
+		filter: filter,
+		events: make(chan models.ImageEvent, eventSubscriberBuffer),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(subscriber.events)
+		return subscriber.events, func() {}
+	}
+	b.subscribers[subscriber] = struct{}{}
+
+	return subscriber.events, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(subscriber)
+	}
+}
+
+// publish delivers events to every subscriber whose filter they match, without blocking on slow ones
+func (b *eventBroker) publish(events []models.ImageEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for subscriber := range b.subscribers {
+		for _, event := range events {
+			if !subscriber.filter.Matches(event) {
+				continue
+			}
+
+			select {
+			case subscriber.events <- event:
+				continue
+			default:
+			}
+
+			log.Printf("Image event stream subscriber fell %d events behind, disconnecting", eventSubscriberBuffer)
+			b.remove(subscriber)
+			break
+		}
+	}
+}
+
+// close disconnects every subscriber and refuses new ones
+func (b *eventBroker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for subscriber := range b.subscribers {
+		b.remove(subscriber)
+	}
+}
+
+// remove closes a subscriber's channel once, the caller holds mu
+func (b *eventBroker) remove(subscriber *eventSubscriber) {
+	if _, found := b.subscribers[subscriber]; !found {
+		return
+	}
+
+	delete(b.subscribers, subscriber)
+	close(subscriber.events)
+}
+
+// SubscribeImageEvents streams the image events matching filter as they are persisted
+// Call the returned function to unsubscribe; the channel is also closed when the subscriber falls behind or on shutdown
+func (s *ImageService) SubscribeImageEvents(filter models.ImageEventFilter) (<-chan models.ImageEvent, func()) {
+	return s.broker.subscribe(filter)
+}
+
+// CloseEventStreams disconnects every image event stream so the server can shut down
+func (s *ImageService) CloseEventStreams() {
+	s.broker.close()
+}
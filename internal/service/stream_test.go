@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/huseyinbabal/kubetag/internal/k8s"
+	"github.com/huseyinbabal/kubetag/internal/mocks"
+	"github.com/huseyinbabal/kubetag/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// receive drains the events already buffered on a subscription
+func receive(events <-chan models.ImageEvent) (received []models.ImageEvent, open bool) {
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return received, false
+			}
+			received = append(received, event)
+		default:
+			return received, true
+		}
+	}
+}
+
+func TestEventBroker(t *testing.T) {
+	t.Run("delivers matching events", func(t *testing.T) {
+		broker := newEventBroker()
+		events, unsubscribe := broker.subscribe(models.ImageEventFilter{Namespace: "default", ImageName: "nginx"})
+		defer unsubscribe()
+
+		broker.publish([]models.ImageEvent{
+			{ID: 1, Namespace: "default", ImageName: "nginx"},
+			{ID: 2, Namespace: "staging", ImageName: "nginx"},
+			{ID: 3, Namespace: "default", ImageName: "redis"},
+			{ID: 4, Namespace: "default", OldImageName: "nginx"},
+		})
+
+		received, open := receive(events)
+		if !open {
+			t.Fatal("Expected the subscription to stay open")
+		}
+		if len(received) != 2 || received[0].ID != 1 || received[1].ID != 4 {
+			t.Errorf("Expected events 1 and 4, got %+v", received)
+		}
+	})
+
+	t.Run("disconnects subscribers that fall behind", func(t *testing.T) {
+		broker := newEventBroker()
+		slow, unsubscribe := broker.subscribe(models.ImageEventFilter{})
+		defer unsubscribe()
+
+		flood := make([]models.ImageEvent, eventSubscriberBuffer+1)
+		for i := range flood {
+			flood[i].ID = uint(i + 1)
+		}
+		broker.publish(flood)
+
+		received, open := receive(slow)
+		if open {
+			t.Error("Expected the slow subscriber to be disconnected")
+		}
+		if len(received) != eventSubscriberBuffer {
+			t.Errorf("Expected the %d buffered events before the disconnect, got %d", eventSubscriberBuffer, len(received))
+		}
+	})
+
+	t.Run("unsubscribe and close end subscriptions", func(t *testing.T) {
+		broker := newEventBroker()
+		first, unsubscribe := broker.subscribe(models.ImageEventFilter{})
+		second, _ := broker.subscribe(models.ImageEventFilter{})
+
+		unsubscribe()
+		unsubscribe()
+		if _, open := receive(first); open {
+			t.Error("Expected unsubscribe to close the subscription")
+		}
+
+		broker.close()
+		if _, open := receive(second); open {
+			t.Error("Expected close to end every subscription")
+		}
+
+		late, _ := broker.subscribe(models.ImageEventFilter{})
+		if _, open := receive(late); open {
+			t.Error("Expected subscriptions after close to be closed at once")
+		}
+	})
+}
+
+func TestAppendImageEventsStreams(t *testing.T) {
+	event := k8s.ImageEvent{Type: k8s.EventTypeAdd, ImageName: "nginx", ImageTag: "1.25", Namespace: "default"}
+
+	t.Run("streams persisted events with their IDs", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().AppendImageEvents(mock.Anything).
+			Run(func(events []models.ImageEvent) { events[0].ID = 7 }).
+			Return(nil).Once()
+
+		svc := NewImageService(mockRepo, nil)
+		events, unsubscribe := svc.SubscribeImageEvents(models.ImageEventFilter{})
+		defer unsubscribe()
+
+		if err := svc.appendImageEvents(event); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		received, _ := receive(events)
+		if len(received) != 1 || received[0].ID != 7 || received[0].NewTag != "1.25" {
+			t.Errorf("Expected event 7 for 1.25, got %+v", received)
+		}
+	})
+
+	t.Run("does not stream events that failed to persist", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().AppendImageEvents(mock.Anything).Return(errors.New("database error")).Once()
+
+		svc := NewImageService(mockRepo, nil)
+		events, unsubscribe := svc.SubscribeImageEvents(models.ImageEventFilter{})
+		defer unsubscribe()
+
+		if err := svc.appendImageEvents(event); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+
+		if received, _ := receive(events); len(received) != 0 {
+			t.Errorf("Expected no streamed events, got %+v", received)
+		}
+	})
+}
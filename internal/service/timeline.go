@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+)
+
+// GetResourceTimeline retrieves the image versions a workload ran, oldest first, with rollbacks flagged
+func (s *ImageService) GetResourceTimeline(ctx context.Context, namespace, resourceType, resourceName string) (*models.ResourceTimeline, error) {
+	imageTags, err := s.repo.GetResourceImageTags(resourceType, resourceName, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resource timeline: %w", err)
+	}
+
+	timeline := &models.ResourceTimeline{
+		ResourceType: resourceType,
+		ResourceName: resourceName,
+		Namespace:    namespace,
+		Entries:      buildTimeline(imageTags, time.Now().UTC()),
+	}
+	for _, entry := range timeline.Entries {
+		if entry.Rollback {
+			timeline.Rollbacks++
+		}
+	}
+
+	return timeline, nil
+}
+
+// buildTimeline turns the image tag rows of a workload into uninterrupted periods per container
+// Every row is one interval a version was in use, a rollback starts a new row
+func buildTimeline(imageTags []models.ImageTag, now time.Time) []models.TimelineEntry {
+	byContainer := make(map[string][]models.ImageTag)
+	for _, it := range imageTags {
+		byContainer[it.ContainerName] = append(byContainer[it.ContainerName], it)
+	}
+
+	entries := []models.TimelineEntry{}
+	for container, rows := range byContainer {
+		entries = append(entries, containerTimeline(container, rows, now)...)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].Start.Equal(entries[j].Start) {
+			return entries[i].Start.Before(entries[j].Start)
+		}
+		return entries[i].Container < entries[j].Container
+	})
+
+	return entries
+}
+
+// containerTimeline turns the rows of a single container into periods, oldest first
+// A row still open when a later one starts, e.g. one the informers missed the replacement of, ends there
+func containerTimeline(container string, rows []models.ImageTag, now time.Time) []models.TimelineEntry {
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].FirstSeen.Equal(rows[j].FirstSeen) {
+			return rows[i].FirstSeen.Before(rows[j].FirstSeen)
+		}
+		return rows[i].ID < rows[j].ID
+	})
+
+	var entries []models.TimelineEntry
+	seen := make(map[string]bool)
+
+	for i, it := range rows {
+		end := tagClosedAt(it)
+		if i+1 < len(rows) && (end == nil || end.After(rows[i+1].FirstSeen)) {
+			next := rows[i+1].FirstSeen
+			end = &next
+		}
+		if end != nil && !end.After(it.FirstSeen) {
+			continue
+		}
+
+		version := fmt.Sprintf("%s/%s|%s|%s", it.Image.Repository, it.Image.Name, it.Tag, it.Digest)
+		entry := models.TimelineEntry{
+			Container:  container,
+			ImageName:  it.Image.Name,
+			Repository: it.Image.Repository,
+			Tag:        it.Tag,
+			Digest:     it.Digest,
+			Start:      it.FirstSeen,
+			Duration:   int64(now.Sub(it.FirstSeen).Seconds()),
+			Active:     true,
+			Rollback:   seen[version],
+		}
+		if end != nil {
+			closeTimelineEntry(&entry, *end)
+		}
+
+		entries = append(entries, entry)
+		seen[version] = true
+	}
+
+	return entries
+}
+
+// tagClosedAt returns when a row was closed out, nil while it is active
+func tagClosedAt(it models.ImageTag) *time.Time {
+	if it.RemovedAt != nil {
+		return it.RemovedAt
+	}
+	if it.DeletedAt.Valid {
+		return &it.DeletedAt.Time
+	}
+	return nil
+}
+
+// closeTimelineEntry ends a period at the given instant
+func closeTimelineEntry(entry *models.TimelineEntry, end time.Time) {
+	entry.End = &end
+	entry.Duration = int64(end.Sub(entry.Start).Seconds())
+	entry.Active = false
+}
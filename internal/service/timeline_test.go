@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/mocks"
+	"github.com/huseyinbabal/kubetag/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := base.Add(10 * time.Hour)
+	at := func(hours int) time.Time { return base.Add(time.Duration(hours) * time.Hour) }
+	closed := func(hours int) *time.Time { t := at(hours); return &t }
+
+	row := func(id uint, container, tag string, first int, removed *time.Time) models.ImageTag {
+		it := models.ImageTag{
+			ID:            id,
+			Image:         models.Image{Name: "nginx", Repository: "docker.io"},
+			Tag:           tag,
+			ContainerName: container,
+			FirstSeen:     at(first),
+			RemovedAt:     removed,
+		}
+		if removed != nil {
+			it.DeletedAt = gorm.DeletedAt{Time: *removed, Valid: true}
+		}
+		return it
+	}
+
+	type span struct {
+		container string
+		tag       string
+		start     int
+		end       int // -1 while running
+		rollback  bool
+	}
+
+	tests := []struct {
+		name     string
+		rows     []models.ImageTag
+		expected []span
+	}{
+		{
+			name:     "single running version",
+			rows:     []models.ImageTag{row(1, "web", "1.24", 0, nil)},
+			expected: []span{{"web", "1.24", 0, -1, false}},
+		},
+		{
+			name: "upgrades",
+			rows: []models.ImageTag{
+				row(1, "web", "1.24", 0, closed(2)),
+				row(2, "web", "1.25", 2, closed(5)),
+				row(3, "web", "1.26", 5, nil),
+			},
+			expected: []span{{"web", "1.24", 0, 2, false}, {"web", "1.25", 2, 5, false}, {"web", "1.26", 5, -1, false}},
+		},
+		{
+			name: "rollback starts a new row",
+			rows: []models.ImageTag{
+				row(1, "web", "1.24", 0, closed(2)),
+				row(2, "web", "1.25", 2, closed(3)),
+				row(3, "web", "1.24", 3, nil),
+			},
+			expected: []span{{"web", "1.24", 0, 2, false}, {"web", "1.25", 2, 3, false}, {"web", "1.24", 3, -1, true}},
+		},
+		{
+			name: "double rollback keeps every period",
+			rows: []models.ImageTag{
+				row(1, "web", "1.24", 0, closed(2)),
+				row(2, "web", "1.25", 2, closed(3)),
+				row(3, "web", "1.24", 3, closed(5)),
+				row(4, "web", "1.25", 5, nil),
+			},
+			expected: []span{
+				{"web", "1.24", 0, 2, false}, {"web", "1.25", 2, 3, false},
+				{"web", "1.24", 3, 5, true}, {"web", "1.25", 5, -1, true},
+			},
+		},
+		{
+			name: "a row left open ends when the next one starts",
+			rows: []models.ImageTag{
+				row(1, "web", "1.24", 0, nil),
+				row(2, "web", "1.25", 2, nil),
+			},
+			expected: []span{{"web", "1.24", 0, 2, false}, {"web", "1.25", 2, -1, false}},
+		},
+		{
+			name: "deleted workload",
+			rows: []models.ImageTag{
+				row(1, "web", "1.24", 0, closed(2)),
+				row(2, "web", "1.25", 2, closed(4)),
+			},
+			expected: []span{{"web", "1.24", 0, 2, false}, {"web", "1.25", 2, 4, false}},
+		},
+		{
+			name: "containers are tracked separately",
+			rows: []models.ImageTag{
+				row(1, "web", "1.24", 0, closed(3)),
+				row(2, "proxy", "1.24", 1, nil),
+				row(3, "web", "1.25", 3, nil),
+			},
+			expected: []span{{"web", "1.24", 0, 3, false}, {"proxy", "1.24", 1, -1, false}, {"web", "1.25", 3, -1, false}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := buildTimeline(tt.rows, now)
+
+			if len(entries) != len(tt.expected) {
+				t.Fatalf("Expected %d entries, got %d: %+v", len(tt.expected), len(entries), entries)
+			}
+
+			for i, expected := range tt.expected {
+				entry := entries[i]
+				if entry.Container != expected.container || entry.Tag != expected.tag {
+					t.Errorf("Entry[%d]: Expected %s:%s, got %s:%s", i, expected.container, expected.tag, entry.Container, entry.Tag)
+				}
+				if !entry.Start.Equal(at(expected.start)) {
+					t.Errorf("Entry[%d]: Expected start %v, got %v", i, at(expected.start), entry.Start)
+				}
+				if entry.Rollback != expected.rollback {
+					t.Errorf("Entry[%d]: Expected rollback %t, got %t", i, expected.rollback, entry.Rollback)
+				}
+
+				end := now
+				if expected.end >= 0 {
+					end = at(expected.end)
+					if entry.End == nil || !entry.End.Equal(end) || entry.Active {
+						t.Errorf("Entry[%d]: Expected inactive entry ending at %v, got %v", i, end, entry.End)
+					}
+				} else if entry.End != nil || !entry.Active {
+					t.Errorf("Entry[%d]: Expected active entry, got end %v", i, entry.End)
+				}
+				if entry.Duration != int64(end.Sub(entry.Start).Seconds()) {
+					t.Errorf("Entry[%d]: Expected duration %v, got %ds", i, end.Sub(entry.Start), entry.Duration)
+				}
+			}
+		})
+	}
+}
+
+func TestGetResourceTimeline(t *testing.T) {
+	first := time.Now().UTC().Add(-2 * time.Hour)
+	removed := first.Add(time.Hour)
+
+	t.Run("counts rollbacks", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		upgraded := first.Add(time.Minute)
+		mockRepo.EXPECT().GetResourceImageTags("Deployment", "web", "default").Return([]models.ImageTag{
+			{ID: 1, Image: models.Image{Name: "nginx"}, Tag: "1.24", ContainerName: "web", FirstSeen: first,
+				RemovedAt: &upgraded, DeletedAt: gorm.DeletedAt{Time: upgraded, Valid: true}},
+			{ID: 2, Image: models.Image{Name: "nginx"}, Tag: "1.25", ContainerName: "web", FirstSeen: upgraded,
+				RemovedAt: &removed, DeletedAt: gorm.DeletedAt{Time: removed, Valid: true}},
+			{ID: 3, Image: models.Image{Name: "nginx"}, Tag: "1.24", ContainerName: "web", FirstSeen: removed},
+		}, nil).Once()
+
+		timeline, err := NewImageService(mockRepo, nil).GetResourceTimeline(context.Background(), "default", "Deployment", "web")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(timeline.Entries) != 3 {
+			t.Fatalf("Expected 3 entries, got %d", len(timeline.Entries))
+		}
+		if timeline.Rollbacks != 1 {
+			t.Errorf("Expected 1 rollback, got %d", timeline.Rollbacks)
+		}
+		if timeline.ResourceName != "web" || timeline.Namespace != "default" {
+			t.Errorf("Expected resource default/web, got %s/%s", timeline.Namespace, timeline.ResourceName)
+		}
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().GetResourceImageTags("Deployment", "web", "default").Return(nil, errors.New("database error")).Once()
+
+		if _, err := NewImageService(mockRepo, nil).GetResourceTimeline(context.Background(), "default", "Deployment", "web"); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/models"
+	"github.com/huseyinbabal/kubetag/internal/registry"
+)
+
+// TagLister lists the tags a registry has for an image
+type TagLister interface {
+	ListTags(ctx context.Context, repository, name string) ([]string, error)
+}
+
+// UpdateCheckResult counts the image tags checked by an update check run
+type UpdateCheckResult struct {
+	Checked  int // Tags compared with their registry
+	Outdated int // Tags with a newer version
+	Failed   int // Tags whose registry could not be listed or that are not versions
+}
+
+// SetTagLister registers the registry client image tags are checked for updates with
+func (s *ImageService) SetTagLister(lister TagLister) {
+	s.tagLister = lister
+}
+
+// CheckImageUpdates lists the registry tags of every image in use and records the newer versions of the tags in use
+func (s *ImageService) CheckImageUpdates(ctx context.Context) (*UpdateCheckResult, error) {
+	if s.tagLister == nil {
+		return nil, fmt.Errorf("failed to check image updates: registry client is not configured")
+	}
+
+	imageTags, err := s.repo.ListActiveImageTags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check image updates: %w", err)
+	}
+
+	// Each image is listed once for all of its tags in use
+	type imageKey struct{ repository, name string }
+	inUse := make(map[imageKey]map[string]bool)
+	for _, it := range imageTags {
+		// Images pinned only by digest have no tag to compare
+		if it.Tag == "" {
+			continue
+		}
+
+		key := imageKey{it.Image.Repository, it.Image.Name}
+		if inUse[key] == nil {
+			inUse[key] = make(map[string]bool)
+		}
+		inUse[key][it.Tag] = true
+	}
+
+	keys := make([]imageKey, 0, len(inUse))
+	for key := range inUse {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].repository != keys[j].repository {
+			return keys[i].repository < keys[j].repository
+		}
+		return keys[i].name < keys[j].name
+	})
+
+	result := &UpdateCheckResult{}
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return result, fmt.Errorf("failed to check image updates: %w", err)
+		}
+
+		available, listErr := s.tagLister.ListTags(ctx, key.repository, key.name)
+		checkedAt := time.Now().UTC()
+
+		tags := make([]string, 0, len(inUse[key]))
+		for tag := range inUse[key] {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+
+		var updates []models.ImageUpdate
+		for _, tag := range tags {
+			update := models.ImageUpdate{ImageName: key.name, Repository: key.repository, Tag: tag, CheckedAt: checkedAt}
+
+			compatible, latest, isVersion := registry.NewerTags(tag, available)
+			switch {
+			case listErr != nil:
+				update.Error = listErr.Error()
+				result.Failed++
+			case !isVersion:
+				update.Error = "tag is not a version"
+				result.Failed++
+			default:
+				update.LatestCompatible = compatible
+				update.Latest = latest
+				update.Outdated = latest != ""
+				result.Checked++
+				if update.Outdated {
+					result.Outdated++
+				}
+			}
+
+			updates = append(updates, update)
+		}
+
+		if err := s.repo.UpsertImageUpdates(updates); err != nil {
+			return result, fmt.Errorf("failed to check image updates: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// StartUpdateChecker checks the images in use for updates now and then every interval until the context is cancelled
+// Registries can be slow, so unlike reconciliation even the first check runs in the background
+func (s *ImageService) StartUpdateChecker(ctx context.Context, interval time.Duration) {
+	go func() {
+		s.runUpdateCheck(ctx)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.runUpdateCheck(ctx)
+			}
+		}
+	}()
+}
+
+// runUpdateCheck runs an update check and logs its outcome
+func (s *ImageService) runUpdateCheck(ctx context.Context) {
+	result, err := s.CheckImageUpdates(ctx)
+	if err != nil {
+		log.Printf("Error checking image updates: %v", err)
+		return
+	}
+
+	log.Printf("Image update check finished: %d checked, %d outdated, %d failed", result.Checked, result.Outdated, result.Failed)
+}
+
+// GetOutdatedImages returns the image tags in use that have a newer version, with the containers using them
+// An empty namespace covers every namespace; compatibleOnly leaves out tags without a compatible update
+func (s *ImageService) GetOutdatedImages(ctx context.Context, namespace string, compatibleOnly bool) (*models.OutdatedImagesResponse, error) {
+	updates, err := s.repo.ListImageUpdates()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outdated images: %w", err)
+	}
+
+	imageTags, err := s.repo.ListActiveImageTags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outdated images: %w", err)
+	}
+
+	// Updates come ordered by image and tag, the response keeps that order
+	outdated := make(map[string]*models.OutdatedImage)
+	var order []string
+	for _, update := range updates {
+		if !update.Outdated || (compatibleOnly && update.LatestCompatible == "") {
+			continue
+		}
+
+		key := update.Repository + "/" + update.ImageName + ":" + update.Tag
+		outdated[key] = &models.OutdatedImage{
+			Name:             update.ImageName,
+			Repository:       update.Repository,
+			Tag:              update.Tag,
+			LatestCompatible: update.LatestCompatible,
+			Latest:           update.Latest,
+			CheckedAt:        update.CheckedAt,
+		}
+		order = append(order, key)
+	}
+
+	for _, it := range imageTags {
+		if namespace != "" && it.Namespace != namespace {
+			continue
+		}
+
+		image, found := outdated[it.Image.Repository+"/"+it.Image.Name+":"+it.Tag]
+		if !found {
+			continue
+		}
+		image.Resources = append(image.Resources, models.ImageResource{
+			ResourceType: it.ResourceType,
+			ResourceName: it.ResourceName,
+			Namespace:    it.Namespace,
+			Container:    it.ContainerName,
+		})
+	}
+
+	response := &models.OutdatedImagesResponse{Images: []models.OutdatedImage{}}
+	for _, key := range order {
+		image := outdated[key]
+		// Checked while in use, but no longer used in the namespaces asked for
+		if len(image.Resources) == 0 {
+			continue
+		}
+
+		sort.Slice(image.Resources, func(i, j int) bool {
+			a, b := image.Resources[i], image.Resources[j]
+			if a.Namespace != b.Namespace {
+				return a.Namespace < b.Namespace
+			}
+			if a.ResourceType != b.ResourceType {
+				return a.ResourceType < b.ResourceType
+			}
+			if a.ResourceName != b.ResourceName {
+				return a.ResourceName < b.ResourceName
+			}
+			return a.Container < b.Container
+		})
+		response.Images = append(response.Images, *image)
+	}
+	response.Total = len(response.Images)
+
+	return response, nil
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/huseyinbabal/kubetag/internal/mocks"
+	"github.com/huseyinbabal/kubetag/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeTagLister serves fixed tags per image and counts the listings
+type fakeTagLister struct {
+	tags  map[string][]string // By repository/name
+	err   error
+	calls map[string]int
+}
+
+func (f *fakeTagLister) ListTags(ctx context.Context, repository, name string) ([]string, error) {
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.calls[repository+"/"+name]++
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.tags[repository+"/"+name], nil
+}
+
+func activeTag(repository, name, tag, namespace, resource, container string) models.ImageTag {
+	return models.ImageTag{
+		Image:         models.Image{Name: name, Repository: repository},
+		Tag:           tag,
+		ResourceType:  "Deployment",
+		ResourceName:  resource,
+		Namespace:     namespace,
+		ContainerName: container,
+	}
+}
+
+func TestCheckImageUpdates(t *testing.T) {
+	t.Run("records newer tags once per image", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().ListActiveImageTags().Return([]models.ImageTag{
+			activeTag("docker.io", "nginx", "1.25.3", "default", "web", "nginx"),
+			activeTag("docker.io", "nginx", "1.25.3", "staging", "web", "nginx"),
+			activeTag("docker.io", "nginx", "1.27.2", "default", "proxy", "nginx"),
+			activeTag("docker.io", "nginx", "latest", "default", "edge", "nginx"),
+			activeTag("docker.io", "redis", "", "default", "cache", "redis"),
+		}, nil).Once()
+
+		var upserted []models.ImageUpdate
+		mockRepo.EXPECT().UpsertImageUpdates(mock.Anything).
+			Run(func(updates []models.ImageUpdate) { upserted = append(upserted, updates...) }).
+			Return(nil).Once()
+
+		lister := &fakeTagLister{tags: map[string][]string{
+			"docker.io/nginx": {"1.25.3", "1.27.2", "2.0.1", "latest"},
+		}}
+		svc := NewImageService(mockRepo, nil)
+		svc.SetTagLister(lister)
+
+		result, err := svc.CheckImageUpdates(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if *result != (UpdateCheckResult{Checked: 2, Outdated: 2, Failed: 1}) {
+			t.Errorf("Expected 2 checked, 2 outdated and 1 failed, got %+v", result)
+		}
+		if lister.calls["docker.io/nginx"] != 1 || len(lister.calls) != 1 {
+			t.Errorf("Expected nginx to be listed once and digest-only images skipped, got %v", lister.calls)
+		}
+
+		if len(upserted) != 3 {
+			t.Fatalf("Expected 3 updates, got %+v", upserted)
+		}
+		expected := []models.ImageUpdate{
+			{Tag: "1.25.3", LatestCompatible: "1.27.2", Latest: "2.0.1", Outdated: true},
+			{Tag: "1.27.2", LatestCompatible: "", Latest: "2.0.1", Outdated: true},
+			{Tag: "latest", Error: "tag is not a version"},
+		}
+		for i, update := range upserted {
+			if update.Tag != expected[i].Tag || update.LatestCompatible != expected[i].LatestCompatible ||
+				update.Latest != expected[i].Latest || update.Outdated != expected[i].Outdated || update.Error != expected[i].Error {
+				t.Errorf("Expected %+v, got %+v", expected[i], update)
+			}
+			if update.ImageName != "nginx" || update.Repository != "docker.io" || update.CheckedAt.IsZero() {
+				t.Errorf("Expected a checked docker.io/nginx update, got %+v", update)
+			}
+		}
+	})
+
+	t.Run("records registry errors", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().ListActiveImageTags().Return([]models.ImageTag{
+			activeTag("ghcr.io/acme", "api", "1.0.0", "default", "api", "api"),
+		}, nil).Once()
+
+		var upserted []models.ImageUpdate
+		mockRepo.EXPECT().UpsertImageUpdates(mock.Anything).
+			Run(func(updates []models.ImageUpdate) { upserted = updates }).
+			Return(nil).Once()
+
+		svc := NewImageService(mockRepo, nil)
+		svc.SetTagLister(&fakeTagLister{err: errors.New("registry responded with status 401")})
+
+		result, err := svc.CheckImageUpdates(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.Failed != 1 || result.Checked != 0 {
+			t.Errorf("Expected 1 failed tag, got %+v", result)
+		}
+		if len(upserted) != 1 || upserted[0].Error != "registry responded with status 401" || upserted[0].Outdated {
+			t.Errorf("Expected the registry error to be recorded, got %+v", upserted)
+		}
+	})
+
+	t.Run("fails without a registry client", func(t *testing.T) {
+		svc := NewImageService(mocks.NewMockImageRepository(t), nil)
+		if _, err := svc.CheckImageUpdates(context.Background()); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+	})
+
+	t.Run("returns repository errors", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().ListActiveImageTags().Return([]models.ImageTag{
+			activeTag("docker.io", "nginx", "1.25.3", "default", "web", "nginx"),
+		}, nil).Once()
+		mockRepo.EXPECT().UpsertImageUpdates(mock.Anything).Return(errors.New("database error")).Once()
+
+		svc := NewImageService(mockRepo, nil)
+		svc.SetTagLister(&fakeTagLister{tags: map[string][]string{"docker.io/nginx": {"1.25.4"}}})
+
+		if _, err := svc.CheckImageUpdates(context.Background()); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+	})
+}
+
+func TestGetOutdatedImages(t *testing.T) {
+	checkedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	updates := []models.ImageUpdate{
+		{ImageName: "nginx", Repository: "docker.io", Tag: "1.25.3", LatestCompatible: "1.27.2", Latest: "2.0.1", Outdated: true, CheckedAt: checkedAt},
+		{ImageName: "nginx", Repository: "docker.io", Tag: "1.27.2", Latest: "2.0.1", Outdated: true, CheckedAt: checkedAt},
+		{ImageName: "nginx", Repository: "docker.io", Tag: "2.0.1", CheckedAt: checkedAt},
+		{ImageName: "redis", Repository: "docker.io", Tag: "7.0", LatestCompatible: "7.2", Latest: "7.2", Outdated: true, CheckedAt: checkedAt},
+	}
+	imageTags := []models.ImageTag{
+		activeTag("docker.io", "nginx", "1.25.3", "staging", "web", "nginx"),
+		activeTag("docker.io", "nginx", "1.25.3", "default", "web", "nginx"),
+		activeTag("docker.io", "nginx", "1.27.2", "default", "proxy", "nginx"),
+		activeTag("docker.io", "nginx", "2.0.1", "default", "edge", "nginx"),
+		activeTag("docker.io", "redis", "7.0", "staging", "cache", "redis"),
+	}
+
+	tests := []struct {
+		name           string
+		namespace      string
+		compatibleOnly bool
+		expected       []string // image:tag with the namespaces of its resources
+	}{
+		{name: "every namespace", expected: []string{"nginx:1.25.3 default staging", "nginx:1.27.2 default", "redis:7.0 staging"}},
+		{name: "one namespace", namespace: "default", expected: []string{"nginx:1.25.3 default", "nginx:1.27.2 default"}},
+		{name: "compatible updates only", compatibleOnly: true, expected: []string{"nginx:1.25.3 default staging", "redis:7.0 staging"}},
+		{name: "nothing outdated", namespace: "production", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := mocks.NewMockImageRepository(t)
+			mockRepo.EXPECT().ListImageUpdates().Return(updates, nil).Once()
+			mockRepo.EXPECT().ListActiveImageTags().Return(imageTags, nil).Once()
+
+			svc := NewImageService(mockRepo, nil)
+			response, err := svc.GetOutdatedImages(context.Background(), tt.namespace, tt.compatibleOnly)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			result := []string{}
+			for _, image := range response.Images {
+				entry := image.Name + ":" + image.Tag
+				for _, resource := range image.Resources {
+					entry += " " + resource.Namespace
+				}
+				result = append(result, entry)
+			}
+
+			if response.Total != len(tt.expected) || len(result) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, result)
+			}
+			for i := range result {
+				if result[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, result)
+					break
+				}
+			}
+		})
+	}
+
+	t.Run("returns repository errors", func(t *testing.T) {
+		mockRepo := mocks.NewMockImageRepository(t)
+		mockRepo.EXPECT().ListImageUpdates().Return(nil, errors.New("database error")).Once()
+
+		svc := NewImageService(mockRepo, nil)
+		if _, err := svc.GetOutdatedImages(context.Background(), "", false); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+	})
+}